@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/spf13/pflag"
+)
+
+// annotationOptions are the annotations of the pushed manifest, its config
+// and its files.
+type annotationOptions struct {
+	annotations         []string
+	annotationFile      string
+	manifestAnnotations string
+}
+
+func (opts *annotationOptions) applyFlags(fs *pflag.FlagSet) {
+	fs.StringArrayVarP(&opts.annotations, "annotation", "a", nil, "manifest annotation in the form of key=value, overriding the annotation file")
+	fs.StringVarP(&opts.annotationFile, "annotation-file", "", "", `annotation file, keyed by "$manifest", "$config" or the file names`)
+	fs.StringVarP(&opts.manifestAnnotations, "manifest-annotations", "", "", "manifest annotation file")
+	fs.MarkDeprecated("manifest-annotations", "use --annotation-file instead")
+}
+
+// load returns the annotations keyed by "$manifest", "$config" or the names
+// of the files referenced by fileRefs. The annotations of the flags are merged
+// into the manifest annotations. The keys matching nothing are reported to w.
+func (opts *annotationOptions) load(fileRefs []string, w io.Writer) (map[string]map[string]string, error) {
+	path := opts.annotationFile
+	if opts.manifestAnnotations != "" {
+		if path != "" {
+			return nil, errors.New("--manifest-annotations cannot be used with --annotation-file")
+		}
+		path = opts.manifestAnnotations
+	}
+	var annotations map[string]map[string]string
+	if path != "" {
+		if err := decodeJSON(path, &annotations); err != nil {
+			return nil, fmt.Errorf("invalid annotation file %s: %v", path, err)
+		}
+		// warn on the keys matching nothing, which are likely typos
+		known := map[string]bool{
+			annotationManifest: true,
+			annotationConfig:   true,
+		}
+		for _, fileRef := range fileRefs {
+			filename, _ := parseFileRef(fileRef, "")
+			known[filename] = true
+		}
+		var unknown []string
+		for key := range annotations {
+			if !known[key] {
+				unknown = append(unknown, key)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			fmt.Fprintf(w, "WARNING: Ignoring the annotations of %q in %s: no such files\n", unknown, path)
+		}
+	}
+
+	flags, err := parseAnnotationFlags(opts.annotations)
+	if err != nil {
+		return nil, err
+	}
+	if len(flags) > 0 {
+		if annotations == nil {
+			annotations = make(map[string]map[string]string)
+		}
+		annotations[annotationManifest] = mergeAnnotations(annotations[annotationManifest], flags)
+	}
+	return annotations, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotationOptionsLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oras_annotation_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "annotations.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{
+		"$manifest": {"purpose": "test"},
+		"$config": {"kind": "config"},
+		"hello.txt": {"lang": "en"},
+		"typo.txt": {"lang": "fr"}
+	}`), 0644))
+
+	for _, tc := range []struct {
+		name     string
+		opts     annotationOptions
+		fileRefs []string
+		want     map[string]map[string]string
+		warning  string
+		err      string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "flags",
+			opts: annotationOptions{annotations: []string{"purpose=flag", "owner=me"}},
+			want: map[string]map[string]string{
+				annotationManifest: {"purpose": "flag", "owner": "me"},
+			},
+		},
+		{
+			name:     "file",
+			opts:     annotationOptions{annotationFile: path},
+			fileRefs: []string{"hello.txt:text/plain", "typo.txt"},
+			want: map[string]map[string]string{
+				annotationManifest: {"purpose": "test"},
+				annotationConfig:   {"kind": "config"},
+				"hello.txt":        {"lang": "en"},
+				"typo.txt":         {"lang": "fr"},
+			},
+		},
+		{
+			name:     "flags override the file",
+			opts:     annotationOptions{annotationFile: path, annotations: []string{"purpose=flag"}},
+			fileRefs: []string{"hello.txt", "typo.txt"},
+			want: map[string]map[string]string{
+				annotationManifest: {"purpose": "flag"},
+				annotationConfig:   {"kind": "config"},
+				"hello.txt":        {"lang": "en"},
+				"typo.txt":         {"lang": "fr"},
+			},
+		},
+		{
+			name:     "unknown keys",
+			opts:     annotationOptions{manifestAnnotations: path},
+			fileRefs: []string{"hello.txt"},
+			want: map[string]map[string]string{
+				annotationManifest: {"purpose": "test"},
+				annotationConfig:   {"kind": "config"},
+				"hello.txt":        {"lang": "en"},
+				"typo.txt":         {"lang": "fr"},
+			},
+			warning: `WARNING: Ignoring the annotations of ["typo.txt"]`,
+		},
+		{
+			name: "both files",
+			opts: annotationOptions{annotationFile: path, manifestAnnotations: path},
+			err:  "--manifest-annotations cannot be used with --annotation-file",
+		},
+		{
+			name: "missing file",
+			opts: annotationOptions{annotationFile: filepath.Join(dir, "missing.json")},
+			err:  "invalid annotation file",
+		},
+		{
+			name: "invalid flag",
+			opts: annotationOptions{annotations: []string{"purpose"}},
+			err:  "purpose",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var warnings bytes.Buffer
+			got, err := tc.opts.load(tc.fileRefs, &warnings)
+			if tc.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			if tc.warning == "" {
+				assert.Empty(t, warnings.String())
+			} else {
+				assert.Contains(t, warnings.String(), tc.warning)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/deislabs/oras/pkg/content"
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/oras"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/remotes/docker"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type attachOptions struct {
+	targetRef              string
+	fileRefs               []string
+	stdinName              string
+	artifactType           string
+	annotation             annotationOptions
+	pathValidationDisabled bool
+	imageManifest          bool
+	concurrency            int
+	sha256Impl             string
+	platform               platformOptions
+	idempotent             bool
+	verbose                bool
+	progress               progressOptions
+	format                 formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func attachCmd() *cobra.Command {
+	var opts attachOptions
+	cmd := &cobra.Command{
+		Use:   "attach <name:tag|name@digest> file[:type] [file...]",
+		Short: "Attach files as an artifact referring to an existing manifest",
+		Long: `Attach files as an artifact referring to an existing manifest
+
+The artifact is pushed as an artifact manifest with the subject set, or as an
+image manifest if the registry does not support artifact manifests. The
+referrers tag schema is updated on registries without the referrers API.
+
+Example - Attach a signature to an image:
+  oras attach --artifact-type application/vnd.example.signature localhost:5000/hello:latest hello.sig
+
+Example - Attach an SBOM with annotations:
+  oras attach --artifact-type application/spdx+json --annotation-file annotations.json localhost:5000/hello:latest sbom.spdx.json
+
+Example - Attach a signature with an annotation on the manifest:
+  oras attach --artifact-type application/vnd.example.signature --annotation org.example.signer=ci localhost:5000/hello:latest hello.sig
+
+Example - Attach a signature generated by a command and streamed from stdin as "hello.sig":
+  sign hello | oras attach --artifact-type application/vnd.example.signature --stdin-name hello.sig localhost:5000/hello:latest -
+
+Example - Attach a signature unless the same signature is already attached:
+  oras attach --idempotent --artifact-type application/vnd.example.signature localhost:5000/hello:latest hello.sig
+
+Example - Attach a signature to the linux/amd64 manifest of a multi-platform image:
+  oras attach --artifact-type application/vnd.example.signature --platform linux/amd64 localhost:5000/hello:latest hello.sig
+`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			opts.fileRefs = args[1:]
+			return runAttach(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.artifactType, "artifact-type", "", "", "type of the attached artifact")
+	cmd.Flags().StringVarP(&opts.stdinName, "stdin-name", "", "stdin", "name of the file read from stdin when referenced as -")
+	opts.annotation.applyFlags(cmd.Flags())
+	cmd.Flags().BoolVarP(&opts.pathValidationDisabled, "disable-path-validation", "", false, "skip path validation")
+	cmd.Flags().BoolVarP(&opts.imageManifest, "image-manifest", "", false, "push an image manifest without trying an artifact manifest first")
+	cmd.Flags().IntVarP(&opts.concurrency, "concurrency", "", 3, "maximum number of blobs uploaded in parallel")
+	cmd.Flags().StringVarP(&opts.sha256Impl, "sha256-impl", "", content.DefaultSHA256, "sha256 implementation computing the digests of the files: "+strings.Join(content.SHA256Implementations(), ", "))
+	opts.platform.applyFlags(cmd.Flags())
+	cmd.Flags().BoolVarP(&opts.idempotent, "idempotent", "", false, "do nothing if the remote state already matches, and report whether anything changed")
+	cmd.Flags().BoolVarP(&opts.verbose, "verbose", "v", false, "verbose output")
+	opts.progress.applyFlags(cmd.Flags())
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runAttach(opts attachOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else if !opts.verbose {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if opts.artifactType == "" {
+		return errors.New("artifact type is required, please specify --artifact-type")
+	}
+	if err := checkWritable(opts.targetRef); err != nil {
+		return err
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+
+	// load files
+	var (
+		store    = content.NewFileStore("")
+		hosts    = opts.remote.registryHosts()
+		client   = registry.NewClient(hosts)
+		pushOpts = []oras.PushOpt{
+			oras.WithArtifactType(opts.artifactType),
+			oras.WithPushConcurrency(opts.concurrency),
+		}
+	)
+	defer store.Close()
+	newHash, err := content.SHA256(opts.sha256Impl)
+	if err != nil {
+		return err
+	}
+	store.NewHash = newHash
+	if opts.progress.summary() && !opts.format.enabled() {
+		pushOpts = append(pushOpts, oras.WithPushStatusTrack(os.Stdout))
+	}
+	if matcher, err := opts.platform.matcher(); err != nil {
+		return err
+	} else if matcher != nil {
+		pushOpts = append(pushOpts, oras.WithSubjectPlatform(matcher))
+	}
+	annotations, err := opts.annotation.load(opts.fileRefs, os.Stderr)
+	if err != nil {
+		return err
+	}
+	if value, ok := annotations[annotationConfig]; ok {
+		pushOpts = append(pushOpts, oras.WithConfigAnnotations(value))
+	}
+	if value, ok := annotations[annotationManifest]; ok {
+		pushOpts = append(pushOpts, oras.WithManifestAnnotations(value))
+	}
+	if opts.pathValidationDisabled {
+		pushOpts = append(pushOpts, oras.WithNameValidation(nil))
+	}
+	if !opts.imageManifest {
+		pushOpts = append(pushOpts, oras.WithArtifactManifest(client))
+	}
+	var changed *bool
+	if opts.idempotent {
+		changed = newTrue()
+		pushOpts = append(pushOpts, oras.WithIdempotent(func(ocispec.Descriptor) {
+			*changed = false
+		}))
+	}
+	stdinPath, err := spoolStdin(opts.fileRefs)
+	if err != nil {
+		return err
+	}
+	if stdinPath != "" {
+		defer os.Remove(stdinPath)
+	}
+	files, err := loadFiles(store, annotations, &pushOptions{
+		fileRefs:  opts.fileRefs,
+		stdinName: opts.stdinName,
+		stdinPath: stdinPath,
+		verbose:   opts.verbose,
+		format:    opts.format,
+	})
+	if err != nil {
+		return err
+	}
+
+	// ready to attach
+	resolver := docker.NewResolver(docker.ResolverOptions{
+		Hosts: hosts,
+	})
+	desc, subject, err := oras.Attach(ctx, resolver, opts.targetRef, store, files, pushOpts...)
+	if err != nil {
+		return err
+	}
+	// keep the artifact discoverable on registries without the referrers API
+	if err := client.AddReferrer(ctx, opts.targetRef, subject, registry.Referrer{
+		Descriptor:   desc,
+		ArtifactType: opts.artifactType,
+	}); err != nil {
+		return err
+	}
+
+	if opts.format.enabled() {
+		return opts.format.write("", artifactResult{
+			Reference:  opts.targetRef,
+			Descriptor: desc,
+			Subject:    &subject,
+			Files:      newFileResults("", files),
+			Changed:    changed,
+		})
+	}
+	if !opts.progress.summary() {
+		opts.progress.printReference(os.Stdout, opts.targetRef, false, desc.Digest)
+		return nil
+	}
+	if changed != nil && !*changed {
+		fmt.Println("No changes to", opts.targetRef)
+	} else {
+		fmt.Println("Attached to", opts.targetRef)
+	}
+	fmt.Println("Digest:", desc.Digest)
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	iauth "github.com/deislabs/oras/pkg/auth"
+
+	"github.com/spf13/cobra"
+)
+
+func authCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth [command]",
+		Short: "Credential operations",
+	}
+	cmd.AddCommand(authExportCmd(), authImportCmd())
+	return cmd
+}
+
+// credentialBundle is the content of the exported credentials, which is
+// encrypted with age.
+type credentialBundle struct {
+	Credentials []iauth.Credential `json:"credentials"`
+}
+
+// readPassphrase reads the passphrase from stdin, or prompts for it on stderr
+// so that the prompt does not mix with the output.
+func readPassphrase(fromStdin, confirm bool) (string, error) {
+	if fromStdin {
+		passphrase, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(passphrase), "\r\n"), nil
+	}
+	passphrase, err := readLineTo(os.Stderr, "Passphrase: ", true)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("passphrase required")
+	}
+	if confirm {
+		again, err := readLineTo(os.Stderr, "Confirm passphrase: ", true)
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
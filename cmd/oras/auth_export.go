@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type authExportOptions struct {
+	hostnames       []string
+	output          string
+	recipients      []string
+	recipientsFiles []string
+	fromStdin       bool
+
+	debug           bool
+	configs         []string
+	credentialStore string
+}
+
+func authExportCmd() *cobra.Command {
+	var opts authExportOptions
+	cmd := &cobra.Command{
+		Use:   "export [registry...]",
+		Short: "Export stored credentials encrypted",
+		Long: `Export stored credentials encrypted
+
+The credentials of the registries, or all the stored credentials if no registry
+is given, are encrypted with age (https://age-encryption.org) for the
+recipients, or with a passphrase if there is no recipient. The output is
+ASCII armored, to be imported on another machine with "oras auth import".
+
+Example - Export all the credentials encrypted with a passphrase:
+  oras auth export -o credentials.age
+
+Example - Export the credentials of a registry for an age recipient:
+  oras auth export -r age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p -o credentials.age localhost:5000
+
+Example - Export the credentials for the age recipients listed in a file:
+  oras auth export -R recipients.txt -o credentials.age
+
+Example - Export all the credentials encrypted with a passphrase from stdin:
+  echo $PASSPHRASE | oras auth export --passphrase-stdin -o credentials.age
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.hostnames = args
+			return runAuthExport(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "-", "output file, or - for stdout")
+	cmd.Flags().StringArrayVarP(&opts.recipients, "recipient", "r", nil, "encrypt for the age recipient")
+	cmd.Flags().StringArrayVarP(&opts.recipientsFiles, "recipients-file", "R", nil, "encrypt for the age recipients listed in the file")
+	cmd.Flags().BoolVarP(&opts.fromStdin, "passphrase-stdin", "", false, "read the passphrase from stdin")
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	cmd.Flags().StringArrayVarP(&opts.configs, "config", "c", nil, "auth config path")
+	cmd.Flags().StringVarP(&opts.credentialStore, "credential-store", "", "", "credential store, docker or keyring, defaulting to the credentialStore of the oras config or docker")
+	return cmd
+}
+
+func runAuthExport(opts authExportOptions) error {
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+
+	var recipients []age.Recipient
+	for _, recipient := range opts.recipients {
+		parsed, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return fmt.Errorf("invalid recipient %q: %v", recipient, err)
+		}
+		recipients = append(recipients, parsed)
+	}
+	for _, path := range opts.recipientsFiles {
+		parsed, err := parseRecipientsFile(path)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, parsed...)
+	}
+	if len(recipients) > 0 && opts.fromStdin {
+		return errors.New("--passphrase-stdin cannot be used with --recipient or --recipients-file")
+	}
+
+	cli, err := newAuthClient(opts.credentialStore, opts.configs...)
+	if err != nil {
+		return err
+	}
+	creds, err := cli.Credentials(context.Background(), opts.hostnames...)
+	if err != nil {
+		return err
+	}
+	if len(creds) == 0 {
+		return errors.New("no credentials stored")
+	}
+
+	if len(recipients) == 0 {
+		passphrase, err := readPassphrase(opts.fromStdin, !opts.fromStdin)
+		if err != nil {
+			return err
+		}
+		recipient, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	var out io.Writer = os.Stdout
+	if opts.output != "-" {
+		file, err := os.OpenFile(opts.output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	armored := armor.NewWriter(out)
+	encrypted, err := age.Encrypt(armored, recipients...)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(encrypted).Encode(credentialBundle{
+		Credentials: creds,
+	}); err != nil {
+		return err
+	}
+	if err := encrypted.Close(); err != nil {
+		return err
+	}
+	if err := armored.Close(); err != nil {
+		return err
+	}
+	if opts.output != "-" {
+		fmt.Printf("Exported credentials of %d registries\n", len(creds))
+	}
+	return nil
+}
+
+func parseRecipientsFile(path string) ([]age.Recipient, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	recipients, err := age.ParseRecipients(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipients file %s: %v", path, err)
+	}
+	return recipients, nil
+}
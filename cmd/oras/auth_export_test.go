@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	iauth "github.com/deislabs/oras/pkg/auth"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeAuthConfig writes a docker config file holding the credentials in the
+// form of hostname: username:password.
+func writeAuthConfig(t *testing.T, path string, creds map[string]string) {
+	auths := make(map[string]map[string]string)
+	for hostname, cred := range creds {
+		auths[hostname] = map[string]string{
+			"auth": base64.StdEncoding.EncodeToString([]byte(cred)),
+		}
+	}
+	content, err := json.Marshal(map[string]interface{}{"auths": auths})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, content, 0600))
+}
+
+// withStdin replaces stdin with the content, and returns a function restoring
+// it.
+func withStdin(t *testing.T, content string) func() {
+	file, err := ioutil.TempFile("", "oras_stdin")
+	require.NoError(t, err)
+	_, err = file.WriteString(content)
+	require.NoError(t, err)
+	_, err = file.Seek(0, 0)
+	require.NoError(t, err)
+	old := os.Stdin
+	os.Stdin = file
+	return func() {
+		os.Stdin = old
+		file.Close()
+		os.Remove(file.Name())
+	}
+}
+
+// runAuthCmd runs the auth command with the arguments.
+func runAuthCmd(args ...string) error {
+	cmd := authCmd()
+	cmd.SetArgs(args)
+	cmd.SetOutput(ioutil.Discard)
+	return cmd.Execute()
+}
+
+// decryptBundle decrypts the exported credentials with the identity.
+func decryptBundle(t *testing.T, path string, identity age.Identity) credentialBundle {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	decrypted, err := age.Decrypt(armor.NewReader(file), identity)
+	require.NoError(t, err)
+	var bundle credentialBundle
+	require.NoError(t, json.NewDecoder(decrypted).Decode(&bundle))
+	return bundle
+}
+
+func TestAuthExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oras_auth_export_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "config.json")
+	writeAuthConfig(t, config, map[string]string{
+		"localhost:5000": "hello:world",
+		"example.com":    "foo:bar",
+	})
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	recipient := identity.Recipient().String()
+	recipientsFile := filepath.Join(dir, "recipients.txt")
+	require.NoError(t, ioutil.WriteFile(recipientsFile, []byte("# test\n"+recipient+"\n"), 0644))
+
+	t.Run("all", func(t *testing.T) {
+		output := filepath.Join(dir, "all.age")
+		require.NoError(t, runAuthCmd("export", "--credential-store", "docker", "-c", config, "-r", recipient, "-o", output))
+		bundle := decryptBundle(t, output, identity)
+		assert.ElementsMatch(t, []iauth.Credential{
+			{ServerAddress: "localhost:5000", Username: "hello", Password: "world"},
+			{ServerAddress: "example.com", Username: "foo", Password: "bar"},
+		}, bundle.Credentials)
+	})
+
+	t.Run("registry", func(t *testing.T) {
+		output := filepath.Join(dir, "registry.age")
+		require.NoError(t, runAuthCmd("export", "--credential-store", "docker", "-c", config, "-R", recipientsFile, "-o", output, "localhost:5000"))
+		bundle := decryptBundle(t, output, identity)
+		assert.Equal(t, []iauth.Credential{
+			{ServerAddress: "localhost:5000", Username: "hello", Password: "world"},
+		}, bundle.Credentials)
+	})
+
+	t.Run("passphrase", func(t *testing.T) {
+		defer withStdin(t, "secret\n")()
+		output := filepath.Join(dir, "passphrase.age")
+		require.NoError(t, runAuthCmd("export", "--credential-store", "docker", "-c", config, "--passphrase-stdin", "-o", output, "example.com"))
+		scrypt, err := age.NewScryptIdentity("secret")
+		require.NoError(t, err)
+		bundle := decryptBundle(t, output, scrypt)
+		assert.Equal(t, []iauth.Credential{
+			{ServerAddress: "example.com", Username: "foo", Password: "bar"},
+		}, bundle.Credentials)
+	})
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{
+			name: "not logged in",
+			args: []string{"-r", recipient, "unknown.io"},
+			err:  "unknown.io",
+		},
+		{
+			name: "invalid recipient",
+			args: []string{"-r", "age1invalid"},
+			err:  "invalid recipient",
+		},
+		{
+			name: "invalid recipients file",
+			args: []string{"-R", config},
+			err:  "invalid recipients file",
+		},
+		{
+			name: "passphrase with recipient",
+			args: []string{"-r", recipient, "--passphrase-stdin"},
+			err:  "--passphrase-stdin cannot be used with --recipient",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			output := filepath.Join(dir, strings.Replace(tc.name, " ", "_", -1)+".age")
+			args := append([]string{"export", "--credential-store", "docker", "-c", config, "-o", output}, tc.args...)
+			err := runAuthCmd(args...)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+			_, err = os.Stat(output)
+			assert.True(t, os.IsNotExist(err), fmt.Sprintf("%s written on failure", output))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type authImportOptions struct {
+	input      string
+	identities []string
+	fromStdin  bool
+
+	debug           bool
+	configs         []string
+	credentialStore string
+}
+
+func authImportCmd() *cobra.Command {
+	var opts authImportOptions
+	cmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Import credentials exported by oras auth export",
+		Long: `Import credentials exported by oras auth export
+
+The credentials are decrypted with the age identities, or with a passphrase if
+there is no identity, and stored as if logged in, without contacting the
+registries. The file defaults to stdin.
+
+Example - Import the credentials encrypted with a passphrase:
+  oras auth import credentials.age
+
+Example - Import the credentials encrypted for an age recipient:
+  oras auth import -i key.txt credentials.age
+
+Example - Import the credentials with a passphrase from stdin:
+  echo $PASSPHRASE | oras auth import --passphrase-stdin credentials.age
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.input = "-"
+			if len(args) > 0 {
+				opts.input = args[0]
+			}
+			return runAuthImport(opts)
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&opts.identities, "identity", "i", nil, "decrypt with the age identities in the file")
+	cmd.Flags().BoolVarP(&opts.fromStdin, "passphrase-stdin", "", false, "read the passphrase from stdin")
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	cmd.Flags().StringArrayVarP(&opts.configs, "config", "c", nil, "auth config path")
+	cmd.Flags().StringVarP(&opts.credentialStore, "credential-store", "", "", "credential store, docker or keyring, defaulting to the credentialStore of the oras config or docker")
+	return cmd
+}
+
+func runAuthImport(opts authImportOptions) error {
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+	if len(opts.identities) > 0 && opts.fromStdin {
+		return errors.New("--passphrase-stdin cannot be used with --identity")
+	}
+	if opts.input == "-" && len(opts.identities) == 0 {
+		return errors.New("the file to import is required to decrypt with a passphrase")
+	}
+
+	var identities []age.Identity
+	for _, path := range opts.identities {
+		parsed, err := parseIdentitiesFile(path)
+		if err != nil {
+			return err
+		}
+		identities = append(identities, parsed...)
+	}
+	if len(identities) == 0 {
+		passphrase, err := readPassphrase(opts.fromStdin, false)
+		if err != nil {
+			return err
+		}
+		identity, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return err
+		}
+		identities = append(identities, identity)
+	}
+
+	var in io.Reader = os.Stdin
+	if opts.input != "-" {
+		file, err := os.Open(opts.input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	// accept both the armored and the binary age files
+	buffered := bufio.NewReader(in)
+	in = buffered
+	if header, _ := buffered.Peek(len(armor.Header)); string(header) == armor.Header {
+		in = armor.NewReader(buffered)
+	}
+	decrypted, err := age.Decrypt(in, identities...)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s: %v", opts.input, err)
+	}
+	var bundle credentialBundle
+	if err := json.NewDecoder(decrypted).Decode(&bundle); err != nil {
+		return fmt.Errorf("invalid credentials in %s: %v", opts.input, err)
+	}
+
+	cli, err := newAuthClient(opts.credentialStore, opts.configs...)
+	if err != nil {
+		return err
+	}
+	if err := cli.StoreCredentials(context.Background(), bundle.Credentials...); err != nil {
+		return err
+	}
+	for _, cred := range bundle.Credentials {
+		fmt.Println("Imported credentials of", cred.ServerAddress)
+	}
+	return nil
+}
+
+func parseIdentitiesFile(path string) ([]age.Identity, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity file %s: %v", path, err)
+	}
+	return identities, nil
+}
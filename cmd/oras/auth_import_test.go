@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	iauth "github.com/deislabs/oras/pkg/auth"
+	auth "github.com/deislabs/oras/pkg/auth/docker"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oras_auth_import_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "source.json")
+	writeAuthConfig(t, source, map[string]string{
+		"localhost:5000": "hello:world",
+		"example.com":    "foo:bar",
+	})
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	identityFile := filepath.Join(dir, "key.txt")
+	require.NoError(t, ioutil.WriteFile(identityFile, []byte(identity.String()+"\n"), 0600))
+	exported := filepath.Join(dir, "credentials.age")
+	require.NoError(t, runAuthCmd("export", "--credential-store", "docker", "-c", source, "-r", identity.Recipient().String(), "-o", exported))
+
+	// importTo imports the credentials into a new config, which holds the
+	// credentials of another registry, and returns the stored credentials.
+	importTo := func(t *testing.T, name string, args ...string) ([]iauth.Credential, error) {
+		config := filepath.Join(dir, name+".json")
+		writeAuthConfig(t, config, map[string]string{
+			"other.io": "other:secret",
+		})
+		args = append([]string{"import", "--credential-store", "docker", "-c", config}, args...)
+		if err := runAuthCmd(args...); err != nil {
+			return nil, err
+		}
+		cli, err := auth.NewClient(config)
+		require.NoError(t, err)
+		return cli.Credentials(context.Background())
+	}
+	want := []iauth.Credential{
+		{ServerAddress: "localhost:5000", Username: "hello", Password: "world"},
+		{ServerAddress: "example.com", Username: "foo", Password: "bar"},
+		{ServerAddress: "other.io", Username: "other", Password: "secret"},
+	}
+
+	t.Run("identity", func(t *testing.T) {
+		creds, err := importTo(t, "identity", "-i", identityFile, exported)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, want, creds)
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		content, err := ioutil.ReadFile(exported)
+		require.NoError(t, err)
+		defer withStdin(t, string(content))()
+		creds, err := importTo(t, "stdin", "-i", identityFile)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, want, creds)
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		binary := filepath.Join(dir, "credentials.bin")
+		file, err := os.Create(binary)
+		require.NoError(t, err)
+		encrypted, err := age.Encrypt(file, identity.Recipient())
+		require.NoError(t, err)
+		require.NoError(t, json.NewEncoder(encrypted).Encode(credentialBundle{
+			Credentials: []iauth.Credential{
+				{ServerAddress: "token.io", IdentityToken: "token"},
+			},
+		}))
+		require.NoError(t, encrypted.Close())
+		require.NoError(t, file.Close())
+
+		creds, err := importTo(t, "binary", "-i", identityFile, binary)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []iauth.Credential{
+			{ServerAddress: "token.io", IdentityToken: "token"},
+			{ServerAddress: "other.io", Username: "other", Password: "secret"},
+		}, creds)
+	})
+
+	t.Run("passphrase", func(t *testing.T) {
+		encrypted := filepath.Join(dir, "passphrase.age")
+		restore := withStdin(t, "secret\n")
+		err := runAuthCmd("export", "--credential-store", "docker", "-c", source, "--passphrase-stdin", "-o", encrypted)
+		restore()
+		require.NoError(t, err)
+
+		defer withStdin(t, "secret\n")()
+		creds, err := importTo(t, "passphrase", "--passphrase-stdin", encrypted)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, want, creds)
+	})
+
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	otherFile := filepath.Join(dir, "other.txt")
+	require.NoError(t, ioutil.WriteFile(otherFile, []byte(other.String()+"\n"), 0600))
+	for _, tc := range []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{
+			name: "wrong identity",
+			args: []string{"-i", otherFile, exported},
+			err:  "failed to decrypt",
+		},
+		{
+			name: "invalid identity file",
+			args: []string{"-i", exported, exported},
+			err:  "invalid identity file",
+		},
+		{
+			name: "passphrase with identity",
+			args: []string{"-i", identityFile, "--passphrase-stdin", exported},
+			err:  "--passphrase-stdin cannot be used with --identity",
+		},
+		{
+			name: "passphrase from stdin",
+			args: []string{"--passphrase-stdin"},
+			err:  "the file to import is required",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			creds, err := importTo(t, "failed", tc.args...)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+			assert.Nil(t, creds)
+		})
+	}
+}
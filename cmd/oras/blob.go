@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/containerd/containerd/reference"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/spf13/cobra"
+)
+
+func blobCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "blob [command]",
+		Short: "Blob operations",
+	}
+	cmd.AddCommand(blobFetchCmd(), blobPushCmd(), blobDeleteCmd(), blobStatCmd())
+	return cmd
+}
+
+// parseBlobRef parses a reference in the form of `name@digest`.
+func parseBlobRef(ref string) (digest.Digest, error) {
+	refspec, err := reference.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	dgst := refspec.Digest()
+	if dgst == "" {
+		return "", fmt.Errorf("%s: blob reference must be in the form of name@digest", ref)
+	}
+	return dgst, dgst.Validate()
+}
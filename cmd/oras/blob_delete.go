@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type blobDeleteOptions struct {
+	targetRef string
+	force     bool
+
+	debug  bool
+	remote remoteOptions
+}
+
+func blobDeleteCmd() *cobra.Command {
+	var opts blobDeleteOptions
+	cmd := &cobra.Command{
+		Use:   "delete <name@digest>",
+		Short: "Delete a blob from a remote registry",
+		Long: `Delete a blob from a remote registry
+
+Example - Delete a blob with confirmation:
+  oras blob delete localhost:5000/hello@sha256:9a201d228ebd966211f7d1131be19f152be428bd373a92071c71d8deaf83b3e5
+
+Example - Delete a blob without confirmation:
+  oras blob delete -f localhost:5000/hello@sha256:9a201d228ebd966211f7d1131be19f152be428bd373a92071c71d8deaf83b3e5
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runBlobDelete(opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.force, "force", "f", false, "delete without confirmation")
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runBlobDelete(opts blobDeleteOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := checkWritable(opts.targetRef); err != nil {
+		return err
+	}
+
+	dgst, err := parseBlobRef(opts.targetRef)
+	if err != nil {
+		return err
+	}
+	if !opts.force {
+		confirmed, err := confirm(fmt.Sprintf("Are you sure you want to delete the blob %s? [y/N] ", dgst))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return nil
+		}
+	}
+
+	hosts := opts.remote.registryHosts()
+	if err := registry.NewClient(hosts).DeleteBlob(ctx, opts.targetRef, dgst); err != nil {
+		return err
+	}
+	fmt.Println("Deleted", opts.targetRef)
+	return nil
+}
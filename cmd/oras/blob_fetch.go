@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/remotes/docker"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type blobFetchOptions struct {
+	targetRef  string
+	output     string
+	descriptor bool
+	pretty     bool
+
+	debug  bool
+	remote remoteOptions
+}
+
+func blobFetchCmd() *cobra.Command {
+	var opts blobFetchOptions
+	cmd := &cobra.Command{
+		Use:   "fetch <name@digest>",
+		Short: "Fetch a blob from a remote registry",
+		Long: `Fetch a blob from a remote registry
+
+The content is verified against the digest while it is streamed.
+
+Example - Fetch a blob to a file:
+  oras blob fetch -o blob.tar localhost:5000/hello@sha256:9a201d228ebd966211f7d1131be19f152be428bd373a92071c71d8deaf83b3e5
+
+Example - Fetch a blob to stdout:
+  oras blob fetch localhost:5000/hello@sha256:9a201d228ebd966211f7d1131be19f152be428bd373a92071c71d8deaf83b3e5
+
+Example - Fetch the descriptor of a blob:
+  oras blob fetch --descriptor localhost:5000/hello@sha256:9a201d228ebd966211f7d1131be19f152be428bd373a92071c71d8deaf83b3e5
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runBlobFetch(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "output file path, or stdout if not specified")
+	cmd.Flags().BoolVarP(&opts.descriptor, "descriptor", "", false, "fetch the descriptor instead of the blob")
+	cmd.Flags().BoolVarP(&opts.pretty, "pretty", "", false, "indent the descriptor output")
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runBlobFetch(opts blobFetchOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+
+	dgst, err := parseBlobRef(opts.targetRef)
+	if err != nil {
+		return err
+	}
+	hosts := opts.remote.registryHosts()
+	status, err := registry.NewClient(hosts).StatBlob(ctx, opts.targetRef, dgst)
+	if err != nil {
+		return err
+	}
+	desc := ocispec.Descriptor{
+		MediaType: "application/octet-stream",
+		Digest:    status.Digest,
+		Size:      status.Size,
+	}
+	if opts.descriptor {
+		return writeDescriptor(opts.output, desc, opts.pretty)
+	}
+
+	fetcher, err := docker.NewResolver(docker.ResolverOptions{
+		Hosts: hosts,
+	}).Fetcher(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if opts.output == "" || opts.output == "-" {
+		return copyVerified(os.Stdout, rc, desc)
+	}
+	file, err := os.Create(opts.output)
+	if err != nil {
+		return err
+	}
+	if err := copyVerified(file, rc, desc); err != nil {
+		file.Close()
+		os.Remove(opts.output)
+		return err
+	}
+	return file.Close()
+}
+
+// copyVerified copies the content described by the descriptor, verifying its
+// size and digest.
+func copyVerified(w io.Writer, r io.Reader, desc ocispec.Descriptor) error {
+	verifier := desc.Digest.Verifier()
+	n, err := io.Copy(io.MultiWriter(w, verifier), io.LimitReader(r, desc.Size))
+	if err != nil {
+		return err
+	}
+	if n != desc.Size {
+		return fmt.Errorf("unexpected content size %d, expected %d", n, desc.Size)
+	}
+	if !verifier.Verified() {
+		return fmt.Errorf("content digest mismatch: %s", desc.Digest)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type blobPushOptions struct {
+	targetRef  string
+	fileRef    string
+	mediaType  string
+	descriptor bool
+	pretty     bool
+
+	debug  bool
+	remote remoteOptions
+}
+
+func blobPushCmd() *cobra.Command {
+	var opts blobPushOptions
+	cmd := &cobra.Command{
+		Use:   "push <name> <file>",
+		Short: "Push a blob to a remote registry",
+		Long: `Push a blob to a remote registry
+
+Example - Push a blob from a file:
+  oras blob push localhost:5000/hello blob.tar
+
+Example - Push a blob from stdin:
+  oras blob push localhost:5000/hello -
+
+Example - Push a blob and print its descriptor with a media type:
+  oras blob push --descriptor --media-type application/vnd.oci.image.layer.v1.tar localhost:5000/hello blob.tar
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			opts.fileRef = args[1]
+			return runBlobPush(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.mediaType, "media-type", "", "application/octet-stream", "media type of the blob in the printed descriptor")
+	cmd.Flags().BoolVarP(&opts.descriptor, "descriptor", "", false, "print the descriptor of the pushed blob")
+	cmd.Flags().BoolVarP(&opts.pretty, "pretty", "", false, "indent the descriptor output")
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runBlobPush(opts blobPushOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := checkWritable(opts.targetRef); err != nil {
+		return err
+	}
+
+	path := opts.fileRef
+	if path == "-" {
+		// the size and the digest are required before uploading
+		tmp, err := bufferStdin()
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp)
+		path = tmp
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	dgst, err := digest.FromReader(file)
+	if err != nil {
+		return err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	desc := ocispec.Descriptor{
+		MediaType: opts.mediaType,
+		Digest:    dgst,
+		Size:      size,
+	}
+
+	resolver := opts.remote.resolver()
+	pusher, err := resolver.Pusher(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	writer, err := pusher.Push(ctx, desc)
+	if err != nil {
+		if !errdefs.IsAlreadyExists(err) {
+			return err
+		}
+	} else if err := content.Copy(ctx, writer, file, desc.Size, desc.Digest); err != nil {
+		return err
+	}
+
+	if opts.descriptor {
+		return writeDescriptor("", desc, opts.pretty)
+	}
+	fmt.Println("Pushed", opts.targetRef)
+	fmt.Println("Digest:", desc.Digest)
+	return nil
+}
+
+// bufferStdin saves stdin to a temporary file and returns its path.
+func bufferStdin() (string, error) {
+	tmp, err := ioutil.TempFile("", "oras-blob-")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(tmp, os.Stdin); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
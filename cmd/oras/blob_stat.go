@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type blobStatOptions struct {
+	targetRef string
+
+	debug  bool
+	remote remoteOptions
+}
+
+func blobStatCmd() *cobra.Command {
+	var opts blobStatOptions
+	cmd := &cobra.Command{
+		Use:   "stat <name@digest>",
+		Short: "Check the existence and size of a blob",
+		Long: `Check the existence and size of a blob without fetching its content
+
+The command exits with a non-zero status if the blob does not exist.
+
+Example - Check a blob:
+  oras blob stat localhost:5000/hello@sha256:9a201d228ebd966211f7d1131be19f152be428bd373a92071c71d8deaf83b3e5
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runBlobStat(opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runBlobStat(opts blobStatOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+
+	dgst, err := parseBlobRef(opts.targetRef)
+	if err != nil {
+		return err
+	}
+	hosts := opts.remote.registryHosts()
+	status, err := registry.NewClient(hosts).StatBlob(ctx, opts.targetRef, dgst)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return fmt.Errorf("%s: blob not found", opts.targetRef)
+		}
+		return err
+	}
+
+	acceptRanges := status.AcceptRanges
+	if acceptRanges == "" {
+		acceptRanges = "none"
+	}
+	fmt.Println("Digest:", status.Digest)
+	fmt.Println("Size:", status.Size)
+	fmt.Println("Accept-Ranges:", acceptRanges)
+	return nil
+}
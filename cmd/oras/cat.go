@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/deislabs/oras/pkg/artifact"
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/oras"
+
+	"github.com/containerd/containerd/images"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type catOptions struct {
+	targetRef string
+	path      string
+	output    string
+	platform  platformOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func catCmd() *cobra.Command {
+	var opts catOptions
+	cmd := &cobra.Command{
+		Use:   "cat <name:tag|name@digest> <path>",
+		Short: "Print a file of an artifact",
+		Long: `Print a file of an artifact without pulling the whole artifact
+
+Only the layer containing the file is fetched. Files in directories pushed with
+oras, and in the tar layers of images, are extracted from their layer, which is
+read only up to the file.
+
+Example - Print the README of an artifact:
+  oras cat localhost:5000/hello:latest README.md
+
+Example - Print a file of a directory pushed with oras:
+  oras cat localhost:5000/hello:latest docs/guide/intro.md
+
+Example - Save a file of the linux/amd64 image of a multi-platform image:
+  oras cat --platform linux/amd64 -o os-release localhost:5000/alpine:latest etc/os-release
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			opts.path = args[1]
+			return runCat(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "output file path, or stdout if not specified")
+	opts.platform.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runCat(opts catOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	matcher, err := opts.platform.matcher()
+	if err != nil {
+		return err
+	}
+
+	hosts := opts.remote.registryHosts()
+	mediaTypes := append(defaultManifestMediaTypes[:len(defaultManifestMediaTypes):len(defaultManifestMediaTypes)], artifact.ArtifactManifestMediaType)
+	resolver := newManifestResolver(hosts, mediaTypes)
+	name, desc, err := resolver.Resolve(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return err
+	}
+	if matcher != nil {
+		if desc, err = oras.SelectPlatform(ctx, fetcher, desc, matcher); err != nil {
+			return err
+		}
+	} else if desc.MediaType == ocispec.MediaTypeImageIndex || desc.MediaType == images.MediaTypeDockerSchema2ManifestList {
+		return fmt.Errorf("%s is an index, please specify --platform", opts.targetRef)
+	}
+
+	if opts.output == "" || opts.output == "-" {
+		return oras.CatFile(ctx, fetcher, desc, opts.path, os.Stdout)
+	}
+	file, err := os.Create(opts.output)
+	if err != nil {
+		return err
+	}
+	if err := oras.CatFile(ctx, fetcher, desc, opts.path, file); err != nil {
+		file.Close()
+		os.Remove(opts.output)
+		return err
+	}
+	return file.Close()
+}
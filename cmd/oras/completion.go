@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/deislabs/oras/internal/refcache"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/spf13/cobra"
+)
+
+// completeRefCmdName is the hidden command listing the references completing
+// a prefix, called by the completion script.
+const completeRefCmdName = "__complete-ref"
+
+// bashCompletionFunction completes the references of the commands from the
+// reference cache, i.e. the first argument, or the first two of the copy
+// commands. The whole word is completed, as bash splits it at colons.
+const bashCompletionFunction = `
+__oras_complete_ref()
+{
+    local word=${COMP_LINE:0:COMP_POINT}
+    word=${word##*[[:space:]]}
+    case ${word} in
+        -*|.*|/*|~*) return ;;
+    esac
+    local ref
+    while IFS='' read -r ref; do
+        COMPREPLY+=("${ref}")
+    done < <(oras ` + completeRefCmdName + ` "${word}" 2>/dev/null)
+    if [[ ${#COMPREPLY[@]} -eq 1 && ( ${COMPREPLY[0]} == */ || ${COMPREPLY[0]} == *: ) ]] && [[ $(type -t compopt) = "builtin" ]]; then
+        compopt -o nospace
+    fi
+    if [[ ${word} == *:* && ${COMP_WORDBREAKS} == *:* ]]; then
+        local prefix=${word%"${word##*:}"}
+        local i
+        for i in "${!COMPREPLY[@]}"; do
+            COMPREPLY[$i]=${COMPREPLY[$i]#"${prefix}"}
+        done
+    fi
+}
+
+__oras_custom_func()
+{
+    case ${last_command} in
+        oras_cp|oras_crane_copy)
+            [[ ${#nouns[@]} -lt 2 ]] && __oras_complete_ref
+            ;;
+        *)
+            [[ ${#nouns[@]} -eq 0 ]] && __oras_complete_ref
+            ;;
+    esac
+}
+`
+
+func completionCmd(root *cobra.Command) *cobra.Command {
+	root.BashCompletionFunction = bashCompletionFunction
+	return &cobra.Command{
+		Use:   "completion <bash|zsh|powershell>",
+		Short: "Generate the shell completion script",
+		Long: `Generate the shell completion script
+
+The commands, the flags and, in bash, the references are completed. The
+references are completed from the reference cache, which records the
+registries, the repositories and the tags seen by the commands.
+
+Example - Load the bash completion in the current shell:
+  source <(oras completion bash)
+
+Example - Load the bash completion, including the references, in zsh:
+  autoload -U +X bashcompinit && bashcompinit && source <(oras completion bash)
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletion(os.Stdout)
+			case "zsh":
+				return root.GenZshCompletion(os.Stdout)
+			case "powershell":
+				return root.GenPowerShellCompletion(os.Stdout)
+			}
+			return fmt.Errorf("unsupported shell %q, expected bash, zsh or powershell", args[0])
+		},
+	}
+}
+
+func completeRefCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    completeRefCmdName + " <prefix>",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := refcache.Path()
+			if err != nil {
+				return err
+			}
+			cache, err := refcache.Load(path)
+			if err != nil {
+				return err
+			}
+			for _, ref := range completeRef(cache, args[0]) {
+				fmt.Println(ref)
+			}
+			return nil
+		},
+	}
+}
+
+// completeRef returns the known references starting with the prefix. Hosts
+// and repositories are completed up to the next separator, i.e. `<host>/` and
+// `<host>/<name>:`, unless the repository has no known tags.
+func completeRef(cache *refcache.Cache, prefix string) []string {
+	var refs []string
+	i := strings.Index(prefix, "/")
+	if i < 0 {
+		for _, host := range cache.Hosts() {
+			if strings.HasPrefix(host+"/", prefix) {
+				refs = append(refs, host+"/")
+			}
+		}
+		return refs
+	}
+	host, rest := prefix[:i], prefix[i+1:]
+	if j := strings.LastIndex(rest, ":"); j >= 0 && !strings.Contains(rest[j:], "/") {
+		name := rest[:j]
+		for _, tag := range cache.Tags(host, name) {
+			ref := host + "/" + name + ":" + tag
+			if !registry.IsDigestTag(tag) && strings.HasPrefix(ref, prefix) {
+				refs = append(refs, ref)
+			}
+		}
+		return refs
+	}
+	for _, name := range cache.Repositories(host) {
+		ref := host + "/" + name
+		if !strings.HasPrefix(ref, prefix) {
+			continue
+		}
+		if len(cache.Tags(host, name)) > 0 {
+			ref += ":"
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/deislabs/oras/pkg/content"
+
+	"github.com/docker/docker/pkg/term"
+	units "github.com/docker/go-units"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spf13/pflag"
+)
+
+const conflictPrompt = "prompt"
+
+// conflictOptions are the options of resolving the files extracted to the
+// same path, or over different existing files.
+type conflictOptions struct {
+	onConflict string
+	decisions  string
+}
+
+func (opts *conflictOptions) applyFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&opts.onConflict, "on-conflict", "", "", `resolution of conflicting files: "prompt", "overwrite", "skip", "rename" or "fail" (default "prompt" on a terminal, "overwrite" otherwise)`)
+	fs.StringVarP(&opts.decisions, "conflict-decisions", "", "", "file recording the conflict resolutions, which are applied on later pulls")
+}
+
+// conflictDecision is a recorded resolution of a conflict.
+type conflictDecision struct {
+	Name   string                 `json:"name"`
+	Digest digest.Digest          `json:"digest"`
+	Action content.ConflictAction `json:"action"`
+}
+
+// conflictResolver resolves the conflicts with the recorded decisions, the
+// policy, or by prompting the user.
+type conflictResolver struct {
+	policy    string
+	path      string
+	decisions []conflictDecision
+	updated   bool
+	suspend   func(func() error) error
+	reader    *bufio.Reader
+}
+
+// resolver returns the conflict resolver of the options, or nil if existing
+// files are simply overwritten.
+func (opts *conflictOptions) resolver() (*conflictResolver, error) {
+	policy := opts.onConflict
+	interactive := isTerminal(os.Stdin) && isTerminal(os.Stderr)
+	switch policy {
+	case "":
+		policy = string(content.ConflictOverwrite)
+		if interactive {
+			policy = conflictPrompt
+		}
+	case conflictPrompt:
+		if !interactive {
+			return nil, fmt.Errorf("cannot prompt for conflicts without a terminal")
+		}
+	default:
+		if _, err := content.ParseConflictAction(policy); err != nil {
+			return nil, err
+		}
+	}
+	if policy == string(content.ConflictOverwrite) && opts.decisions == "" {
+		return nil, nil
+	}
+
+	r := &conflictResolver{
+		policy: policy,
+		path:   opts.decisions,
+		reader: bufio.NewReader(os.Stdin),
+	}
+	if r.path != "" {
+		data, err := ioutil.ReadFile(r.path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &r.decisions); err != nil {
+				return nil, fmt.Errorf("invalid conflict decisions %s: %v", r.path, err)
+			}
+		}
+	}
+	return r, nil
+}
+
+// Resolve implements content.ConflictResolver.
+func (r *conflictResolver) Resolve(conflict content.Conflict) (content.ConflictAction, error) {
+	for _, decision := range r.decisions {
+		if decision.Name == conflict.Name && decision.Digest == conflict.Incoming.Digest {
+			return decision.Action, nil
+		}
+	}
+	if r.policy != conflictPrompt {
+		return content.ConflictAction(r.policy), nil
+	}
+
+	var action content.ConflictAction
+	prompt := func() (err error) {
+		action, err = r.prompt(conflict)
+		return err
+	}
+	var err error
+	if r.suspend != nil {
+		err = r.suspend(prompt)
+	} else {
+		err = prompt()
+	}
+	if err != nil {
+		return "", err
+	}
+	r.decisions = append(r.decisions, conflictDecision{
+		Name:   conflict.Name,
+		Digest: conflict.Incoming.Digest,
+		Action: action,
+	})
+	r.updated = true
+	return action, nil
+}
+
+func (r *conflictResolver) prompt(conflict content.Conflict) (content.ConflictAction, error) {
+	existing := "existing file"
+	if conflict.Written {
+		existing = "pulled blob"
+	}
+	renamed := content.RenamedPath(conflict.Path, conflict.Incoming)
+	fmt.Fprintf(os.Stderr, "Conflict at %s:\n", conflict.Path)
+	fmt.Fprintf(os.Stderr, "  [s]kip       keep the %s %s\n", existing, describeConflictBlob(conflict.Existing))
+	fmt.Fprintf(os.Stderr, "  [o]verwrite  replace it with the incoming blob %s\n", describeConflictBlob(conflict.Incoming))
+	fmt.Fprintf(os.Stderr, "  [r]ename     write the incoming blob to %s\n", renamed)
+	fmt.Fprintf(os.Stderr, "  [f]ail       abort the pull\n")
+	for {
+		fmt.Fprint(os.Stderr, "Resolution [s/o/r/f]: ")
+		answer, err := r.reader.ReadString('\n')
+		if err != nil && answer == "" {
+			return "", err
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "s", "skip":
+			return content.ConflictSkip, nil
+		case "o", "overwrite":
+			return content.ConflictOverwrite, nil
+		case "r", "rename":
+			return content.ConflictRename, nil
+		case "f", "fail":
+			return content.ConflictFail, nil
+		}
+	}
+}
+
+// Save writes the decisions to the decisions file if new decisions are made.
+func (r *conflictResolver) Save() error {
+	if r.path == "" || !r.updated {
+		return nil
+	}
+	data, err := json.MarshalIndent(r.decisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, append(data, '\n'), 0644)
+}
+
+func describeConflictBlob(desc ocispec.Descriptor) string {
+	return fmt.Sprintf("(%s, %s)", shortDigest(desc.Digest.String()), units.BytesSize(float64(desc.Size)))
+}
+
+func isTerminal(file *os.File) bool {
+	_, ok := term.GetFdInfo(file)
+	return ok
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/deislabs/oras/internal/config"
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/oras"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type copyOptions struct {
+	srcRef        string
+	dstRef        string
+	recursive     bool
+	concurrency   int
+	platform      platformOptions
+	fromOCILayout bool
+	toOCILayout   bool
+	verbose       bool
+	progress      progressOptions
+	format        formatOptions
+
+	stripAnnotations       []string
+	mediaTypeRules         []string
+	noConfigMediaTypeRules bool
+	remapReport            string
+
+	debug  bool
+	remote remoteOptions
+}
+
+func copyCmd() *cobra.Command {
+	var opts copyOptions
+	cmd := &cobra.Command{
+		Use:     "cp <from-ref> <to-ref>",
+		Aliases: []string{"copy"},
+		Short:   "Copy artifacts from one reference to another",
+		Long: `Copy artifacts from one reference to another without storing the files locally
+
+Example - Copy an artifact between registries:
+  oras cp localhost:5000/hello:latest localhost:6000/hello:latest
+
+Example - Copy an artifact and its referrers:
+  oras cp -r localhost:5000/hello:latest localhost:6000/hello:latest
+
+Example - Copy an artifact to a new tag in the same repository:
+  oras cp localhost:5000/hello:latest localhost:5000/hello:v1
+
+Example - Copy an artifact and its referrers without the build timestamp annotations:
+  oras cp -r --strip-annotation "org.example.build.*" localhost:5000/hello:latest localhost:6000/hello:latest
+
+Example - Copy an artifact and its referrers, saving the digests of the rewritten manifests to remap.json:
+  oras cp -r --strip-annotation "org.example.build.*" --remap-report remap.json localhost:5000/hello:latest localhost:6000/hello:latest
+
+Example - Copy an artifact, rewriting a deprecated layer media type to the OCI one:
+  oras cp --media-type-rule application/vnd.acme.layer=application/vnd.oci.image.layer.v1.tar localhost:5000/hello:latest localhost:6000/hello:latest
+
+Example - Copy only the linux/arm64 manifest of a multi-platform image:
+  oras cp --platform linux/arm64 localhost:5000/hello:latest localhost:6000/hello:arm64
+
+Example - Copy an artifact to the OCI image layout directory "layout" for air-gapped transfer:
+  oras cp --to-oci-layout localhost:5000/hello:latest layout:latest
+
+Example - Copy an artifact from the OCI image layout directory "layout" to a registry:
+  oras cp --from-oci-layout layout:latest localhost:6000/hello:latest
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.srcRef = args[0]
+			opts.dstRef = args[1]
+			return runCopy(opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.recursive, "recursive", "r", false, "recursively copy the artifact and its referrers")
+	cmd.Flags().IntVarP(&opts.concurrency, "concurrency", "", 3, "maximum number of blobs copied in parallel")
+	opts.platform.applyFlags(cmd.Flags())
+	cmd.Flags().BoolVarP(&opts.fromOCILayout, "from-oci-layout", "", false, "copy from an OCI image layout directory referenced as <path:tag>")
+	cmd.Flags().BoolVarP(&opts.toOCILayout, "to-oci-layout", "", false, "copy to an OCI image layout directory referenced as <path:tag>")
+	cmd.Flags().StringArrayVarP(&opts.stripAnnotations, "strip-annotation", "", nil, "strip the annotations matching the glob pattern from the copied manifests")
+	cmd.Flags().StringArrayVarP(&opts.mediaTypeRules, "media-type-rule", "", nil, "rewrite the blob media types matching the glob pattern in the form of pattern=media-type, applied before the rules in the oras config")
+	cmd.Flags().BoolVarP(&opts.noConfigMediaTypeRules, "no-config-media-type-rules", "", false, "do not apply the media type rules in the oras config")
+	cmd.Flags().StringVarP(&opts.remapReport, "remap-report", "", "", "save the original and the new digests of the rewritten manifests to the JSON file")
+	cmd.Flags().BoolVarP(&opts.verbose, "verbose", "v", false, "verbose output")
+	opts.progress.applyFlags(cmd.Flags())
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runCopy(opts copyOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else if !opts.verbose {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	if !opts.fromOCILayout {
+		opts.srcRef = opts.remote.reference(opts.srcRef)
+	}
+	if !opts.toOCILayout {
+		opts.dstRef = opts.remote.reference(opts.dstRef)
+		if err := checkWritable(opts.dstRef); err != nil {
+			return err
+		}
+	}
+	if opts.recursive && (opts.fromOCILayout || opts.toOCILayout) {
+		return errors.New("recursive copy is not supported with OCI image layouts")
+	}
+
+	hosts := opts.remote.registryHosts()
+	resolver := docker.NewResolver(docker.ResolverOptions{
+		Hosts: hosts,
+	})
+	src, srcRef, err := copyTarget(opts.srcRef, opts.fromOCILayout, resolver)
+	if err != nil {
+		return err
+	}
+	dst, dstRef, err := copyTarget(opts.dstRef, opts.toOCILayout, resolver)
+	if err != nil {
+		return err
+	}
+	copyOpts := []oras.CopyOpt{
+		oras.WithCopyConcurrency(opts.concurrency),
+	}
+	if opts.progress.summary() && !opts.format.enabled() {
+		copyOpts = append(copyOpts, oras.WithCopyStatusTrack(os.Stdout))
+	}
+	if matcher, err := opts.platform.matcher(); err != nil {
+		return err
+	} else if matcher != nil {
+		copyOpts = append(copyOpts, oras.WithCopyPlatform(matcher))
+	}
+	if len(opts.stripAnnotations) > 0 {
+		copyOpts = append(copyOpts, oras.WithStripAnnotations(opts.stripAnnotations...))
+	}
+	rules, err := opts.rules()
+	if err != nil {
+		return err
+	}
+	if len(rules) > 0 {
+		copyOpts = append(copyOpts, oras.WithMediaTypeRules(rules...))
+	}
+	if !opts.fromOCILayout && !opts.toOCILayout {
+		mount, err := sharesStorage(srcRef, dstRef)
+		if err != nil {
+			return err
+		}
+		if mount {
+			copyOpts = append(copyOpts, oras.WithCrossRepositoryMount())
+		}
+	}
+	var rewritten []rewriteResult
+	copyOpts = append(copyOpts, oras.WithCopyRewritten(func(original, desc ocispec.Descriptor) {
+		rewritten = append(rewritten, rewriteResult{
+			Original:  original,
+			Rewritten: desc,
+		})
+	}))
+	if opts.recursive {
+		client := registry.NewClient(hosts)
+		// fail fast before a long recursive copy
+		if err := client.CheckPush(ctx, dstRef); err != nil {
+			return err
+		}
+		copyOpts = append(copyOpts, oras.WithReferrers(client))
+	}
+
+	desc, err := oras.Copy(ctx, src, srcRef, dst, dstRef, copyOpts...)
+	if err != nil {
+		if err == reference.ErrObjectRequired {
+			return fmt.Errorf("image reference format is invalid. Please specify <name:tag|name@digest>")
+		}
+		return err
+	}
+
+	if opts.remapReport != "" {
+		if err := writeRemapReport(opts, rewritten); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Saved digest remapping report to", opts.remapReport)
+	}
+	if opts.format.enabled() {
+		return opts.format.write("", copyResult{
+			Source:      opts.srcRef,
+			Destination: opts.dstRef,
+			Descriptor:  desc,
+			Rewritten:   rewritten,
+		})
+	}
+	if len(rewritten) > 0 {
+		if opts.recursive {
+			fmt.Fprintln(os.Stderr, "WARNING: The rewritten manifests have new digests. The copied referrers refer to the new digests, but other references to the original digests, such as pins, do not apply to them.")
+		} else {
+			fmt.Fprintln(os.Stderr, "WARNING: The rewritten manifests have new digests. References to the original digests, such as signatures, do not apply to them.")
+		}
+	}
+	if !opts.progress.summary() {
+		opts.progress.printReference(os.Stdout, opts.dstRef, opts.toOCILayout, desc.Digest)
+		return nil
+	}
+	for _, result := range rewritten {
+		fmt.Println("Rewritten", result.Original.Digest, "=>", result.Rewritten.Digest)
+	}
+	fmt.Println("Copied", opts.srcRef, "=>", opts.dstRef)
+	fmt.Println("Digest:", desc.Digest)
+
+	return nil
+}
+
+// writeRemapReport writes the digest remapping report of the rewritten
+// manifests to the file of the flag.
+func writeRemapReport(opts copyOptions, rewritten []rewriteResult) error {
+	report := remapReport{
+		Source:      opts.srcRef,
+		Destination: opts.dstRef,
+		Digests:     make(map[string]string),
+		Rewritten:   rewritten,
+	}
+	if report.Rewritten == nil {
+		report.Rewritten = []rewriteResult{}
+	}
+	for _, result := range rewritten {
+		report.Digests[result.Original.Digest.String()] = result.Rewritten.Digest.String()
+	}
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeJSON(opts.remapReport, append(content, '\n'), false)
+}
+
+// rules returns the media type rules of the flags followed by the ones in the
+// oras config.
+func (opts *copyOptions) rules() ([]oras.MediaTypeRule, error) {
+	var rules []oras.MediaTypeRule
+	for _, rule := range opts.mediaTypeRules {
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid media type rule %q: expected pattern=media-type", rule)
+		}
+		rules = append(rules, oras.MediaTypeRule{
+			From: parts[0],
+			To:   parts[1],
+		})
+	}
+	if opts.noConfigMediaTypeRules {
+		return rules, nil
+	}
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range cfg.MediaTypeRules {
+		rules = append(rules, oras.MediaTypeRule(rule))
+	}
+	return rules, nil
+}
+
+// sharesStorage returns whether the blobs of the source are present at the
+// destination registry, i.e. the registries are the same or declared in the
+// config as sharing their storage.
+func sharesStorage(srcRef, dstRef string) (bool, error) {
+	src, err := reference.Parse(srcRef)
+	if err != nil {
+		return false, err
+	}
+	dst, err := reference.Parse(dstRef)
+	if err != nil {
+		return false, err
+	}
+	if src.Hostname() == dst.Hostname() {
+		return true, nil
+	}
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return false, err
+	}
+	return cfg.SharesStorage(src.Hostname(), dst.Hostname()), nil
+}
+
+// copyTarget returns the resolver and the reference of the copy source or
+// destination, which is either in a registry or in an OCI image layout.
+func copyTarget(ref string, ociLayout bool, resolver remotes.Resolver) (remotes.Resolver, string, error) {
+	if !ociLayout {
+		return resolver, ref, nil
+	}
+	path, ref := parseOCILayoutRef(ref)
+	if ref == "" {
+		return nil, "", fmt.Errorf("OCI layout reference format is invalid. Please specify <path:tag|path@digest>")
+	}
+	resolver, err := newOCILayoutResolver(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return resolver, ref, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// craneOptions are the global flags of the crane commands. As with crane, the
+// credentials are read from the docker config files.
+type craneOptions struct {
+	platform platformOptions
+	insecure bool
+	verbose  bool
+}
+
+func craneCmd() *cobra.Command {
+	var opts craneOptions
+	cmd := &cobra.Command{
+		Use:   "crane [command]",
+		Short: "Crane-compatible commands",
+		Long: `Crane-compatible commands
+
+The commands accept the arguments and the flags of their crane equivalents,
+and print the same output, easing the migration of scripts from crane. As with
+crane, references without a registry, such as "ubuntu", refer to Docker Hub,
+and references without a tag or digest to the "latest" tag.
+
+Example - Print the digest of a manifest:
+  oras crane digest localhost:5000/hello:latest
+
+Example - Copy an image between registries:
+  oras crane copy localhost:5000/hello:latest localhost:6000/hello:latest
+
+Example - List the tags of a repository:
+  oras crane ls localhost:5000/hello
+`,
+	}
+
+	opts.platform.applyFlags(cmd.PersistentFlags())
+	cmd.PersistentFlags().BoolVarP(&opts.insecure, "insecure", "", false, "allow connections to SSL registry without certs")
+	cmd.PersistentFlags().BoolVarP(&opts.verbose, "verbose", "v", false, "enable debug logs")
+
+	cmd.AddCommand(
+		craneDigestCmd(&opts),
+		craneCopyCmd(&opts),
+		craneLsCmd(&opts),
+		craneManifestCmd(&opts),
+		craneConfigCmd(&opts),
+		craneTagCmd(&opts),
+		craneDeleteCmd(&opts),
+	)
+	return cmd
+}
+
+// context returns the context of the commands, logging at debug level if
+// verbose.
+func (opts *craneOptions) context() context.Context {
+	if opts.verbose {
+		logrus.SetLevel(logrus.DebugLevel)
+		return context.Background()
+	}
+	return ctxo.WithLoggerDiscarded(context.Background())
+}
+
+// hosts returns the registry hosts configured by the flags and the oras
+// config.
+func (opts *craneOptions) hosts() docker.RegistryHosts {
+	return newRegistryHosts("", "", opts.insecure, false, tlsOptions{}, retryOptions{})
+}
+
+// dockerHubRegistry is the registry of the references without a registry.
+const dockerHubRegistry = "docker.io"
+
+// craneRepository normalizes the repository as crane does: repositories
+// without a registry are on Docker Hub, in the library namespace if their name
+// has a single component, unless the profile sets a default registry.
+func craneRepository(repository string) string {
+	if hasRegistryHost(repository) {
+		return repository
+	}
+	if defaultRegistry != "" {
+		return withDefaultRegistry(repository, defaultRegistry)
+	}
+	if !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	return dockerHubRegistry + "/" + repository
+}
+
+// craneRef normalizes the reference as crane does: the repository as by
+// craneRepository, and the latest tag if there is no tag or digest.
+func craneRef(ref string) string {
+	ref = craneRepository(ref)
+	if !strings.ContainsAny(ref[strings.LastIndex(ref, "/")+1:], ":@") {
+		ref += ":latest"
+	}
+	return ref
+}
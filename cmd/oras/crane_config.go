@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/deislabs/oras/pkg/oras"
+
+	"github.com/containerd/containerd/reference"
+	"github.com/spf13/cobra"
+)
+
+func craneConfigCmd(craneOpts *craneOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "config <image>",
+		Short: "Get the config of an image",
+		Long: `Get the config of an image, as "oras manifest fetch-config"
+
+Example - Print the config of an image:
+  oras crane config localhost:5000/hello:latest
+
+Example - Print the config of the linux/arm64 image of a multi-platform image:
+  oras crane config --platform linux/arm64 localhost:5000/hello:latest
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ref, err := cranePlatformRef(craneOpts, craneRef(args[0]))
+			if err != nil {
+				return err
+			}
+			return runManifestFetchConfig(manifestFetchConfigOptions{
+				targetRef: ref,
+				debug:     craneOpts.verbose,
+				remote:    remoteOptions{insecure: craneOpts.insecure},
+			})
+		},
+	}
+}
+
+// cranePlatformRef returns the reference pinned to the manifest of the
+// platform if specified, or the reference as is.
+func cranePlatformRef(craneOpts *craneOptions, ref string) (string, error) {
+	matcher, err := craneOpts.platform.matcher()
+	if err != nil || matcher == nil {
+		return ref, err
+	}
+	refspec, err := reference.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	ctx := craneOpts.context()
+	resolver := newManifestResolver(craneOpts.hosts(), nil)
+	name, desc, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if desc, err = oras.SelectPlatform(ctx, fetcher, desc, matcher); err != nil {
+		return "", err
+	}
+	return refspec.Locator + "@" + desc.Digest.String(), nil
+}
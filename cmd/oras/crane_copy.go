@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/deislabs/oras/pkg/oras"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/spf13/cobra"
+)
+
+type craneCopyOptions struct {
+	srcRef    string
+	dstRef    string
+	allTags   bool
+	noClobber bool
+	jobs      int
+}
+
+func craneCopyCmd(craneOpts *craneOptions) *cobra.Command {
+	var opts craneCopyOptions
+	cmd := &cobra.Command{
+		Use:     "copy <source> <target>",
+		Aliases: []string{"cp"},
+		Short:   "Efficiently copy a remote image from src to dst while retaining the digest value",
+		Long: `Efficiently copy a remote image from src to dst while retaining the digest value, as "oras cp"
+
+Example - Copy an image between registries:
+  oras crane copy localhost:5000/hello:latest localhost:6000/hello:latest
+
+Example - Copy all the tags of a repository, keeping the existing tags of the target:
+  oras crane copy --all-tags --no-clobber localhost:5000/hello localhost:6000/hello
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.srcRef = args[0]
+			opts.dstRef = args[1]
+			return runCraneCopy(craneOpts, opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.allTags, "all-tags", "a", false, "copy all the tags from src to dst, which are repositories")
+	cmd.Flags().BoolVarP(&opts.noClobber, "no-clobber", "n", false, "avoid overwriting existing tags in dst")
+	cmd.Flags().IntVarP(&opts.jobs, "jobs", "j", runtime.GOMAXPROCS(0), "maximum number of blobs copied in parallel")
+	return cmd
+}
+
+func runCraneCopy(craneOpts *craneOptions, opts craneCopyOptions) error {
+	ctx := craneOpts.context()
+	if opts.allTags {
+		opts.srcRef, opts.dstRef = craneRepository(opts.srcRef), craneRepository(opts.dstRef)
+	} else {
+		opts.srcRef, opts.dstRef = craneRef(opts.srcRef), craneRef(opts.dstRef)
+	}
+	if err := checkWritable(opts.dstRef); err != nil {
+		return err
+	}
+
+	hosts := craneOpts.hosts()
+	copyOpts := []oras.CopyOpt{
+		oras.WithCopyConcurrency(opts.jobs),
+	}
+	if matcher, err := craneOpts.platform.matcher(); err != nil {
+		return err
+	} else if matcher != nil {
+		copyOpts = append(copyOpts, oras.WithCopyPlatform(matcher))
+	}
+	if !opts.allTags {
+		return craneCopy(ctx, hosts, opts.srcRef, opts.dstRef, opts.noClobber, copyOpts)
+	}
+
+	src, err := reference.Parse(opts.srcRef)
+	if err != nil {
+		return err
+	}
+	dst, err := reference.Parse(opts.dstRef)
+	if err != nil {
+		return err
+	}
+	tags, err := registry.NewClient(hosts).Tags(ctx, opts.srcRef)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if err := craneCopy(ctx, hosts, src.Locator+":"+tag, dst.Locator+":"+tag, opts.noClobber, copyOpts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// craneCopy copies the manifest of src to dst, logging to stderr as crane.
+// Existing dst references are skipped if noClobber. Each copy has its own
+// resolver, whose push tracker would skip the manifests pushed to other tags.
+func craneCopy(ctx context.Context, hosts docker.RegistryHosts, src, dst string, noClobber bool, copyOpts []oras.CopyOpt) error {
+	resolver := docker.NewResolver(docker.ResolverOptions{
+		Hosts: hosts,
+	})
+	if noClobber {
+		_, _, err := resolver.Resolve(ctx, dst)
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "Skipping %s: already exists\n", dst)
+			return nil
+		}
+		if !errdefs.IsNotFound(err) {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Copying from %s to %s\n", src, dst)
+	_, err := oras.Copy(ctx, resolver, src, resolver, dst, copyOpts...)
+	return err
+}
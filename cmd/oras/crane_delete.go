@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/spf13/cobra"
+)
+
+func craneDeleteCmd(craneOpts *craneOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <image>",
+		Short: "Delete an image reference from its registry",
+		Long: `Delete an image reference from its registry without confirmation, as "oras manifest delete --force"
+
+Example - Delete an image:
+  oras crane delete localhost:5000/hello@sha256:a1b2c3...
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCraneDelete(craneOpts, craneRef(args[0]))
+		},
+	}
+}
+
+func runCraneDelete(craneOpts *craneOptions, ref string) error {
+	ctx := craneOpts.context()
+	if err := checkWritable(ref); err != nil {
+		return err
+	}
+
+	hosts := craneOpts.hosts()
+	_, desc, err := newManifestResolver(hosts, nil).Resolve(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return registry.NewClient(hosts).DeleteManifest(ctx, ref, desc.Digest)
+}
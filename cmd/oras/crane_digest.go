@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func craneDigestCmd(craneOpts *craneOptions) *cobra.Command {
+	var fullRef bool
+	cmd := &cobra.Command{
+		Use:   "digest <image>",
+		Short: "Get the digest of an image",
+		Long: `Get the digest of an image, as "oras resolve"
+
+Example - Print the digest of an image:
+  oras crane digest localhost:5000/hello:latest
+
+Example - Print the reference pinned to the digest:
+  oras crane digest --full-ref localhost:5000/hello:latest
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runResolve(resolveOptions{
+				targetRef:     craneRef(args[0]),
+				fullReference: fullRef,
+				platform:      craneOpts.platform,
+				debug:         craneOpts.verbose,
+				remote:        remoteOptions{insecure: craneOpts.insecure},
+			})
+		},
+	}
+
+	cmd.Flags().BoolVarP(&fullRef, "full-ref", "", false, "print the full image reference by digest")
+	return cmd
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/reference"
+	"github.com/spf13/cobra"
+)
+
+type craneLsOptions struct {
+	repository     string
+	fullRef        bool
+	omitDigestTags bool
+}
+
+func craneLsCmd(craneOpts *craneOptions) *cobra.Command {
+	var opts craneLsOptions
+	cmd := &cobra.Command{
+		Use:   "ls <repository>",
+		Short: "List the tags in a repository",
+		Long: `List the tags in a repository, as "oras repo tags"
+
+Example - List the tags of a repository:
+  oras crane ls localhost:5000/hello
+
+Example - List the full references of the tags, excluding the digest tags:
+  oras crane ls --full-ref --omit-digest-tags localhost:5000/hello
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.repository = craneRepository(args[0])
+			return runCraneLs(craneOpts, opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.fullRef, "full-ref", "", false, "print the full image reference")
+	cmd.Flags().BoolVarP(&opts.omitDigestTags, "omit-digest-tags", "O", false, "omit the digest tags, e.g. sha256-<encoded>")
+	return cmd
+}
+
+func runCraneLs(craneOpts *craneOptions, opts craneLsOptions) error {
+	ctx := craneOpts.context()
+	refspec, err := reference.Parse(opts.repository)
+	if err != nil {
+		return err
+	}
+	tags, err := registry.NewClient(craneOpts.hosts()).Tags(ctx, opts.repository)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if opts.omitDigestTags && registry.IsDigestTag(tag) {
+			continue
+		}
+		if opts.fullRef {
+			fmt.Printf("%s:%s\n", refspec.Locator, tag)
+		} else {
+			fmt.Println(tag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func craneManifestCmd(craneOpts *craneOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "manifest <image>",
+		Short: "Get the manifest of an image",
+		Long: `Get the manifest of an image, as "oras manifest fetch"
+
+Example - Print the manifest of an image:
+  oras crane manifest localhost:5000/hello:latest
+
+Example - Print the linux/arm64 manifest of a multi-platform image:
+  oras crane manifest --platform linux/arm64 localhost:5000/hello:latest
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runManifestFetch(manifestFetchOptions{
+				targetRef: craneRef(args[0]),
+				platform:  craneOpts.platform,
+				debug:     craneOpts.verbose,
+				remote:    remoteOptions{insecure: craneOpts.insecure},
+			})
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/deislabs/oras/pkg/artifact"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/reference"
+	"github.com/spf13/cobra"
+)
+
+func craneTagCmd(craneOpts *craneOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "tag <image> <tag>",
+		Short: "Efficiently tag a remote image",
+		Long: `Efficiently tag a remote image, as "oras tag"
+
+Example - Tag an image:
+  oras crane tag localhost:5000/hello:latest v1
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCraneTag(craneOpts, craneRef(args[0]), args[1])
+		},
+	}
+}
+
+func runCraneTag(craneOpts *craneOptions, ref, tag string) error {
+	ctx := craneOpts.context()
+	if err := checkWritable(ref); err != nil {
+		return err
+	}
+	if !tagPattern.MatchString(tag) {
+		return fmt.Errorf("invalid tag %q", tag)
+	}
+	refspec, err := reference.Parse(ref)
+	if err != nil {
+		return err
+	}
+
+	hosts := craneOpts.hosts()
+	client := registry.NewClient(hosts)
+	mediaTypes := append(defaultManifestMediaTypes[:len(defaultManifestMediaTypes):len(defaultManifestMediaTypes)], artifact.ArtifactManifestMediaType)
+	_, desc, err := newManifestResolver(hosts, mediaTypes).Resolve(ctx, ref)
+	if err != nil {
+		return err
+	}
+	manifest, err := client.FetchManifest(ctx, ref, desc)
+	if err != nil {
+		return err
+	}
+	return client.PushManifest(ctx, refspec.Locator+":"+tag, desc, manifest)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCraneRef(t *testing.T) {
+	for _, tc := range []struct {
+		ref        string
+		repository string
+		want       string
+	}{
+		{ref: "ubuntu", repository: "docker.io/library/ubuntu", want: "docker.io/library/ubuntu:latest"},
+		{ref: "ubuntu:20.04", want: "docker.io/library/ubuntu:20.04"},
+		{ref: "ubuntu@sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b", want: "docker.io/library/ubuntu@sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b"},
+		{ref: "acme/web", repository: "docker.io/acme/web", want: "docker.io/acme/web:latest"},
+		{ref: "acme/web:v1", want: "docker.io/acme/web:v1"},
+		{ref: "docker.io/library/ubuntu", repository: "docker.io/library/ubuntu", want: "docker.io/library/ubuntu:latest"},
+		{ref: "localhost/hello", repository: "localhost/hello", want: "localhost/hello:latest"},
+		{ref: "localhost:5000/hello", repository: "localhost:5000/hello", want: "localhost:5000/hello:latest"},
+		{ref: "localhost:5000/hello:v1", want: "localhost:5000/hello:v1"},
+		{ref: "ghcr.io/acme/web/api", repository: "ghcr.io/acme/web/api", want: "ghcr.io/acme/web/api:latest"},
+	} {
+		assert.Equal(t, tc.want, craneRef(tc.ref), tc.ref)
+		if tc.repository != "" {
+			assert.Equal(t, tc.repository, craneRepository(tc.ref), tc.ref)
+		}
+	}
+}
+
+func TestCraneCommands(t *testing.T) {
+	defer isolateConfig(t)()
+	registry := newTestRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	repository := host + "/hello"
+
+	config := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromString("{}"),
+		Size:      2,
+	}
+	latest := registry.put("hello", "latest", map[string]interface{}{
+		"schemaVersion": 2,
+		"config":        config,
+		"layers":        []ocispec.Descriptor{},
+	})
+	registry.store("hello", "sha256-"+latest.Digest.Hex()+".sig", latest, registry.blobs[latest.Digest])
+
+	t.Run("digest", func(t *testing.T) {
+		output, err := runCmd(t, craneCmd(), "digest", repository)
+		require.NoError(t, err)
+		assert.Equal(t, latest.Digest.String()+"\n", output, "latest tag by default")
+
+		output, err = runCmd(t, craneCmd(), "digest", "--full-ref", repository+":latest")
+		require.NoError(t, err)
+		assert.Equal(t, repository+"@"+latest.Digest.String()+"\n", output)
+	})
+
+	t.Run("manifest", func(t *testing.T) {
+		output, err := runCmd(t, craneCmd(), "manifest", repository)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(registry.blobs[latest.Digest]), output)
+	})
+
+	t.Run("tag", func(t *testing.T) {
+		_, err := runCmd(t, craneCmd(), "tag", repository, "v1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"latest", "sha256-" + latest.Digest.Hex() + ".sig", "v1"}, registry.tags("hello"))
+
+		_, err = runCmd(t, craneCmd(), "tag", repository, "invalid/tag")
+		assert.Error(t, err, "invalid tag")
+	})
+
+	t.Run("ls", func(t *testing.T) {
+		output, err := runCmd(t, craneCmd(), "ls", repository)
+		require.NoError(t, err)
+		assert.Equal(t, "latest\nsha256-"+latest.Digest.Hex()+".sig\nv1\n", output)
+
+		output, err = runCmd(t, craneCmd(), "ls", "--full-ref", "-O", repository)
+		require.NoError(t, err)
+		assert.Equal(t, repository+":latest\n"+repository+":v1\n", output, "digest tags omitted")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := runCmd(t, craneCmd(), "delete", repository+"@"+latest.Digest.String())
+		require.NoError(t, err)
+		assert.Empty(t, registry.tags("hello"), "all tags of the manifest deleted")
+
+		_, err = runCmd(t, craneCmd(), "digest", repository)
+		assert.Error(t, err, "deleted manifest not found")
+	})
+}
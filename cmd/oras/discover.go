@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type discoverOptions struct {
+	targetRef    string
+	artifactType string
+	output       string
+	format       formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func discoverCmd() *cobra.Command {
+	var opts discoverOptions
+	cmd := &cobra.Command{
+		Use:   "discover <name:tag|name@digest>",
+		Short: "Discover the artifacts referencing a manifest",
+		Long: `Discover the artifacts referencing a manifest
+
+The referrers are discovered recursively. Registries without the referrers API
+are queried by the referrers tag schema.
+
+Example - Discover the referrers as a tree:
+  oras discover localhost:5000/hello:latest
+
+Example - Discover the referrers of an artifact type:
+  oras discover --artifact-type application/vnd.example.sbom localhost:5000/hello:latest
+
+Example - Discover the referrers in JSON:
+  oras discover --output json localhost:5000/hello:latest
+
+Example - Print the digests of the direct referrers:
+  oras discover --format '{{range .Referrers}}{{println .Digest}}{{end}}' localhost:5000/hello:latest
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runDiscover(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.artifactType, "artifact-type", "", "", "only discover the direct referrers of the artifact type")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "tree", "output format: tree or json")
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runDiscover(opts discoverOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if opts.output != "tree" && opts.output != "json" {
+		return fmt.Errorf("unknown output format: %s", opts.output)
+	}
+	if opts.output == "json" && !opts.format.enabled() {
+		opts.format.format = formatJSON
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+
+	hosts := opts.remote.registryHosts()
+	_, desc, err := newManifestResolver(hosts, nil).Resolve(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	refspec, err := reference.Parse(opts.targetRef)
+	if err != nil {
+		return err
+	}
+
+	root := referrerNode{
+		Referrer: registry.Referrer{Descriptor: desc},
+	}
+	d := &discoverer{
+		client:   registry.NewClient(hosts),
+		visiting: map[string]bool{desc.Digest.String(): true},
+	}
+	if err := d.discover(ctx, opts.targetRef, &root, opts.artifactType); err != nil {
+		return err
+	}
+
+	if opts.format.enabled() {
+		return opts.format.write("", root)
+	}
+	fmt.Printf("%s@%s\n", refspec.Locator, desc.Digest)
+	printReferrerTree(os.Stdout, root.Referrers, "")
+	return nil
+}
+
+// referrerNode is a node of the referrer tree.
+type referrerNode struct {
+	registry.Referrer
+	Referrers []referrerNode `json:"referrers,omitempty"`
+}
+
+type discoverer struct {
+	client   *registry.Client
+	visiting map[string]bool
+}
+
+// discover adds the referrers of the node recursively. The artifact type
+// filter applies to the direct referrers of the node only.
+func (d *discoverer) discover(ctx context.Context, ref string, node *referrerNode, artifactType string) error {
+	referrers, err := d.client.ListReferrers(ctx, ref, node.Descriptor, artifactType)
+	if err != nil {
+		return err
+	}
+	for _, referrer := range referrers {
+		// skip cycles in the current path
+		if d.visiting[referrer.Digest.String()] {
+			continue
+		}
+		d.visiting[referrer.Digest.String()] = true
+		child := referrerNode{
+			Referrer: referrer,
+		}
+		err := d.discover(ctx, ref, &child, "")
+		delete(d.visiting, referrer.Digest.String())
+		if err != nil {
+			return err
+		}
+		node.Referrers = append(node.Referrers, child)
+	}
+	return nil
+}
+
+// printReferrerTree prints the referrers grouped by artifact type.
+func printReferrerTree(w io.Writer, referrers []referrerNode, prefix string) {
+	groups := make(map[string][]referrerNode)
+	var artifactTypes []string
+	for _, referrer := range referrers {
+		artifactType := referrer.ArtifactType
+		if artifactType == "" {
+			artifactType = "<unknown>"
+		}
+		if _, ok := groups[artifactType]; !ok {
+			artifactTypes = append(artifactTypes, artifactType)
+		}
+		groups[artifactType] = append(groups[artifactType], referrer)
+	}
+	sort.Strings(artifactTypes)
+
+	for i, artifactType := range artifactTypes {
+		typeBranch, typePrefix := treeBranch(prefix, i == len(artifactTypes)-1)
+		fmt.Fprintln(w, typeBranch+artifactType)
+		group := groups[artifactType]
+		for j, referrer := range group {
+			branch, childPrefix := treeBranch(typePrefix, j == len(group)-1)
+			fmt.Fprintln(w, branch+referrer.Digest.String())
+			printReferrerTree(w, referrer.Referrers, childPrefix)
+		}
+	}
+}
+
+// treeBranch returns the branch of a tree item and the prefix of its
+// children.
+func treeBranch(prefix string, last bool) (string, string) {
+	if last {
+		return prefix + "└── ", prefix + "    "
+	}
+	return prefix + "├── ", prefix + "│   "
+}
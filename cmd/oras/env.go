@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// envPrefix is the prefix of the names of the variables written to the env
+// file.
+const envPrefix = "ORAS_ANNOTATION_"
+
+// envOptions write the manifest annotations to a dotenv-style file for the
+// next steps of CI pipelines, e.g. ORAS_ANNOTATION_VERSION=1.2.3.
+type envOptions struct {
+	output      string
+	annotations []string
+}
+
+func (opts *envOptions) applyFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&opts.output, "env-out", "", "", "write the manifest annotations to the dotenv file as ORAS_ANNOTATION_<NAME>=<value>")
+	fs.StringArrayVarP(&opts.annotations, "env-annotation", "", nil, "annotation written to --env-out in the form of key[=NAME], all the annotations if not specified")
+}
+
+// validate checks the flags before the artifact is fetched.
+func (opts *envOptions) validate() error {
+	if len(opts.annotations) > 0 && opts.output == "" {
+		return fmt.Errorf("--env-annotation requires --env-out")
+	}
+	_, err := opts.selected()
+	return err
+}
+
+// selected returns the names of the variables keyed by the selected
+// annotations, or nil for all the annotations.
+func (opts *envOptions) selected() (map[string]string, error) {
+	if len(opts.annotations) == 0 {
+		return nil, nil
+	}
+	names := make(map[string]string)
+	for _, selector := range opts.annotations {
+		key, name := selector, envName(selector)
+		if i := strings.LastIndex(selector, "="); i >= 0 {
+			key, name = selector[:i], selector[i+1:]
+			if !envNamePattern.MatchString(name) {
+				return nil, fmt.Errorf("invalid --env-annotation %q: %q is not a valid variable name", selector, name)
+			}
+		}
+		if key == "" {
+			return nil, fmt.Errorf("invalid --env-annotation %q: expected key[=NAME]", selector)
+		}
+		names[key] = name
+	}
+	return names, nil
+}
+
+// write writes the annotations to the env file, sorted by name. The selected
+// annotations missing from the manifest are reported on stderr.
+func (opts *envOptions) write(annotations map[string]string) error {
+	if opts.output == "" {
+		return nil
+	}
+	names, err := opts.selected()
+	if err != nil {
+		return err
+	}
+	if names == nil {
+		names = make(map[string]string, len(annotations))
+		for key := range annotations {
+			names[key] = envName(key)
+		}
+	}
+
+	keys := make(map[string]string, len(names))
+	for key, name := range names {
+		if _, ok := annotations[key]; !ok {
+			fmt.Fprintf(os.Stderr, "WARNING: annotation %q not found, not written to %s\n", key, opts.output)
+			continue
+		}
+		if name == "" {
+			fmt.Fprintf(os.Stderr, "WARNING: annotation %q has no variable name, please name it with --env-annotation key=NAME\n", key)
+			continue
+		}
+		if other, ok := keys[name]; ok {
+			if other > key {
+				other, key = key, other
+			}
+			return fmt.Errorf("annotations %q and %q are both written as %s%s, please rename one with --env-annotation key=NAME", other, key, envPrefix, name)
+		}
+		keys[name] = key
+	}
+	sorted := make([]string, 0, len(keys))
+	for name := range keys {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var b strings.Builder
+	for _, name := range sorted {
+		fmt.Fprintf(&b, "%s%s=%s\n", envPrefix, name, envQuote(annotations[keys[name]]))
+	}
+	return ioutil.WriteFile(opts.output, []byte(b.String()), 0644)
+}
+
+var (
+	envNamePattern   = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	envInvalidChars  = regexp.MustCompile(`[^A-Z0-9]+`)
+	envUnquotedValue = regexp.MustCompile(`^[A-Za-z0-9_./:@+,=-]*$`)
+	envEscaper       = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`)
+)
+
+// envName returns the variable name of the annotation, which is the key
+// without the "org.opencontainers.image." prefix in upper case, with any
+// other character than letters and digits replaced by underscores, e.g.
+// VERSION for "org.opencontainers.image.version".
+func envName(key string) string {
+	key = strings.TrimPrefix(key, "org.opencontainers.image.")
+	return strings.Trim(envInvalidChars.ReplaceAllString(strings.ToUpper(key), "_"), "_")
+}
+
+// envQuote double-quotes the value if needed, escaping backslashes, double
+// quotes, dollar signs and line breaks as dotenv parsers expect, so that
+// multi-line values are written on one line. Single-line values can also be
+// sourced by shells.
+func envQuote(value string) string {
+	if envUnquotedValue.MatchString(value) {
+		return value
+	}
+	return `"` + envEscaper.Replace(value) + `"`
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvName(t *testing.T) {
+	for key, want := range map[string]string{
+		"org.opencontainers.image.version":  "VERSION",
+		"org.opencontainers.image.ref.name": "REF_NAME",
+		"com.example.build-id":              "COM_EXAMPLE_BUILD_ID",
+		"io.x/y..z":                         "IO_X_Y_Z",
+		"-leading.and.trailing-":            "LEADING_AND_TRAILING",
+		"café":                              "CAF",
+		"日本":                                "",
+	} {
+		assert.Equal(t, want, envName(key), key)
+	}
+}
+
+func TestEnvOptionsSelected(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		annotations []string
+		want        map[string]string
+		wantErr     string
+	}{
+		{
+			name: "all",
+		},
+		{
+			name:        "derived names",
+			annotations: []string{"org.opencontainers.image.version", "com.example.commit"},
+			want: map[string]string{
+				"org.opencontainers.image.version": "VERSION",
+				"com.example.commit":               "COM_EXAMPLE_COMMIT",
+			},
+		},
+		{
+			name:        "named",
+			annotations: []string{"org.opencontainers.image.version=release", "a=b=C"},
+			want: map[string]string{
+				"org.opencontainers.image.version": "release",
+				"a=b":                              "C",
+			},
+		},
+		{
+			name:        "invalid name",
+			annotations: []string{"com.example.commit=COMMIT-ID"},
+			wantErr:     `invalid --env-annotation "com.example.commit=COMMIT-ID": "COMMIT-ID" is not a valid variable name`,
+		},
+		{
+			name:        "empty name",
+			annotations: []string{"com.example.commit="},
+			wantErr:     `"" is not a valid variable name`,
+		},
+		{
+			name:        "empty key",
+			annotations: []string{"=COMMIT"},
+			wantErr:     `invalid --env-annotation "=COMMIT": expected key[=NAME]`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := envOptions{annotations: tc.annotations}
+			names, err := opts.selected()
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, names)
+		})
+	}
+
+	opts := envOptions{annotations: []string{"com.example.commit"}}
+	assert.EqualError(t, opts.validate(), "--env-annotation requires --env-out")
+}
+
+func TestEnvOptionsWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oras_env_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "oras.env")
+
+	annotations := map[string]string{
+		"org.opencontainers.image.version": "1.2.3",
+		"org.opencontainers.image.title":   "hello world",
+		"com.example.notes":                "line 1\nline \"2\"\r\n",
+		"com.example.path":                 `C:\dir $HOME 'quoted'`,
+		"com.example.empty":                "",
+		"日本":                               "unnamed",
+	}
+	for _, tc := range []struct {
+		name     string
+		selected []string
+		want     string
+		wantErr  string
+	}{
+		{
+			name: "all",
+			want: `ORAS_ANNOTATION_COM_EXAMPLE_EMPTY=
+ORAS_ANNOTATION_COM_EXAMPLE_NOTES="line 1\nline \"2\"\r\n"
+ORAS_ANNOTATION_COM_EXAMPLE_PATH="C:\\dir \$HOME 'quoted'"
+ORAS_ANNOTATION_TITLE="hello world"
+ORAS_ANNOTATION_VERSION=1.2.3
+`,
+		},
+		{
+			name:     "selected",
+			selected: []string{"org.opencontainers.image.version=V", "日本=JA", "com.example.missing"},
+			want: `ORAS_ANNOTATION_JA=unnamed
+ORAS_ANNOTATION_V=1.2.3
+`,
+		},
+		{
+			name:     "collision",
+			selected: []string{"org.opencontainers.image.version", "org.opencontainers.image.title=VERSION"},
+			wantErr:  `annotations "org.opencontainers.image.title" and "org.opencontainers.image.version" are both written as ORAS_ANNOTATION_VERSION, please rename one with --env-annotation key=NAME`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := envOptions{output: path, annotations: tc.selected}
+			err := opts.write(annotations)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			content, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(content))
+		})
+	}
+
+	opts := envOptions{}
+	assert.NoError(t, opts.write(annotations), "nothing written without --env-out")
+}
+
+func TestEnvQuote(t *testing.T) {
+	for value, want := range map[string]string{
+		"":                       "",
+		"1.2.3":                  "1.2.3",
+		"user@example.com:a,b":   "user@example.com:a,b",
+		"hello world":            `"hello world"`,
+		`say "hi"`:               `"say \"hi\""`,
+		"it's":                   `"it's"`,
+		`C:\dir`:                 `"C:\\dir"`,
+		"$HOME and ${PATH}":      `"\$HOME and \${PATH}"`,
+		"line 1\nline 2\r\n":     `"line 1\nline 2\r\n"`,
+		"tab\tseparated":         "\"tab\tseparated\"",
+		"`command substitution`": "\"`command substitution`\"",
+	} {
+		assert.Equal(t, want, envQuote(value), value)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deislabs/oras/pkg/artifact"
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/oras"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type historyOptions struct {
+	targetRef string
+	format    formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func historyCmd() *cobra.Command {
+	var opts historyOptions
+	cmd := &cobra.Command{
+		Use:   "history <name:tag>",
+		Short: "Show the change log of a tag",
+		Long: `Show the change log of a tag
+
+The change log is recorded by pushing with --history, which attaches it as a
+referrer to the manifest the tag points to.
+
+Example - Show the changes of a tag, newest first:
+  oras history localhost:5000/hello:latest
+
+Example - Show the changes of a tag as JSON:
+  oras history --format json localhost:5000/hello:latest
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runHistory(opts)
+		},
+	}
+
+	opts.format.applyFlags(cmd.Flags())
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runHistory(opts historyOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	tag, err := parseHistoryTag(opts.targetRef)
+	if err != nil {
+		return err
+	}
+
+	hosts := opts.remote.registryHosts()
+	resolver := newManifestResolver(hosts, nil)
+	_, desc, err := resolver.Resolve(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	history, err := oras.FetchTagHistory(ctx, resolver, registry.NewClient(hosts), opts.targetRef, tag, desc)
+	if err != nil {
+		return err
+	}
+	if latest := history.Latest(); latest != nil && latest.Digest != desc.Digest {
+		fmt.Fprintf(os.Stderr, "WARNING: %s was changed without recording the history\n", opts.targetRef)
+	}
+
+	if opts.format.enabled() {
+		return opts.format.write("", history)
+	}
+	if len(history.Changes) == 0 {
+		fmt.Println("No history recorded for", opts.targetRef)
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tACTOR\tPREVIOUS\tDIGEST")
+	for i := len(history.Changes) - 1; i >= 0; i-- {
+		change := history.Changes[i]
+		actor, previous := change.Actor, change.Previous.String()
+		if actor == "" {
+			actor = "-"
+		}
+		if previous == "" {
+			previous = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", change.Time.Format(time.RFC3339), actor, previous, change.Digest)
+	}
+	return tw.Flush()
+}
+
+// parseHistoryTag returns the tag of the reference, which is required to
+// record or show its history.
+func parseHistoryTag(ref string) (string, error) {
+	refspec, err := reference.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	tag, dgst := reference.SplitObject(refspec.Object)
+	if dgst != "" || tag == "" {
+		return "", fmt.Errorf("%s: a tag is required for the history", ref)
+	}
+	return tag, nil
+}
+
+// defaultHistoryActor returns the registry username if any, or the local
+// user at the host.
+func defaultHistoryActor(username string) string {
+	if username != "" {
+		return username
+	}
+	actor := "unknown"
+	if u, err := user.Current(); err == nil {
+		actor = u.Username
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		actor += "@" + hostname
+	}
+	return actor
+}
+
+// resolveTag resolves the manifest the tag of ref points to, or returns nil
+// if the tag does not exist yet.
+func resolveTag(ctx context.Context, resolver remotes.Resolver, ref string) (*ocispec.Descriptor, error) {
+	_, desc, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &desc, nil
+}
+
+// pushTagHistory appends the change of the tag from previous to desc to its
+// history, and attaches the history to desc, which is kept discoverable on
+// registries without the referrers API.
+func pushTagHistory(ctx context.Context, resolver remotes.Resolver, hosts docker.RegistryHosts, ref, tag string, previous *ocispec.Descriptor, desc ocispec.Descriptor, actor string, concurrency int) (*ocispec.Descriptor, error) {
+	client := registry.NewClient(hosts)
+	history := &artifact.TagHistory{
+		Tag: tag,
+	}
+	change := artifact.TagChange{
+		Digest: desc.Digest,
+		Actor:  actor,
+		Time:   time.Now().UTC(),
+	}
+	if previous != nil {
+		var err error
+		if history, err = oras.FetchTagHistory(ctx, resolver, client, ref, tag, *previous); err != nil {
+			return nil, fmt.Errorf("failed to fetch tag history: %v", err)
+		}
+		change.Previous = previous.Digest
+	}
+	history.Changes = append(history.Changes, change)
+
+	hdesc, err := oras.PushTagHistory(ctx, resolver, ref, desc, history,
+		oras.WithArtifactManifest(client),
+		oras.WithPushConcurrency(concurrency),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to push tag history: %v", err)
+	}
+	if err := client.AddReferrer(ctx, ref, desc, registry.Referrer{
+		Descriptor:   hdesc,
+		ArtifactType: artifact.TagHistoryArtifactType,
+	}); err != nil {
+		return nil, err
+	}
+	return &hdesc, nil
+}
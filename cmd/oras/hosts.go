@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/deislabs/oras/internal/config"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/remotes/docker"
+	hostsconfig "github.com/containerd/containerd/remotes/docker/config"
+)
+
+// hostsDir configures the registries with the hosts.toml files of a directory
+// in the containerd layout.
+type hostsDir struct {
+	root          string
+	credentials   func(string) (string, string, error)
+	retry         registry.RetryOptions
+	budget        *registry.RetryBudget
+	plainHTTP     func(string) bool
+	checkRedirect func(*http.Request, []*http.Request) error
+
+	lock  sync.Mutex
+	hosts map[string][]docker.RegistryHost
+}
+
+// configure returns the hosts of the registry configured by its hosts.toml,
+// and false if the registry has none.
+func (d *hostsDir) configure(host string) ([]docker.RegistryHost, bool, error) {
+	dir, ok := d.hostDir(host)
+	if !ok {
+		return nil, false, nil
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if hosts, ok := d.hosts[host]; ok {
+		return hosts, true, nil
+	}
+
+	scheme := "https"
+	if d.plainHTTP(host) {
+		scheme = "http"
+	}
+	hosts, err := hostsconfig.ConfigureHosts(context.Background(), hostsconfig.HostOptions{
+		HostDir: func(string) (string, error) {
+			return dir, nil
+		},
+		Credentials:   d.credentials,
+		DefaultScheme: scheme,
+	})(host)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %v", filepath.Join(dir, "hosts.toml"), err)
+	}
+	for i := range hosts {
+		// retry transient failures as the other registries
+		client := &http.Client{
+			Transport:     newRetryTransport(hosts[i].Client.Transport, d.retry, d.budget),
+			CheckRedirect: d.checkRedirect,
+		}
+		hosts[i].Client = client
+		hosts[i].Authorizer = docker.NewDockerAuthorizer(
+			docker.WithAuthClient(client),
+			docker.WithAuthCreds(d.credentials),
+		)
+	}
+	if d.hosts == nil {
+		d.hosts = make(map[string][]docker.RegistryHost)
+	}
+	d.hosts[host] = hosts
+	return hosts, true, nil
+}
+
+// hostDir returns the directory of the registry containing a hosts.toml. The
+// port is written as `_port_` or kept as is in the directory name.
+func (d *hostsDir) hostDir(host string) (string, bool) {
+	names := []string{host}
+	if i := strings.LastIndex(host, ":"); i > 0 {
+		names = []string{host[:i] + "_" + host[i+1:] + "_", host}
+	}
+	for _, name := range names {
+		dir := filepath.Join(d.root, name)
+		if _, err := os.Stat(filepath.Join(dir, "hosts.toml")); err == nil {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// configureMirrors puts the mirrors of the registry in the oras config before
+// its hosts, and rewrites the endpoint of the hosts.
+func configureMirrors(hosts []docker.RegistryHost, settings config.RegistryConfig, mirror docker.RegistryHost, plainHTTP func(string) bool) ([]docker.RegistryHost, error) {
+	if len(settings.Mirrors) == 0 && settings.Endpoint == "" {
+		return hosts, nil
+	}
+	configured := make([]docker.RegistryHost, 0, len(settings.Mirrors)+len(hosts))
+	for _, raw := range settings.Mirrors {
+		endpoint, err := parseEndpoint(raw, plainHTTP)
+		if err != nil {
+			return nil, err
+		}
+		host := mirror
+		host.Scheme = endpoint.Scheme
+		host.Host = endpoint.Host
+		host.Path = endpoint.Path
+		host.Capabilities = docker.HostCapabilityPull | docker.HostCapabilityResolve
+		configured = append(configured, host)
+	}
+	for _, host := range hosts {
+		if settings.Endpoint != "" {
+			endpoint, err := parseEndpoint(settings.Endpoint, plainHTTP)
+			if err != nil {
+				return nil, err
+			}
+			host.Scheme = endpoint.Scheme
+			host.Host = endpoint.Host
+			host.Path = endpoint.Path
+		}
+		configured = append(configured, host)
+	}
+	return configured, nil
+}
+
+// parseEndpoint parses the endpoint of a registry, whose scheme defaults to
+// https unless the host is accessed with plain http, and whose path ends with
+// `/v2`.
+func parseEndpoint(raw string, plainHTTP func(string) bool) (*url.URL, error) {
+	if !strings.HasPrefix(raw, "http://") && !strings.HasPrefix(raw, "https://") {
+		u, err := url.Parse("//" + raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q: %v", raw, err)
+		}
+		u.Scheme = "https"
+		if plainHTTP(u.Host) {
+			u.Scheme = "http"
+		}
+		raw = u.String()
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q", raw)
+	}
+	u.Path = path.Clean("/" + u.Path)
+	if !strings.HasSuffix(u.Path, "/v2") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/v2"
+	}
+	return u, nil
+}
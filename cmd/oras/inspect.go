@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/deislabs/oras/pkg/artifact"
+	"github.com/deislabs/oras/pkg/content"
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/oras"
+
+	units "github.com/docker/go-units"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type inspectOptions struct {
+	targetRef string
+	format    formatOptions
+	env       envOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func inspectCmd() *cobra.Command {
+	var opts inspectOptions
+	cmd := &cobra.Command{
+		Use:   "inspect <name:tag|name@digest>",
+		Short: "Show the details of an artifact",
+		Long: `Show the details of an artifact
+
+The manifest annotations and the layers of the artifact are listed. Manifest
+annotations moved to an annotations blob on push, as the manifest would exceed
+the size limit of registries otherwise, are resolved. The metadata of models, such as the
+name, the format and the files with their shards, is shown as well.
+
+With --format, the result is printed as JSON, or with a Go template over its
+fields .Reference, the fields of the manifest descriptor, .Config,
+.ManifestAnnotations, .Layers and .Model.
+
+Example - Inspect an artifact:
+  oras inspect localhost:5000/llama:7b
+
+Example - Print the digest and the size of the manifest:
+  oras inspect --format '{{.Digest}} {{.Size}}' localhost:5000/llama:7b
+
+Example - Print the version annotation of the manifest:
+  oras inspect --format '{{index .ManifestAnnotations "org.opencontainers.image.version"}}' localhost:5000/llama:7b
+
+Example - Print the names of the layers:
+  oras inspect --format '{{range .Layers}}{{index .Annotations "org.opencontainers.image.title"}}{{"\n"}}{{end}}' localhost:5000/llama:7b
+
+Example - Write the version annotation to the dotenv file "oras.env" as ORAS_ANNOTATION_VERSION:
+  oras inspect --env-out oras.env --env-annotation org.opencontainers.image.version localhost:5000/llama:7b
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runInspect(opts)
+		},
+	}
+
+	opts.format.applyFlags(cmd.Flags())
+	opts.env.applyFlags(cmd.Flags())
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runInspect(opts inspectOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	if err := opts.env.validate(); err != nil {
+		return err
+	}
+
+	hosts := opts.remote.registryHosts()
+	resolver := newManifestResolver(hosts, []string{ocispec.MediaTypeImageManifest})
+	name, desc, err := resolver.Resolve(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return err
+	}
+	var manifest ocispec.Manifest
+	if err := fetchJSON(ctx, fetcher, desc, &manifest); err != nil {
+		return err
+	}
+	annotations, err := oras.FetchManifestAnnotations(ctx, fetcher, manifest)
+	if err != nil {
+		return err
+	}
+	if err := opts.env.write(annotations); err != nil {
+		return err
+	}
+	var model *artifact.ModelConfig
+	if manifest.Config.MediaType == artifact.ModelConfigMediaType {
+		model = &artifact.ModelConfig{}
+		if err := fetchJSON(ctx, fetcher, manifest.Config, model); err != nil {
+			return err
+		}
+	}
+
+	if opts.format.enabled() {
+		return opts.format.write("", inspectResult{
+			Reference:           opts.targetRef,
+			Descriptor:          desc,
+			Config:              manifest.Config,
+			ManifestAnnotations: annotations,
+			Layers:              manifest.Layers,
+			Model:               model,
+		})
+	}
+	fmt.Println("Reference:", opts.targetRef)
+	fmt.Println("Digest:", desc.Digest)
+	fmt.Println("Media Type:", desc.MediaType)
+	fmt.Println("Config:", manifest.Config.MediaType)
+	fmt.Println()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if len(annotations) > 0 {
+		keys := make([]string, 0, len(annotations))
+		for key := range annotations {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fmt.Fprintln(tw, "ANNOTATION\tVALUE")
+		for _, key := range keys {
+			fmt.Fprintf(tw, "%s\t%s\n", key, annotations[key])
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+
+	fmt.Fprintln(tw, "LAYER\tMEDIA TYPE\tSIZE\tNAME")
+	for _, layer := range manifest.Layers {
+		name, _ := content.ResolveName(layer)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", layer.Digest, layer.MediaType, units.BytesSize(float64(layer.Size)), name)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if model == nil {
+		return nil
+	}
+	fmt.Println()
+	fmt.Println("Model:", model.Name)
+	fmt.Println("Format:", model.Format)
+	fmt.Println()
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSIZE\tSHARDS\tDIGEST")
+	for _, file := range model.Files {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", file.Name, units.BytesSize(float64(file.Size)), file.Shards, file.Digest)
+	}
+	return tw.Flush()
+}
+
+// inspectResult is the machine-readable output of inspect.
+type inspectResult struct {
+	Reference string `json:"reference"`
+	ocispec.Descriptor
+	Config ocispec.Descriptor `json:"config"`
+	// ManifestAnnotations are the manifest annotations, including the ones
+	// moved to an annotations blob.
+	ManifestAnnotations map[string]string     `json:"manifestAnnotations,omitempty"`
+	Layers              []ocispec.Descriptor  `json:"layers"`
+	Model               *artifact.ModelConfig `json:"model,omitempty"`
+}
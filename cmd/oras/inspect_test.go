@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectResultAnnotations(t *testing.T) {
+	result := inspectResult{
+		Reference: "localhost:5000/hello:latest",
+		Descriptor: ocispec.Descriptor{
+			MediaType:   ocispec.MediaTypeImageManifest,
+			Annotations: map[string]string{"descriptor": "true"},
+		},
+		ManifestAnnotations: map[string]string{"manifest": "true"},
+	}
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &fields))
+	assert.JSONEq(t, `{"descriptor": "true"}`, string(fields["annotations"]))
+	assert.JSONEq(t, `{"manifest": "true"}`, string(fields["manifestAnnotations"]))
+
+	format := formatOptions{format: `{{index .Annotations "descriptor"}} {{index .ManifestAnnotations "manifest"}}`}
+	tmpl, err := format.template()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Execute(&buf, result))
+	assert.Equal(t, "true true", buf.String())
+}
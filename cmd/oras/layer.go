@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func layerCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "layer [command]",
+		Short: "Layer operations",
+	}
+	cmd.AddCommand(layerVerifyCmd())
+	return cmd
+}
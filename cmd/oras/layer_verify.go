@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/deislabs/oras/pkg/content"
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/remotes/docker"
+	units "github.com/docker/go-units"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type layerVerifyOptions struct {
+	targetRef string
+	mediaType string
+	format    formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+// layerVerifyResult is the machine-readable output of `oras layer verify`.
+type layerVerifyResult struct {
+	Reference string `json:"reference"`
+	ocispec.Descriptor
+	content.LayerReport
+}
+
+func layerVerifyCmd() *cobra.Command {
+	var opts layerVerifyOptions
+	cmd := &cobra.Command{
+		Use:   "verify <name@digest>",
+		Short: "Verify the structure of a layer",
+		Long: `Verify the structure of a layer
+
+The layer is downloaded and verified against its digest, then its structure is
+checked: the integrity of its gzip or zstd compression, and the readability of
+every entry of its tar archive. This catches the layers corrupted by broken
+producers before they were pushed, which match their digest.
+
+The compression and the tar format are given by the media type, or detected
+from the content if not specified.
+
+Example - Verify a zstd-compressed tar layer:
+  oras layer verify --media-type application/vnd.oci.image.layer.v1.tar+zstd localhost:5000/hello@sha256:9a201d228ebd966211f7d1131be19f152be428bd373a92071c71d8deaf83b3e5
+
+Example - Verify a layer, detecting its format, with JSON output:
+  oras layer verify --format json localhost:5000/hello@sha256:9a201d228ebd966211f7d1131be19f152be428bd373a92071c71d8deaf83b3e5
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runLayerVerify(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.mediaType, "media-type", "", "", "media type of the layer, e.g. application/vnd.oci.image.layer.v1.tar+gzip (default: detected from the content)")
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runLayerVerify(opts layerVerifyOptions) error {
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+
+	dgst, err := parseBlobRef(opts.targetRef)
+	if err != nil {
+		return err
+	}
+	hosts := opts.remote.registryHosts()
+	status, err := registry.NewClient(hosts).StatBlob(ctx, opts.targetRef, dgst)
+	if err != nil {
+		return err
+	}
+	desc := ocispec.Descriptor{
+		MediaType: opts.mediaType,
+		Digest:    status.Digest,
+		Size:      status.Size,
+	}
+	if desc.MediaType == "" {
+		desc.MediaType = "application/octet-stream"
+	}
+
+	fetcher, err := docker.NewResolver(docker.ResolverOptions{
+		Hosts: hosts,
+	}).Fetcher(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	// the digest is verified first, as a layer not matching it is corrupt in
+	// transit or in the registry rather than by its producer
+	verifier := desc.Digest.Verifier()
+	r := io.TeeReader(io.LimitReader(rc, desc.Size), verifier)
+	report, verifyErr := content.VerifyLayer(r, opts.mediaType)
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return err
+	}
+	if !verifier.Verified() {
+		return fmt.Errorf("content digest mismatch: %s", desc.Digest)
+	}
+	if verifyErr != nil {
+		return fmt.Errorf("%s: corrupt layer: %v", opts.targetRef, verifyErr)
+	}
+
+	if opts.format.enabled() {
+		return opts.format.write("", layerVerifyResult{
+			Reference:   opts.targetRef,
+			Descriptor:  desc,
+			LayerReport: report,
+		})
+	}
+	fmt.Println("Verified", opts.targetRef)
+	fmt.Println("Compression:", report.Compression)
+	if report.Tar {
+		fmt.Println("Entries:", report.Entries)
+	}
+	fmt.Println("Uncompressed size:", units.BytesSize(float64(report.UncompressedSize)))
+	return nil
+}
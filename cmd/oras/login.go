@@ -5,11 +5,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"strings"
 
-	auth "github.com/deislabs/oras/pkg/auth/docker"
+	iauth "github.com/deislabs/oras/pkg/auth"
 
 	"github.com/docker/docker/pkg/term"
 	"github.com/sirupsen/logrus"
@@ -17,14 +19,18 @@ import (
 )
 
 type loginOptions struct {
-	hostname  string
-	fromStdin bool
-
-	debug    bool
-	configs  []string
-	username string
-	password string
-	insecure bool
+	hostname      string
+	fromStdin     bool
+	identityToken string
+
+	debug           bool
+	configs         []string
+	credentialStore string
+	username        string
+	password        string
+	insecure        bool
+	plainHTTP       bool
+	tls             tlsOptions
 }
 
 func loginCmd() *cobra.Command {
@@ -46,11 +52,17 @@ Example - Login with identity token from command line:
 Example - Login with identity token from stdin:
   oras login --password-stdin localhost:5000
 
+Example - Login with an OAuth2 identity token explicitly:
+  oras login --identity-token token localhost:5000
+
 Example - Login with username and password by prompt:
   oras login localhost:5000
 
 Example - Login with insecure registry from command line:
   oras login --insecure localhost:5000
+
+Example - Login with client certificates and a private CA:
+  oras login --cert-file client.crt --key-file client.key --ca-file ca.crt registry.example.com
 `,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -61,10 +73,14 @@ Example - Login with insecure registry from command line:
 
 	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
 	cmd.Flags().StringArrayVarP(&opts.configs, "config", "c", nil, "auth config path")
+	cmd.Flags().StringVarP(&opts.credentialStore, "credential-store", "", "", "credential store, docker or keyring, defaulting to the credentialStore of the oras config or docker")
 	cmd.Flags().StringVarP(&opts.username, "username", "u", "", "registry username")
 	cmd.Flags().StringVarP(&opts.password, "password", "p", "", "registry password or identity token")
 	cmd.Flags().BoolVarP(&opts.fromStdin, "password-stdin", "", false, "read password or identity token from stdin")
+	cmd.Flags().StringVarP(&opts.identityToken, "identity-token", "", "", "registry identity token (OAuth2 refresh token)")
 	cmd.Flags().BoolVarP(&opts.insecure, "insecure", "k", false, "allow connections to SSL registry without certs")
+	cmd.Flags().BoolVarP(&opts.plainHTTP, "plain-http", "", false, "use plain http and not https")
+	opts.tls.applyFlags(cmd.Flags())
 	return cmd
 }
 
@@ -73,14 +89,23 @@ func runLogin(opts loginOptions) error {
 		logrus.SetLevel(logrus.DebugLevel)
 	}
 
+	if opts.identityToken != "" && (opts.username != "" || opts.password != "" || opts.fromStdin) {
+		return errors.New("--identity-token cannot be used with --username, --password or --password-stdin")
+	}
+	if offline {
+		return errOffline
+	}
+
 	// Prepare auth client
-	cli, err := auth.NewClient(opts.configs...)
+	cli, err := newAuthClient(opts.credentialStore, opts.configs...)
 	if err != nil {
 		return err
 	}
 
 	// Prompt credential
-	if opts.fromStdin {
+	if opts.identityToken != "" {
+		fmt.Fprintln(os.Stderr, "WARNING! Using --identity-token via the CLI is insecure. Use --password-stdin.")
+	} else if opts.fromStdin {
 		password, err := ioutil.ReadAll(os.Stdin)
 		if err != nil {
 			return err
@@ -113,7 +138,31 @@ func runLogin(opts loginOptions) error {
 	}
 
 	// Login
-	if err := cli.Login(context.Background(), opts.hostname, opts.username, opts.password, opts.insecure); err != nil {
+	options := []iauth.LoginOption{
+		iauth.WithLoginContext(context.Background()),
+		iauth.WithLoginHostname(opts.hostname),
+		iauth.WithLoginUsername(opts.username),
+		iauth.WithLoginSecret(opts.password),
+		iauth.WithLoginIdentityToken(opts.identityToken),
+	}
+	transport := newRegistryTransport(opts.insecure, opts.plainHTTP, opts.tls)
+	transport.addRegistry(opts.hostname)
+	if opts.insecure || transport.config.Registry(opts.hostname).Insecure {
+		options = append(options, iauth.WithLoginInsecure())
+	}
+	if plainHTTP := transport.isPlainHTTP(opts.hostname); plainHTTP || transport.isCustomized(opts.hostname) {
+		if _, err := transport.transport(opts.hostname); err != nil {
+			return err
+		}
+		options = append(options, iauth.WithLoginClient(&http.Client{
+			Transport:     transport,
+			CheckRedirect: transport.redirectPolicy().CheckRedirect,
+		}))
+		if plainHTTP {
+			options = append(options, iauth.WithLoginPlainHTTP())
+		}
+	}
+	if err := cli.LoginWithOpts(options...); err != nil {
 		return err
 	}
 
@@ -122,7 +171,12 @@ func runLogin(opts loginOptions) error {
 }
 
 func readLine(prompt string, slient bool) (string, error) {
-	fmt.Print(prompt)
+	return readLineTo(os.Stdout, prompt, slient)
+}
+
+// readLineTo reads a line from stdin, writing the prompt to w.
+func readLineTo(w io.Writer, prompt string, slient bool) (string, error) {
+	fmt.Fprint(w, prompt)
 	if slient {
 		fd := os.Stdin.Fd()
 		state, err := term.SaveState(fd)
@@ -139,7 +193,7 @@ func readLine(prompt string, slient bool) (string, error) {
 		return "", err
 	}
 	if slient {
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
 
 	return string(line), nil
@@ -3,8 +3,6 @@ package main
 import (
 	"context"
 
-	auth "github.com/deislabs/oras/pkg/auth/docker"
-
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -12,8 +10,9 @@ import (
 type logoutOptions struct {
 	hostname string
 
-	debug   bool
-	configs []string
+	debug           bool
+	configs         []string
+	credentialStore string
 }
 
 func logoutCmd() *cobra.Command {
@@ -35,6 +34,7 @@ Example - Logout:
 
 	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
 	cmd.Flags().StringArrayVarP(&opts.configs, "config", "c", nil, "auth config path")
+	cmd.Flags().StringVarP(&opts.credentialStore, "credential-store", "", "", "credential store, docker or keyring, defaulting to the credentialStore of the oras config or docker")
 	return cmd
 }
 
@@ -43,7 +43,7 @@ func runLogout(opts logoutOptions) error {
 		logrus.SetLevel(logrus.DebugLevel)
 	}
 
-	cli, err := auth.NewClient(opts.configs...)
+	cli, err := newAuthClient(opts.credentialStore, opts.configs...)
 	if err != nil {
 		return err
 	}
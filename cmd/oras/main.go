@@ -3,16 +3,26 @@ package main
 import (
 	"os"
 
+	"github.com/deislabs/oras/internal/config"
+
 	"github.com/spf13/cobra"
 )
 
 func main() {
-	cmd := &cobra.Command{
-		Use:          "oras [command]",
-		SilenceUsage: true,
-	}
-	cmd.AddCommand(pullCmd(), pushCmd(), loginCmd(), logoutCmd(), versionCmd())
-	if err := cmd.Execute(); err != nil {
+	if err := newRootCmd().Execute(); err != nil {
 		os.Exit(1)
 	}
 }
+
+// newRootCmd creates the oras command with all its subcommands.
+func newRootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "oras [command]",
+		SilenceUsage:      true,
+		PersistentPreRunE: applyProfile,
+	}
+	cmd.PersistentFlags().StringVarP(&profile, "profile", "", profile, "profile of the oras config to use (env "+config.EnvProfile+")")
+	cmd.PersistentFlags().BoolVarP(&offline, "offline", "", offline, "refuse all network access, resolving tags from the reference cache (env "+envOffline+")")
+	cmd.AddCommand(pullCmd(), pushCmd(), copyCmd(), tagCmd(), repoCmd(), manifestCmd(), blobCmd(), layerCmd(), discoverCmd(), historyCmd(), inspectCmd(), resolveCmd(), attachCmd(), verifyCmd(), catCmd(), craneCmd(), schemaCmd(), completionCmd(cmd), completeRefCmd(), loginCmd(), logoutCmd(), authCmd(), versionCmd())
+	return cmd
+}
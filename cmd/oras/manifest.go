@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spf13/cobra"
+)
+
+func manifestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "manifest [command]",
+		Short: "Manifest operations",
+	}
+	cmd.AddCommand(manifestFetchCmd(), manifestFetchConfigCmd(), manifestPushCmd(), manifestDeleteCmd(), manifestIndexCmd())
+	return cmd
+}
+
+// defaultManifestMediaTypes are the manifest media types accepted if not
+// specified.
+var defaultManifestMediaTypes = []string{
+	ocispec.MediaTypeImageManifest,
+	ocispec.MediaTypeImageIndex,
+	images.MediaTypeDockerSchema2Manifest,
+	images.MediaTypeDockerSchema2ManifestList,
+}
+
+// newManifestResolver creates a resolver accepting the given manifest media
+// types, which are negotiated with the registry.
+func newManifestResolver(hosts docker.RegistryHosts, mediaTypes []string) remotes.Resolver {
+	if len(mediaTypes) == 0 {
+		mediaTypes = defaultManifestMediaTypes
+	}
+	return docker.NewResolver(docker.ResolverOptions{
+		Hosts: hosts,
+		Headers: http.Header{
+			"Accept": []string{strings.Join(mediaTypes, ", ")},
+		},
+	})
+}
+
+// writeJSON writes the JSON content to the file, or stdout if the path is
+// empty or "-". The content is indented if pretty is set.
+func writeJSON(path string, content []byte, pretty bool) error {
+	if pretty {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, content, "", "  "); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		content = buf.Bytes()
+	}
+	if path == "" || path == "-" {
+		_, err := os.Stdout.Write(content)
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// writeDescriptor writes the descriptor as JSON to the file, or stdout if the
+// path is empty or "-".
+func writeDescriptor(path string, desc ocispec.Descriptor, pretty bool) error {
+	content, err := json.Marshal(desc)
+	if err != nil {
+		return err
+	}
+	if !pretty {
+		content = append(content, '\n')
+	}
+	return writeJSON(path, content, pretty)
+}
+
+// fetchAll reads the whole content described by the descriptor and verifies
+// it against the digest.
+func fetchAll(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor) ([]byte, error) {
+	if desc.Size > maxMetadataSize {
+		return nil, fmt.Errorf("content size %d exceeds limit %d", desc.Size, maxMetadataSize)
+	}
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	content, err := ioutil.ReadAll(io.LimitReader(rc, desc.Size))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) != desc.Size {
+		return nil, fmt.Errorf("unexpected content size %d, expected %d", len(content), desc.Size)
+	}
+	if desc.Digest.Algorithm().FromBytes(content) != desc.Digest {
+		return nil, fmt.Errorf("content digest mismatch: %s", desc.Digest)
+	}
+	return content, nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/deislabs/oras/pkg/artifact"
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/oras"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type manifestDeleteOptions struct {
+	targetRef   string
+	force       bool
+	tombstone   bool
+	replacement string
+	verify      bool
+	format      formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func manifestDeleteCmd() *cobra.Command {
+	var opts manifestDeleteOptions
+	cmd := &cobra.Command{
+		Use:   "delete <name:tag|name@digest>",
+		Short: "Delete a manifest from a remote registry",
+		Long: `Delete a manifest from a remote registry
+
+All tags referencing the manifest are deleted as well. Where the registry
+disallows deletes, --tombstone replaces the manifest of the tag with an empty
+tombstone marking the tag as deprecated, on which resolve and pull warn, or
+fail with --no-deprecated.
+
+Example - Delete a manifest with confirmation:
+  oras manifest delete localhost:5000/hello:latest
+
+Example - Delete a manifest without confirmation:
+  oras manifest delete -f localhost:5000/hello:latest
+
+Example - Delete a manifest, or deprecate the tag in favor of v2 if deletes are disallowed:
+  oras manifest delete -f --tombstone --replacement localhost:5000/hello:v2 localhost:5000/hello:v1
+
+Example - Delete a manifest and check that it is gone:
+  oras manifest delete -f --verify localhost:5000/hello:latest
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runManifestDelete(opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.force, "force", "f", false, "delete without confirmation")
+	cmd.Flags().BoolVarP(&opts.tombstone, "tombstone", "", false, "replace the manifest of the tag with a tombstone if the registry disallows deletes")
+	cmd.Flags().StringVarP(&opts.replacement, "replacement", "", "", "reference of the replacement recorded in the tombstone")
+	cmd.Flags().BoolVarP(&opts.verify, "verify", "", false, "check that the manifest is deleted, or the tag is tombstoned, retrying on eventually consistent registries")
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runManifestDelete(opts manifestDeleteOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	opts.replacement = opts.remote.reference(opts.replacement)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	if err := checkWritable(opts.targetRef); err != nil {
+		return err
+	}
+	refspec, err := reference.Parse(opts.targetRef)
+	if err != nil {
+		return err
+	}
+	if opts.replacement != "" && !opts.tombstone {
+		return errors.New("--replacement requires --tombstone")
+	}
+	if opts.tombstone && (refspec.Object == "" || refspec.Digest() != "") {
+		return errors.New("--tombstone requires a reference by tag, i.e. <name:tag>")
+	}
+
+	hosts := opts.remote.registryHosts()
+	resolver := newManifestResolver(hosts, nil)
+	_, desc, err := resolver.Resolve(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+
+	if !opts.force {
+		confirmed, err := confirm(fmt.Sprintf("Are you sure you want to delete the manifest %s and all tags associated with it? [y/N] ", desc.Digest))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return nil
+		}
+	}
+
+	client := registry.NewClient(hosts)
+	err = client.DeleteManifest(ctx, opts.targetRef, desc.Digest)
+	switch {
+	case err == nil:
+	case errdefs.IsNotFound(err):
+		// deleted meanwhile, e.g. by a retried request whose response was lost
+	case errdefs.IsNotImplemented(err) && opts.tombstone:
+		return runTombstone(ctx, opts, resolver, desc)
+	case errdefs.IsNotImplemented(err):
+		return fmt.Errorf("%v\nthe registry disallows deletes, use --tombstone to deprecate the tag instead", err)
+	default:
+		return err
+	}
+	if opts.verify {
+		// resolved from the manifests only, as the resolver falls back to
+		// the blobs, which keep the manifest until garbage collected
+		ref := refspec.Locator + "@" + desc.Digest.String()
+		if err := waitUntil(ctx, opts.remote.retry.options(), func() (bool, error) {
+			_, _, err := client.ResolveManifest(ctx, ref, defaultManifestMediaTypes)
+			if errdefs.IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		}); err != nil {
+			return fmt.Errorf("failed to verify the deletion of %s: %v", ref, err)
+		}
+	}
+	if opts.format.enabled() {
+		return opts.format.write("", artifactResult{
+			Reference:  opts.targetRef,
+			Descriptor: desc,
+		})
+	}
+	fmt.Println("Deleted", opts.targetRef)
+	fmt.Println("Digest:", desc.Digest)
+	return nil
+}
+
+// runTombstone replaces the manifest of the tag with a tombstone recording
+// the deprecation of the tag.
+func runTombstone(ctx context.Context, opts manifestDeleteOptions, resolver remotes.Resolver, deleted ocispec.Descriptor) error {
+	fmt.Fprintln(os.Stderr, "WARNING: The registry disallows deletes, replacing the manifest of the tag with a tombstone")
+	desc, err := oras.PushTombstone(ctx, resolver, opts.targetRef, artifact.Tombstone{
+		Time:        time.Now(),
+		Digest:      deleted.Digest,
+		Replacement: opts.replacement,
+	})
+	if err != nil {
+		return err
+	}
+	if opts.verify {
+		if err := waitUntil(ctx, opts.remote.retry.options(), func() (bool, error) {
+			_, resolved, err := resolver.Resolve(ctx, opts.targetRef)
+			if err != nil {
+				return false, err
+			}
+			return resolved.Digest == desc.Digest, nil
+		}); err != nil {
+			return fmt.Errorf("failed to verify the tombstone of %s: %v", opts.targetRef, err)
+		}
+	}
+	if opts.format.enabled() {
+		return opts.format.write("", artifactResult{
+			Reference:  opts.targetRef,
+			Descriptor: desc,
+		})
+	}
+	fmt.Println("Tombstoned", opts.targetRef)
+	fmt.Println("Digest:", desc.Digest)
+	return nil
+}
+
+// waitUntil polls the condition with the backoff of the retry options until
+// it is met, failing once the retries are exhausted.
+func waitUntil(ctx context.Context, retry registry.RetryOptions, condition func() (bool, error)) error {
+	backoff := retry.Backoff
+	for attempt := 0; ; attempt++ {
+		ok, err := condition()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if attempt >= retry.MaxRetries {
+			return errors.New("not effective yet after all retries")
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if backoff *= 2; retry.MaxBackoff > 0 && backoff > retry.MaxBackoff {
+			backoff = retry.MaxBackoff
+		}
+	}
+}
+
+// confirm prompts the user and reads a yes or no answer from stdin.
+func confirm(prompt string) (bool, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/oras"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type manifestFetchOptions struct {
+	targetRef  string
+	mediaTypes []string
+	descriptor bool
+	pretty     bool
+	output     string
+	rawOutput  string
+	format     formatOptions
+	platform   platformOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func manifestFetchCmd() *cobra.Command {
+	var opts manifestFetchOptions
+	cmd := &cobra.Command{
+		Use:   "fetch <name:tag|name@digest>",
+		Short: "Fetch a manifest from a remote registry",
+		Long: `Fetch a manifest from a remote registry
+
+Example - Fetch the raw manifest:
+  oras manifest fetch localhost:5000/hello:latest
+
+Example - Fetch the manifest and indent the output:
+  oras manifest fetch --pretty localhost:5000/hello:latest
+
+Example - Fetch the descriptor of the manifest:
+  oras manifest fetch --descriptor localhost:5000/hello:latest
+
+Example - Fetch the manifest of a specific media type:
+  oras manifest fetch --media-type application/vnd.oci.image.manifest.v1+json localhost:5000/hello:latest
+
+Example - Fetch the linux/arm64 manifest of a multi-platform image:
+  oras manifest fetch --platform linux/arm64 localhost:5000/hello:latest
+
+Example - Save the exact responses of the registry to the directory "raw" for debugging:
+  oras manifest fetch --raw-output raw localhost:5000/hello:latest
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runManifestFetch(opts)
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&opts.mediaTypes, "media-type", "", nil, "accepted media types of the manifest")
+	cmd.Flags().BoolVarP(&opts.descriptor, "descriptor", "", false, "fetch the descriptor instead of the manifest")
+	cmd.Flags().BoolVarP(&opts.pretty, "pretty", "", false, "indent the JSON output")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "output file path, or stdout if not specified")
+	opts.format.applyFlags(cmd.Flags())
+	opts.platform.applyFlags(cmd.Flags())
+	cmd.Flags().StringVarP(&opts.rawOutput, "raw-output", "", "", "directory to save the exact bytes, headers and digests of the registry responses")
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runManifestFetch(opts manifestFetchOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	matcher, err := opts.platform.matcher()
+	if err != nil {
+		return err
+	}
+
+	hosts := opts.remote.registryHosts()
+	if opts.rawOutput != "" {
+		recorder, err := newRawRecorder(opts.rawOutput)
+		if err != nil {
+			return err
+		}
+		hosts = recorder.hosts(hosts)
+		defer func() {
+			// the responses are saved even if the fetch failed
+			if recordErr := recorder.Err(); recordErr != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: Error saving raw responses: %v\n", recordErr)
+			} else {
+				fmt.Fprintln(os.Stderr, "Saved raw responses to", opts.rawOutput)
+			}
+		}()
+	}
+	resolver := newManifestResolver(hosts, opts.mediaTypes)
+	name, desc, err := resolver.Resolve(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return err
+	}
+	if matcher != nil {
+		if desc, err = oras.SelectPlatform(ctx, fetcher, desc, matcher); err != nil {
+			return err
+		}
+	}
+	if opts.descriptor && !opts.format.enabled() {
+		return writeDescriptor(opts.output, desc, opts.pretty)
+	}
+	manifest, err := fetchAll(ctx, fetcher, desc)
+	if err != nil {
+		return err
+	}
+	if opts.format.enabled() {
+		return opts.format.write(opts.output, manifestResult{
+			Reference:  opts.targetRef,
+			Descriptor: desc,
+			Manifest:   manifest,
+		})
+	}
+	return writeJSON(opts.output, manifest, opts.pretty)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+
+	"github.com/containerd/containerd/images"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type manifestFetchConfigOptions struct {
+	targetRef  string
+	descriptor bool
+	pretty     bool
+	output     string
+	format     formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func manifestFetchConfigCmd() *cobra.Command {
+	var opts manifestFetchConfigOptions
+	cmd := &cobra.Command{
+		Use:   "fetch-config <name:tag|name@digest>",
+		Short: "Fetch the config of a manifest from a remote registry",
+		Long: `Fetch the config of a manifest from a remote registry
+
+Example - Fetch the config:
+  oras manifest fetch-config localhost:5000/hello:latest
+
+Example - Fetch the descriptor of the config:
+  oras manifest fetch-config --descriptor localhost:5000/hello:latest
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runManifestFetchConfig(opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.descriptor, "descriptor", "", false, "fetch the descriptor instead of the config")
+	cmd.Flags().BoolVarP(&opts.pretty, "pretty", "", false, "indent the JSON output")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "output file path, or stdout if not specified")
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runManifestFetchConfig(opts manifestFetchConfigOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+
+	hosts := opts.remote.registryHosts()
+	resolver := newManifestResolver(hosts, []string{ocispec.MediaTypeImageManifest, images.MediaTypeDockerSchema2Manifest})
+	name, desc, err := resolver.Resolve(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return err
+	}
+	content, err := fetchAll(ctx, fetcher, desc)
+	if err != nil {
+		return err
+	}
+	var manifest struct {
+		Config *ocispec.Descriptor `json:"config"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return err
+	}
+	if manifest.Config == nil {
+		return errors.New("manifest has no config")
+	}
+	if opts.format.enabled() {
+		return opts.format.write(opts.output, artifactResult{
+			Reference:  opts.targetRef,
+			Descriptor: *manifest.Config,
+		})
+	}
+	if opts.descriptor {
+		return writeDescriptor(opts.output, *manifest.Config, opts.pretty)
+	}
+
+	config, err := fetchAll(ctx, fetcher, *manifest.Config)
+	if err != nil {
+		return err
+	}
+	// configs are not necessarily JSON
+	return writeJSON(opts.output, config, opts.pretty && json.Valid(config))
+}
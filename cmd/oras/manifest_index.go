@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spf13/cobra"
+)
+
+func manifestIndexCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "index [command]",
+		Short: "Index operations",
+	}
+	cmd.AddCommand(manifestIndexCreateCmd(), manifestIndexUpdateCmd(), manifestIndexAnnotateCmd())
+	return cmd
+}
+
+// imageIndex is an image index with the media type field, which is not in
+// the index struct of the OCI image specification module.
+type imageIndex struct {
+	ocispec.Index
+	MediaType string `json:"mediaType"`
+}
+
+// indexEntryRef returns the reference of an entry of the index of targetRef,
+// which is either a tag or a digest in the repository of the index, or a full
+// reference to the same repository, in the default registry of the profile if
+// it has no registry.
+func indexEntryRef(targetRef, ref string) (string, error) {
+	target, err := reference.Parse(targetRef)
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(ref, "/") {
+		if _, err := digest.Parse(ref); err == nil {
+			return target.Locator + "@" + ref, nil
+		}
+		return target.Locator + ":" + ref, nil
+	}
+	ref = withDefaultRegistry(ref, defaultRegistry)
+	refspec, err := reference.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	if refspec.Locator != target.Locator {
+		return "", fmt.Errorf("%s: not in the repository of the index %s", ref, target.Locator)
+	}
+	return ref, nil
+}
+
+// updatedIndexRef returns the reference to push the updated index of targetRef
+// to: targetRef itself if it is a tag, or the digest of the updated index in
+// the repository of targetRef, since a digest cannot refer to other content.
+func updatedIndexRef(targetRef string, dgst digest.Digest) (string, error) {
+	refspec, err := reference.Parse(targetRef)
+	if err != nil {
+		return "", err
+	}
+	if refspec.Digest() == "" {
+		return targetRef, nil
+	}
+	return refspec.Locator + "@" + dgst.String(), nil
+}
+
+// resolveIndexEntry resolves the descriptor of a manifest to add to the index
+// of targetRef, with the platform detected from the config of the manifest.
+// The platform is left empty if the manifest is not an image.
+func resolveIndexEntry(ctx context.Context, resolver remotes.Resolver, targetRef, ref string) (ocispec.Descriptor, error) {
+	ref, err := indexEntryRef(targetRef, ref)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	name, desc, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc = ocispec.Descriptor{
+		MediaType: desc.MediaType,
+		Digest:    desc.Digest,
+		Size:      desc.Size,
+	}
+	if desc.MediaType != ocispec.MediaTypeImageManifest && desc.MediaType != images.MediaTypeDockerSchema2Manifest {
+		return desc, nil
+	}
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	manifestBytes, err := fetchAll(ctx, fetcher, desc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("%s: invalid manifest: %v", ref, err)
+	}
+	if manifest.Config.MediaType != ocispec.MediaTypeImageConfig && manifest.Config.MediaType != images.MediaTypeDockerSchema2Config {
+		return desc, nil
+	}
+	configBytes, err := fetchAll(ctx, fetcher, manifest.Config)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	// the platform fields of the image config, some of which are not in the
+	// image struct of the OCI image specification module
+	var config struct {
+		Architecture string   `json:"architecture"`
+		OS           string   `json:"os"`
+		OSVersion    string   `json:"os.version"`
+		OSFeatures   []string `json:"os.features"`
+		Variant      string   `json:"variant"`
+	}
+	if err := json.Unmarshal(configBytes, &config); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("%s: invalid config: %v", ref, err)
+	}
+	if config.OS != "" && config.Architecture != "" {
+		desc.Platform = &ocispec.Platform{
+			Architecture: config.Architecture,
+			OS:           config.OS,
+			OSVersion:    config.OSVersion,
+			OSFeatures:   config.OSFeatures,
+			Variant:      config.Variant,
+		}
+	}
+	return desc, nil
+}
+
+// pushManifestContent pushes the manifest content described by desc to ref.
+// Content already in the registry is not pushed again.
+func pushManifestContent(ctx context.Context, resolver remotes.Resolver, ref string, desc ocispec.Descriptor, manifest []byte) error {
+	pusher, err := resolver.Pusher(ctx, ref)
+	if err != nil {
+		return err
+	}
+	writer, err := pusher.Push(ctx, desc)
+	if err != nil {
+		if errdefs.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+	return content.Copy(ctx, writer, bytes.NewReader(manifest), desc.Size, desc.Digest)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+
+	"github.com/containerd/containerd/images"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type manifestIndexAnnotateOptions struct {
+	targetRef   string
+	platform    platformOptions
+	annotations []string
+	format      formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func manifestIndexAnnotateCmd() *cobra.Command {
+	var opts manifestIndexAnnotateOptions
+	cmd := &cobra.Command{
+		Use:   "annotate <name:tag|name@digest>",
+		Short: "Annotate the entry of a platform in an index",
+		Long: `Annotate the entry of a platform in an index
+
+The annotations of the descriptor of the platform-specific manifest are
+updated, and the index is pushed to the same reference. The other entries and
+fields of the index are kept, though compacted.
+
+Example - Annotate the linux/arm64 entry of an index:
+  oras manifest index annotate --platform linux/arm64 --annotation com.example.key=value localhost:5000/hello:latest
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runManifestIndexAnnotate(opts)
+		},
+	}
+
+	opts.platform.applyFlags(cmd.Flags())
+	cmd.Flags().StringArrayVarP(&opts.annotations, "annotation", "a", nil, "annotation in the form of key=value to set on the entry")
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runManifestIndexAnnotate(opts manifestIndexAnnotateOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	if err := checkWritable(opts.targetRef); err != nil {
+		return err
+	}
+	if opts.platform.platform == "" {
+		return errors.New("--platform is required")
+	}
+	matcher, err := opts.platform.matcher()
+	if err != nil {
+		return err
+	}
+	annotations, err := parseAnnotationFlags(opts.annotations)
+	if err != nil {
+		return err
+	}
+	if len(annotations) == 0 {
+		return errors.New("no annotations specified")
+	}
+
+	hosts := opts.remote.registryHosts()
+	resolver := newManifestResolver(hosts, []string{ocispec.MediaTypeImageIndex, images.MediaTypeDockerSchema2ManifestList})
+	name, desc, err := resolver.Resolve(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	if desc.MediaType != ocispec.MediaTypeImageIndex && desc.MediaType != images.MediaTypeDockerSchema2ManifestList {
+		return fmt.Errorf("%s is not an index: %s", opts.targetRef, desc.MediaType)
+	}
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return err
+	}
+	index, err := fetchAll(ctx, fetcher, desc)
+	if err != nil {
+		return err
+	}
+
+	index, err = annotateIndexEntry(index, func(entry ocispec.Descriptor) bool {
+		return entry.Platform != nil && matcher.Match(entry)
+	}, annotations)
+	if err != nil {
+		return fmt.Errorf("%s: %v", opts.targetRef, err)
+	}
+	desc = ocispec.Descriptor{
+		MediaType: desc.MediaType,
+		Digest:    digest.FromBytes(index),
+		Size:      int64(len(index)),
+	}
+
+	if err := pushManifestContent(ctx, resolver, opts.targetRef, desc, index); err != nil {
+		return err
+	}
+
+	if opts.format.enabled() {
+		return opts.format.write("", artifactResult{
+			Reference:  opts.targetRef,
+			Descriptor: desc,
+		})
+	}
+	fmt.Println("Annotated", opts.targetRef)
+	fmt.Println("Digest:", desc.Digest)
+	return nil
+}
+
+// annotateIndexEntry sets the annotations of the only entry of the index
+// matched, and returns the updated index. The other entries and fields of the
+// index are kept, though compacted.
+func annotateIndexEntry(index []byte, match func(ocispec.Descriptor) bool, annotations map[string]string) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(index, &fields); err != nil {
+		return nil, fmt.Errorf("invalid index: %v", err)
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(fields["manifests"], &entries); err != nil {
+		return nil, fmt.Errorf("invalid index manifests: %v", err)
+	}
+
+	target := -1
+	for i, raw := range entries {
+		var entry ocispec.Descriptor
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, fmt.Errorf("invalid index entry: %v", err)
+		}
+		if !match(entry) {
+			continue
+		}
+		if target >= 0 {
+			return nil, errors.New("more than one manifest matches the platform")
+		}
+		target = i
+	}
+	if target < 0 {
+		return nil, errors.New("no manifest matches the platform")
+	}
+
+	var entry map[string]json.RawMessage
+	if err := json.Unmarshal(entries[target], &entry); err != nil {
+		return nil, fmt.Errorf("invalid index entry: %v", err)
+	}
+	merged := make(map[string]string)
+	if raw, ok := entry["annotations"]; ok {
+		if err := json.Unmarshal(raw, &merged); err != nil {
+			return nil, fmt.Errorf("invalid index entry annotations: %v", err)
+		}
+		if merged == nil {
+			merged = make(map[string]string)
+		}
+	}
+	for k, v := range annotations {
+		merged[k] = v
+	}
+	raw, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	entry["annotations"] = raw
+	if entries[target], err = json.Marshal(entry); err != nil {
+		return nil, err
+	}
+
+	if fields["manifests"], err = json.Marshal(entries); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// parseAnnotationFlags parses the annotations in the form of key=value.
+func parseAnnotationFlags(flags []string) (map[string]string, error) {
+	annotations := make(map[string]string)
+	for _, flag := range flags {
+		parts := strings.SplitN(flag, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid annotation %q: expected key=value", flag)
+		}
+		annotations[parts[0]] = parts[1]
+	}
+	return annotations, nil
+}
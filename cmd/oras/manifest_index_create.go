@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+
+	"github.com/containerd/containerd/platforms"
+	"github.com/containerd/containerd/remotes"
+	digest "github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type manifestIndexCreateOptions struct {
+	targetRef   string
+	sourceRefs  []string
+	annotations []string
+	format      formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func manifestIndexCreateCmd() *cobra.Command {
+	var opts manifestIndexCreateOptions
+	cmd := &cobra.Command{
+		Use:   "create <name:tag> <tag|digest|name:tag|name@digest>...",
+		Short: "Create an index of manifests in the registry",
+		Long: `Create an index of manifests in the registry
+
+The manifests are referenced by tag or digest in the repository of the index.
+The platform of each image manifest is detected from its config.
+
+Example - Create a multi-platform index from the tags of the platforms:
+  oras manifest index create localhost:5000/hello:latest linux-amd64 linux-arm64
+
+Example - Create an index from the digests of the manifests:
+  oras manifest index create localhost:5000/hello:v1 sha256:9a2b... localhost:5000/hello@sha256:4f1c...
+
+Example - Create an annotated index:
+  oras manifest index create --annotation org.opencontainers.image.version=1.0 localhost:5000/hello:v1 linux-amd64 linux-arm64
+`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			opts.sourceRefs = args[1:]
+			return runManifestIndexCreate(opts)
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&opts.annotations, "annotation", "a", nil, "annotation in the form of key=value to set on the index")
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runManifestIndexCreate(opts manifestIndexCreateOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	if err := checkWritable(opts.targetRef); err != nil {
+		return err
+	}
+	annotations, err := parseAnnotationFlags(opts.annotations)
+	if err != nil {
+		return err
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+
+	hosts := opts.remote.registryHosts()
+	resolver := newManifestResolver(hosts, nil)
+	index, err := newIndex(ctx, resolver, opts.targetRef, opts.sourceRefs, annotations)
+	if err != nil {
+		return err
+	}
+
+	indexBytes, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(indexBytes),
+		Size:      int64(len(indexBytes)),
+	}
+	if err := pushManifestContent(ctx, resolver, opts.targetRef, desc, indexBytes); err != nil {
+		return err
+	}
+
+	if opts.format.enabled() {
+		return opts.format.write("", artifactResult{
+			Reference:  opts.targetRef,
+			Descriptor: desc,
+		})
+	}
+	for _, entry := range index.Manifests {
+		fmt.Println("Added", entry.Digest, formatPlatform(entry.Platform))
+	}
+	fmt.Println("Created", opts.targetRef)
+	fmt.Println("Digest:", desc.Digest)
+	return nil
+}
+
+// newIndex creates an index of the manifests of sourceRefs, which are in the
+// repository of targetRef.
+func newIndex(ctx context.Context, resolver remotes.Resolver, targetRef string, sourceRefs []string, annotations map[string]string) (imageIndex, error) {
+	index := imageIndex{
+		Index: ocispec.Index{
+			Versioned: specs.Versioned{
+				SchemaVersion: 2,
+			},
+			Manifests:   []ocispec.Descriptor{},
+			Annotations: annotations,
+		},
+		MediaType: ocispec.MediaTypeImageIndex,
+	}
+	added := make(map[digest.Digest]bool)
+	for _, ref := range sourceRefs {
+		entry, err := resolveIndexEntry(ctx, resolver, targetRef, ref)
+		if err != nil {
+			return imageIndex{}, err
+		}
+		if added[entry.Digest] {
+			return imageIndex{}, fmt.Errorf("%s: %s is already in the index", ref, entry.Digest)
+		}
+		added[entry.Digest] = true
+		index.Manifests = append(index.Manifests, entry)
+	}
+	return index, nil
+}
+
+// formatPlatform formats the platform of an index entry as os/arch[/variant],
+// or returns "-" if none.
+func formatPlatform(platform *ocispec.Platform) string {
+	if platform == nil {
+		return "-"
+	}
+	return platforms.Format(*platform)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/containerd/containerd/platforms"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIndex = `{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b", "size": 1, "platform": {"architecture": "amd64", "os": "linux"}, "x-vendor": {"key": "a \"quoted\" ] } value"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:c6f2ef5b88f6c8e3f1bb3b8ea8d0a9f1f0b2b6a1b0e1a1f1b2c3d4e5f6a7b8c9", "size": 2, "platform": {"architecture": "arm64", "os": "linux"}, "annotations": {"existing": "true"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:d4735e3a265e16eee03f59718b9b5d03019c07d8b6c51f90da3a666eec13ab35", "size": 3, "annotations": null}
+  ],
+  "annotations": {"index": "true"},
+  "x-index-extension": [1, 2, {"nested": []}]
+}`
+
+func platformMatch(platform string) func(ocispec.Descriptor) bool {
+	matcher := platforms.Only(platforms.MustParse(platform))
+	return func(entry ocispec.Descriptor) bool {
+		return entry.Platform != nil && matcher.Match(*entry.Platform)
+	}
+}
+
+func TestAnnotateIndexEntry(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		index    string
+		match    func(ocispec.Descriptor) bool
+		wantErr  string
+		entry    int
+		expected map[string]string
+	}{
+		{
+			name:     "entry without annotations",
+			index:    testIndex,
+			match:    platformMatch("linux/amd64"),
+			entry:    0,
+			expected: map[string]string{"key": "value"},
+		},
+		{
+			name:     "entry with annotations",
+			index:    testIndex,
+			match:    platformMatch("linux/arm64"),
+			entry:    1,
+			expected: map[string]string{"existing": "true", "key": "value"},
+		},
+		{
+			name:  "entry with null annotations",
+			index: testIndex,
+			match: func(entry ocispec.Descriptor) bool {
+				return entry.Size == 3
+			},
+			entry:    2,
+			expected: map[string]string{"key": "value"},
+		},
+		{
+			name:    "no match",
+			index:   testIndex,
+			match:   platformMatch("windows/amd64"),
+			wantErr: "no manifest matches the platform",
+		},
+		{
+			name:  "several matches",
+			index: testIndex,
+			match: func(entry ocispec.Descriptor) bool {
+				return entry.Platform != nil
+			},
+			wantErr: "more than one manifest matches the platform",
+		},
+		{
+			name:    "invalid index",
+			index:   `{"manifests": [}`,
+			match:   platformMatch("linux/amd64"),
+			wantErr: "invalid index",
+		},
+		{
+			name:    "invalid manifests",
+			index:   `{"manifests": {}}`,
+			match:   platformMatch("linux/amd64"),
+			wantErr: "invalid index manifests",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			updated, err := annotateIndexEntry([]byte(tc.index), tc.match, map[string]string{"key": "value"})
+			if tc.wantErr != "" {
+				assert.Contains(t, errString(err), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var original, index struct {
+				Manifests  []map[string]interface{} `json:"manifests"`
+				Extensions []interface{}            `json:"x-index-extension"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(tc.index), &original))
+			require.NoError(t, json.Unmarshal(updated, &index))
+			require.Len(t, index.Manifests, len(original.Manifests))
+			assert.Equal(t, original.Extensions, index.Extensions, "unknown fields of the index kept")
+			for i := range index.Manifests {
+				if i != tc.entry {
+					assert.Equal(t, original.Manifests[i], index.Manifests[i], "other entries kept")
+					continue
+				}
+				annotations := index.Manifests[i]["annotations"].(map[string]interface{})
+				assert.Len(t, annotations, len(tc.expected))
+				for k, v := range tc.expected {
+					assert.Equal(t, v, annotations[k])
+				}
+				delete(index.Manifests[i], "annotations")
+				delete(original.Manifests[i], "annotations")
+				assert.Equal(t, original.Manifests[i], index.Manifests[i], "other fields of the entry kept")
+			}
+		})
+	}
+}
+
+func TestIndexEntryRef(t *testing.T) {
+	const target = "localhost:5000/hello:latest"
+	for _, tc := range []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "v1", want: "localhost:5000/hello:v1"},
+		{ref: "sha256:d4735e3a265e16eee03f59718b9b5d03019c07d8b6c51f90da3a666eec13ab35", want: "localhost:5000/hello@sha256:d4735e3a265e16eee03f59718b9b5d03019c07d8b6c51f90da3a666eec13ab35"},
+		{ref: "localhost:5000/hello:v2", want: "localhost:5000/hello:v2"},
+		{ref: "localhost:5000/other:v2", wantErr: true},
+	} {
+		got, err := indexEntryRef(target, tc.ref)
+		if tc.wantErr {
+			assert.Error(t, err, tc.ref)
+			continue
+		}
+		assert.NoError(t, err, tc.ref)
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestNewIndex(t *testing.T) {
+	resolver := newTestResolver()
+	config := resolver.add("", map[string]interface{}{
+		"architecture": "arm64",
+		"os":           "linux",
+		"variant":      "v8",
+	})
+	config.MediaType = ocispec.MediaTypeImageConfig
+	image := resolver.add("localhost:5000/hello:arm64", map[string]interface{}{
+		"schemaVersion": 2,
+		"config":        config,
+	})
+	resolver.refs["localhost:5000/hello@"+image.Digest.String()] = image
+	artifact := resolver.add("localhost:5000/hello:artifact", map[string]interface{}{
+		"schemaVersion": 2,
+		"config": ocispec.Descriptor{
+			MediaType: "application/vnd.unknown.config.v1+json",
+			Digest:    digest.FromString("{}"),
+			Size:      2,
+		},
+	})
+
+	const target = "localhost:5000/hello:latest"
+	index, err := newIndex(context.Background(), resolver, target, []string{"arm64", "artifact"}, map[string]string{"key": "value"})
+	require.NoError(t, err)
+	assert.Equal(t, ocispec.MediaTypeImageIndex, index.MediaType)
+	assert.Equal(t, 2, index.SchemaVersion)
+	assert.Equal(t, map[string]string{"key": "value"}, index.Annotations)
+	assert.Equal(t, []ocispec.Descriptor{
+		{
+			MediaType: image.MediaType,
+			Digest:    image.Digest,
+			Size:      image.Size,
+			Platform: &ocispec.Platform{
+				Architecture: "arm64",
+				OS:           "linux",
+				Variant:      "v8",
+			},
+		},
+		artifact,
+	}, index.Manifests)
+
+	for _, tc := range []struct {
+		name    string
+		refs    []string
+		wantErr string
+	}{
+		{
+			name:    "duplicate",
+			refs:    []string{"arm64", image.Digest.String()},
+			wantErr: "is already in the index",
+		},
+		{
+			name:    "not found",
+			refs:    []string{"missing"},
+			wantErr: "not found",
+		},
+		{
+			name:    "other repository",
+			refs:    []string{"localhost:5000/other:arm64"},
+			wantErr: "not in the repository of the index",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newIndex(context.Background(), resolver, target, tc.refs, nil)
+			assert.Contains(t, errString(err), tc.wantErr)
+		})
+	}
+}
+
+func TestUpdateIndexEntries(t *testing.T) {
+	var original struct {
+		Manifests  []map[string]interface{} `json:"manifests"`
+		Extensions []interface{}            `json:"x-index-extension"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(testIndex), &original))
+	amd64 := digest.Digest("sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b")
+	arm64 := digest.Digest("sha256:c6f2ef5b88f6c8e3f1bb3b8ea8d0a9f1f0b2b6a1b0e1a1f1b2c3d4e5f6a7b8c9")
+	added := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromString("s390x"),
+		Size:      4,
+		Platform: &ocispec.Platform{
+			Architecture: "s390x",
+			OS:           "linux",
+		},
+	}
+
+	t.Run("remove and add", func(t *testing.T) {
+		updated, err := updateIndexEntries([]byte(testIndex), []digest.Digest{arm64}, []ocispec.Descriptor{added})
+		require.NoError(t, err)
+		var index struct {
+			MediaType   string                   `json:"mediaType"`
+			Manifests   []map[string]interface{} `json:"manifests"`
+			Annotations map[string]string        `json:"annotations"`
+			Extensions  []interface{}            `json:"x-index-extension"`
+		}
+		require.NoError(t, json.Unmarshal(updated, &index))
+		assert.Equal(t, ocispec.MediaTypeImageIndex, index.MediaType)
+		assert.Equal(t, map[string]string{"index": "true"}, index.Annotations)
+		assert.Equal(t, original.Extensions, index.Extensions, "unknown fields of the index kept")
+		require.Len(t, index.Manifests, 3)
+		assert.Equal(t, original.Manifests[0], index.Manifests[0], "unknown fields of the entries kept")
+		assert.Equal(t, original.Manifests[2], index.Manifests[1])
+		assert.Equal(t, added.Digest.String(), index.Manifests[2]["digest"])
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		replaced := added
+		replaced.Digest = amd64
+		updated, err := updateIndexEntries([]byte(testIndex), []digest.Digest{amd64}, []ocispec.Descriptor{replaced})
+		require.NoError(t, err)
+		var index ocispec.Index
+		require.NoError(t, json.Unmarshal(updated, &index))
+		require.Len(t, index.Manifests, 3)
+		assert.Equal(t, replaced, index.Manifests[2])
+	})
+
+	for _, tc := range []struct {
+		name    string
+		index   string
+		removed []digest.Digest
+		added   []ocispec.Descriptor
+		wantErr string
+	}{
+		{
+			name:    "remove missing",
+			index:   testIndex,
+			removed: []digest.Digest{added.Digest},
+			wantErr: "is not in the index",
+		},
+		{
+			name:    "add present",
+			index:   testIndex,
+			added:   []ocispec.Descriptor{{Digest: arm64}},
+			wantErr: "is already in the index",
+		},
+		{
+			name:    "add twice",
+			index:   testIndex,
+			added:   []ocispec.Descriptor{added, added},
+			wantErr: "is already in the index",
+		},
+		{
+			name:    "invalid index",
+			index:   `{"manifests": [}`,
+			wantErr: "invalid index",
+		},
+		{
+			name:    "invalid entry",
+			index:   `{"manifests": [{"digest": 1}]}`,
+			wantErr: "invalid index entry",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := updateIndexEntries([]byte(tc.index), tc.removed, tc.added)
+			assert.Contains(t, errString(err), tc.wantErr)
+		})
+	}
+}
+
+func TestUpdatedIndexRef(t *testing.T) {
+	dgst := digest.FromString("updated")
+	for _, tc := range []struct {
+		ref  string
+		want string
+	}{
+		{ref: "localhost:5000/hello:latest", want: "localhost:5000/hello:latest"},
+		{ref: "localhost:5000/hello@" + digest.FromString("original").String(), want: "localhost:5000/hello@" + dgst.String()},
+	} {
+		got, err := updatedIndexRef(tc.ref, dgst)
+		assert.NoError(t, err, tc.ref)
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
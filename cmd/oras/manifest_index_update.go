@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+
+	"github.com/containerd/containerd/images"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type manifestIndexUpdateOptions struct {
+	targetRef string
+	add       []string
+	remove    []string
+	format    formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func manifestIndexUpdateCmd() *cobra.Command {
+	var opts manifestIndexUpdateOptions
+	cmd := &cobra.Command{
+		Use:   "update <name:tag|name@digest>",
+		Short: "Add or remove manifests of an index in the registry",
+		Long: `Add or remove manifests of an index in the registry
+
+The manifests are referenced by tag or digest in the repository of the index.
+The manifests are removed before adding, and the updated index is pushed to
+the same tag, or by its new digest if the index is referenced by digest. The
+other entries and fields of the index are kept, though compacted.
+
+Example - Add the manifest of a platform to an index:
+  oras manifest index update --add linux-arm64 localhost:5000/hello:latest
+
+Example - Replace the manifest of a platform:
+  oras manifest index update --remove sha256:9a2b... --add linux-amd64 localhost:5000/hello:latest
+
+Example - Add the manifest of a platform to an index referenced by digest, pushing a new index:
+  oras manifest index update --add linux-arm64 localhost:5000/hello@sha256:5f3c...
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runManifestIndexUpdate(opts)
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&opts.add, "add", "", nil, "manifest to add by tag or digest")
+	cmd.Flags().StringArrayVarP(&opts.remove, "remove", "", nil, "manifest to remove by tag or digest")
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runManifestIndexUpdate(opts manifestIndexUpdateOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	if err := checkWritable(opts.targetRef); err != nil {
+		return err
+	}
+	if len(opts.add) == 0 && len(opts.remove) == 0 {
+		return errors.New("no manifests to add or remove")
+	}
+
+	hosts := opts.remote.registryHosts()
+	resolver := newManifestResolver(hosts, nil)
+	name, desc, err := resolver.Resolve(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	if desc.MediaType != ocispec.MediaTypeImageIndex && desc.MediaType != images.MediaTypeDockerSchema2ManifestList {
+		return fmt.Errorf("%s is not an index: %s", opts.targetRef, desc.MediaType)
+	}
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return err
+	}
+	index, err := fetchAll(ctx, fetcher, desc)
+	if err != nil {
+		return err
+	}
+
+	var removed []digest.Digest
+	for _, ref := range opts.remove {
+		dgst, err := digest.Parse(ref)
+		if err != nil {
+			entryRef, err := indexEntryRef(opts.targetRef, ref)
+			if err != nil {
+				return err
+			}
+			_, entry, err := resolver.Resolve(ctx, entryRef)
+			if err != nil {
+				return err
+			}
+			dgst = entry.Digest
+		}
+		removed = append(removed, dgst)
+	}
+	var added []ocispec.Descriptor
+	for _, ref := range opts.add {
+		entry, err := resolveIndexEntry(ctx, resolver, opts.targetRef, ref)
+		if err != nil {
+			return err
+		}
+		added = append(added, entry)
+	}
+	if index, err = updateIndexEntries(index, removed, added); err != nil {
+		return fmt.Errorf("%s: %v", opts.targetRef, err)
+	}
+	desc = ocispec.Descriptor{
+		MediaType: desc.MediaType,
+		Digest:    digest.FromBytes(index),
+		Size:      int64(len(index)),
+	}
+	updatedRef, err := updatedIndexRef(opts.targetRef, desc.Digest)
+	if err != nil {
+		return err
+	}
+	if err := pushManifestContent(ctx, resolver, updatedRef, desc, index); err != nil {
+		return err
+	}
+
+	if opts.format.enabled() {
+		return opts.format.write("", artifactResult{
+			Reference:  updatedRef,
+			Descriptor: desc,
+		})
+	}
+	for _, dgst := range removed {
+		fmt.Println("Removed", dgst)
+	}
+	for _, entry := range added {
+		fmt.Println("Added", entry.Digest, formatPlatform(entry.Platform))
+	}
+	fmt.Println("Updated", updatedRef)
+	fmt.Println("Digest:", desc.Digest)
+	return nil
+}
+
+// updateIndexEntries removes the entries of the digests from the index, and
+// appends the added entries. The other entries and fields of the index are
+// kept, though compacted.
+func updateIndexEntries(index []byte, removed []digest.Digest, added []ocispec.Descriptor) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(index, &fields); err != nil {
+		return nil, fmt.Errorf("invalid index: %v", err)
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(fields["manifests"], &entries); err != nil {
+		return nil, fmt.Errorf("invalid index manifests: %v", err)
+	}
+
+	found := make(map[digest.Digest]bool)
+	for _, dgst := range removed {
+		found[dgst] = false
+	}
+	kept := make([]json.RawMessage, 0, len(entries)+len(added))
+	present := make(map[digest.Digest]bool)
+	for _, raw := range entries {
+		var entry ocispec.Descriptor
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, fmt.Errorf("invalid index entry: %v", err)
+		}
+		if _, ok := found[entry.Digest]; ok {
+			found[entry.Digest] = true
+			continue
+		}
+		present[entry.Digest] = true
+		kept = append(kept, raw)
+	}
+	for _, dgst := range removed {
+		if !found[dgst] {
+			return nil, fmt.Errorf("%s is not in the index", dgst)
+		}
+	}
+	for _, entry := range added {
+		if present[entry.Digest] {
+			return nil, fmt.Errorf("%s is already in the index", entry.Digest)
+		}
+		present[entry.Digest] = true
+		raw, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		kept = append(kept, raw)
+	}
+
+	raw, err := json.Marshal(kept)
+	if err != nil {
+		return nil, err
+	}
+	fields["manifests"] = raw
+	return json.Marshal(fields)
+}
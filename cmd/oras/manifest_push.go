@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type manifestPushOptions struct {
+	targetRef  string
+	fileRef    string
+	mediaType  string
+	descriptor bool
+	pretty     bool
+	format     formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func manifestPushCmd() *cobra.Command {
+	var opts manifestPushOptions
+	cmd := &cobra.Command{
+		Use:   "push <name[:tag|@digest]> <file>",
+		Short: "Push a manifest to a remote registry",
+		Long: `Push a manifest to a remote registry
+
+Example - Push a manifest from a file:
+  oras manifest push localhost:5000/hello:latest manifest.json
+
+Example - Push a manifest from stdin:
+  oras manifest push localhost:5000/hello:latest -
+
+Example - Push a manifest with a specific media type:
+  oras manifest push --media-type application/vnd.docker.distribution.manifest.v2+json localhost:5000/hello:latest manifest.json
+
+Example - Push a manifest and print its descriptor:
+  oras manifest push --descriptor localhost:5000/hello:latest manifest.json
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			opts.fileRef = args[1]
+			return runManifestPush(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.mediaType, "media-type", "", "", "media type of the manifest, read from the manifest if not specified")
+	cmd.Flags().BoolVarP(&opts.descriptor, "descriptor", "", false, "print the descriptor of the pushed manifest")
+	cmd.Flags().BoolVarP(&opts.pretty, "pretty", "", false, "indent the descriptor output")
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runManifestPush(opts manifestPushOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	if err := checkWritable(opts.targetRef); err != nil {
+		return err
+	}
+
+	var (
+		manifest []byte
+		err      error
+	)
+	if opts.fileRef == "-" {
+		manifest, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		manifest, err = ioutil.ReadFile(opts.fileRef)
+	}
+	if err != nil {
+		return err
+	}
+	mediaType, err := manifestMediaType(manifest, opts.mediaType)
+	if err != nil {
+		return err
+	}
+	desc := ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(manifest),
+		Size:      int64(len(manifest)),
+	}
+
+	hosts := opts.remote.registryHosts()
+	resolver := newManifestResolver(hosts, []string{mediaType})
+	pusher, err := resolver.Pusher(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	writer, err := pusher.Push(ctx, desc)
+	if err != nil {
+		if !errdefs.IsAlreadyExists(err) {
+			return err
+		}
+	} else if err := content.Copy(ctx, writer, bytes.NewReader(manifest), desc.Size, desc.Digest); err != nil {
+		return err
+	}
+
+	if opts.format.enabled() {
+		return opts.format.write("", artifactResult{
+			Reference:  opts.targetRef,
+			Descriptor: desc,
+		})
+	}
+	if opts.descriptor {
+		return writeDescriptor("", desc, opts.pretty)
+	}
+	fmt.Println("Pushed", opts.targetRef)
+	fmt.Println("Digest:", desc.Digest)
+	return nil
+}
+
+// manifestMediaType returns the specified media type, or the media type
+// declared by the manifest. The OCI image manifest media type is assumed if
+// neither is available.
+func manifestMediaType(manifest []byte, specified string) (string, error) {
+	var m struct {
+		MediaType string `json:"mediaType"`
+	}
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return "", fmt.Errorf("invalid manifest: %v", err)
+	}
+	if specified != "" {
+		if m.MediaType != "" && m.MediaType != specified {
+			return "", fmt.Errorf("media type %q does not match the manifest media type %q", specified, m.MediaType)
+		}
+		return specified, nil
+	}
+	if m.MediaType != "" {
+		return m.MediaType, nil
+	}
+	return ocispec.MediaTypeImageManifest, nil
+}
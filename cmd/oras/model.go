@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/deislabs/oras/pkg/artifact"
+	"github.com/deislabs/oras/pkg/content"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// newModelConfig describes the model consisting of the files, which may be
+// split into shards.
+func newModelConfig(files []ocispec.Descriptor, name, format string) artifact.ModelConfig {
+	config := artifact.ModelConfig{
+		Name:   name,
+		Format: format,
+		Files:  []artifact.ModelFile{},
+	}
+	index := make(map[string]int)
+	for _, desc := range files {
+		fileName, sharded := desc.Annotations[content.AnnotationShardFile]
+		if !sharded {
+			fileName, _ = content.ResolveName(desc)
+		}
+		i, ok := index[fileName]
+		if !ok {
+			i = len(config.Files)
+			index[fileName] = i
+			config.Files = append(config.Files, artifact.ModelFile{
+				Name:   fileName,
+				Digest: desc.Digest,
+			})
+			if sharded {
+				config.Files[i].Digest = digest.Digest(desc.Annotations[content.AnnotationShardFileDigest])
+			}
+		}
+		config.Files[i].Size += desc.Size
+		config.Files[i].Shards++
+	}
+	return config
+}
+
+// addModelConfig adds the model config of the files to the store. The config
+// is written to a temporary file, which should be removed by the caller after
+// pushing.
+func addModelConfig(store *content.FileStore, files []ocispec.Descriptor, name, format string) (ocispec.Descriptor, string, error) {
+	configBytes, err := json.Marshal(newModelConfig(files, name, format))
+	if err != nil {
+		return ocispec.Descriptor{}, "", err
+	}
+	file, err := ioutil.TempFile("", content.TempFilePattern)
+	if err != nil {
+		return ocispec.Descriptor{}, "", err
+	}
+	_, err = file.Write(configBytes)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		var config ocispec.Descriptor
+		if config, err = store.Add(annotationConfig, artifact.ModelConfigMediaType, file.Name()); err == nil {
+			config.Annotations = nil
+			return config, file.Name(), nil
+		}
+	}
+	os.Remove(file.Name())
+	return ocispec.Descriptor{}, "", err
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/deislabs/oras/pkg/content"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	units "github.com/docker/go-units"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// envS3Endpoint overrides the endpoint of s3:// outputs, for S3
+	// compatible storages.
+	envS3Endpoint = "ORAS_S3_ENDPOINT"
+
+	// gcsEndpoint is the S3 compatible XML API of Google Cloud Storage,
+	// authenticated with HMAC keys.
+	gcsEndpoint = "https://storage.googleapis.com"
+)
+
+// objectStorageOptions are the options of uploading to an object storage.
+type objectStorageOptions struct {
+	partSize    string
+	concurrency int
+}
+
+func (opts *objectStorageOptions) applyFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&opts.partSize, "upload-part-size", "", "16MiB", "size of the parts of the multipart uploads to object storage")
+	fs.IntVarP(&opts.concurrency, "upload-concurrency", "", s3manager.DefaultUploadConcurrency, "maximum number of parts of a file uploaded to object storage in parallel")
+}
+
+// isObjectStorageURL tells if the output is an s3:// or gs:// URL.
+func isObjectStorageURL(output string) bool {
+	return strings.HasPrefix(output, "s3://") || strings.HasPrefix(output, "gs://")
+}
+
+// newObjectStore creates the store uploading to the bucket and prefix of the
+// s3:// or gs:// URL. Uploads are refused in offline mode.
+func (opts *objectStorageOptions) newObjectStore(ctx context.Context, output string) (*content.ObjectStore, error) {
+	u, err := url.Parse(output)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid output %q: missing bucket", output)
+	}
+	if offline {
+		return nil, fmt.Errorf("%s: %v", output, errOffline)
+	}
+	partSize, err := units.RAMInBytes(opts.partSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload part size %q: %v", opts.partSize, err)
+	}
+	if partSize < s3manager.MinUploadPartSize {
+		return nil, fmt.Errorf("invalid upload part size %q: less than %s", opts.partSize, units.BytesSize(float64(s3manager.MinUploadPartSize)))
+	}
+	if opts.concurrency < 1 {
+		return nil, fmt.Errorf("invalid upload concurrency %d", opts.concurrency)
+	}
+
+	config := aws.NewConfig()
+	switch u.Scheme {
+	case "gs":
+		config = config.WithEndpoint(gcsEndpoint).WithRegion("auto")
+	default:
+		if endpoint := os.Getenv(envS3Endpoint); endpoint != "" {
+			config = config.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+		}
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(sess.Config.Region) == "" {
+		region, err := s3manager.GetBucketRegion(ctx, sess, u.Host, "us-east-1")
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate bucket %s: %v", u.Host, err)
+		}
+		sess.Config.Region = aws.String(region)
+	}
+
+	uploader := s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+		u.PartSize = partSize
+		u.Concurrency = opts.concurrency
+	})
+	return content.NewObjectStore(&s3Uploader{
+		uploader: uploader,
+		bucket:   u.Host,
+	}, u.Path), nil
+}
+
+// s3Uploader uploads the objects to an S3 bucket with multipart uploads.
+type s3Uploader struct {
+	uploader *s3manager.Uploader
+	bucket   string
+}
+
+// Upload implements content.ObjectUploader.
+func (u *s3Uploader) Upload(ctx context.Context, key string, r io.Reader) error {
+	_, err := u.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(u.bucket),
+		Key:    aws.String(key),
+		Body:   r,
+	})
+	return err
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	ccontent "github.com/containerd/containerd/content"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsObjectStorageURL(t *testing.T) {
+	for output, want := range map[string]bool{
+		"s3://bucket/prefix": true,
+		"gs://bucket":        true,
+		"bucket/prefix":      false,
+		"https://bucket":     false,
+		"":                   false,
+	} {
+		assert.Equal(t, want, isObjectStorageURL(output), output)
+	}
+}
+
+func TestNewObjectStoreInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		output  string
+		opts    objectStorageOptions
+		wantErr string
+	}{
+		{"missing bucket", "s3:///prefix", objectStorageOptions{partSize: "16MiB", concurrency: 1}, "missing bucket"},
+		{"invalid part size", "s3://bucket", objectStorageOptions{partSize: "large", concurrency: 1}, "invalid upload part size"},
+		{"small part size", "s3://bucket", objectStorageOptions{partSize: "1MiB", concurrency: 1}, "less than"},
+		{"invalid concurrency", "s3://bucket", objectStorageOptions{partSize: "16MiB", concurrency: 0}, "invalid upload concurrency"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.opts.newObjectStore(context.Background(), tc.output)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestNewObjectStoreS3Endpoint(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		objects = make(map[string]string)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		objects[r.URL.Path] = string(body)
+		lock.Unlock()
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	defer server.Close()
+
+	for key, value := range map[string]string{
+		envS3Endpoint:                 server.URL,
+		"AWS_REGION":                  "us-east-1",
+		"AWS_ACCESS_KEY_ID":           "access",
+		"AWS_SECRET_ACCESS_KEY":       "secret",
+		"AWS_CONFIG_FILE":             "/nonexistent/config",
+		"AWS_SHARED_CREDENTIALS_FILE": "/nonexistent/credentials",
+		"AWS_PROFILE":                 "",
+	} {
+		defer setenv(t, key, value)()
+	}
+
+	opts := objectStorageOptions{partSize: "16MiB", concurrency: 1}
+	store, err := opts.newObjectStore(context.Background(), "s3://bucket/prefix/")
+	require.NoError(t, err)
+	key, err := store.Key("dir/hi.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "prefix/dir/hi.txt", key)
+
+	// the objects are uploaded to the endpoint in the path style
+	ctx := context.Background()
+	data := []byte("hi")
+	desc := ocispec.Descriptor{
+		MediaType: "text/plain",
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+		Annotations: map[string]string{
+			ocispec.AnnotationTitle: "dir/hi.txt",
+		},
+	}
+	writer, err := store.Writer(ctx, ccontent.WithDescriptor(desc))
+	require.NoError(t, err)
+	defer writer.Close()
+	require.NoError(t, ccontent.Copy(ctx, writer, bytes.NewReader(data), desc.Size, desc.Digest))
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, map[string]string{"/bucket/prefix/dir/hi.txt": "hi"}, objects)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/deislabs/oras/pkg/content"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spf13/pflag"
+)
+
+const formatJSON = "json"
+
+// formatOptions are the options of the machine-readable output, which
+// replaces the human readable output if specified.
+type formatOptions struct {
+	format string
+}
+
+func (opts *formatOptions) applyFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&opts.format, "format", "", "", `machine-readable output: "json", or a Go template, e.g. "{{.Digest}}"`)
+}
+
+// enabled reports whether the machine-readable output is requested.
+func (opts *formatOptions) enabled() bool {
+	return opts.format != ""
+}
+
+// template parses the Go template of the format. The `json` function is
+// available to encode values in JSON.
+func (opts *formatOptions) template() (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			content, err := json.Marshal(v)
+			return string(content), err
+		},
+	}).Parse(opts.format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// validate checks the format before any work is done.
+func (opts *formatOptions) validate() error {
+	if !opts.enabled() || opts.format == formatJSON {
+		return nil
+	}
+	_, err := opts.template()
+	return err
+}
+
+// write writes the data in the format to the file, or stdout if the path is
+// empty or "-".
+func (opts *formatOptions) write(path string, data interface{}) error {
+	if opts.format == formatJSON {
+		content, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeJSON(path, append(content, '\n'), false)
+	}
+
+	tmpl, err := opts.template()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	return writeJSON(path, buf.Bytes(), false)
+}
+
+// artifactResult is the machine-readable output of the commands handling an
+// artifact.
+type artifactResult struct {
+	Reference string `json:"reference"`
+	ocispec.Descriptor
+	Subject *ocispec.Descriptor `json:"subject,omitempty"`
+	Files   []fileResult        `json:"files,omitempty"`
+	// Changed is set in the idempotent mode, and false if the remote state
+	// already matched.
+	Changed *bool `json:"changed,omitempty"`
+	// Merkle is the referrer holding the Merkle trees of the files.
+	Merkle *ocispec.Descriptor `json:"merkle,omitempty"`
+	// History is the referrer holding the history of the tag.
+	History *ocispec.Descriptor `json:"history,omitempty"`
+}
+
+// newTrue returns a pointer to true.
+func newTrue() *bool {
+	b := true
+	return &b
+}
+
+// fileResult maps a file to its blob. Files split into shards or chunks are
+// mapped to the blobs of the parts, with the descriptor of the whole file, or
+// of its chunk recipe.
+type fileResult struct {
+	Path string `json:"path"`
+	ocispec.Descriptor
+	Parts []ocispec.Descriptor `json:"parts,omitempty"`
+}
+
+// newFileResults maps the named blobs to the files in the directory. Shards
+// and chunks are merged into the entries of the files they are split from.
+func newFileResults(dir string, descs []ocispec.Descriptor) []fileResult {
+	var files []fileResult
+	split := make(map[string]int)
+	for _, desc := range descs {
+		name, isShard := desc.Annotations[content.AnnotationShardFile]
+		isChunk := false
+		if !isShard {
+			name, isChunk = content.ChunkedFileName(desc)
+		}
+		if !isShard && !isChunk {
+			var ok bool
+			if name, ok = content.ResolveName(desc); !ok {
+				continue
+			}
+		}
+		path := name
+		if isObjectStorageURL(dir) {
+			path = strings.TrimSuffix(dir, "/") + "/" + name
+		} else if dir != "" && !filepath.IsAbs(name) {
+			path = filepath.Join(dir, name)
+		}
+		if !isShard && !isChunk {
+			files = append(files, fileResult{
+				Path:       path,
+				Descriptor: desc,
+			})
+			continue
+		}
+
+		i, ok := split[path]
+		if !ok {
+			i = len(files)
+			split[path] = i
+			files = append(files, fileResult{
+				Path: path,
+			})
+		}
+		file := &files[i]
+		switch {
+		case desc.MediaType == content.ChunkRecipeMediaType:
+			file.Descriptor = desc
+		case isShard:
+			file.MediaType = desc.MediaType
+			file.Digest = digest.Digest(desc.Annotations[content.AnnotationShardFileDigest])
+			file.Size += desc.Size
+			file.Parts = append(file.Parts, desc)
+		default:
+			file.Parts = append(file.Parts, desc)
+		}
+	}
+	return files
+}
+
+// copyResult is the machine-readable output of cp.
+type copyResult struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	ocispec.Descriptor
+	Rewritten []rewriteResult `json:"rewritten,omitempty"`
+}
+
+// rewriteResult is a manifest rewritten on copy, which has a new digest.
+type rewriteResult struct {
+	Original  ocispec.Descriptor `json:"original"`
+	Rewritten ocispec.Descriptor `json:"rewritten"`
+}
+
+// remapReport maps the original digests of the manifests rewritten on copy to
+// their new digests, so that the consumers can update their pins.
+type remapReport struct {
+	Source      string            `json:"source"`
+	Destination string            `json:"destination"`
+	Digests     map[string]string `json:"digests"`
+	Rewritten   []rewriteResult   `json:"rewritten"`
+}
+
+// tagResult is the machine-readable output of tag. Only the tags pushed are
+// listed.
+type tagResult struct {
+	Reference string `json:"reference"`
+	ocispec.Descriptor
+	Tags    []string `json:"tags"`
+	Changed *bool    `json:"changed,omitempty"`
+}
+
+// manifestResult is the machine-readable output of manifest fetch.
+type manifestResult struct {
+	Reference string `json:"reference"`
+	ocispec.Descriptor
+	Manifest json.RawMessage `json:"manifest"`
+}
+
+// repoListResult is the machine-readable output of repo ls.
+type repoListResult struct {
+	Registry     string   `json:"registry"`
+	Namespace    string   `json:"namespace,omitempty"`
+	Repositories []string `json:"repositories"`
+}
+
+// tagListResult is the machine-readable output of repo tags.
+type tagListResult struct {
+	Repository string            `json:"repository"`
+	Tags       []string          `json:"tags"`
+	Details    []tagDetailResult `json:"details,omitempty"`
+}
+
+// pruneResult is the machine-readable output of repo prune.
+type pruneResult struct {
+	Repository string         `json:"repository"`
+	DryRun     bool           `json:"dryRun,omitempty"`
+	Deleted    []prunedResult `json:"deleted"`
+}
+
+// prunedResult is an expired manifest deleted with its tags.
+type prunedResult struct {
+	Digest  digest.Digest `json:"digest"`
+	Tags    []string      `json:"tags"`
+	Expires time.Time     `json:"expires"`
+}
+
+// tagDetailResult is the detail of a tag.
+type tagDetailResult struct {
+	Tag     string     `json:"tag"`
+	Digest  string     `json:"digest,omitempty"`
+	Created *time.Time `json:"created,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/deislabs/oras/pkg/content"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFileResults(t *testing.T) {
+	titled := func(mediaType, title string, size int64, annotations map[string]string) ocispec.Descriptor {
+		desc := ocispec.Descriptor{
+			MediaType: mediaType,
+			Digest:    digest.FromString(title),
+			Size:      size,
+			Annotations: map[string]string{
+				ocispec.AnnotationTitle: title,
+			},
+		}
+		for k, v := range annotations {
+			desc.Annotations[k] = v
+		}
+		return desc
+	}
+	fileDigest := digest.FromString("weights")
+	shard := func(title string, size int64) ocispec.Descriptor {
+		return titled("application/vnd.example.weights", title, size, map[string]string{
+			content.AnnotationShardFile:       "weights.bin",
+			content.AnnotationShardFileDigest: fileDigest.String(),
+		})
+	}
+	plain := titled("text/plain", "hi.txt", 2, nil)
+	shards := []ocispec.Descriptor{shard("weights.bin.shard-0", 4), shard("weights.bin.shard-1", 2)}
+	recipe := titled(content.ChunkRecipeMediaType, "data.bin.chunks.json", 100, nil)
+	chunks := []ocispec.Descriptor{
+		titled(content.ChunkMediaType, "data.bin.chunk-0", 10, nil),
+		titled(content.ChunkMediaType, "data.bin.chunk-1", 20, nil),
+	}
+	untitled := ocispec.Descriptor{MediaType: "application/vnd.example.config"}
+
+	descs := []ocispec.Descriptor{plain, shards[0], recipe, chunks[0], shards[1], chunks[1], untitled}
+	files := newFileResults("out", descs)
+	require.Len(t, files, 3, "one entry per file")
+
+	assert.Equal(t, filepath.Join("out", "hi.txt"), files[0].Path)
+	assert.Equal(t, plain, files[0].Descriptor)
+	assert.Empty(t, files[0].Parts)
+
+	assert.Equal(t, filepath.Join("out", "weights.bin"), files[1].Path)
+	assert.Equal(t, fileDigest, files[1].Digest, "digest of the whole file")
+	assert.Equal(t, int64(6), files[1].Size, "size of the whole file")
+	assert.Equal(t, shards, files[1].Parts)
+
+	assert.Equal(t, filepath.Join("out", "data.bin"), files[2].Path)
+	assert.Equal(t, recipe, files[2].Descriptor, "described by the recipe")
+	assert.Equal(t, chunks, files[2].Parts)
+
+	files = newFileResults("s3://bucket/prefix/", []ocispec.Descriptor{plain})
+	require.Len(t, files, 1)
+	assert.Equal(t, "s3://bucket/prefix/hi.txt", files[0].Path)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/deislabs/oras/pkg/oras"
+
+	"github.com/containerd/containerd/platforms"
+	"github.com/spf13/pflag"
+)
+
+// platformOptions select the platform-specific manifest of an index.
+type platformOptions struct {
+	platform string
+}
+
+func (opts *platformOptions) applyFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&opts.platform, "platform", "", "", "select the manifest of the platform in the form of os/arch[/variant] if the reference is an index")
+}
+
+// matcher returns the matcher of the platform, or nil if not specified.
+func (opts *platformOptions) matcher() (oras.PlatformMatcher, error) {
+	if opts.platform == "" {
+		return nil, nil
+	}
+	platform, err := platforms.Parse(opts.platform)
+	if err != nil {
+		return nil, fmt.Errorf("invalid platform %q: %v", opts.platform, err)
+	}
+	return oras.MatchPlatform(platforms.NewMatcher(platform)), nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/deislabs/oras/internal/config"
+	"github.com/deislabs/oras/pkg/oras"
+
+	units "github.com/docker/go-units"
+)
+
+// contentPolicyOpts returns the push option checking the files against the
+// content policy of the oras config, if any.
+func contentPolicyOpts() ([]oras.PushOpt, error) {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load oras config: %v", err)
+	}
+	policy := cfg.ContentPolicy
+	rules := oras.ContentRules{
+		MaxFileCount:        policy.MaxFileCount,
+		ForbiddenExtensions: policy.ForbiddenExtensions,
+		RequiredFiles:       policy.RequiredFiles,
+	}
+	if policy.MaxLayerSize != "" {
+		if rules.MaxLayerSize, err = units.RAMInBytes(policy.MaxLayerSize); err != nil || rules.MaxLayerSize <= 0 {
+			return nil, fmt.Errorf("invalid maxLayerSize %q in the content policy of the oras config", policy.MaxLayerSize)
+		}
+	}
+	if rules.MaxLayerSize == 0 && rules.MaxFileCount == 0 && len(rules.ForbiddenExtensions) == 0 && len(rules.RequiredFiles) == 0 {
+		return nil, nil
+	}
+	return []oras.PushOpt{oras.WithContentPolicy(rules)}, nil
+}
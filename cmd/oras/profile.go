@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/deislabs/oras/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// profile is the profile of the oras config selected by the global --profile
+// flag or the ORAS_PROFILE environment variable.
+var profile = os.Getenv(config.EnvProfile)
+
+// defaultRegistry is the registry of the references without one, set by the
+// selected profile.
+var defaultRegistry string
+
+// applyProfile selects the profile for all the commands, which load the
+// config with the profile applied, and applies the settings given as flag
+// defaults, i.e. the credentials file and the concurrency, and the default
+// registry of the references.
+func applyProfile(cmd *cobra.Command, args []string) error {
+	if profile == "" {
+		return nil
+	}
+	if err := os.Setenv(config.EnvProfile, profile); err != nil {
+		return err
+	}
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	if cfg.Profile.CredentialsFile != "" {
+		setFlagDefault(cmd, "config", os.ExpandEnv(cfg.Profile.CredentialsFile))
+	}
+	if cfg.Profile.Concurrency > 0 {
+		setFlagDefault(cmd, "concurrency", strconv.Itoa(cfg.Profile.Concurrency))
+	}
+	defaultRegistry = cfg.Profile.DefaultRegistry
+	return nil
+}
+
+// setFlagDefault sets the flag of the command unless given.
+func setFlagDefault(cmd *cobra.Command, name, value string) {
+	if flag := cmd.Flags().Lookup(name); flag != nil && !flag.Changed {
+		flag.Value.Set(value)
+	}
+}
+
+// withDefaultRegistry prefixes the reference with the registry if it has no
+// registry host, which is the case if the first component of its name has no
+// dot or port and is not localhost, as for docker references.
+func withDefaultRegistry(ref, registry string) string {
+	if registry == "" || ref == "" || strings.HasPrefix(ref, "-") || hasRegistryHost(ref) {
+		return ref
+	}
+	return strings.TrimSuffix(registry, "/") + "/" + ref
+}
+
+// hasRegistryHost reports whether the first component of the name of the
+// reference is a registry host, i.e. it has a dot or a port, or is localhost.
+func hasRegistryHost(ref string) bool {
+	i := strings.Index(ref, "/")
+	if i < 0 {
+		return false
+	}
+	host := ref[:i]
+	return strings.ContainsAny(host, ".:") || host == "localhost"
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deislabs/oras/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasRegistryHost(t *testing.T) {
+	for _, tc := range []struct {
+		ref  string
+		want bool
+	}{
+		{ref: "hello", want: false},
+		{ref: "hello:v1", want: false},
+		{ref: "acme/hello:v1", want: false},
+		{ref: "localhost/hello", want: true},
+		{ref: "localhost:5000/hello", want: true},
+		{ref: "ghcr.io/acme/hello", want: true},
+		{ref: "registry:5000/hello", want: true},
+	} {
+		assert.Equal(t, tc.want, hasRegistryHost(tc.ref), tc.ref)
+	}
+}
+
+func TestWithDefaultRegistry(t *testing.T) {
+	for _, tc := range []struct {
+		ref  string
+		want string
+	}{
+		{ref: "hello:v1", want: "staging.example.com/hello:v1"},
+		{ref: "acme/hello@sha256:abc", want: "staging.example.com/acme/hello@sha256:abc"},
+		{ref: "localhost:5000/hello:v1", want: "localhost:5000/hello:v1"},
+		{ref: "ghcr.io/acme/hello:v1", want: "ghcr.io/acme/hello:v1"},
+		{ref: "", want: ""},
+		{ref: "-", want: "-"},
+	} {
+		assert.Equal(t, tc.want, withDefaultRegistry(tc.ref, "staging.example.com/"), tc.ref)
+	}
+	assert.Equal(t, "hello:v1", withDefaultRegistry("hello:v1", ""), "no default registry")
+}
+
+// setDefaultRegistry sets the default registry of the profile, and returns a
+// function restoring it.
+func setDefaultRegistry(registry string) func() {
+	old := defaultRegistry
+	defaultRegistry = registry
+	return func() {
+		defaultRegistry = old
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	defer isolateConfig(t)()
+	credentials := filepath.Join(os.TempDir(), "staging.json")
+	content, err := json.Marshal(config.Config{
+		Profiles: map[string]config.Profile{
+			"staging": {
+				CredentialsFile: credentials,
+				DefaultRegistry: "staging.example.com",
+				Concurrency:     7,
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(os.Getenv(config.EnvConfigPath), content, 0600))
+	defer setenv(t, config.EnvProfile, "")()
+
+	defer setDefaultRegistry("")()
+	cmd, _, err := newRootCmd().Find([]string{"pull"})
+	require.NoError(t, err)
+	require.NoError(t, applyProfile(cmd, []string{"hello:v1"}))
+	assert.Empty(t, defaultRegistry, "no profile")
+	profile = "staging"
+	err = applyProfile(cmd, []string{"hello:v1"})
+	profile = ""
+	require.NoError(t, err)
+	assert.Equal(t, "staging.example.com", defaultRegistry, "default registry of the profile")
+
+	t.Run("flag defaults", func(t *testing.T) {
+		cmd, _, err := newRootCmd().Find([]string{"pull"})
+		require.NoError(t, err)
+		require.NoError(t, cmd.ParseFlags([]string{"--concurrency", "2"}))
+		profile = "staging"
+		err = applyProfile(cmd, []string{"hello:v1"})
+		profile = ""
+		require.NoError(t, err)
+		configs, err := cmd.Flags().GetStringArray("config")
+		require.NoError(t, err)
+		assert.Equal(t, []string{credentials}, configs, "credentials file of the profile")
+		concurrency, err := cmd.Flags().GetInt("concurrency")
+		require.NoError(t, err)
+		assert.Equal(t, 2, concurrency, "flag given")
+
+		cmd, _, err = newRootCmd().Find([]string{"pull"})
+		require.NoError(t, err)
+		profile = "staging"
+		err = applyProfile(cmd, []string{"hello:v1"})
+		profile = ""
+		require.NoError(t, err)
+		concurrency, err = cmd.Flags().GetInt("concurrency")
+		require.NoError(t, err)
+		assert.Equal(t, 7, concurrency, "concurrency of the profile")
+	})
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	defer isolateConfig(t)()
+	registry, repository, closer := newArtifactRegistry(t)
+	defer closer()
+	desc, _, _ := registry.resolve("hello", "v1")
+	defer setDefaultRegistry(strings.TrimSuffix(repository, "/hello"))()
+
+	output, err := runCmd(t, resolveCmd(), "hello:v1")
+	require.NoError(t, err)
+	assert.Contains(t, output, desc.Digest.String())
+
+	_, err = runCmd(t, tagCmd(), "hello:v1", "v2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1", "v2"}, registry.tags("hello"))
+
+	output, err = runCmd(t, craneCmd(), "digest", "hello:v2")
+	require.NoError(t, err)
+	assert.Contains(t, output, desc.Digest.String())
+
+	got, err := indexEntryRef(repository+"/web:latest", "hello/web:v1")
+	require.NoError(t, err)
+	assert.Equal(t, repository+"/web:v1", got, "source of the index in the default registry")
+
+	dir, err := ioutil.TempDir("", "oras_profile_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	layout := filepath.Join(dir, "layout")
+	_, err = runCmd(t, copyCmd(), "--to-oci-layout", "hello:v1", layout+":v1")
+	require.NoError(t, err)
+	_, err = runCmd(t, copyCmd(), "--from-oci-layout", layout+":v1", "world:v1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1"}, registry.tags("world"), "destination in the default registry")
+	_, err = runCmd(t, pullCmd(), "--oci-layout", "-o", filepath.Join(dir, "out"), layout+":v1")
+	require.NoError(t, err, "OCI layout references not prefixed")
+	data, err := ioutil.ReadFile(filepath.Join(dir, "out", "hello.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+}
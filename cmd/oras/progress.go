@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deislabs/oras/pkg/content"
+	"github.com/deislabs/oras/pkg/oras"
+
+	"github.com/containerd/containerd/reference"
+	units "github.com/docker/go-units"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/spf13/pflag"
+)
+
+const (
+	progressRefreshInterval = 200 * time.Millisecond
+	progressLogInterval     = 5 * time.Second
+	progressBarWidth        = 30
+)
+
+// progressOptions are the options of reporting the transfer progress and
+// the result. By default, the progress and a human readable summary are
+// printed. With -q, only the reference of the result pinned to its digest is
+// printed, and nothing with -qq, so that scripts rely on the exit code.
+type progressOptions struct {
+	quiet int
+}
+
+func (opts *progressOptions) applyFlags(fs *pflag.FlagSet) {
+	fs.CountVarP(&opts.quiet, "quiet", "q", "print only the digest reference of the result, or nothing if repeated as -qq")
+}
+
+// summary returns whether the progress and the summary are printed.
+func (opts *progressOptions) summary() bool {
+	return opts.quiet == 0
+}
+
+// printReference prints the reference pinned to the digest with -q. The name
+// is the repository, or the path of an OCI image layout, referenced by ref.
+func (opts *progressOptions) printReference(w io.Writer, ref string, ociLayout bool, dgst digest.Digest) {
+	if opts.quiet != 1 {
+		return
+	}
+	name := ref
+	if ociLayout {
+		name, _ = parseOCILayoutRef(ref)
+	} else if refspec, err := reference.Parse(ref); err == nil {
+		name = refspec.Locator
+	}
+	fmt.Fprintf(w, "%s@%s\n", name, dgst)
+}
+
+// blobProgress is the progress of a named blob.
+type blobProgress struct {
+	name    string
+	digest  string
+	total   int64
+	offset  int64
+	done    bool
+	started time.Time
+	elapsed time.Duration
+}
+
+// speed returns the transfer speed in bytes per second.
+func (b *blobProgress) speed() float64 {
+	elapsed := b.elapsed
+	if !b.done {
+		elapsed = time.Since(b.started)
+	}
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(b.offset) / elapsed.Seconds()
+}
+
+// progressRenderer renders the progress of the transfers as progress bars on
+// a terminal, or as periodic log lines otherwise.
+type progressRenderer struct {
+	out    io.Writer
+	tty    bool
+	action string
+
+	lock  sync.Mutex
+	blobs map[string]*blobProgress
+	order []*blobProgress
+	lines int
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// newProgressRenderer starts rendering the progress to stderr. The action,
+// e.g. "Uploading", prefixes the log lines.
+func newProgressRenderer(action string) *progressRenderer {
+	r := &progressRenderer{
+		out:     os.Stderr,
+		tty:     isTerminal(os.Stderr),
+		action:  action,
+		blobs:   make(map[string]*blobProgress),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Update records the progress reported by oras. Only named blobs are shown.
+func (r *progressRenderer) Update(p oras.Progress) {
+	name, ok := content.ResolveName(p.Descriptor)
+	if !ok {
+		return
+	}
+	key := p.Descriptor.Digest.String() + name
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	blob, ok := r.blobs[key]
+	if !ok {
+		blob = &blobProgress{
+			name:    name,
+			digest:  shortDigest(p.Descriptor.Digest.String()),
+			total:   p.Descriptor.Size,
+			started: time.Now(),
+		}
+		r.blobs[key] = blob
+		r.order = append(r.order, blob)
+	}
+	if blob.done {
+		return
+	}
+	blob.offset = p.Offset
+	if p.Done {
+		blob.done = true
+		blob.elapsed = time.Since(blob.started)
+	}
+}
+
+// Writer returns a writer printing the lines above the progress bars.
+func (r *progressRenderer) Writer(w io.Writer) io.Writer {
+	return &progressLineWriter{
+		renderer: r,
+		out:      w,
+	}
+}
+
+// Suspend runs fn with the rendering suspended, e.g. to prompt the user.
+func (r *progressRenderer) Suspend(fn func() error) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.tty {
+		r.clear()
+	}
+	return fn()
+}
+
+// Stop stops rendering after rendering the final progress.
+func (r *progressRenderer) Stop() {
+	close(r.stop)
+	<-r.stopped
+}
+
+func (r *progressRenderer) run() {
+	defer close(r.stopped)
+	interval := progressLogInterval
+	if r.tty {
+		interval = progressRefreshInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.render()
+		case <-r.stop:
+			if r.tty {
+				r.render()
+			}
+			return
+		}
+	}
+}
+
+func (r *progressRenderer) render() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.tty {
+		for _, blob := range r.order {
+			if !blob.done && blob.offset > 0 {
+				fmt.Fprintf(r.out, "%s %s %s: %s\n", r.action, blob.digest, blob.name, formatProgress(blob))
+			}
+		}
+		return
+	}
+	r.clear()
+	for _, blob := range r.order {
+		fmt.Fprintf(r.out, "%s %-20s %s %s\n", blob.digest, truncateName(blob.name, 20), progressBar(blob), formatProgress(blob))
+	}
+	r.lines = len(r.order)
+}
+
+// clear erases the progress bars rendered.
+func (r *progressRenderer) clear() {
+	if r.lines > 0 {
+		fmt.Fprintf(r.out, "\x1b[%dA\x1b[J", r.lines)
+		r.lines = 0
+	}
+}
+
+// progressLineWriter writes the lines through the renderer, so that the lines
+// are not mixed with the progress bars.
+type progressLineWriter struct {
+	renderer *progressRenderer
+	out      io.Writer
+}
+
+func (w *progressLineWriter) Write(p []byte) (int, error) {
+	w.renderer.lock.Lock()
+	defer w.renderer.lock.Unlock()
+	if w.renderer.tty {
+		w.renderer.clear()
+	}
+	return w.out.Write(p)
+}
+
+func progressBar(blob *blobProgress) string {
+	filled := progressBarWidth
+	if blob.total > 0 && !blob.done {
+		filled = int(blob.offset * progressBarWidth / blob.total)
+	}
+	if filled >= progressBarWidth {
+		return "[" + strings.Repeat("=", progressBarWidth) + "]"
+	}
+	return "[" + strings.Repeat("=", filled) + ">" + strings.Repeat(" ", progressBarWidth-filled-1) + "]"
+}
+
+func formatProgress(blob *blobProgress) string {
+	if blob.done {
+		return fmt.Sprintf("%s done, %s/s", units.BytesSize(float64(blob.total)), units.BytesSize(blob.speed()))
+	}
+	percent := 100
+	if blob.total > 0 {
+		percent = int(blob.offset * 100 / blob.total)
+	}
+	return fmt.Sprintf("%s/%s (%d%%), %s/s", units.BytesSize(float64(blob.offset)), units.BytesSize(float64(blob.total)), percent, units.BytesSize(blob.speed()))
+}
+
+func truncateName(name string, width int) string {
+	if len(name) <= width {
+		return name
+	}
+	return name[:width-3] + "..."
+}
+
+// shortDigest returns the first 12 characters of the encoded digest.
+func shortDigest(digest string) string {
+	if i := strings.Index(digest, ":"); i >= 0 {
+		digest = digest[i+1:]
+	}
+	if len(digest) > 12 {
+		digest = digest[:12]
+	}
+	return digest
+}
@@ -2,33 +2,57 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sync"
 
+	"github.com/deislabs/oras/pkg/artifact"
 	"github.com/deislabs/oras/pkg/content"
 	ctxo "github.com/deislabs/oras/pkg/context"
 	"github.com/deislabs/oras/pkg/oras"
+	"github.com/deislabs/oras/pkg/registry"
 
+	ccontent "github.com/containerd/containerd/content"
 	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	units "github.com/docker/go-units"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+// pullManifestMediaTypes are the manifest media types accepted by pulls, as
+// by the containerd resolver.
+var pullManifestMediaTypes = append(defaultManifestMediaTypes[:len(defaultManifestMediaTypes):len(defaultManifestMediaTypes)], "*/*")
+
 type pullOptions struct {
 	targetRef          string
 	allowedMediaTypes  []string
 	allowAllMediaTypes bool
 	keepOldFiles       bool
+	conflict           conflictOptions
 	pathTraversal      bool
+	preserveAttributes bool
 	output             string
+	objectStorage      objectStorageOptions
+	concurrency        int
+	prefetchSize       string
+	prefetchSizeSet    bool
+	platform           platformOptions
+	ociLayout          bool
+	model              bool
+	verify             bool
+	trust              trustOptions
 	verbose            bool
+	progress           progressOptions
+	format             formatOptions
+	env                envOptions
+	deprecation        deprecationOptions
 
-	debug     bool
-	configs   []string
-	username  string
-	password  string
-	insecure  bool
-	plainHTTP bool
+	debug  bool
+	remote remoteOptions
 }
 
 func pullCmd() *cobra.Command {
@@ -47,15 +71,46 @@ Example - Pull only files with the custom "application/vnd.me.hi" media type:
 Example - Pull all files, any media type:
   oras pull localhost:5000/hello:latest -a
 
+Example - Pull a model and reassemble its sharded weights:
+  oras pull --model localhost:5000/llama:7b
+
+Example - Pull the files of the linux/amd64 manifest of a multi-platform artifact:
+  oras pull --platform linux/amd64 localhost:5000/hello:latest
+
+Example - Pull files, keeping the existing files on conflicts:
+  oras pull --on-conflict skip localhost:5000/hello:latest
+
+Example - Pull files, recording the conflict resolutions for later pulls:
+  oras pull --conflict-decisions decisions.json localhost:5000/hello:latest
+
+Example - Pull files, restoring the permissions and modification times recorded by "oras push --preserve-attributes":
+  oras pull --preserve-attributes localhost:5000/hello:latest
+
+Example - Pull the single file of an artifact to stdout:
+  oras pull -o - localhost:5000/hello:latest | tar -xz
+
+Example - Pull files into an S3 bucket under the prefix "hello":
+  oras pull -o s3://bucket/hello localhost:5000/hello:latest
+
+Example - Pull files, putting them in place only if a notation signature of the artifact is valid:
+  oras pull --verify localhost:5000/hello:latest
+
+Example - Pull files and write the version annotation to the dotenv file "oras.env" as ORAS_ANNOTATION_VERSION:
+  oras pull --env-out oras.env --env-annotation org.opencontainers.image.version localhost:5000/hello:latest
+
 Example - Pull files from the insecure registry:
   oras pull localhost:5000/hello:latest --insecure
 
 Example - Pull files from the HTTP registry:
   oras pull localhost:5000/hello:latest --plain-http
+
+Example - Pull files from the OCI image layout directory "layout" with the tag "latest":
+  oras pull --oci-layout layout:latest
 `,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			opts.targetRef = args[0]
+			opts.prefetchSizeSet = cmd.Flags().Changed("prefetch-size")
 			return runPull(opts)
 		},
 	}
@@ -63,53 +118,327 @@ Example - Pull files from the HTTP registry:
 	cmd.Flags().StringArrayVarP(&opts.allowedMediaTypes, "media-type", "t", nil, "allowed media types to be pulled")
 	cmd.Flags().BoolVarP(&opts.allowAllMediaTypes, "allow-all", "a", false, "allow all media types to be pulled")
 	cmd.Flags().BoolVarP(&opts.keepOldFiles, "keep-old-files", "k", false, "do not replace existing files when pulling, treat them as errors")
+	opts.conflict.applyFlags(cmd.Flags())
 	cmd.Flags().BoolVarP(&opts.pathTraversal, "allow-path-traversal", "T", false, "allow storing files out of the output directory")
-	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "output directory")
+	cmd.Flags().BoolVarP(&opts.preserveAttributes, "preserve-attributes", "", false, "restore the permissions and modification times of the files recorded on push")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "output directory, - to write the single file to stdout, or s3://<bucket>/<prefix> or gs://<bucket>/<prefix> to upload to object storage")
+	opts.objectStorage.applyFlags(cmd.Flags())
+	cmd.Flags().IntVarP(&opts.concurrency, "concurrency", "", 3, "maximum number of blobs downloaded in parallel")
+	cmd.Flags().StringVarP(&opts.prefetchSize, "prefetch-size", "", "64MiB", "maximum size of the next blob buffered in memory while the current one is written, 0 to disable; sequential pulls only, i.e. with --concurrency 1 or --output -")
+	opts.platform.applyFlags(cmd.Flags())
+	cmd.Flags().BoolVarP(&opts.ociLayout, "oci-layout", "", false, "pull from an OCI image layout directory referenced as <path:tag> instead of a registry")
+	cmd.Flags().BoolVarP(&opts.model, "model", "", false, "allow the model layer media type to be pulled")
+	cmd.Flags().BoolVarP(&opts.verify, "verify", "", false, "verify the notation signatures of the artifact before putting the files in place")
+	opts.trust.applyFlags(cmd.Flags())
 	cmd.Flags().BoolVarP(&opts.verbose, "verbose", "v", false, "verbose output")
+	opts.progress.applyFlags(cmd.Flags())
+	opts.format.applyFlags(cmd.Flags())
+	opts.env.applyFlags(cmd.Flags())
+	opts.deprecation.applyFlags(cmd.Flags())
 
 	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
-	cmd.Flags().StringArrayVarP(&opts.configs, "config", "c", nil, "auth config path")
-	cmd.Flags().StringVarP(&opts.username, "username", "u", "", "registry username")
-	cmd.Flags().StringVarP(&opts.password, "password", "p", "", "registry password")
-	cmd.Flags().BoolVarP(&opts.insecure, "insecure", "", false, "allow connections to SSL registry without certs")
-	cmd.Flags().BoolVarP(&opts.plainHTTP, "plain-http", "", false, "use plain http and not https")
+	opts.remote.applyFlags(cmd.Flags())
 	return cmd
 }
 
 func runPull(opts pullOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	if !opts.ociLayout {
+		opts.targetRef = opts.remote.reference(opts.targetRef)
+	}
 	ctx := context.Background()
 	if opts.debug {
 		logrus.SetLevel(logrus.DebugLevel)
 	} else if !opts.verbose {
 		ctx = ctxo.WithLoggerDiscarded(ctx)
 	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	if err := opts.env.validate(); err != nil {
+		return err
+	}
 	if opts.allowAllMediaTypes {
 		opts.allowedMediaTypes = nil
 	} else if len(opts.allowedMediaTypes) == 0 {
 		opts.allowedMediaTypes = []string{content.DefaultBlobMediaType, content.DefaultBlobDirMediaType}
 	}
+	if opts.model && len(opts.allowedMediaTypes) > 0 {
+		opts.allowedMediaTypes = append(opts.allowedMediaTypes, artifact.ModelLayerMediaType)
+	}
+	if len(opts.allowedMediaTypes) > 0 {
+		// files split into content-defined chunks
+		opts.allowedMediaTypes = append(opts.allowedMediaTypes, content.ChunkRecipeMediaType, content.ChunkMediaType)
+	}
 
-	resolver := newResolver(opts.username, opts.password, opts.insecure, opts.plainHTTP, opts.configs...)
-	store := content.NewFileStore(opts.output)
-	defer store.Close()
-	store.DisableOverwrite = opts.keepOldFiles
-	store.AllowPathTraversalOnWrite = opts.pathTraversal
-
-	desc, artifacts, err := oras.Pull(ctx, resolver, opts.targetRef, store,
-		oras.WithAllowedMediaTypes(opts.allowedMediaTypes),
-		oras.WithPullStatusTrack(os.Stdout),
+	var (
+		resolver remotes.Resolver
+		hosts    docker.RegistryHosts
+		ref      = opts.targetRef
 	)
+	if opts.verify && opts.ociLayout {
+		return errors.New("--verify cannot be used with --oci-layout")
+	}
+	if opts.prefetchSizeSet && opts.output != "-" && opts.concurrency != 1 {
+		return errors.New("--prefetch-size requires --concurrency 1 or --output -")
+	}
+	if opts.ociLayout {
+		var (
+			path string
+			err  error
+		)
+		path, ref = parseOCILayoutRef(opts.targetRef)
+		if ref == "" {
+			return fmt.Errorf("OCI layout reference format is invalid. Please specify <path:tag|path@digest>")
+		}
+		if resolver, err = newOCILayoutResolver(path); err != nil {
+			return err
+		}
+	} else {
+		hosts = opts.remote.registryHosts()
+		resolver = docker.NewResolver(docker.ResolverOptions{
+			Hosts: hosts,
+		})
+	}
+	var (
+		ingester  ccontent.Ingester
+		store     *content.FileStore
+		conflicts *conflictResolver
+	)
+	// the status goes to stderr while the content is written to stdout
+	out := os.Stdout
+	if opts.output == "-" {
+		if opts.keepOldFiles || opts.pathTraversal || opts.preserveAttributes || opts.conflict.onConflict != "" || opts.conflict.decisions != "" {
+			return fmt.Errorf("--keep-old-files, --allow-path-traversal, --preserve-attributes and the conflict options are not supported with stdout output")
+		}
+		if opts.format.enabled() {
+			return fmt.Errorf("--format is not supported with stdout output")
+		}
+		out = os.Stderr
+		ingester = &stdoutIngester{}
+	} else if isObjectStorageURL(opts.output) {
+		if opts.keepOldFiles || opts.pathTraversal || opts.preserveAttributes || opts.conflict.onConflict != "" || opts.conflict.decisions != "" {
+			return fmt.Errorf("--keep-old-files, --allow-path-traversal, --preserve-attributes and the conflict options are not supported with object storage output")
+		}
+		objects, err := opts.objectStorage.newObjectStore(ctx, opts.output)
+		if err != nil {
+			return err
+		}
+		ingester = objects
+	} else {
+		store = content.NewFileStore(opts.output)
+		defer store.Close()
+		store.DisableOverwrite = opts.keepOldFiles
+		store.AllowPathTraversalOnWrite = opts.pathTraversal
+		store.PreserveAttributes = opts.preserveAttributes
+		var err error
+		if conflicts, err = opts.conflict.resolver(); err != nil {
+			return err
+		}
+		if conflicts != nil {
+			store.ResolveConflict = conflicts.Resolve
+			defer func() {
+				if err := conflicts.Save(); err != nil {
+					fmt.Fprintf(os.Stderr, "WARNING: Error saving conflict decisions: %v\n", err)
+				}
+			}()
+		}
+		ingester = store
+	}
+
+	// check the deprecation before any file is written, fetching the
+	// manifest once for the check and the pull
+	var client *registry.Client
+	if hosts != nil {
+		client = registry.NewClient(hosts)
+	}
+	prefetched, err := prefetchManifest(ctx, resolver, client, ref, pullManifestMediaTypes)
 	if err != nil {
 		if err == reference.ErrObjectRequired {
 			return fmt.Errorf("image reference format is invalid. Please specify <name:tag|name@digest>")
 		}
 		return err
 	}
+	resolver = prefetched
+	var annotations map[string]string
+	if opts.env.output != "" || prefetched.desc.MediaType == ocispec.MediaTypeImageManifest {
+		if annotations, err = fetchAnnotations(ctx, resolver, ref, prefetched.desc); err != nil {
+			return err
+		}
+		if err := opts.deprecation.check(opts.targetRef, annotations); err != nil {
+			return err
+		}
+	}
+
+	pullOpts := []oras.PullOpt{
+		oras.WithAllowedMediaTypes(opts.allowedMediaTypes),
+		oras.WithPullConcurrency(opts.concurrency),
+	}
+	if opts.output == "-" {
+		// one blob at a time, so that a second file fails before being written
+		pullOpts = append(pullOpts, oras.WithPullByBFS)
+	}
+	if opts.output == "-" || opts.concurrency == 1 {
+		prefetchSize, err := units.RAMInBytes(opts.prefetchSize)
+		if err != nil || prefetchSize < 0 {
+			return fmt.Errorf("invalid prefetch size %q", opts.prefetchSize)
+		}
+		pullOpts = append(pullOpts, oras.WithPullPrefetch(prefetchSize))
+	}
+	if matcher, err := opts.platform.matcher(); err != nil {
+		return err
+	} else if matcher != nil {
+		pullOpts = append(pullOpts, oras.WithPullPlatform(matcher))
+	}
+	var (
+		verified   *verification
+		verifyDone chan error
+	)
+	if opts.verify {
+		verifier, err := opts.trust.verifier()
+		if err != nil {
+			return err
+		}
+		refspec, err := reference.Parse(ref)
+		if err != nil {
+			return err
+		}
+		desc := prefetched.desc
+		// pull the verified manifest even if the tag is moved meanwhile
+		ref = refspec.Locator + "@" + desc.Digest.String()
+		prefetched.ref = ref
+		verify := func() error {
+			var err error
+			if verified, err = verifyNotationSignatures(ctx, hosts, verifier, refspec.Locator, desc); err != nil {
+				return fmt.Errorf("verification failed: %v", err)
+			}
+			return nil
+		}
+		if store != nil {
+			// verify while downloading, and put the files in place once verified
+			store.Staged = true
+			verifyDone = make(chan error, 1)
+			go func() {
+				verifyDone <- verify()
+			}()
+		} else if err := verify(); err != nil {
+			return err
+		}
+	}
+	var renderer *progressRenderer
+	if opts.progress.summary() && !opts.format.enabled() {
+		renderer = newProgressRenderer("Downloading")
+		if conflicts != nil {
+			conflicts.suspend = renderer.Suspend
+		}
+		pullOpts = append(pullOpts, oras.WithPullStatusTrack(renderer.Writer(out)), oras.WithPullProgress(renderer.Update))
+	}
+	desc, artifacts, err := oras.Pull(ctx, resolver, ref, ingester, pullOpts...)
+	if renderer != nil {
+		renderer.Stop()
+	}
+	if err != nil {
+		return err
+	}
+	if verifyDone != nil {
+		if err := <-verifyDone; err != nil {
+			return err
+		}
+		if err := store.Promote(); err != nil {
+			return err
+		}
+	}
+	if err := opts.env.write(annotations); err != nil {
+		return err
+	}
+	var joined []string
+	if store != nil {
+		if joined, err = store.JoinShards(artifacts); err != nil {
+			return err
+		}
+		chunked, err := store.JoinChunks(artifacts)
+		if err != nil {
+			return err
+		}
+		joined = append(joined, chunked...)
+	}
+	if opts.format.enabled() {
+		return opts.format.write("", artifactResult{
+			Reference:  opts.targetRef,
+			Descriptor: desc,
+			Files:      renameFileResults(store, newFileResults(opts.output, artifacts)),
+		})
+	}
+	if !opts.progress.summary() {
+		opts.progress.printReference(out, opts.targetRef, opts.ociLayout, desc.Digest)
+		return nil
+	}
 	if len(artifacts) == 0 {
-		fmt.Println("Downloaded empty artifact")
+		fmt.Fprintln(out, "Downloaded empty artifact")
+	}
+	for _, desc := range artifacts {
+		if name, ok := content.ResolveName(desc); ok && store != nil {
+			if renamed, ok := store.RenamedPathOf(name, desc); ok {
+				fmt.Fprintln(out, "Renamed", name, "to", renamed)
+			}
+		}
+	}
+	for _, name := range joined {
+		fmt.Fprintln(out, "Reassembled", name)
 	}
-	fmt.Println("Pulled", opts.targetRef)
-	fmt.Println("Digest:", desc.Digest)
+	if verified != nil {
+		printVerification(out, verified, ref)
+	}
+	fmt.Fprintln(out, "Pulled", opts.targetRef)
+	fmt.Fprintln(out, "Digest:", desc.Digest)
 
 	return nil
 }
+
+// fetchAnnotations returns the annotations of the pulled manifest, including
+// the annotations moved to an annotations blob.
+func fetchAnnotations(ctx context.Context, resolver remotes.Resolver, ref string, desc ocispec.Descriptor) (map[string]string, error) {
+	fetcher, err := resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	var manifest ocispec.Manifest
+	if err := fetchJSON(ctx, fetcher, desc, &manifest); err != nil {
+		return nil, err
+	}
+	return oras.FetchManifestAnnotations(ctx, fetcher, manifest)
+}
+
+// stdoutIngester streams the content of a single blob to stdout.
+type stdoutIngester struct {
+	lock    sync.Mutex
+	written bool
+}
+
+// Writer implements content.Ingester.
+func (i *stdoutIngester) Writer(ctx context.Context, opts ...ccontent.WriterOpt) (ccontent.Writer, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.written {
+		return nil, errors.New("only a single file can be written to stdout, please select it with --media-type")
+	}
+	i.written = true
+	return content.NewIoContentWriter(os.Stdout), nil
+}
+
+// renameFileResults updates the paths of the files renamed on conflicts.
+func renameFileResults(store *content.FileStore, files []fileResult) []fileResult {
+	if store == nil {
+		return files
+	}
+	for i, file := range files {
+		if name, ok := content.ResolveName(file.Descriptor); ok {
+			if renamed, ok := store.RenamedPathOf(name, file.Descriptor); ok {
+				files[i].Path = renamed
+			}
+		}
+	}
+	return files
+}
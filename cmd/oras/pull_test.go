@@ -0,0 +1,130 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deislabs/oras/pkg/content"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullPrefetchSize(t *testing.T) {
+	cmd := pullCmd()
+	cmd.SetArgs([]string{"--prefetch-size", "128MiB", "localhost:5000/hello:latest"})
+	cmd.SetOutput(ioutil.Discard)
+	err := cmd.Execute()
+	if assert.Error(t, err) {
+		assert.Equal(t, "--prefetch-size requires --concurrency 1 or --output -", err.Error())
+	}
+}
+
+// putDirectory replaces the manifest of hello:v1 with the one of the
+// directory "dir", pushed as a gzipped tarball of its files.
+func putDirectory(t *testing.T, registry *testRegistry, files map[string]string) {
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for name, data := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     "dir/" + name,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(data)),
+		}))
+		_, err := tw.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	var blob bytes.Buffer
+	zw := gzip.NewWriter(&blob)
+	_, err := zw.Write(tarball.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	config := registry.putBlob([]byte("{}"))
+	config.MediaType = ocispec.MediaTypeImageConfig
+	layer := registry.putBlob(blob.Bytes())
+	layer.MediaType = ocispec.MediaTypeImageLayerGzip
+	layer.Annotations = map[string]string{
+		ocispec.AnnotationTitle:  "dir",
+		content.AnnotationDigest: digest.FromBytes(tarball.Bytes()).String(),
+		content.AnnotationUnpack: "true",
+	}
+	registry.put("hello", "v1", ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Config:    config,
+		Layers:    []ocispec.Descriptor{layer},
+	})
+}
+
+func TestPullConflictingDirectory(t *testing.T) {
+	defer isolateConfig(t)()
+	registry, repository, closer := newArtifactRegistry(t)
+	defer closer()
+	putDirectory(t, registry, map[string]string{
+		"a.txt": "theirs",
+		"b.txt": "new",
+	})
+
+	pull := func(t *testing.T, onConflict string) (string, error) {
+		output, err := ioutil.TempDir("", "oras_pull_test")
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Join(output, "dir"), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(output, "dir", "a.txt"), []byte("mine"), 0644))
+		_, err = runCmd(t, pullCmd(), "--on-conflict", onConflict, "-o", output, repository+":v1")
+		return output, err
+	}
+	readFile := func(t *testing.T, path string) string {
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("skip", func(t *testing.T) {
+		output, err := pull(t, "skip")
+		defer os.RemoveAll(output)
+		require.NoError(t, err)
+		assert.Equal(t, "mine", readFile(t, filepath.Join(output, "dir", "a.txt")), "existing file kept")
+		assert.Equal(t, "new", readFile(t, filepath.Join(output, "dir", "b.txt")), "other files extracted")
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		output, err := pull(t, "rename")
+		defer os.RemoveAll(output)
+		require.NoError(t, err)
+		assert.Equal(t, "mine", readFile(t, filepath.Join(output, "dir", "a.txt")), "existing file kept")
+		files, err := filepath.Glob(filepath.Join(output, "dir", "a.*"))
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+		for _, file := range files {
+			if !strings.HasSuffix(file, "a.txt") {
+				assert.Equal(t, "theirs", readFile(t, file), "incoming file renamed")
+			}
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		output, err := pull(t, "fail")
+		defer os.RemoveAll(output)
+		assert.Contains(t, errString(err), content.ErrConflict.Error())
+		assert.Equal(t, "mine", readFile(t, filepath.Join(output, "dir", "a.txt")), "existing file kept")
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		output, err := pull(t, "overwrite")
+		defer os.RemoveAll(output)
+		require.NoError(t, err)
+		assert.Equal(t, "theirs", readFile(t, filepath.Join(output, "dir", "a.txt")))
+	})
+}
@@ -3,14 +3,24 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/deislabs/oras/internal/config"
+	"github.com/deislabs/oras/pkg/artifact"
 	"github.com/deislabs/oras/pkg/content"
 	ctxo "github.com/deislabs/oras/pkg/context"
 	"github.com/deislabs/oras/pkg/oras"
+	"github.com/deislabs/oras/pkg/registry"
 
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	units "github.com/docker/go-units"
+	digest "github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -24,17 +34,37 @@ const (
 type pushOptions struct {
 	targetRef              string
 	fileRefs               []string
+	stdinName              string
+	stdinPath              string
 	manifestConfigRef      string
-	manifestAnnotations    string
+	annotation             annotationOptions
 	pathValidationDisabled bool
+	preserveAttributes     bool
+	compress               string
+	reproducible           bool
+	sha256Impl             string
+	noDefaultAnnotations   bool
+	expiresIn              string
+	ociLayout              bool
+	concurrency            int
+	model                  bool
+	modelName              string
+	modelFormat            string
+	shardSize              string
+	cdc                    bool
+	cdcAvgSize             string
+	chunkSize              string
+	merkle                 bool
+	merkleChunkSize        string
+	history                bool
+	historyActor           string
+	idempotent             bool
 	verbose                bool
+	progress               progressOptions
+	format                 formatOptions
 
-	debug     bool
-	configs   []string
-	username  string
-	password  string
-	insecure  bool
-	plainHTTP bool
+	debug  bool
+	remote remoteOptions
 }
 
 func pushCmd() *cobra.Command {
@@ -50,17 +80,53 @@ Example - Push file "hi.txt" with the "application/vnd.oci.image.layer.v1.tar" m
 Example - Push file "hi.txt" with the custom "application/vnd.me.hi" media type:
   oras push localhost:5000/hello:latest hi.txt:application/vnd.me.hi
 
+Example - Push the output of a command streamed from stdin as "build.tar" with the custom "application/vnd.me.build" media type:
+  tar -c ./build | oras push --stdin-name build.tar localhost:5000/hello:latest -- -:application/vnd.me.build
+
 Example - Push multiple files with different media types:
   oras push localhost:5000/hello:latest hi.txt:application/vnd.me.hi bye.txt:application/vnd.me.bye
 
 Example - Push file "hi.txt" with the custom manifest config "config.json" of the custom "application/vnd.me.config" media type:
   oras push --manifest-config config.json:application/vnd.me.config localhost:5000/hello:latest hi.txt
 
+Example - Push file "hi.txt" with build metadata annotations on the manifest:
+  oras push --annotation org.opencontainers.image.revision=$GIT_COMMIT localhost:5000/hello:latest hi.txt
+
+Example - Push file "hi.txt" with the annotations of the manifest, the config and "hi.txt" in "annotations.json":
+  oras push --annotation-file annotations.json localhost:5000/hello:latest hi.txt
+
+Example - Push a directory as a zstd-compressed tarball with the same digest on every push:
+  oras push --compress zstd --reproducible localhost:5000/hello:latest ./dist
+
+Example - Push the weights of a model in shards of at most 1 GiB:
+  oras push --model --model-name llama --model-format safetensors localhost:5000/llama:7b model-00001.safetensors model-00002.safetensors
+
+Example - Push a large database split into content-defined chunks, uploading only the chunks changed since the last push (experimental):
+  oras push --cdc localhost:5000/hello:latest data.db
+
+Example - Push a large file in chunks of 64 MiB, resuming the upload if it was interrupted before:
+  oras push --chunk-size 64MiB localhost:5000/hello:latest large.bin
+
+Example - Push a large file with the Merkle trees of its chunks attached, to verify ranges of it later:
+  oras push --merkle localhost:5000/hello:latest large.bin
+
+Example - Push a file and record the change of the tag in its history:
+  oras push --history localhost:5000/hello:latest hi.txt
+
+Example - Push a CI artifact expiring in 30 days, to be deleted by oras repo prune --expired:
+  oras push --expires-in 30d localhost:5000/hello:ci-1234 hi.txt
+
+Example - Push file "hi.txt" unless the tag already refers to the same manifest:
+  oras push --idempotent --no-default-annotations localhost:5000/hello:latest hi.txt
+
 Example - Push file to the insecure registry:
   oras push localhost:5000/hello:latest hi.txt --insecure
 
 Example - Push file to the HTTP registry:
   oras push localhost:5000/hello:latest hi.txt --plain-http
+
+Example - Push file to the OCI image layout directory "layout" with the tag "latest":
+  oras push --oci-layout layout:latest hi.txt
 `,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -71,42 +137,133 @@ Example - Push file to the HTTP registry:
 	}
 
 	cmd.Flags().StringVarP(&opts.manifestConfigRef, "manifest-config", "", "", "manifest config file")
-	cmd.Flags().StringVarP(&opts.manifestAnnotations, "manifest-annotations", "", "", "manifest annotation file")
+	cmd.Flags().StringVarP(&opts.stdinName, "stdin-name", "", "stdin", "name of the file read from stdin when referenced as -")
+	opts.annotation.applyFlags(cmd.Flags())
 	cmd.Flags().BoolVarP(&opts.pathValidationDisabled, "disable-path-validation", "", false, "skip path validation")
+	cmd.Flags().BoolVarP(&opts.preserveAttributes, "preserve-attributes", "", false, "record the permissions and modification times of the files in their annotations")
+	cmd.Flags().StringVarP(&opts.compress, "compress", "", "", "compression of the layers: gzip, zstd, or none (default: gzip for directories, none for files)")
+	cmd.Flags().BoolVarP(&opts.reproducible, "reproducible", "", false, "strip the timestamps of directories and files, so that identical content yields identical digests")
+	cmd.Flags().StringVarP(&opts.sha256Impl, "sha256-impl", "", content.DefaultSHA256, "sha256 implementation computing the digests of the files: "+strings.Join(content.SHA256Implementations(), ", "))
+	cmd.Flags().BoolVarP(&opts.ociLayout, "oci-layout", "", false, "push to an OCI image layout directory referenced as <path:tag> instead of a registry")
+	cmd.Flags().BoolVarP(&opts.noDefaultAnnotations, "no-default-annotations", "", false, "do not add the default annotations in the oras config")
+	cmd.Flags().StringVarP(&opts.expiresIn, "expires-in", "", "", "record the expiry of the artifact after the retention period, e.g. 12h, 30d or 2w")
+	cmd.Flags().IntVarP(&opts.concurrency, "concurrency", "", 3, "maximum number of blobs uploaded in parallel")
+	cmd.Flags().BoolVarP(&opts.model, "model", "", false, "push the files as a model artifact with sharded weights")
+	cmd.Flags().StringVarP(&opts.modelName, "model-name", "", "", "name of the model in the model config")
+	cmd.Flags().StringVarP(&opts.modelFormat, "model-format", "", "", "weight format of the model in the model config, e.g. safetensors")
+	cmd.Flags().StringVarP(&opts.shardSize, "shard-size", "", "1GiB", "maximum size of the weight shards of a model")
+	cmd.Flags().BoolVarP(&opts.cdc, "cdc", "", false, "(experimental) split the files into content-defined chunks, so that the unchanged chunks of new versions are not uploaded again")
+	cmd.Flags().StringVarP(&opts.cdcAvgSize, "cdc-avg-size", "", "4MiB", "average size of the content-defined chunks")
+	cmd.Flags().StringVarP(&opts.chunkSize, "chunk-size", "", "", "upload blobs larger than the size in resumable chunks of the size, e.g. 64MiB")
+	cmd.Flags().BoolVarP(&opts.merkle, "merkle", "", false, "attach the Merkle trees of the chunks of the layers as a referrer")
+	cmd.Flags().StringVarP(&opts.merkleChunkSize, "merkle-chunk-size", "", "1MiB", "size of the chunks hashed as the leaves of the Merkle trees")
+	cmd.Flags().BoolVarP(&opts.history, "history", "", false, "record the change of the tag in its history, shown by oras history")
+	cmd.Flags().StringVarP(&opts.historyActor, "history-actor", "", "", "actor recorded in the tag history (default: the username, or the local user at the host)")
+	cmd.Flags().BoolVarP(&opts.idempotent, "idempotent", "", false, "do nothing if the remote state already matches, and report whether anything changed")
 	cmd.Flags().BoolVarP(&opts.verbose, "verbose", "v", false, "verbose output")
+	opts.progress.applyFlags(cmd.Flags())
+	opts.format.applyFlags(cmd.Flags())
 	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
-	cmd.Flags().StringArrayVarP(&opts.configs, "config", "c", nil, "auth config path")
-	cmd.Flags().StringVarP(&opts.username, "username", "u", "", "registry username")
-	cmd.Flags().StringVarP(&opts.password, "password", "p", "", "registry password")
-	cmd.Flags().BoolVarP(&opts.insecure, "insecure", "", false, "allow connections to SSL registry without certs")
-	cmd.Flags().BoolVarP(&opts.plainHTTP, "plain-http", "", false, "use plain http and not https")
+	opts.remote.applyFlags(cmd.Flags())
 	return cmd
 }
 
 func runPush(opts pushOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
 	ctx := context.Background()
 	if opts.debug {
 		logrus.SetLevel(logrus.DebugLevel)
 	} else if !opts.verbose {
 		ctx = ctxo.WithLoggerDiscarded(ctx)
 	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	if !opts.ociLayout {
+		opts.targetRef = opts.remote.reference(opts.targetRef)
+		if err := checkWritable(opts.targetRef); err != nil {
+			return err
+		}
+	}
 
 	// load files
 	var (
-		annotations map[string]map[string]string
-		store       = content.NewFileStore("")
-		pushOpts    []oras.PushOpt
+		manifestAnnotations map[string]string
+		store               = content.NewFileStore("")
+		pushOpts            []oras.PushOpt
 	)
 	defer store.Close()
-	if opts.manifestAnnotations != "" {
-		if err := decodeJSON(opts.manifestAnnotations, &annotations); err != nil {
+	store.PreserveAttributes = opts.preserveAttributes
+	store.Reproducible = opts.reproducible
+	newHash, err := content.SHA256(opts.sha256Impl)
+	if err != nil {
+		return err
+	}
+	store.NewHash = newHash
+	if opts.compress != "" {
+		compression, err := content.ParseCompression(opts.compress)
+		if err != nil {
 			return err
 		}
-		if value, ok := annotations[annotationConfig]; ok {
-			pushOpts = append(pushOpts, oras.WithConfigAnnotations(value))
+		store.Compression = compression
+	}
+	if !opts.noDefaultAnnotations {
+		cfg, err := config.LoadDefault()
+		if err != nil {
+			return err
 		}
-		if value, ok := annotations[annotationManifest]; ok {
-			pushOpts = append(pushOpts, oras.WithManifestAnnotations(value))
+		manifestAnnotations = cfg.ExpandedDefaultAnnotations()
+	}
+	annotations, err := opts.annotation.load(opts.fileRefs, os.Stderr)
+	if err != nil {
+		return err
+	}
+	if value, ok := annotations[annotationConfig]; ok {
+		pushOpts = append(pushOpts, oras.WithConfigAnnotations(value))
+	}
+	if value, ok := annotations[annotationManifest]; ok {
+		manifestAnnotations = mergeAnnotations(manifestAnnotations, value)
+	}
+	if opts.expiresIn != "" {
+		if opts.idempotent {
+			return errors.New("--expires-in cannot be used with --idempotent")
+		}
+		retention, err := artifact.ParseRetention(opts.expiresIn)
+		if err != nil {
+			return err
+		}
+		manifestAnnotations = mergeAnnotations(manifestAnnotations, artifact.ExpiryAnnotations(time.Now(), retention))
+	}
+	if manifestAnnotations != nil {
+		pushOpts = append(pushOpts, oras.WithManifestAnnotations(manifestAnnotations))
+	}
+	if opts.model && opts.manifestConfigRef != "" {
+		return errors.New("--manifest-config cannot be used with --model")
+	}
+	if opts.cdc && (opts.model || opts.compress != "") {
+		return errors.New("--cdc cannot be used with --model or --compress")
+	}
+	var merkleChunkSize int64
+	if opts.merkle {
+		if opts.ociLayout {
+			return errors.New("--merkle cannot be used with --oci-layout")
+		}
+		if merkleChunkSize, err = units.RAMInBytes(opts.merkleChunkSize); err != nil || merkleChunkSize <= 0 {
+			return fmt.Errorf("invalid Merkle chunk size %q", opts.merkleChunkSize)
+		}
+	}
+	var historyTag string
+	if opts.history {
+		if opts.ociLayout {
+			return errors.New("--history cannot be used with --oci-layout")
+		}
+		if historyTag, err = parseHistoryTag(opts.targetRef); err != nil {
+			return err
+		}
+		if opts.historyActor == "" {
+			opts.historyActor = defaultHistoryActor(opts.remote.username)
 		}
 	}
 	if opts.manifestConfigRef != "" {
@@ -121,28 +278,163 @@ func runPush(opts pushOptions) error {
 	if opts.pathValidationDisabled {
 		pushOpts = append(pushOpts, oras.WithNameValidation(nil))
 	}
+	policyOpts, err := contentPolicyOpts()
+	if err != nil {
+		return err
+	}
+	pushOpts = append(pushOpts, policyOpts...)
+	if opts.stdinPath, err = spoolStdin(opts.fileRefs); err != nil {
+		return err
+	}
+	if opts.stdinPath != "" {
+		defer os.Remove(opts.stdinPath)
+	}
 	files, err := loadFiles(store, annotations, &opts)
 	if err != nil {
 		return err
 	}
-	if len(files) == 0 {
+	if opts.model {
+		config, configPath, err := addModelConfig(store, files, opts.modelName, opts.modelFormat)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(configPath)
+		pushOpts = append(pushOpts, oras.WithConfig(config))
+	}
+	if len(files) == 0 && opts.progress.summary() && !opts.format.enabled() {
 		fmt.Println("Uploading empty artifact")
 	}
 
 	// ready to push
-	resolver := newResolver(opts.username, opts.password, opts.insecure, opts.plainHTTP, opts.configs...)
-	pushOpts = append(pushOpts, oras.WithPushStatusTrack(os.Stdout))
-	desc, err := oras.Push(ctx, resolver, opts.targetRef, store, files, pushOpts...)
+	var (
+		resolver remotes.Resolver
+		hosts    docker.RegistryHosts
+		ref      = opts.targetRef
+	)
+	if opts.ociLayout {
+		var path string
+		path, ref = parseOCILayoutRef(opts.targetRef)
+		if ref == "" {
+			return fmt.Errorf("OCI layout reference format is invalid. Please specify <path:tag|path@digest>")
+		}
+		if resolver, err = newOCILayoutResolver(path); err != nil {
+			return err
+		}
+	} else {
+		hosts = opts.remote.registryHosts()
+		resolver = docker.NewResolver(docker.ResolverOptions{
+			Hosts: hosts,
+		})
+		if opts.chunkSize != "" {
+			uploader, err := newChunkedUploader(hosts, opts.chunkSize)
+			if err != nil {
+				return err
+			}
+			pushOpts = append(pushOpts, oras.WithChunkedUpload(uploader))
+		}
+	}
+	pushOpts = append(pushOpts, oras.WithPushConcurrency(opts.concurrency))
+	var changed *bool
+	if opts.idempotent {
+		changed = newTrue()
+		pushOpts = append(pushOpts, oras.WithIdempotent(func(ocispec.Descriptor) {
+			*changed = false
+		}))
+	}
+	var previous *ocispec.Descriptor
+	if opts.history {
+		if previous, err = resolveTag(ctx, newManifestResolver(hosts, nil), ref); err != nil {
+			return err
+		}
+	}
+	var renderer *progressRenderer
+	if opts.progress.summary() && !opts.format.enabled() {
+		renderer = newProgressRenderer("Uploading")
+		pushOpts = append(pushOpts, oras.WithPushStatusTrack(renderer.Writer(os.Stdout)), oras.WithPushProgress(renderer.Update))
+	}
+	desc, err := oras.Push(ctx, resolver, ref, store, files, pushOpts...)
+	if renderer != nil {
+		renderer.Stop()
+	}
 	if err != nil {
 		return err
 	}
+	var merkle *ocispec.Descriptor
+	if opts.merkle {
+		if merkle, err = pushMerkleTrees(ctx, resolver, hosts, ref, store, desc, files, merkleChunkSize, opts.concurrency); err != nil {
+			return err
+		}
+	}
+	var history *ocispec.Descriptor
+	if opts.history && (previous == nil || previous.Digest != desc.Digest) {
+		if history, err = pushTagHistory(ctx, resolver, hosts, ref, historyTag, previous, desc, opts.historyActor, opts.concurrency); err != nil {
+			return err
+		}
+	}
 
-	fmt.Println("Pushed", opts.targetRef)
+	if opts.format.enabled() {
+		return opts.format.write("", artifactResult{
+			Reference:  opts.targetRef,
+			Descriptor: desc,
+			Files:      newFileResults("", files),
+			Changed:    changed,
+			Merkle:     merkle,
+			History:    history,
+		})
+	}
+	if !opts.progress.summary() {
+		opts.progress.printReference(os.Stdout, opts.targetRef, opts.ociLayout, desc.Digest)
+		return nil
+	}
+	if changed != nil && !*changed {
+		fmt.Println("No changes to", opts.targetRef)
+	} else {
+		fmt.Println("Pushed", opts.targetRef)
+	}
 	fmt.Println("Digest:", desc.Digest)
+	if merkle != nil {
+		fmt.Println("Merkle trees:", merkle.Digest)
+	}
+	if history != nil {
+		fmt.Println("Tag history:", history.Digest)
+	}
 
 	return nil
 }
 
+// pushMerkleTrees attaches the Merkle trees of the files to the pushed
+// manifest, which is kept discoverable on registries without the referrers
+// API.
+func pushMerkleTrees(ctx context.Context, resolver remotes.Resolver, hosts docker.RegistryHosts, ref string, store *content.FileStore, subject ocispec.Descriptor, files []ocispec.Descriptor, chunkSize int64, concurrency int) (*ocispec.Descriptor, error) {
+	client := registry.NewClient(hosts)
+	desc, err := oras.PushMerkleTrees(ctx, resolver, ref, store, subject, files, chunkSize,
+		oras.WithArtifactManifest(client),
+		oras.WithPushConcurrency(concurrency),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to push Merkle trees: %v", err)
+	}
+	if err := client.AddReferrer(ctx, ref, subject, registry.Referrer{
+		Descriptor:   desc,
+		ArtifactType: artifact.MerkleTreeArtifactType,
+	}); err != nil {
+		return nil, err
+	}
+	return &desc, nil
+}
+
+// mergeAnnotations merges the annotations into the base annotations, where
+// the values of the base annotations are overridden.
+func mergeAnnotations(base, annotations map[string]string) map[string]string {
+	if base == nil {
+		return annotations
+	}
+	for k, v := range annotations {
+		base[k] = v
+	}
+	return base
+}
+
 func decodeJSON(filename string, v interface{}) error {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -153,33 +445,110 @@ func decodeJSON(filename string, v interface{}) error {
 }
 
 func loadFiles(store *content.FileStore, annotations map[string]map[string]string, opts *pushOptions) ([]ocispec.Descriptor, error) {
-	var files []ocispec.Descriptor
-	for _, fileRef := range opts.fileRefs {
+	var shardSize int64
+	if opts.model {
+		size, err := units.RAMInBytes(opts.shardSize)
+		if err != nil {
+			return nil, err
+		}
+		shardSize = size
+	}
+	var cdcAvgSize int64
+	if opts.cdc {
+		size, err := units.RAMInBytes(opts.cdcAvgSize)
+		if err != nil || size < 64 {
+			return nil, fmt.Errorf("invalid average chunk size %q", opts.cdcAvgSize)
+		}
+		cdcAvgSize = size
+	}
+
+	refs := make([]content.FileRef, len(opts.fileRefs))
+	for i, fileRef := range opts.fileRefs {
 		filename, mediaType := parseFileRef(fileRef, "")
 		name := filepath.Clean(filename)
 		if !filepath.IsAbs(name) {
 			// convert to slash-separated path unless it is absolute path
 			name = filepath.ToSlash(name)
 		}
+		path := filename
+		if filename == "-" {
+			name, path = opts.stdinName, opts.stdinPath
+		}
+		if shardSize > 0 && mediaType == "" {
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				mediaType = artifact.ModelLayerMediaType
+			}
+		}
 		if opts.verbose {
-			fmt.Println("Preparing", name)
+			// keep the machine-readable output clean
+			out := os.Stdout
+			if opts.format.enabled() {
+				out = os.Stderr
+			}
+			fmt.Fprintln(out, "Preparing", name)
 		}
-		file, err := store.Add(name, mediaType, filename)
-		if err != nil {
-			return nil, err
+		refs[i] = content.FileRef{
+			Name:      name,
+			MediaType: mediaType,
+			Path:      path,
+			ShardSize: shardSize,
+			ChunkSize: cdcAvgSize,
 		}
-		if annotations != nil {
-			if value, ok := annotations[filename]; ok {
-				if file.Annotations == nil {
-					file.Annotations = value
-				} else {
-					for k, v := range value {
-						file.Annotations[k] = v
-					}
+	}
+	// files are independent of each other and hashed in parallel
+	files, err := store.AddAll(context.Background(), refs, 0)
+	if err != nil {
+		return nil, err
+	}
+	if annotations != nil {
+		for i, fileRef := range opts.fileRefs {
+			filename, _ := parseFileRef(fileRef, "")
+			value, ok := annotations[filename]
+			if !ok {
+				continue
+			}
+			for j := range files[i] {
+				if files[i][j].MediaType == content.ChunkMediaType {
+					// chunks may be shared by several files
+					continue
+				}
+				files[i][j].Annotations = mergeAnnotations(files[i][j].Annotations, value)
+			}
+		}
+	}
+
+	var descs []ocispec.Descriptor
+	chunks := make(map[digest.Digest]bool)
+	for _, file := range files {
+		for _, desc := range file {
+			if desc.MediaType == content.ChunkMediaType {
+				// upload the chunks shared by several files once
+				if chunks[desc.Digest] {
+					continue
 				}
+				chunks[desc.Digest] = true
 			}
+			descs = append(descs, desc)
 		}
-		files = append(files, file)
 	}
-	return files, nil
+	return descs, nil
+}
+
+// spoolStdin buffers stdin to a temporary file if it is referenced as `-` by
+// the file references, since the size and the digest of the files are required
+// before uploading. The path of the temporary file is returned.
+func spoolStdin(fileRefs []string) (string, error) {
+	var count int
+	for _, fileRef := range fileRefs {
+		if filename, _ := parseFileRef(fileRef, ""); filename == "-" {
+			count++
+		}
+	}
+	switch count {
+	case 0:
+		return "", nil
+	case 1:
+		return bufferStdin()
+	}
+	return "", errors.New("stdin can be referenced only once")
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdinFileRefs(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		args          []string
+		wantMediaType string
+	}{
+		{
+			name: "stdin",
+			args: []string{"--verbose", "localhost:5000/hello:latest", "hi.txt", "-"},
+		},
+		{
+			name:          "media type after terminator",
+			args:          []string{"localhost:5000/hello:latest", "hi.txt", "--verbose", "--", "-:application/vnd.me.build"},
+			wantMediaType: "application/vnd.me.build",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, cmd := range []func() *cobra.Command{pushCmd, attachCmd} {
+				c := cmd()
+				require.NoError(t, c.ParseFlags(tc.args))
+				args := c.Flags().Args()
+				require.Len(t, args, 3)
+				assert.Equal(t, "hi.txt", args[1])
+				filename, mediaType := parseFileRef(args[2], "")
+				assert.Equal(t, "-", filename)
+				assert.Equal(t, tc.wantMediaType, mediaType)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/containerd/containerd/remotes/docker"
+	digest "github.com/opencontainers/go-digest"
+)
+
+// rawRecord describes a response saved by the raw recorder.
+type rawRecord struct {
+	Method          string        `json:"method"`
+	URL             string        `json:"url"`
+	RequestHeaders  http.Header   `json:"requestHeaders"`
+	Status          string        `json:"status"`
+	ResponseHeaders http.Header   `json:"responseHeaders"`
+	BodyFile        string        `json:"bodyFile,omitempty"`
+	Size            int64         `json:"size"`
+	Digest          digest.Digest `json:"digest,omitempty"`
+	ContentDigest   digest.Digest `json:"contentDigest,omitempty"`
+	DigestMatch     *bool         `json:"digestMatch,omitempty"`
+	Truncated       bool          `json:"truncated,omitempty"`
+}
+
+// rawRecorder saves the exact bytes and headers of the registry responses to
+// a directory, without decoding them. Each response is saved as
+// `<n>-<method>-<name>.body` along with its headers and digests in
+// `<n>-<method>-<name>.json`.
+type rawRecorder struct {
+	dir string
+
+	lock  sync.Mutex
+	count int
+	err   error
+}
+
+func newRawRecorder(dir string) (*rawRecorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &rawRecorder{dir: dir}, nil
+}
+
+// hosts wraps the clients of the hosts to record the responses.
+func (r *rawRecorder) hosts(hosts docker.RegistryHosts) docker.RegistryHosts {
+	return func(host string) ([]docker.RegistryHost, error) {
+		configs, err := hosts(host)
+		if err != nil {
+			return nil, err
+		}
+		for i, config := range configs {
+			client := http.DefaultClient
+			if config.Client != nil {
+				client = config.Client
+			}
+			wrapped := *client
+			wrapped.Transport = &rawTransport{
+				base:     client.Transport,
+				recorder: r,
+			}
+			configs[i].Client = &wrapped
+		}
+		return configs, nil
+	}
+}
+
+// Err returns the first error saving the responses.
+func (r *rawRecorder) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+var rawNameReplacer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// next returns the path prefix of the next record.
+func (r *rawRecorder) next(req *http.Request) string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.count++
+	name := rawNameReplacer.ReplaceAllString(path.Base(req.URL.Path), "_")
+	return filepath.Join(r.dir, fmt.Sprintf("%02d-%s-%s", r.count, req.Method, name))
+}
+
+func (r *rawRecorder) fail(err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// rawTransport records the responses of the base transport.
+type rawTransport struct {
+	base     http.RoundTripper
+	recorder *rawRecorder
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rawTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := t.recorder.next(req)
+	record := &rawRecord{
+		Method:          req.Method,
+		URL:             req.URL.String(),
+		RequestHeaders:  req.Header.Clone(),
+		Status:          resp.Status,
+		ResponseHeaders: resp.Header.Clone(),
+		ContentDigest:   digest.Digest(resp.Header.Get("Docker-Content-Digest")),
+	}
+	// credentials are not saved
+	record.RequestHeaders.Del("Authorization")
+	if req.Method == http.MethodHead {
+		if err := writeRawRecord(prefix, record); err != nil {
+			t.recorder.fail(err)
+		}
+		return resp, nil
+	}
+
+	file, err := os.Create(prefix + ".body")
+	if err != nil {
+		t.recorder.fail(err)
+		return resp, nil
+	}
+	record.BodyFile = filepath.Base(file.Name())
+	algorithm := digest.Canonical
+	if record.ContentDigest.Validate() == nil {
+		algorithm = record.ContentDigest.Algorithm()
+	}
+	resp.Body = &rawBody{
+		ReadCloser: resp.Body,
+		length:     resp.ContentLength,
+		file:       file,
+		digester:   algorithm.Digester(),
+		prefix:     prefix,
+		record:     record,
+		recorder:   t.recorder,
+	}
+	return resp, nil
+}
+
+// rawBody saves the body as it is read. The body is not drained on close, as
+// it may be a large blob the reader gave up on, and the record of a body
+// closed early is marked truncated without digests.
+type rawBody struct {
+	io.ReadCloser
+	length   int64
+	file     *os.File
+	digester digest.Digester
+	prefix   string
+	record   *rawRecord
+	recorder *rawRecorder
+	eof      bool
+	closed   bool
+}
+
+func (b *rawBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.save(p[:n])
+	}
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *rawBody) save(p []byte) {
+	b.digester.Hash().Write(p)
+	b.record.Size += int64(len(p))
+	if _, err := b.file.Write(p); err != nil {
+		b.recorder.fail(err)
+	}
+}
+
+func (b *rawBody) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	if err := b.file.Close(); err != nil {
+		b.recorder.fail(err)
+	}
+	if !b.eof && b.record.Size != b.length {
+		b.record.Truncated = true
+	} else {
+		b.record.Digest = b.digester.Digest()
+		if b.record.ContentDigest != "" {
+			match := b.record.ContentDigest == b.record.Digest
+			b.record.DigestMatch = &match
+		}
+	}
+	if err := writeRawRecord(b.prefix, b.record); err != nil {
+		b.recorder.fail(err)
+	}
+	return b.ReadCloser.Close()
+}
+
+func writeRawRecord(prefix string, record *rawRecord) error {
+	content, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(prefix+".json", content, 0644)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/containerd/containerd/remotes/docker"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readRawRecord(t *testing.T, dir, prefix string) rawRecord {
+	content, err := ioutil.ReadFile(filepath.Join(dir, prefix+".json"))
+	require.NoError(t, err)
+	var record rawRecord
+	require.NoError(t, json.Unmarshal(content, &record))
+	return record
+}
+
+func TestRawRecorder(t *testing.T) {
+	body := strings.Repeat("manifest", 1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Content-Digest", digest.FromString(body).String())
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		if r.Method != http.MethodHead {
+			w.Write([]byte(body))
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "oras_raw_recorder_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	recorder, err := newRawRecorder(dir)
+	require.NoError(t, err)
+	hosts, err := recorder.hosts(func(host string) ([]docker.RegistryHost, error) {
+		return []docker.RegistryHost{{Host: host}}, nil
+	})("localhost")
+	require.NoError(t, err)
+	client := hosts[0].Client
+
+	// a response read to the end is recorded with its digests
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v2/hello/manifests/latest", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	record := readRawRecord(t, dir, "01-GET-latest")
+	assert.Empty(t, record.RequestHeaders.Get("Authorization"), "credentials not saved")
+	assert.Equal(t, int64(len(body)), record.Size)
+	assert.Equal(t, digest.FromString(body), record.Digest)
+	require.NotNil(t, record.DigestMatch)
+	assert.True(t, *record.DigestMatch)
+	assert.False(t, record.Truncated)
+	saved, err := ioutil.ReadFile(filepath.Join(dir, record.BodyFile))
+	require.NoError(t, err)
+	assert.Equal(t, body, string(saved))
+
+	// a response read up to its length is complete
+	resp, err = client.Get(server.URL + "/v2/hello/manifests/limited")
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(io.LimitReader(resp.Body, resp.ContentLength))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	record = readRawRecord(t, dir, "02-GET-limited")
+	assert.False(t, record.Truncated)
+	assert.Equal(t, digest.FromString(body), record.Digest)
+
+	// a response closed early is not drained
+	resp, err = client.Get(server.URL + "/v2/hello/blobs/large")
+	require.NoError(t, err)
+	_, err = resp.Body.Read(make([]byte, 8))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	record = readRawRecord(t, dir, "03-GET-large")
+	assert.True(t, record.Truncated)
+	assert.Equal(t, int64(8), record.Size)
+	assert.Empty(t, record.Digest)
+	assert.Nil(t, record.DigestMatch)
+
+	// HEAD responses are recorded without bodies
+	resp, err = client.Head(server.URL + "/v2/hello/manifests/latest")
+	require.NoError(t, err)
+	resp.Body.Close()
+	record = readRawRecord(t, dir, "04-HEAD-latest")
+	assert.Empty(t, record.BodyFile)
+	assert.Equal(t, digest.FromString(body), record.ContentDigest)
+	assert.NoError(t, recorder.Err())
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deislabs/oras/internal/refcache"
+
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/remotes/docker"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// offline refuses all network access, set by the global --offline flag or
+// the ORAS_OFFLINE environment variable. The tags, the repositories and the
+// manifests are resolved from the reference cache instead.
+var offline = offlineFromEnv()
+
+// envOffline is the environment variable enabling the offline mode.
+const envOffline = "ORAS_OFFLINE"
+
+// offlineFromEnv tells if the offline mode is enabled by the environment
+// variable, which is a boolean. Invalid values are ignored with a warning.
+func offlineFromEnv() bool {
+	value := os.Getenv(envOffline)
+	if value == "" {
+		return false
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Ignoring invalid %s: %v\n", envOffline, err)
+		return false
+	}
+	return enabled
+}
+
+// errOffline is returned by the requests refused in offline mode.
+var errOffline = errors.New("network access refused in offline mode")
+
+// maxListingSize is the maximum size of the tag and repository listings
+// recorded in the reference cache.
+const maxListingSize = 4 * 1024 * 1024
+
+// maxCachedManifestSize is the maximum size of the manifests whose content is
+// kept in the reference cache.
+const maxCachedManifestSize = 256 * 1024
+
+// refCache records the references seen in the responses of the registries in
+// the reference cache, which is updated on every change as several oras
+// processes may run at once. Failures to update the cache are reported once.
+type refCache struct {
+	path string
+
+	lock   sync.Mutex
+	warned bool
+}
+
+func newRefCache() *refCache {
+	path, err := refcache.Path()
+	if err != nil {
+		return &refCache{}
+	}
+	return &refCache{path: path}
+}
+
+// hosts wraps the clients of the hosts of the registry to record the
+// references in the cache, or to answer from the cache in offline mode.
+func (c *refCache) hosts(hosts docker.RegistryHosts) docker.RegistryHosts {
+	return func(host string) ([]docker.RegistryHost, error) {
+		configs, err := hosts(host)
+		if err != nil {
+			return nil, err
+		}
+		// the configs may be shared with other calls
+		wrapped := make([]docker.RegistryHost, len(configs))
+		for i, config := range configs {
+			client := http.DefaultClient
+			if config.Client != nil {
+				client = config.Client
+			}
+			wrappedClient := *client
+			wrappedClient.Transport = &refCacheTransport{
+				base:   client.Transport,
+				cache:  c,
+				host:   host,
+				prefix: config.Path,
+			}
+			config.Client = &wrappedClient
+			wrapped[i] = config
+		}
+		return wrapped, nil
+	}
+}
+
+// load returns the cache, or an empty cache if it cannot be read.
+func (c *refCache) load() *refcache.Cache {
+	if c.path == "" {
+		return &refcache.Cache{}
+	}
+	cache, err := refcache.Load(c.path)
+	if err != nil {
+		c.warn(err)
+		return &refcache.Cache{}
+	}
+	return cache
+}
+
+// update applies the change to the cache file.
+func (c *refCache) update(change func(*refcache.Cache)) {
+	if c.path == "" {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := refcache.Update(c.path, change); err != nil {
+		c.warn(err)
+	}
+}
+
+// writeManifest keeps the content of the manifest, reporting whether it is
+// cached.
+func (c *refCache) writeManifest(dgst digest.Digest, content []byte) bool {
+	if c.path == "" {
+		return false
+	}
+	if err := refcache.WriteManifest(c.path, dgst, content); err != nil {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		c.warn(err)
+		return false
+	}
+	return true
+}
+
+func (c *refCache) readManifest(dgst digest.Digest) ([]byte, error) {
+	if c.path == "" {
+		return nil, errOffline
+	}
+	return refcache.ReadManifest(c.path, dgst)
+}
+
+func (c *refCache) warn(err error) {
+	if !c.warned {
+		c.warned = true
+		fmt.Fprintf(os.Stderr, "WARNING: Error updating the reference cache %s: %v\n", c.path, err)
+	}
+}
+
+// refCacheTransport records the tags, the repositories and the manifests listed
+// or fetched through the base transport. In offline mode, the requests are answered from
+// the cache if possible, and refused otherwise.
+type refCacheTransport struct {
+	base   http.RoundTripper
+	cache  *refCache
+	host   string
+	prefix string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *refCacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := parseRegistryEndpoint(strings.TrimPrefix(req.URL.Path, t.prefix))
+	if offline {
+		if resp, ok := t.cached(req, endpoint); ok {
+			return resp, nil
+		}
+		return nil, fmt.Errorf("%s %s: %v", req.Method, req.URL, errOffline)
+	}
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil || endpoint.kind == "" {
+		return resp, err
+	}
+	now := time.Now()
+	switch {
+	case endpoint.kind == "manifests" && resp.StatusCode == http.StatusOK && (req.Method == http.MethodHead || req.Method == http.MethodGet),
+		endpoint.kind == "manifests" && resp.StatusCode == http.StatusCreated && req.Method == http.MethodPut:
+		desc, ok := manifestDescriptor(req, resp)
+		cached := false
+		if ok && req.Method == http.MethodGet && desc.Size <= maxCachedManifestSize {
+			if content, ok := peekBody(resp, maxCachedManifestSize); ok {
+				cached = t.cache.writeManifest(desc.Digest, content)
+			}
+		}
+		t.cache.update(func(cache *refcache.Cache) {
+			cache.AddRepositories(t.host, []string{endpoint.name}, now)
+			if cached {
+				cache.AddManifest(t.host, endpoint.name, desc, now)
+			}
+			if ok && !isDigest(endpoint.object) {
+				cache.SetTag(t.host, endpoint.name, endpoint.object, desc, now)
+			}
+		})
+	case endpoint.kind == "manifests" && resp.StatusCode == http.StatusNotFound && !isDigest(endpoint.object):
+		t.cache.update(func(cache *refcache.Cache) {
+			cache.RemoveTag(t.host, endpoint.name, endpoint.object)
+		})
+	case endpoint.kind == "manifests" && req.Method == http.MethodDelete && (resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusOK):
+		t.cache.update(func(cache *refcache.Cache) {
+			cache.RemoveManifest(t.host, endpoint.name, digest.Digest(endpoint.object))
+		})
+	case endpoint.kind == "tags" && req.Method == http.MethodGet && resp.StatusCode == http.StatusOK:
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if peekJSON(resp, &page) {
+			t.cache.update(func(cache *refcache.Cache) {
+				cache.AddTags(t.host, endpoint.name, page.Tags, now)
+			})
+		}
+	case endpoint.kind == "catalog" && req.Method == http.MethodGet && resp.StatusCode == http.StatusOK:
+		var page struct {
+			Repositories []string `json:"repositories"`
+		}
+		if peekJSON(resp, &page) {
+			t.cache.update(func(cache *refcache.Cache) {
+				cache.AddRepositories(t.host, page.Repositories, now)
+			})
+		}
+	}
+	return resp, nil
+}
+
+// cached answers the request from the cache, which holds the descriptors of
+// the manifests, and the content of the small manifests fetched.
+func (t *refCacheTransport) cached(req *http.Request, endpoint registryEndpoint) (*http.Response, bool) {
+	cache := t.cache.load()
+	switch {
+	case endpoint.kind == "manifests" && (req.Method == http.MethodHead || req.Method == http.MethodGet):
+		desc, updated, ok := cache.Resolve(t.host, endpoint.name, endpoint.object)
+		if !ok {
+			return nil, false
+		}
+		var content []byte
+		if req.Method == http.MethodGet {
+			var err error
+			if content, err = t.cache.readManifest(desc.Digest); err != nil {
+				return nil, false
+			}
+		}
+		log.G(req.Context()).WithField("updated", updated).Debugf("resolved %s/%s:%s from the reference cache", t.host, endpoint.name, endpoint.object)
+		resp := newCachedResponse(req, content)
+		resp.Header.Set("Content-Type", desc.MediaType)
+		resp.Header.Set("Docker-Content-Digest", desc.Digest.String())
+		resp.Header.Set("Content-Length", strconv.FormatInt(desc.Size, 10))
+		resp.ContentLength = desc.Size
+		return resp, true
+	case endpoint.kind == "tags" && req.Method == http.MethodGet:
+		if _, ok := cache.Registries[t.host][endpoint.name]; !ok {
+			return nil, false
+		}
+		return newCachedListing(req, map[string]interface{}{
+			"name": endpoint.name,
+			"tags": after(cache.Tags(t.host, endpoint.name), req.URL.Query().Get("last")),
+		}), true
+	case endpoint.kind == "catalog" && req.Method == http.MethodGet:
+		if _, ok := cache.Registries[t.host]; !ok {
+			return nil, false
+		}
+		return newCachedListing(req, map[string]interface{}{
+			"repositories": after(cache.Repositories(t.host), req.URL.Query().Get("last")),
+		}), true
+	}
+	return nil, false
+}
+
+// registryEndpoint is a registry API endpoint recorded by the reference
+// cache, i.e. `/<name>/manifests/<object>`, `/<name>/tags/list` or
+// `/_catalog`.
+type registryEndpoint struct {
+	kind   string
+	name   string
+	object string
+}
+
+func parseRegistryEndpoint(path string) registryEndpoint {
+	if path == "/_catalog" {
+		return registryEndpoint{kind: "catalog"}
+	}
+	if strings.HasSuffix(path, "/tags/list") {
+		return registryEndpoint{
+			kind: "tags",
+			name: strings.TrimPrefix(strings.TrimSuffix(path, "/tags/list"), "/"),
+		}
+	}
+	if i := strings.LastIndex(path, "/manifests/"); i > 0 {
+		return registryEndpoint{
+			kind:   "manifests",
+			name:   strings.TrimPrefix(path[:i], "/"),
+			object: path[i+len("/manifests/"):],
+		}
+	}
+	return registryEndpoint{}
+}
+
+// manifestDescriptor returns the descriptor of the manifest of the response,
+// or of the request for pushes.
+func manifestDescriptor(req *http.Request, resp *http.Response) (ocispec.Descriptor, bool) {
+	dgst, err := digest.Parse(resp.Header.Get("Docker-Content-Digest"))
+	if err != nil {
+		return ocispec.Descriptor{}, false
+	}
+	header, size := resp.Header, resp.ContentLength
+	if req.Method == http.MethodPut {
+		header, size = req.Header, req.ContentLength
+	}
+	mediaType := header.Get("Content-Type")
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = strings.TrimSpace(mediaType[:i])
+	}
+	if mediaType == "" || size < 0 {
+		return ocispec.Descriptor{}, false
+	}
+	return ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    dgst,
+		Size:      size,
+	}, true
+}
+
+// peekJSON decodes the body of the response, which is restored for the
+// caller. Bodies larger than maxListingSize are not decoded.
+func peekJSON(resp *http.Response, v interface{}) bool {
+	content, ok := peekBody(resp, maxListingSize)
+	return ok && json.Unmarshal(content, v) == nil
+}
+
+// peekBody reads the body of the response, which is restored for the caller,
+// unless it is larger than limit.
+func peekBody(resp *http.Response, limit int64) ([]byte, bool) {
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	rest := resp.Body
+	resp.Body = &peekedBody{
+		Reader: io.MultiReader(bytes.NewReader(content), rest),
+		Closer: rest,
+	}
+	if err != nil || int64(len(content)) > limit {
+		return nil, false
+	}
+	return content, true
+}
+
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+func newCachedResponse(req *http.Request, content []byte) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(content)),
+		ContentLength: int64(len(content)),
+		Request:       req,
+	}
+}
+
+func newCachedListing(req *http.Request, page map[string]interface{}) *http.Response {
+	content, _ := json.Marshal(page)
+	resp := newCachedResponse(req, content)
+	resp.Header.Set("Content-Type", "application/json")
+	return resp
+}
+
+// after returns the sorted names lexically after last.
+func after(names []string, last string) []string {
+	i := sort.SearchStrings(names, last)
+	if i < len(names) && names[i] == last {
+		i++
+	}
+	if last == "" {
+		i = 0
+	}
+	return names[i:]
+}
+
+func isDigest(object string) bool {
+	return strings.Contains(object, ":")
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/remotes/docker"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineFromEnv(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  bool
+	}{
+		{value: "", want: false},
+		{value: "1", want: true},
+		{value: "true", want: true},
+		{value: "TRUE", want: true},
+		{value: "0", want: false},
+		{value: "false", want: false},
+		{value: "invalid", want: false},
+	} {
+		restore := setenv(t, envOffline, tc.value)
+		assert.Equal(t, tc.want, offlineFromEnv(), "%s=%q", envOffline, tc.value)
+		restore()
+	}
+}
+
+func TestParseRegistryEndpoint(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		want registryEndpoint
+	}{
+		{path: "/_catalog", want: registryEndpoint{kind: "catalog"}},
+		{path: "/hello/tags/list", want: registryEndpoint{kind: "tags", name: "hello"}},
+		{path: "/library/hello/tags/list", want: registryEndpoint{kind: "tags", name: "library/hello"}},
+		{path: "/hello/manifests/v1", want: registryEndpoint{kind: "manifests", name: "hello", object: "v1"}},
+		{path: "/library/hello/manifests/sha256:abc", want: registryEndpoint{kind: "manifests", name: "library/hello", object: "sha256:abc"}},
+		{path: "/manifests/v1"},
+		{path: "/hello/blobs/sha256:abc"},
+		{path: "/hello/blobs/uploads/"},
+		{path: "/"},
+	} {
+		assert.Equal(t, tc.want, parseRegistryEndpoint(tc.path), tc.path)
+	}
+}
+
+// setOffline sets the offline mode, and returns a function restoring it.
+func setOffline(enabled bool) func() {
+	old := offline
+	offline = enabled
+	return func() {
+		offline = old
+	}
+}
+
+func TestRefCacheTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oras_refcache_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cache := &refCache{path: filepath.Join(dir, "refs.json")}
+
+	registry, repository, closer := newArtifactRegistry(t)
+	defer closer()
+	host := strings.TrimSuffix(repository, "/hello")
+	client := &http.Client{
+		Transport: &refCacheTransport{
+			cache:  cache,
+			host:   host,
+			prefix: "/v2",
+		},
+	}
+	request := func(method, path string) (*http.Response, []byte, error) {
+		req, err := http.NewRequest(method, "http://"+host+"/v2"+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", ocispec.MediaTypeImageManifest)
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer resp.Body.Close()
+		content, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, content, nil
+	}
+	v1, manifest, _ := registry.resolve("hello", "v1")
+
+	t.Run("online", func(t *testing.T) {
+		defer setOffline(false)()
+
+		resp, content, err := request(http.MethodGet, "/hello/manifests/v1")
+		require.NoError(t, err)
+		assert.Equal(t, manifest, content, "body restored for the caller")
+		loaded := cache.load()
+		desc, _, ok := loaded.Resolve(host, "hello", "v1")
+		require.True(t, ok, "tag recorded")
+		assert.Equal(t, v1, desc)
+		cached, err := cache.readManifest(v1.Digest)
+		require.NoError(t, err)
+		assert.Equal(t, manifest, cached, "content of the manifest kept")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		registry.put("hello", "v2", map[string]interface{}{"schemaVersion": 2})
+		_, content, err = request(http.MethodGet, "/hello/tags/list")
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "v2")
+		assert.Equal(t, []string{"v1", "v2"}, cache.load().Tags(host, "hello"), "listed tags recorded")
+
+		registry.lock.Lock()
+		delete(registry.manifests["hello"], "v2")
+		registry.lock.Unlock()
+		_, _, err = request(http.MethodHead, "/hello/manifests/v2")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1"}, cache.load().Tags(host, "hello"), "missing tag removed")
+	})
+
+	t.Run("offline", func(t *testing.T) {
+		defer setOffline(true)()
+		registry.requests = 0
+
+		resp, content, err := request(http.MethodGet, "/hello/manifests/v1")
+		require.NoError(t, err)
+		assert.Equal(t, manifest, content)
+		assert.Equal(t, v1.MediaType, resp.Header.Get("Content-Type"))
+		assert.Equal(t, v1.Digest.String(), resp.Header.Get("Docker-Content-Digest"))
+
+		resp, content, err = request(http.MethodHead, "/hello/manifests/"+v1.Digest.String())
+		require.NoError(t, err)
+		assert.Empty(t, content)
+		assert.Equal(t, v1.Size, resp.ContentLength)
+
+		_, content, err = request(http.MethodGet, "/hello/tags/list")
+		require.NoError(t, err)
+		var page struct {
+			Name string   `json:"name"`
+			Tags []string `json:"tags"`
+		}
+		require.NoError(t, json.Unmarshal(content, &page))
+		assert.Equal(t, "hello", page.Name)
+		assert.Equal(t, []string{"v1"}, page.Tags)
+
+		_, content, err = request(http.MethodGet, "/_catalog")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"repositories":["hello"]}`, string(content))
+
+		for _, path := range []string{
+			"/hello/manifests/v3",
+			"/unknown/tags/list",
+			"/hello/blobs/" + v1.Digest.String(),
+		} {
+			_, _, err = request(http.MethodGet, path)
+			assert.Contains(t, errString(err), errOffline.Error(), path)
+		}
+		assert.Equal(t, 0, registry.requests, "no request sent")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		defer setOffline(false)()
+
+		resp, _, err := request(http.MethodDelete, "/hello/manifests/"+v1.Digest.String())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		_, _, ok := cache.load().Resolve(host, "hello", v1.Digest.String())
+		assert.False(t, ok, "manifest removed")
+	})
+}
+
+func TestRefCacheHosts(t *testing.T) {
+	cache := &refCache{}
+	base := &http.Client{Timeout: time.Minute}
+	hosts := cache.hosts(func(host string) ([]docker.RegistryHost, error) {
+		return []docker.RegistryHost{{Host: host, Path: "/v2", Client: base}}, nil
+	})
+	configs, err := hosts("localhost:5000")
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.NotSame(t, base, configs[0].Client, "client of the config not modified")
+	assert.Equal(t, time.Minute, configs[0].Client.Timeout)
+	transport, ok := configs[0].Client.Transport.(*refCacheTransport)
+	require.True(t, ok)
+	assert.Equal(t, "localhost:5000", transport.host)
+	assert.Equal(t, "/v2", transport.prefix)
+	assert.Nil(t, base.Transport)
+}
+
+func TestOfflineObjectStore(t *testing.T) {
+	defer setOffline(true)()
+	opts := objectStorageOptions{partSize: "16MiB", concurrency: 1}
+	_, err := opts.newObjectStore(context.Background(), "s3://bucket/prefix")
+	assert.Contains(t, errString(err), errOffline.Error())
+}
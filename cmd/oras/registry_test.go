@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/deislabs/oras/internal/config"
+	"github.com/deislabs/oras/internal/refcache"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+// testRegistry is a registry serving the manifests and the blobs of its
+// repositories from memory.
+type testRegistry struct {
+	lock      sync.Mutex
+	manifests map[string]map[string]ocispec.Descriptor
+	blobs     map[digest.Digest][]byte
+	uploads   int
+	// requests counts the manifest reads.
+	requests int
+
+	// disallowDelete answers the manifest deletes with 405 Method Not
+	// Allowed, as registries disallowing deletes do.
+	disallowDelete bool
+	// lag is the number of manifest reads still answered from the manifests
+	// before a delete or a push, as eventually consistent registries do.
+	lag        int
+	stale      map[string]map[string]ocispec.Descriptor
+	staleReads int
+}
+
+func newTestRegistry() *testRegistry {
+	return &testRegistry{
+		manifests: make(map[string]map[string]ocispec.Descriptor),
+		blobs:     make(map[digest.Digest][]byte),
+	}
+}
+
+// put stores the manifest in the repository with the tag.
+func (r *testRegistry) put(repository, tag string, v interface{}) ocispec.Descriptor {
+	data, _ := json.Marshal(v)
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.store(repository, tag, desc, data)
+	return desc
+}
+
+// putBlob stores the blob.
+func (r *testRegistry) putBlob(data []byte) ocispec.Descriptor {
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.blobs[desc.Digest] = data
+	return desc
+}
+
+func (r *testRegistry) store(repository, tag string, desc ocispec.Descriptor, data []byte) {
+	r.mutate()
+	if r.manifests[repository] == nil {
+		r.manifests[repository] = make(map[string]ocispec.Descriptor)
+	}
+	r.manifests[repository][desc.Digest.String()] = desc
+	if tag != "" {
+		r.manifests[repository][tag] = desc
+	}
+	r.blobs[desc.Digest] = data
+}
+
+// mutate keeps the manifests before a change for the reads of the lag.
+func (r *testRegistry) mutate() {
+	if r.lag == 0 || r.staleReads > 0 {
+		return
+	}
+	r.stale = make(map[string]map[string]ocispec.Descriptor)
+	for repository, manifests := range r.manifests {
+		r.stale[repository] = make(map[string]ocispec.Descriptor)
+		for ref, desc := range manifests {
+			r.stale[repository][ref] = desc
+		}
+	}
+	r.staleReads = r.lag
+}
+
+// manifest returns the manifest of the reference as read by a client.
+func (r *testRegistry) manifest(repository, ref string) (ocispec.Descriptor, bool) {
+	manifests := r.manifests
+	if r.staleReads > 0 {
+		r.staleReads--
+		manifests = r.stale
+	}
+	desc, ok := manifests[repository][ref]
+	return desc, ok
+}
+
+// tags returns the sorted tags of the repository.
+func (r *testRegistry) tags(repository string) []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.tagsOf(repository)
+}
+
+func (r *testRegistry) tagsOf(repository string) []string {
+	tags := []string{}
+	for ref := range r.manifests[repository] {
+		if _, err := digest.Parse(ref); err != nil {
+			tags = append(tags, ref)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// resolve returns the current manifest of the tag and its content.
+func (r *testRegistry) resolve(repository, tag string) (ocispec.Descriptor, []byte, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	desc, ok := r.manifests[repository][tag]
+	return desc, r.blobs[desc.Digest], ok
+}
+
+func (r *testRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	if path == "" || path == req.URL.Path {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if strings.HasSuffix(path, "/tags/list") {
+		repository := strings.TrimSuffix(path, "/tags/list")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"name": repository,
+			"tags": r.tagsOf(repository),
+		})
+		return
+	}
+	if i := strings.LastIndex(path, "/blobs/"); i >= 0 {
+		r.serveBlob(w, req, path[:i], path[i+len("/blobs/"):])
+		return
+	}
+	i := strings.LastIndex(path, "/manifests/")
+	if i < 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	repository, ref := path[:i], path[i+len("/manifests/"):]
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		r.requests++
+		desc, ok := r.manifest(repository, ref)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", desc.MediaType)
+		w.Header().Set("Docker-Content-Digest", desc.Digest.String())
+		w.Header().Set("Content-Length", strconv.FormatInt(desc.Size, 10))
+		if req.Method == http.MethodGet {
+			w.Write(r.blobs[desc.Digest])
+		}
+	case http.MethodPut:
+		data, _ := ioutil.ReadAll(req.Body)
+		desc := ocispec.Descriptor{
+			MediaType: req.Header.Get("Content-Type"),
+			Digest:    digest.FromBytes(data),
+			Size:      int64(len(data)),
+		}
+		tag := ref
+		if _, err := digest.Parse(ref); err == nil {
+			tag = ""
+		}
+		r.store(repository, tag, desc, data)
+		w.Header().Set("Docker-Content-Digest", desc.Digest.String())
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if r.disallowDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if _, ok := r.manifests[repository][ref]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		r.mutate()
+		for key, desc := range r.manifests[repository] {
+			if desc.Digest.String() == ref {
+				delete(r.manifests[repository], key)
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// serveBlob serves the blobs, and the monolithic uploads of the blobs.
+func (r *testRegistry) serveBlob(w http.ResponseWriter, req *http.Request, repository, ref string) {
+	switch {
+	case req.Method == http.MethodPost && ref == "uploads/":
+		r.uploads++
+		w.Header().Set("Location", "/v2/"+repository+"/blobs/uploads/"+strconv.Itoa(r.uploads))
+		w.WriteHeader(http.StatusAccepted)
+	case req.Method == http.MethodPut && strings.HasPrefix(ref, "uploads/"):
+		data, _ := ioutil.ReadAll(req.Body)
+		dgst := digest.FromBytes(data)
+		if req.URL.Query().Get("digest") != dgst.String() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.blobs[dgst] = data
+		w.Header().Set("Docker-Content-Digest", dgst.String())
+		w.WriteHeader(http.StatusCreated)
+	case req.Method == http.MethodGet || req.Method == http.MethodHead:
+		data, ok := r.blobs[digest.Digest(ref)]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Docker-Content-Digest", ref)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if req.Method == http.MethodGet {
+			w.Write(data)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// captureStdout returns what the function writes to stdout.
+func captureStdout(t *testing.T, f func() error) (string, error) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	old := os.Stdout
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		output <- buf.String()
+	}()
+	err = f()
+	os.Stdout = old
+	w.Close()
+	return <-output, err
+}
+
+// isolateConfig points the oras config, the reference cache and the docker
+// config to an empty directory, and returns a function restoring them.
+func isolateConfig(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "oras_config_test")
+	require.NoError(t, err)
+	restores := []func(){
+		setenv(t, config.EnvConfigPath, filepath.Join(dir, "config.json")),
+		setenv(t, refcache.EnvCachePath, filepath.Join(dir, "refs.json")),
+		setenv(t, "DOCKER_CONFIG", dir),
+	}
+	return func() {
+		for _, restore := range restores {
+			restore()
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+// runCmd runs the command with the arguments, and returns its output.
+func runCmd(t *testing.T, cmd *cobra.Command, args ...string) (string, error) {
+	cmd.SetArgs(args)
+	cmd.SetOutput(ioutil.Discard)
+	return captureStdout(t, cmd.Execute)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/spf13/pflag"
+)
+
+// remoteOptions are the credentials and the connection settings of the
+// registries, shared by the commands talking to them.
+type remoteOptions struct {
+	configs   []string
+	username  string
+	password  string
+	secret    secretOptions
+	insecure  bool
+	plainHTTP bool
+	tls       tlsOptions
+	retry     retryOptions
+}
+
+func (opts *remoteOptions) applyFlags(fs *pflag.FlagSet) {
+	fs.StringArrayVarP(&opts.configs, "config", "c", nil, "auth config path")
+	fs.StringVarP(&opts.username, "username", "u", "", "registry username")
+	fs.StringVarP(&opts.password, "password", "p", "", "registry password")
+	opts.secret.applyFlags(fs)
+	fs.BoolVarP(&opts.insecure, "insecure", "", false, "allow connections to SSL registry without certs")
+	fs.BoolVarP(&opts.plainHTTP, "plain-http", "", false, "use plain http and not https")
+	opts.tls.applyFlags(fs)
+	opts.retry.applyFlags(fs)
+}
+
+// resolveSecret sets the password to the secret read from the file
+// descriptors, if specified.
+func (opts *remoteOptions) resolveSecret() error {
+	return opts.secret.resolve(&opts.username, &opts.password)
+}
+
+// reference returns the reference with the default registry of the profile
+// if it has no registry.
+func (opts *remoteOptions) reference(ref string) string {
+	return withDefaultRegistry(ref, defaultRegistry)
+}
+
+// registryHosts creates the registry host configurations of the options.
+func (opts *remoteOptions) registryHosts() docker.RegistryHosts {
+	return newRegistryHosts(opts.username, opts.password, opts.insecure, opts.plainHTTP, opts.tls, opts.retry, opts.configs...)
+}
+
+// resolver creates a resolver against the registries of the options.
+func (opts *remoteOptions) resolver() remotes.Resolver {
+	return newResolver(opts.username, opts.password, opts.insecure, opts.plainHTTP, opts.tls, opts.retry, opts.configs...)
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// maxMetadataSize limits the size of manifests and configs read for details.
+const maxMetadataSize = 4 * 1024 * 1024
+
+func repoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repo [command]",
+		Short: "Repository operations",
+	}
+	cmd.AddCommand(repoListCmd(), repoTagsCmd(), repoPruneCmd())
+	return cmd
+}
+
+type repoTagsOptions struct {
+	targetRef         string
+	detail            bool
+	last              string
+	excludeDigestTags bool
+	format            formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func repoTagsCmd() *cobra.Command {
+	var opts repoTagsOptions
+	cmd := &cobra.Command{
+		Use:   "tags <name>",
+		Short: "List the tags of a repository",
+		Long: `List the tags of a repository
+
+Example - List the tags of a repository:
+  oras repo tags localhost:5000/hello
+
+Example - List the tags with digests and created timestamps, newest first:
+  oras repo tags --detail localhost:5000/hello
+
+Example - List the tags after "v1", excluding the digest tags of the referrers:
+  oras repo tags --last v1 --exclude-digest-tags localhost:5000/hello
+
+Example - List the tags as JSON:
+  oras repo tags --format json localhost:5000/hello
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runRepoTags(opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.detail, "detail", "", false, "show digests and created timestamps, sorted newest first")
+	cmd.Flags().StringVarP(&opts.last, "last", "", "", "start listing after this tag")
+	cmd.Flags().BoolVarP(&opts.excludeDigestTags, "exclude-digest-tags", "", false, "exclude the tags derived from digests, e.g. sha256-<encoded>")
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runRepoTags(opts repoTagsOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+
+	hosts := opts.remote.registryHosts()
+	tags, err := registry.NewClient(hosts).TagsAfter(ctx, opts.targetRef, opts.last)
+	if err != nil {
+		return err
+	}
+	if opts.excludeDigestTags {
+		filtered := tags[:0]
+		for _, tag := range tags {
+			if !registry.IsDigestTag(tag) {
+				filtered = append(filtered, tag)
+			}
+		}
+		tags = filtered
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	if !opts.detail {
+		if opts.format.enabled() {
+			return opts.format.write("", tagListResult{
+				Repository: opts.targetRef,
+				Tags:       tags,
+			})
+		}
+		for _, tag := range tags {
+			fmt.Println(tag)
+		}
+		return nil
+	}
+
+	refspec, err := reference.Parse(opts.targetRef)
+	if err != nil {
+		return err
+	}
+	resolver := docker.NewResolver(docker.ResolverOptions{
+		Hosts: hosts,
+	})
+	details := resolveTagDetails(ctx, resolver, refspec.Locator, tags, os.Stderr)
+
+	if opts.format.enabled() {
+		result := tagListResult{
+			Repository: opts.targetRef,
+			Tags:       tags,
+		}
+		for _, detail := range details {
+			detailResult := tagDetailResult{
+				Tag:    detail.tag,
+				Digest: detail.digest,
+			}
+			if detail.err != nil {
+				detailResult.Error = detail.err.Error()
+			}
+			if !detail.created.IsZero() {
+				created := detail.created
+				detailResult.Created = &created
+			}
+			result.Details = append(result.Details, detailResult)
+		}
+		return opts.format.write("", result)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "TAG\tDIGEST\tCREATED")
+	for _, detail := range details {
+		digest, created := detail.digest, "-"
+		if digest == "" {
+			digest = "-"
+		}
+		if !detail.created.IsZero() {
+			created = detail.created.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", detail.tag, digest, created)
+	}
+	return tw.Flush()
+}
+
+type tagDetail struct {
+	tag     string
+	digest  string
+	created time.Time
+	err     error
+}
+
+// resolveTagDetails resolves the details of the tags of the repository, sorted
+// newest first. The details are best-effort: a tag failed to resolve, e.g.
+// deleted while listing, is warned to w and kept without details.
+func resolveTagDetails(ctx context.Context, resolver remotes.Resolver, locator string, tags []string, w io.Writer) []tagDetail {
+	details := make([]tagDetail, 0, len(tags))
+	for _, tag := range tags {
+		detail, err := resolveTagDetail(ctx, resolver, locator+":"+tag)
+		if err != nil {
+			fmt.Fprintf(w, "WARNING: Failed to resolve the details of %s: %v\n", tag, err)
+			detail = tagDetail{err: err}
+		}
+		detail.tag = tag
+		details = append(details, detail)
+	}
+	sortTagDetails(details)
+	return details
+}
+
+// sortTagDetails sorts the tags newest first. Tags without created timestamps
+// are placed last.
+func sortTagDetails(details []tagDetail) {
+	sort.SliceStable(details, func(i, j int) bool {
+		return details[i].created.After(details[j].created)
+	})
+}
+
+// resolveTagDetail resolves the digest of the tag and the best-effort created
+// timestamp, which is read from the `org.opencontainers.image.created`
+// annotation of the manifest, or the `created` field of the config.
+func resolveTagDetail(ctx context.Context, resolver remotes.Resolver, ref string) (tagDetail, error) {
+	_, desc, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return tagDetail{}, err
+	}
+	detail := tagDetail{
+		digest: desc.Digest.String(),
+	}
+	fetcher, err := resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return tagDetail{}, err
+	}
+
+	var manifest struct {
+		Config      *ocispec.Descriptor `json:"config"`
+		Annotations map[string]string   `json:"annotations"`
+	}
+	if err := fetchJSON(ctx, fetcher, desc, &manifest); err != nil {
+		return tagDetail{}, err
+	}
+	if created, err := time.Parse(time.RFC3339, manifest.Annotations[ocispec.AnnotationCreated]); err == nil {
+		detail.created = created
+		return detail, nil
+	}
+	if manifest.Config == nil || !isConfigWithCreated(manifest.Config.MediaType) {
+		return detail, nil
+	}
+
+	var config struct {
+		Created *time.Time `json:"created"`
+	}
+	if err := fetchJSON(ctx, fetcher, *manifest.Config, &config); err != nil {
+		// the config is optional for the details
+		return detail, nil
+	}
+	if config.Created != nil {
+		detail.created = *config.Created
+	}
+	return detail, nil
+}
+
+// isConfigWithCreated checks if the config of the media type may have the
+// `created` field.
+func isConfigWithCreated(mediaType string) bool {
+	switch mediaType {
+	case ocispec.MediaTypeImageConfig, images.MediaTypeDockerSchema2Config:
+		return true
+	}
+	return false
+}
+
+func fetchJSON(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor, v interface{}) error {
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(io.LimitReader(rc, maxMetadataSize)).Decode(v)
+}
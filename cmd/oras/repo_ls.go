@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type repoListOptions struct {
+	targetRef string
+	last      string
+	format    formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func repoListCmd() *cobra.Command {
+	var opts repoListOptions
+	cmd := &cobra.Command{
+		Use:     "ls <registry>[/<namespace>]",
+		Aliases: []string{"list"},
+		Short:   "List the repositories of a registry",
+		Long: `List the repositories of a registry with the catalog API
+
+Docker Hub and GHCR do not support the catalog API, and their repositories are
+listed with the REST APIs of Docker Hub and GitHub under a namespace, i.e. a
+user or an organization, with the same credentials. GHCR requires a token with
+the read:packages scope as the password.
+
+Example - List the repositories of a registry:
+  oras repo ls localhost:5000
+
+Example - List the repositories under a namespace:
+  oras repo ls localhost:5000/example
+
+Example - List the repositories of an organization on Docker Hub or GHCR:
+  oras repo ls docker.io/library
+  oras repo ls ghcr.io/example
+
+Example - List the repositories after "example/hello":
+  oras repo ls --last example/hello localhost:5000
+
+Example - List the repositories as JSON:
+  oras repo ls --format json localhost:5000
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runRepoList(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.last, "last", "", "", "start listing after this repository, relative to the namespace if specified")
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runRepoList(opts repoListOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+
+	host := strings.TrimSuffix(opts.targetRef, "/")
+	var namespace string
+	if i := strings.Index(host, "/"); i >= 0 {
+		host, namespace = host[:i], host[i+1:]+"/"
+	}
+	last := opts.last
+	if namespace != "" {
+		if last != "" {
+			last = namespace + last
+		} else {
+			// skip the repositories lexically before the namespace
+			last = strings.TrimSuffix(namespace, "/")
+		}
+	}
+
+	transport := newRegistryTransport(opts.remote.insecure, opts.remote.plainHTTP, opts.remote.tls)
+	retry := opts.remote.retry.options()
+	catalog := registry.NewCatalog(&http.Client{
+		Transport:     newRetryTransport(transport, retry, registry.NewRetryBudgetWithRetry(retry)),
+		CheckRedirect: transport.redirectPolicy().CheckRedirect,
+	}, newCredential(opts.remote.username, opts.remote.password, opts.remote.configs...))
+	var (
+		repos []string
+		err   error
+	)
+	if catalog.Supports(host) {
+		repos, err = catalog.Repositories(ctx, host, namespace)
+	} else {
+		hosts := opts.remote.registryHosts()
+		repos, err = registry.NewClient(hosts).Repositories(ctx, host, last)
+	}
+	if err != nil {
+		return err
+	}
+	var filtered []string
+	for _, repo := range repos {
+		if last != "" && repo <= last {
+			continue
+		}
+		if strings.HasPrefix(repo, namespace) {
+			filtered = append(filtered, strings.TrimPrefix(repo, namespace))
+		}
+	}
+
+	if opts.format.enabled() {
+		if filtered == nil {
+			filtered = []string{}
+		}
+		return opts.format.write("", repoListResult{
+			Registry:     host,
+			Namespace:    strings.TrimSuffix(namespace, "/"),
+			Repositories: filtered,
+		})
+	}
+	for _, repo := range filtered {
+		fmt.Println(repo)
+	}
+	return nil
+}
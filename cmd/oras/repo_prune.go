@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deislabs/oras/pkg/artifact"
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type repoPruneOptions struct {
+	targetRef string
+	expired   bool
+	dryRun    bool
+	force     bool
+	format    formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func repoPruneCmd() *cobra.Command {
+	var opts repoPruneOptions
+	cmd := &cobra.Command{
+		Use:   "prune <name>",
+		Short: "Delete the expired artifacts of a repository",
+		Long: `Delete the expired artifacts of a repository
+
+The manifests of the tags are deleted with all their tags once past the expiry
+recorded by oras push --expires-in. Untagged manifests are not pruned.
+
+Example - Show the expired artifacts of a repository:
+  oras repo prune --expired --dry-run localhost:5000/hello
+
+Example - Delete the expired artifacts of a repository without confirmation:
+  oras repo prune --expired -f localhost:5000/hello
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runRepoPrune(opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.expired, "expired", "", false, "delete the artifacts past their recorded expiry")
+	cmd.Flags().BoolVarP(&opts.dryRun, "dry-run", "", false, "only show the artifacts to delete")
+	cmd.Flags().BoolVarP(&opts.force, "force", "f", false, "delete without confirmation")
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runRepoPrune(opts repoPruneOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	if !opts.dryRun {
+		if err := checkWritable(opts.targetRef); err != nil {
+			return err
+		}
+	}
+	if !opts.expired {
+		return errors.New("nothing to prune, specify --expired")
+	}
+	if opts.format.enabled() && !opts.force && !opts.dryRun {
+		return errors.New("--format requires --force or --dry-run")
+	}
+	refspec, err := reference.Parse(opts.targetRef)
+	if err != nil {
+		return err
+	}
+	if refspec.Object != "" {
+		return fmt.Errorf("%s: expected a repository without tag or digest", opts.targetRef)
+	}
+
+	hosts := opts.remote.registryHosts()
+	client := registry.NewClient(hosts)
+	tags, err := client.Tags(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	expired, err := findExpired(ctx, newManifestResolver(hosts, nil), refspec.Locator, tags, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if len(expired) > 0 && !opts.force && !opts.dryRun {
+		confirmed, err := confirm(fmt.Sprintf("Are you sure you want to delete %d expired manifests and all tags associated with them? [y/N] ", len(expired)))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return nil
+		}
+	}
+	result := pruneResult{
+		Repository: refspec.Locator,
+		DryRun:     opts.dryRun,
+		Deleted:    []prunedResult{},
+	}
+	for _, manifest := range expired {
+		if !opts.dryRun {
+			if err := client.DeleteManifest(ctx, opts.targetRef, manifest.Digest); err != nil {
+				return fmt.Errorf("%s@%s: %v", refspec.Locator, manifest.Digest, err)
+			}
+		}
+		result.Deleted = append(result.Deleted, manifest)
+		if opts.format.enabled() {
+			continue
+		}
+		action := "Deleted"
+		if opts.dryRun {
+			action = "Would delete"
+		}
+		fmt.Printf("%s %s (%s), expired %s\n", action, manifest.Digest, strings.Join(manifest.Tags, ", "), manifest.Expires.Format(time.RFC3339))
+	}
+	if opts.format.enabled() {
+		return opts.format.write("", result)
+	}
+	if len(expired) == 0 {
+		fmt.Println("No expired artifacts in", refspec.Locator)
+	}
+	return nil
+}
+
+// findExpired returns the manifests of the tags past their expiry at now,
+// with the tags referencing them.
+func findExpired(ctx context.Context, resolver remotes.Resolver, locator string, tags []string, now time.Time) ([]prunedResult, error) {
+	manifests := make(map[digest.Digest]*prunedResult)
+	var expired []*prunedResult
+	for _, tag := range tags {
+		ref := locator + ":" + tag
+		_, desc, err := resolver.Resolve(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if manifest, ok := manifests[desc.Digest]; ok {
+			manifest.Tags = append(manifest.Tags, tag)
+			continue
+		}
+		fetcher, err := resolver.Fetcher(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		var manifest struct {
+			Annotations map[string]string `json:"annotations"`
+		}
+		if err := fetchJSON(ctx, fetcher, desc, &manifest); err != nil {
+			return nil, fmt.Errorf("%s: %v", ref, err)
+		}
+		expires, ok, err := artifact.Expiry(manifest.Annotations)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: Skipping %s: %v\n", ref, err)
+		}
+		pruned := &prunedResult{
+			Digest:  desc.Digest,
+			Tags:    []string{tag},
+			Expires: expires,
+		}
+		manifests[desc.Digest] = pruned
+		if ok && !expires.After(now) {
+			expired = append(expired, pruned)
+		}
+	}
+	results := make([]prunedResult, 0, len(expired))
+	for _, pruned := range expired {
+		results = append(results, *pruned)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Expires.Before(results[j].Expires)
+	})
+	return results, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/deislabs/oras/pkg/artifact"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindExpired(t *testing.T) {
+	now := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	resolver := newTestResolver()
+	manifest := func(ref, expires string) {
+		annotations := map[string]string{}
+		if expires != "" {
+			annotations[artifact.AnnotationExpires] = expires
+		}
+		resolver.add(ref, map[string]interface{}{
+			"schemaVersion": 2,
+			"annotations":   annotations,
+		})
+	}
+	manifest("localhost:5000/hello:old", "2020-01-01T00:00:00Z")
+	manifest("localhost:5000/hello:older", "2019-12-01T00:00:00Z")
+	resolver.refs["localhost:5000/hello:older-alias"] = resolver.refs["localhost:5000/hello:older"]
+	manifest("localhost:5000/hello:now", "2020-01-02T00:00:00Z")
+	manifest("localhost:5000/hello:new", "2020-01-03T00:00:00Z")
+	manifest("localhost:5000/hello:kept", "")
+	manifest("localhost:5000/hello:invalid", "yesterday")
+
+	tags := []string{"new", "old", "older", "kept", "invalid", "now", "older-alias"}
+	expired, err := findExpired(context.Background(), resolver, "localhost:5000/hello", tags, now)
+	require.NoError(t, err)
+	require.Len(t, expired, 3)
+	assert.Equal(t, resolver.refs["localhost:5000/hello:older"].Digest, expired[0].Digest)
+	assert.Equal(t, []string{"older", "older-alias"}, expired[0].Tags, "all tags of the manifest")
+	assert.Equal(t, time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC), expired[0].Expires)
+	assert.Equal(t, resolver.refs["localhost:5000/hello:old"].Digest, expired[1].Digest)
+	assert.Equal(t, []string{"old"}, expired[1].Tags)
+	assert.Equal(t, resolver.refs["localhost:5000/hello:now"].Digest, expired[2].Digest, "expired at the expiry")
+
+	expired, err = findExpired(context.Background(), resolver, "localhost:5000/hello", nil, now)
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+
+	_, err = findExpired(context.Background(), resolver, "localhost:5000/hello", []string{"old", "missing"}, now)
+	assert.Error(t, err, "error resolving missing tag")
+}
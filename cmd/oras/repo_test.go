@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testResolver resolves the references to the manifests in memory.
+type testResolver struct {
+	refs  map[string]ocispec.Descriptor
+	blobs map[digest.Digest][]byte
+}
+
+func newTestResolver() *testResolver {
+	return &testResolver{
+		refs:  make(map[string]ocispec.Descriptor),
+		blobs: make(map[digest.Digest][]byte),
+	}
+}
+
+func (r *testResolver) add(ref string, v interface{}) ocispec.Descriptor {
+	data, _ := json.Marshal(v)
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+	}
+	r.blobs[desc.Digest] = data
+	if ref != "" {
+		r.refs[ref] = desc
+	}
+	return desc
+}
+
+func (r *testResolver) Resolve(ctx context.Context, ref string) (string, ocispec.Descriptor, error) {
+	desc, ok := r.refs[ref]
+	if !ok {
+		return "", ocispec.Descriptor{}, errdefs.ErrNotFound
+	}
+	return ref, desc, nil
+}
+
+func (r *testResolver) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	return r, nil
+}
+
+func (r *testResolver) Pusher(ctx context.Context, ref string) (remotes.Pusher, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+func (r *testResolver) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	data, ok := r.blobs[desc.Digest]
+	if !ok {
+		return nil, errdefs.ErrNotFound
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func TestResolveTagDetails(t *testing.T) {
+	resolver := newTestResolver()
+	config := resolver.add("", map[string]interface{}{
+		"created": "2020-01-02T00:00:00Z",
+	})
+	config.MediaType = ocispec.MediaTypeImageConfig
+	old := resolver.add("localhost:5000/hello:old", map[string]interface{}{
+		"config": config,
+	})
+	latest := resolver.add("localhost:5000/hello:latest", map[string]interface{}{
+		"annotations": map[string]string{
+			ocispec.AnnotationCreated: "2021-01-02T00:00:00Z",
+		},
+	})
+
+	var warnings bytes.Buffer
+	details := resolveTagDetails(context.Background(), resolver, "localhost:5000/hello", []string{"old", "deleted", "latest"}, &warnings)
+	require.Len(t, details, 3)
+
+	assert.Equal(t, "latest", details[0].tag)
+	assert.Equal(t, latest.Digest.String(), details[0].digest)
+	assert.Equal(t, time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), details[0].created)
+	assert.Equal(t, "old", details[1].tag)
+	assert.Equal(t, old.Digest.String(), details[1].digest)
+	assert.Equal(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), details[1].created)
+
+	// the tag failed to resolve is kept without details
+	assert.Equal(t, "deleted", details[2].tag)
+	assert.Empty(t, details[2].digest)
+	assert.True(t, errdefs.IsNotFound(details[2].err))
+	assert.Contains(t, warnings.String(), "WARNING: Failed to resolve the details of deleted")
+}
+
+func TestSortTagDetails(t *testing.T) {
+	details := []tagDetail{
+		{tag: "none"},
+		{tag: "old", created: time.Unix(1, 0)},
+		{tag: "new", created: time.Unix(2, 0)},
+	}
+	sortTagDetails(details)
+	var tags []string
+	for _, detail := range details {
+		tags = append(tags, detail.tag)
+	}
+	assert.Equal(t, []string{"new", "old", "none"}, tags)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/oras"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type resolveOptions struct {
+	targetRef     string
+	fullReference bool
+	platform      platformOptions
+	deprecation   deprecationOptions
+	format        formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func resolveCmd() *cobra.Command {
+	var opts resolveOptions
+	cmd := &cobra.Command{
+		Use:   "resolve <name:tag|name@digest>",
+		Short: "Resolve a reference to the digest of its manifest",
+		Long: `Resolve a reference to the digest of its manifest
+
+With --format, the descriptor of the manifest is printed as JSON, or with a Go
+template over its fields .Reference, .MediaType, .Digest, .Size, .Annotations
+and .Platform.
+
+Example - Resolve the digest of a tag:
+  oras resolve localhost:5000/hello:latest
+
+Example - Resolve the reference pinned to the digest:
+  oras resolve --full-reference localhost:5000/hello:latest
+
+Example - Resolve the digest and the size of the linux/arm64 manifest of an index:
+  oras resolve --platform linux/arm64 --format '{{.Digest}} {{.Size}}' localhost:5000/hello:latest
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runResolve(opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.fullReference, "full-reference", "l", false, "print the reference pinned to the digest, i.e. <name@digest>")
+	opts.platform.applyFlags(cmd.Flags())
+	opts.deprecation.applyFlags(cmd.Flags())
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runResolve(opts resolveOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	matcher, err := opts.platform.matcher()
+	if err != nil {
+		return err
+	}
+	refspec, err := reference.Parse(opts.targetRef)
+	if err != nil {
+		return err
+	}
+
+	hosts := opts.remote.registryHosts()
+	resolver, err := prefetchManifest(ctx, newManifestResolver(hosts, nil), registry.NewClient(hosts), opts.targetRef, defaultManifestMediaTypes)
+	if err != nil {
+		return err
+	}
+	desc, err := resolveManifest(ctx, resolver, opts.targetRef, matcher, opts.deprecation)
+	if err != nil {
+		return err
+	}
+
+	if opts.format.enabled() {
+		return opts.format.write("", artifactResult{
+			Reference:  opts.targetRef,
+			Descriptor: desc,
+		})
+	}
+	if opts.fullReference {
+		fmt.Printf("%s@%s\n", refspec.Locator, desc.Digest)
+		return nil
+	}
+	fmt.Println(desc.Digest)
+	return nil
+}
+
+// resolveManifest resolves the descriptor of the manifest of ref, checking
+// whether it is deprecated by a tombstone, and selects the manifest of the
+// platform matched by matcher if ref refers to an index.
+func resolveManifest(ctx context.Context, resolver remotes.Resolver, ref string, matcher oras.PlatformMatcher, deprecation deprecationOptions) (ocispec.Descriptor, error) {
+	name, desc, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if desc.MediaType == ocispec.MediaTypeImageManifest {
+		annotations, err := fetchAnnotations(ctx, resolver, name, desc)
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		if err := deprecation.check(ref, annotations); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+	if matcher == nil {
+		return desc, nil
+	}
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return oras.SelectPlatform(ctx, fetcher, desc, matcher)
+}
+
+// prefetchedResolver serves the manifest of ref fetched ahead from memory, so
+// that the manifest checked before a pull is not fetched again.
+type prefetchedResolver struct {
+	remotes.Resolver
+	ref     string
+	desc    ocispec.Descriptor
+	content []byte
+}
+
+// prefetchManifest resolves ref and fetches its manifest, in a single request
+// to the registry if client is not nil, or through the resolver otherwise,
+// e.g. for OCI image layouts.
+func prefetchManifest(ctx context.Context, resolver remotes.Resolver, client *registry.Client, ref string, mediaTypes []string) (*prefetchedResolver, error) {
+	prefetched := &prefetchedResolver{
+		Resolver: resolver,
+		ref:      ref,
+	}
+	var err error
+	if client != nil {
+		prefetched.desc, prefetched.content, err = client.ResolveManifest(ctx, ref, mediaTypes)
+		if err != nil {
+			return nil, err
+		}
+		return prefetched, nil
+	}
+	name, desc, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	if prefetched.content, err = ioutil.ReadAll(io.LimitReader(rc, desc.Size)); err != nil {
+		return nil, err
+	}
+	prefetched.desc = desc
+	return prefetched, nil
+}
+
+// Resolve implements remotes.Resolver.
+func (r *prefetchedResolver) Resolve(ctx context.Context, ref string) (string, ocispec.Descriptor, error) {
+	if ref == r.ref {
+		return ref, r.desc, nil
+	}
+	return r.Resolver.Resolve(ctx, ref)
+}
+
+// Fetcher implements remotes.Resolver.
+func (r *prefetchedResolver) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	fetcher, err := r.Resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return remotes.FetcherFunc(func(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+		if desc.Digest == r.desc.Digest {
+			return ioutil.NopCloser(bytes.NewReader(r.content)), nil
+		}
+		return fetcher.Fetch(ctx, desc)
+	}), nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/deislabs/oras/pkg/artifact"
+	"github.com/deislabs/oras/pkg/oras"
+
+	"github.com/containerd/containerd/errdefs"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveManifest(t *testing.T) {
+	resolver := newTestResolver()
+	config := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+	}
+	amd64 := resolver.add("localhost:5000/hello:amd64", map[string]interface{}{
+		"schemaVersion": 2,
+		"config":        config,
+	})
+	amd64.Platform = &ocispec.Platform{Architecture: "amd64", OS: "linux"}
+	arm64 := resolver.add("localhost:5000/hello:arm64", map[string]interface{}{
+		"schemaVersion": 2,
+		"config":        config,
+		"annotations": map[string]string{
+			"arch": "arm64",
+		},
+	})
+	arm64.Platform = &ocispec.Platform{Architecture: "arm64", OS: "linux"}
+	index := resolver.add("", map[string]interface{}{
+		"schemaVersion": 2,
+		"manifests":     []ocispec.Descriptor{amd64, arm64},
+	})
+	index.MediaType = ocispec.MediaTypeImageIndex
+	resolver.refs["localhost:5000/hello:latest"] = index
+	tombstone := resolver.add("localhost:5000/hello:old", map[string]interface{}{
+		"schemaVersion": 2,
+		"config":        config,
+		"annotations": map[string]string{
+			artifact.AnnotationDeprecated:  "2020-01-02T00:00:00Z",
+			artifact.AnnotationReplacement: "localhost:5000/hello:latest",
+		},
+	})
+
+	for _, tc := range []struct {
+		name         string
+		ref          string
+		platform     string
+		noDeprecated bool
+		want         ocispec.Descriptor
+		wantErr      string
+	}{
+		{
+			name: "manifest",
+			ref:  "localhost:5000/hello:arm64",
+			want: resolver.refs["localhost:5000/hello:arm64"],
+		},
+		{
+			name: "index",
+			ref:  "localhost:5000/hello:latest",
+			want: index,
+		},
+		{
+			name:     "platform",
+			ref:      "localhost:5000/hello:latest",
+			platform: "linux/arm64",
+			want:     arm64,
+		},
+		{
+			name:     "platform of manifest",
+			ref:      "localhost:5000/hello:amd64",
+			platform: "linux/arm64",
+			want:     resolver.refs["localhost:5000/hello:amd64"],
+		},
+		{
+			name:     "platform not matched",
+			ref:      "localhost:5000/hello:latest",
+			platform: "windows/amd64",
+			wantErr:  oras.ErrPlatformNotMatched.Error(),
+		},
+		{
+			name: "deprecated",
+			ref:  "localhost:5000/hello:old",
+			want: tombstone,
+		},
+		{
+			name:         "deprecated rejected",
+			ref:          "localhost:5000/hello:old",
+			noDeprecated: true,
+			wantErr:      "localhost:5000/hello:old is deprecated since 2020-01-02T00:00:00Z, use localhost:5000/hello:latest instead",
+		},
+		{
+			name:    "not found",
+			ref:     "localhost:5000/hello:missing",
+			wantErr: errdefs.ErrNotFound.Error(),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			platform := platformOptions{platform: tc.platform}
+			matcher, err := platform.matcher()
+			require.NoError(t, err)
+			deprecation := deprecationOptions{noDeprecated: tc.noDeprecated}
+			desc, err := resolveManifest(context.Background(), resolver, tc.ref, matcher, deprecation)
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, desc)
+		})
+	}
+}
+
+func TestResolveManifestNotFetched(t *testing.T) {
+	resolver := newTestResolver()
+	resolver.refs["localhost:5000/hello:broken"] = ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Size:      1,
+	}
+	_, err := resolveManifest(context.Background(), resolver, "localhost:5000/hello:broken", nil, deprecationOptions{})
+	assert.True(t, errors.Is(err, errdefs.ErrNotFound), "manifest not fetched")
+}
@@ -1,48 +1,214 @@
 package main
 
 import (
-	"context"
-	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/deislabs/oras/internal/config"
+	iauth "github.com/deislabs/oras/pkg/auth"
 	auth "github.com/deislabs/oras/pkg/auth/docker"
+	"github.com/deislabs/oras/pkg/content"
+	"github.com/deislabs/oras/pkg/registry"
 
+	"github.com/containerd/containerd/reference"
 	"github.com/containerd/containerd/remotes"
 	"github.com/containerd/containerd/remotes/docker"
+	units "github.com/docker/go-units"
 )
 
-func newResolver(username, password string, insecure bool, plainHTTP bool, configs ...string) remotes.Resolver {
+func newResolver(username, password string, insecure bool, plainHTTP bool, tlsOpts tlsOptions, retryOpts retryOptions, configs ...string) remotes.Resolver {
+	return docker.NewResolver(docker.ResolverOptions{
+		Hosts: newRegistryHosts(username, password, insecure, plainHTTP, tlsOpts, retryOpts, configs...),
+	})
+}
 
-	opts := docker.ResolverOptions{
-		PlainHTTP: plainHTTP,
+// newRegistryHosts creates the registry host configurations shared by the
+// resolver and the registry client. The TLS settings, plain http, mirrors and
+// endpoints are configured per registry by the oras config or the hosts.toml
+// files of its hosts directory, and the TLS settings and plain http are
+// overridden by the options. The references seen are recorded in the
+// reference cache.
+func newRegistryHosts(username, password string, insecure bool, plainHTTP bool, tlsOpts tlsOptions, retryOpts retryOptions, configs ...string) docker.RegistryHosts {
+	transport := newRegistryTransport(insecure, plainHTTP, tlsOpts)
+	retry := retryOpts.options()
+	budget := registry.NewRetryBudgetWithRetry(retry)
+	client := &http.Client{
+		// retry transient failures and fail fast against unhealthy registries
+		Transport:     newRetryTransport(transport, retry, budget),
+		CheckRedirect: transport.redirectPolicy().CheckRedirect,
+	}
+	isPlainHTTP := func(host string) bool {
+		if transport.isPlainHTTP(host) {
+			return true
+		}
+		local, _ := docker.MatchLocalhost(host)
+		return local
 	}
 
-	client := http.DefaultClient
-	if insecure {
-		client.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
+	credential := newCredential(username, password, configs...)
+	authorizer := docker.NewDockerAuthorizer(
+		docker.WithAuthClient(client),
+		docker.WithAuthCreds(credential),
+	)
+	hosts := docker.ConfigureDefaultRegistries(
+		docker.WithClient(client),
+		docker.WithPlainHTTP(func(host string) (bool, error) {
+			return isPlainHTTP(host), nil
+		}),
+		docker.WithAuthorizer(authorizer),
+	)
+	var dir *hostsDir
+	if root := transport.config.ExpandedHostsDir(); root != "" {
+		dir = &hostsDir{
+			root:          root,
+			credentials:   credential,
+			retry:         retry,
+			budget:        budget,
+			plainHTTP:     isPlainHTTP,
+			checkRedirect: client.CheckRedirect,
 		}
 	}
-	opts.Client = client
+	return newRefCache().hosts(func(host string) ([]docker.RegistryHost, error) {
+		if dir != nil {
+			if hosts, ok, err := dir.configure(host); ok {
+				return hosts, err
+			}
+		}
+		defaults, err := hosts(host)
+		if err != nil {
+			return nil, err
+		}
+		configured, err := configureMirrors(defaults, transport.config.Registry(host), docker.RegistryHost{
+			Client:     client,
+			Authorizer: authorizer,
+		}, isPlainHTTP)
+		if err != nil {
+			return nil, err
+		}
+		transport.addRegistry(host)
+		for _, registryHost := range configured {
+			transport.addRegistry(registryHost.Host)
+		}
+		// fail early on invalid TLS settings
+		if _, err := transport.transport(host); err != nil {
+			return nil, err
+		}
+		return configured, nil
+	})
+}
 
+// newChunkedUploader creates an uploader with the chunk size in human
+// readable form. The upload sessions are persisted in the user cache directory
+// to resume interrupted uploads.
+func newChunkedUploader(hosts docker.RegistryHosts, chunkSize string) (*registry.ChunkedUploader, error) {
+	size, err := units.RAMInBytes(chunkSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chunk size %q: %v", chunkSize, err)
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %q: must be positive", chunkSize)
+	}
+	var stateDir string
+	if dir, err := os.UserCacheDir(); err == nil {
+		stateDir = filepath.Join(dir, "oras", "uploads")
+	}
+	return registry.NewChunkedUploader(registry.NewClient(hosts), size, stateDir), nil
+}
+
+// newCredential returns the static credential if provided. Otherwise, the
+// credentials are read from the auth configs.
+func newCredential(username, password string, configs ...string) func(string) (string, string, error) {
 	if username != "" || password != "" {
-		opts.Credentials = func(hostName string) (string, string, error) {
+		return func(hostName string) (string, string, error) {
 			return username, password, nil
 		}
-		return docker.NewResolver(opts)
 	}
-	cli, err := auth.NewClient(configs...)
+	cli, err := newAuthClient("", configs...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "WARNING: Error loading auth file: %v\n", err)
+		return nil
+	}
+	return cli.Credential
+}
+
+// checkWritable returns an error if the registry of the reference is not
+// allowed to be modified by the writableRegistries of the oras config.
+func checkWritable(ref string) error {
+	refspec, err := reference.Parse(ref)
+	if err != nil {
+		return err
 	}
-	resolver, err := cli.Resolver(context.Background(), client, plainHTTP)
+	cfg, err := config.LoadDefault()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "WARNING: Error loading resolver: %v\n", err)
-		resolver = docker.NewResolver(opts)
+		return fmt.Errorf("failed to load oras config: %v", err)
+	}
+	if host := refspec.Hostname(); !cfg.IsWritable(host) {
+		return fmt.Errorf("%s: registry %s is not in the writable registries of the oras config", ref, host)
+	}
+	return nil
+}
+
+// Credential stores selected by --credential-store or the oras config.
+const (
+	credentialStoreDocker  = "docker"
+	credentialStoreKeyring = "keyring"
+)
+
+// newAuthClient creates an auth client keeping the credentials in the store,
+// which defaults to the one of the oras config, or to the docker config files.
+func newAuthClient(store string, configs ...string) (iauth.Client, error) {
+	if store == "" {
+		cfg, err := config.LoadDefault()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: Error loading oras config: %v\n", err)
+			cfg = &config.Config{}
+		}
+		store = cfg.CredentialStore
+	}
+	switch store {
+	case "", credentialStoreDocker:
+		return auth.NewClient(configs...)
+	case credentialStoreKeyring:
+		if len(configs) > 0 {
+			return nil, errors.New("auth config paths cannot be used with the keyring credential store")
+		}
+		keyring, err := auth.NewKeyringStore("")
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewClientWithStore(keyring), nil
+	}
+	return nil, fmt.Errorf("unknown credential store %q, expected %s or %s", store, credentialStoreDocker, credentialStoreKeyring)
+}
+
+// newOCILayoutResolver creates a resolver against the OCI image layout at the
+// given path, which is created if not exists.
+func newOCILayoutResolver(path string) (remotes.Resolver, error) {
+	store, err := content.NewOCIStore(path)
+	if err != nil {
+		return nil, err
+	}
+	return store.Resolver(), nil
+}
+
+// parseOCILayoutRef splits the OCI image layout reference in the form of
+// `<path>:<tag>` or `<path>@<digest>` into the path and the reference in the
+// layout. The reference is empty if not specified.
+func parseOCILayoutRef(raw string) (string, string) {
+	if i := strings.LastIndex(raw, "@"); i >= 0 {
+		return raw[:i], raw[i:]
+	}
+	i := strings.LastIndex(raw, ":")
+	if i < 0 || strings.ContainsAny(raw[i+1:], `/\`) {
+		return raw, ""
+	}
+	if i == 1 && filepath.VolumeName(raw) != "" {
+		// windows drive letter
+		return raw, ""
 	}
-	return resolver
+	return raw[:i], raw[i+1:]
 }
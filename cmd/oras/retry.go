@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/spf13/pflag"
+)
+
+// Environment variables overriding the default retry settings.
+const (
+	envRetry      = "ORAS_RETRY"
+	envRetryDelay = "ORAS_RETRY_DELAY"
+)
+
+// retryOptions are the retry settings of registry requests. The flags take
+// precedence over the environment variables.
+type retryOptions struct {
+	retry      int
+	retryDelay time.Duration
+
+	flags *pflag.FlagSet
+}
+
+func (opts *retryOptions) applyFlags(fs *pflag.FlagSet) {
+	fs.IntVarP(&opts.retry, "retry", "", registry.DefaultMaxRetries, "maximum number of retries of requests failed transiently, 0 to disable (env "+envRetry+")")
+	fs.DurationVarP(&opts.retryDelay, "retry-delay", "", registry.DefaultRetryBackoff, "initial delay between retries, doubled on every retry with jitter (env "+envRetryDelay+")")
+	opts.flags = fs
+}
+
+// options returns the retry options of the flags and the environment
+// variables. Invalid environment variables are ignored with a warning.
+func (opts *retryOptions) options() registry.RetryOptions {
+	retry := registry.DefaultRetryOptions()
+	if opts.flags != nil {
+		retry.MaxRetries = opts.retry
+		retry.Backoff = opts.retryDelay
+	}
+	if value := os.Getenv(envRetry); value != "" && !opts.changed("retry") {
+		if n, err := strconv.Atoi(value); err == nil {
+			retry.MaxRetries = n
+		} else {
+			fmt.Fprintf(os.Stderr, "WARNING: Ignoring invalid %s: %v\n", envRetry, err)
+		}
+	}
+	if value := os.Getenv(envRetryDelay); value != "" && !opts.changed("retry-delay") {
+		if delay, err := time.ParseDuration(value); err == nil {
+			retry.Backoff = delay
+		} else {
+			fmt.Fprintf(os.Stderr, "WARNING: Ignoring invalid %s: %v\n", envRetryDelay, err)
+		}
+	}
+	if retry.MaxRetries < 0 {
+		retry.MaxRetries = 0
+	}
+	if retry.Backoff > retry.MaxBackoff {
+		retry.MaxBackoff = retry.Backoff
+	}
+	return retry
+}
+
+func (opts *retryOptions) changed(name string) bool {
+	return opts.flags != nil && opts.flags.Changed(name)
+}
+
+// budgetTransport sends the requests with the retry budget of the command. A
+// command is a single operation, so all its requests share the budget.
+type budgetTransport struct {
+	base   http.RoundTripper
+	budget *registry.RetryBudget
+}
+
+// newRetryTransport creates a transport retrying the transient failures of the
+// requests sent through base, drawing the retries from the budget.
+func newRetryTransport(base http.RoundTripper, opts registry.RetryOptions, budget *registry.RetryBudget) http.RoundTripper {
+	return &budgetTransport{
+		base:   registry.NewTransportWithRetry(base, opts),
+		budget: budget,
+	}
+}
+
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(registry.WithRetryBudget(req.Context(), t.budget)))
+}
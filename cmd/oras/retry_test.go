@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setenv sets the environment variable, or unsets it if empty, and returns a
+// function restoring it.
+func setenv(t *testing.T, key, value string) func() {
+	old, ok := os.LookupEnv(key)
+	if value == "" {
+		require.NoError(t, os.Unsetenv(key))
+	} else {
+		require.NoError(t, os.Setenv(key, value))
+	}
+	return func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestRetryOptions(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		args       []string
+		env        string
+		envDelay   string
+		retries    int
+		backoff    time.Duration
+		maxBackoff time.Duration
+	}{
+		{
+			name:       "defaults",
+			retries:    registry.DefaultMaxRetries,
+			backoff:    registry.DefaultRetryBackoff,
+			maxBackoff: registry.DefaultMaxRetryBackoff,
+		},
+		{
+			name:       "environment",
+			env:        "5",
+			envDelay:   "1s",
+			retries:    5,
+			backoff:    time.Second,
+			maxBackoff: registry.DefaultMaxRetryBackoff,
+		},
+		{
+			name:       "flags take precedence over the environment",
+			args:       []string{"--retry", "1", "--retry-delay", "2s"},
+			env:        "5",
+			envDelay:   "1s",
+			retries:    1,
+			backoff:    2 * time.Second,
+			maxBackoff: registry.DefaultMaxRetryBackoff,
+		},
+		{
+			name:       "flags and environment mixed",
+			args:       []string{"--retry", "0"},
+			envDelay:   "1s",
+			retries:    0,
+			backoff:    time.Second,
+			maxBackoff: registry.DefaultMaxRetryBackoff,
+		},
+		{
+			name:       "invalid environment ignored",
+			env:        "many",
+			envDelay:   "soon",
+			retries:    registry.DefaultMaxRetries,
+			backoff:    registry.DefaultRetryBackoff,
+			maxBackoff: registry.DefaultMaxRetryBackoff,
+		},
+		{
+			name:       "negative retries disabled",
+			env:        "-1",
+			retries:    0,
+			backoff:    registry.DefaultRetryBackoff,
+			maxBackoff: registry.DefaultMaxRetryBackoff,
+		},
+		{
+			name:       "max backoff raised to the delay",
+			args:       []string{"--retry-delay", "1m"},
+			retries:    registry.DefaultMaxRetries,
+			backoff:    time.Minute,
+			maxBackoff: time.Minute,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer setenv(t, envRetry, tc.env)()
+			defer setenv(t, envRetryDelay, tc.envDelay)()
+
+			var opts retryOptions
+			fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			opts.applyFlags(fs)
+			require.NoError(t, fs.Parse(tc.args))
+
+			retry := opts.options()
+			assert.Equal(t, tc.retries, retry.MaxRetries)
+			assert.Equal(t, tc.backoff, retry.Backoff)
+			assert.Equal(t, tc.maxBackoff, retry.MaxBackoff)
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deislabs/oras/pkg/artifact"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/spf13/cobra"
+)
+
+// schemaVersion is the version of the schemas, which is bumped on breaking
+// changes of the outputs.
+const schemaVersion = "v1"
+
+// outputSchema is a machine-readable output of oras, described by the JSON
+// schema generated from its Go type.
+type outputSchema struct {
+	name        string
+	description string
+	value       interface{}
+}
+
+var outputSchemas = []outputSchema{
+	{"artifact", "--format json of push, pull, attach, resolve, manifest push, manifest delete, manifest fetch-config and manifest index", artifactResult{}},
+	{"copy", "--format json of cp", copyResult{}},
+	{"tag", "--format json of tag", tagResult{}},
+	{"manifest", "--format json of manifest fetch", manifestResult{}},
+	{"discover", "--format json of discover", referrerNode{}},
+	{"history", "--format json of history", artifact.TagHistory{}},
+	{"inspect", "--format json of inspect", inspectResult{}},
+	{"repo-list", "--format json of repo ls", repoListResult{}},
+	{"tag-list", "--format json of repo tags", tagListResult{}},
+	{"prune", "--format json of repo prune", pruneResult{}},
+	{"layer-verify", "--format json of layer verify", layerVerifyResult{}},
+	{"remap-report", "digest remapping report of cp --remap-report", remapReport{}},
+	{"annotation-file", "--annotation-file of push and attach", map[string]map[string]string{}},
+	{"conflict-decisions", "conflict decisions file of pull --conflict-decisions", []conflictDecision{}},
+	{"raw-record", "response records of --raw-output", rawRecord{}},
+}
+
+type schemaOptions struct {
+	name   string
+	output string
+}
+
+func schemaCmd() *cobra.Command {
+	var opts schemaOptions
+	cmd := &cobra.Command{
+		Use:   "schema [name]",
+		Short: "Print the JSON schemas of the machine-readable outputs",
+		Long: `Print the JSON schemas of the machine-readable outputs
+
+The schemas describe the --format json outputs, the reports written by oras
+and the files read by oras, for validating them and generating code. Without a name, the schemas
+are listed.
+
+Example - List the schemas:
+  oras schema
+
+Example - Print the schema of the output of push and pull:
+  oras schema artifact
+
+Example - Save all the schemas to the directory "schemas":
+  oras schema -o schemas
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				opts.name = args[0]
+			}
+			return runSchema(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "directory to save the schemas to as <name>.json")
+	return cmd
+}
+
+func runSchema(opts schemaOptions) error {
+	var schemas []outputSchema
+	for _, schema := range outputSchemas {
+		if opts.name == "" || schema.name == opts.name {
+			schemas = append(schemas, schema)
+		}
+	}
+	if len(schemas) == 0 {
+		return fmt.Errorf("unknown schema %q, run \"oras schema\" to list the schemas", opts.name)
+	}
+
+	if opts.output != "" {
+		if err := os.MkdirAll(opts.output, 0755); err != nil {
+			return err
+		}
+		for _, schema := range schemas {
+			content, err := schema.marshal()
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(opts.output, schema.name+".json")
+			if err := ioutil.WriteFile(path, content, 0644); err != nil {
+				return err
+			}
+			fmt.Println("Saved", path)
+		}
+		return nil
+	}
+	if opts.name == "" {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tDESCRIPTION")
+		for _, schema := range schemas {
+			fmt.Fprintf(tw, "%s\t%s\n", schema.name, schema.description)
+		}
+		return tw.Flush()
+	}
+	content, err := schemas[0].marshal()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(content)
+	return err
+}
+
+// marshal returns the indented JSON schema.
+func (s outputSchema) marshal() ([]byte, error) {
+	g := schemaGenerator{
+		defs:    make(map[string]interface{}),
+		visited: make(map[reflect.Type]bool),
+	}
+	schema := g.schema(reflect.TypeOf(s.value))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = "urn:oras:schema:" + schemaVersion + ":" + s.name
+	schema["title"] = s.name
+	schema["description"] = "The " + s.description + "."
+	if len(g.defs) > 0 {
+		schema["$defs"] = g.defs
+	}
+	content, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	digestType     = reflect.TypeOf(digest.Digest(""))
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaGenerator generates JSON schemas from Go types as encoded by
+// encoding/json. Recursive types are defined in $defs.
+type schemaGenerator struct {
+	defs    map[string]interface{}
+	visited map[reflect.Type]bool
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case digestType:
+		return map[string]interface{}{"type": "string", "pattern": `^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`}
+	case rawMessageType:
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+	return map[string]interface{}{}
+}
+
+// structSchema returns the schema of the struct, or a reference to its
+// definition if the struct is recursive.
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	ref := map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	if g.visited[t] {
+		g.defs[t.Name()] = nil
+		return ref
+	}
+	g.visited[t] = true
+	properties := make(map[string]interface{})
+	var required []string
+	g.addFields(t, properties, &required)
+	delete(g.visited, t)
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	if _, ok := g.defs[t.Name()]; ok {
+		g.defs[t.Name()] = schema
+		return ref
+	}
+	return schema
+}
+
+// addFields adds the fields of the struct, promoting the fields of the
+// embedded structs unless shadowed.
+func (g *schemaGenerator) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, opts = tag[:i], tag[i:]
+		}
+		if field.Anonymous && name == "" {
+			if ft := field.Type; ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := properties[name]; ok {
+			continue
+		}
+		properties[name] = g.schema(field.Type)
+		if !strings.Contains(opts, ",omitempty") {
+			*required = append(*required, name)
+		}
+	}
+	for _, ft := range embedded {
+		g.addFields(ft, properties, required)
+	}
+}
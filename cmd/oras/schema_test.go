@@ -0,0 +1,153 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputSchemas(t *testing.T) {
+	names := make(map[string]bool)
+	for _, schema := range outputSchemas {
+		assert.False(t, names[schema.name], "unique name %s", schema.name)
+		names[schema.name] = true
+		_, err := schema.marshal()
+		assert.NoError(t, err, "schema %s", schema.name)
+	}
+}
+
+// TestOutputSchemasRegistered checks that the type of every value written by
+// formatOptions.write has a schema.
+func TestOutputSchemasRegistered(t *testing.T) {
+	registered := make(map[string]bool)
+	for _, schema := range outputSchemas {
+		typ := reflect.TypeOf(schema.value)
+		for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+		registered[typ.Name()] = true
+	}
+
+	fset := token.NewFileSet()
+	notTest := func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}
+	var files []*ast.File
+	for _, dir := range []string{".", "../../pkg/oras"} {
+		pkgs, err := parser.ParseDir(fset, dir, notTest, 0)
+		require.NoError(t, err)
+		for _, pkg := range pkgs {
+			for _, file := range pkg.Files {
+				files = append(files, file)
+			}
+		}
+	}
+	results := make(map[string]*ast.FieldList)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				results[fn.Name.Name] = fn.Type.Results
+			}
+		}
+	}
+
+	written := 0
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok || len(call.Args) != 2 {
+					return true
+				}
+				if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "write" || typeName(sel.X) != "format" {
+					return true
+				}
+				written++
+				name := valueTypeName(fn.Body, results, call.Args[1])
+				pos := fset.Position(call.Pos())
+				if assert.NotEmpty(t, name, "type of the value written at %s", pos) {
+					assert.True(t, registered[name], "schema of %s written at %s", name, pos)
+				}
+				return true
+			})
+		}
+	}
+	assert.NotZero(t, written, "values written")
+}
+
+// valueTypeName returns the name of the type of the expression, following the
+// assignments of the function body and the results of the called functions.
+func valueTypeName(body *ast.BlockStmt, results map[string]*ast.FieldList, expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.UnaryExpr:
+		return valueTypeName(body, results, e.X)
+	case *ast.CompositeLit:
+		return typeName(e.Type)
+	case *ast.CallExpr:
+		return resultTypeName(results, e, 0)
+	case *ast.Ident:
+		var name string
+		ast.Inspect(body, func(n ast.Node) bool {
+			assign, ok := n.(*ast.AssignStmt)
+			if !ok || name != "" {
+				return name == ""
+			}
+			for i, lhs := range assign.Lhs {
+				if ident, ok := lhs.(*ast.Ident); !ok || ident.Name != e.Name {
+					continue
+				}
+				if len(assign.Rhs) == len(assign.Lhs) {
+					name = valueTypeName(body, results, assign.Rhs[i])
+				} else if call, ok := assign.Rhs[0].(*ast.CallExpr); ok {
+					name = resultTypeName(results, call, i)
+				}
+			}
+			return name == ""
+		})
+		return name
+	}
+	return ""
+}
+
+// resultTypeName returns the name of the type of the i-th result of the call.
+func resultTypeName(results map[string]*ast.FieldList, call *ast.CallExpr, i int) string {
+	fields := results[typeName(call.Fun)]
+	if fields == nil {
+		return ""
+	}
+	for _, field := range fields.List {
+		count := len(field.Names)
+		if count == 0 {
+			count = 1
+		}
+		if i < count {
+			return typeName(field.Type)
+		}
+		i -= count
+	}
+	return ""
+}
+
+// typeName returns the unqualified name of the type or function expression.
+func typeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.StarExpr:
+		return typeName(e.X)
+	}
+	return ""
+}
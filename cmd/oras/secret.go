@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// secretOptions read the registry secrets from file descriptors inherited
+// from the parent process, so that they are not passed in argv, the
+// environment or files. The secrets are used for the command only.
+type secretOptions struct {
+	passwordFD      int
+	identityTokenFD int
+
+	flags *pflag.FlagSet
+}
+
+func (opts *secretOptions) applyFlags(fs *pflag.FlagSet) {
+	fs.IntVarP(&opts.passwordFD, "password-fd", "", -1, "read the registry password from the file descriptor, e.g. 3")
+	fs.IntVarP(&opts.identityTokenFD, "identity-token-fd", "", -1, "read the registry identity token (OAuth2 refresh token) from the file descriptor")
+	opts.flags = fs
+}
+
+// resolve sets the password to the secret read from the file descriptors, if
+// specified. An identity token is used without username.
+func (opts *secretOptions) resolve(username, password *string) error {
+	passwordFD, identityTokenFD := opts.changed("password-fd"), opts.changed("identity-token-fd")
+	switch {
+	case passwordFD && identityTokenFD:
+		return errors.New("--password-fd cannot be used with --identity-token-fd")
+	case (passwordFD || identityTokenFD) && *password != "":
+		return errors.New("--password cannot be used with --password-fd or --identity-token-fd")
+	case identityTokenFD && *username != "":
+		return errors.New("--identity-token-fd cannot be used with --username")
+	case passwordFD:
+		secret, err := readSecretFD(opts.passwordFD)
+		if err != nil {
+			return fmt.Errorf("failed to read password from file descriptor %d: %v", opts.passwordFD, err)
+		}
+		*password = secret
+	case identityTokenFD:
+		secret, err := readSecretFD(opts.identityTokenFD)
+		if err != nil {
+			return fmt.Errorf("failed to read identity token from file descriptor %d: %v", opts.identityTokenFD, err)
+		}
+		*password = secret
+	}
+	return nil
+}
+
+func (opts *secretOptions) changed(name string) bool {
+	return opts.flags != nil && opts.flags.Changed(name)
+}
+
+// readSecretFD reads the secret from the file descriptor up to EOF, and
+// closes it. The trailing newline is trimmed.
+func readSecretFD(fd int) (string, error) {
+	if fd < 0 {
+		return "", errors.New("invalid file descriptor")
+	}
+	file := os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd))
+	if file == nil {
+		return "", errors.New("invalid file descriptor")
+	}
+	defer file.Close()
+	secret, err := ioutil.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimRight(string(secret), "\r\n")
+	if value == "" {
+		return "", errors.New("empty secret")
+	}
+	return value, nil
+}
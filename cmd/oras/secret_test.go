@@ -0,0 +1,124 @@
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// secretFD returns a file descriptor to read the secret from, owned by the
+// reader.
+func secretFD(t *testing.T, secret string) string {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	_, err = w.WriteString(secret)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	fd, err := syscall.Dup(int(r.Fd()))
+	require.NoError(t, err)
+	return strconv.Itoa(fd)
+}
+
+func TestRemoteOptionsResolveSecret(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		args         func(t *testing.T) []string
+		wantUsername string
+		wantPassword string
+		wantErr      string
+	}{
+		{
+			name: "none",
+			args: func(t *testing.T) []string {
+				return []string{"-u", "admin", "-p", "secret"}
+			},
+			wantUsername: "admin",
+			wantPassword: "secret",
+		},
+		{
+			name: "password",
+			args: func(t *testing.T) []string {
+				return []string{"-u", "admin", "--password-fd", secretFD(t, "secret\n")}
+			},
+			wantUsername: "admin",
+			wantPassword: "secret",
+		},
+		{
+			name: "identity token",
+			args: func(t *testing.T) []string {
+				return []string{"--identity-token-fd", secretFD(t, "token\r\n")}
+			},
+			wantPassword: "token",
+		},
+		{
+			name: "password and identity token",
+			args: func(t *testing.T) []string {
+				return []string{"--password-fd", "3", "--identity-token-fd", "4"}
+			},
+			wantErr: "--password-fd cannot be used with --identity-token-fd",
+		},
+		{
+			name: "password with password flag",
+			args: func(t *testing.T) []string {
+				return []string{"-p", "secret", "--password-fd", "3"}
+			},
+			wantErr: "--password cannot be used with --password-fd or --identity-token-fd",
+		},
+		{
+			name: "identity token with username",
+			args: func(t *testing.T) []string {
+				return []string{"-u", "admin", "--identity-token-fd", "3"}
+			},
+			wantErr: "--identity-token-fd cannot be used with --username",
+		},
+		{
+			name: "empty",
+			args: func(t *testing.T) []string {
+				return []string{"--password-fd", secretFD(t, "\n")}
+			},
+			wantErr: "failed to read password from file descriptor",
+		},
+		{
+			name: "invalid",
+			args: func(t *testing.T) []string {
+				return []string{"--identity-token-fd", "-1"}
+			},
+			wantErr: "failed to read identity token from file descriptor -1: invalid file descriptor",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			var opts remoteOptions
+			opts.applyFlags(fs)
+			require.NoError(t, fs.Parse(tc.args(t)))
+			err := opts.resolveSecret()
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantUsername, opts.username)
+			assert.Equal(t, tc.wantPassword, opts.password)
+		})
+	}
+}
+
+func TestReadSecretFD(t *testing.T) {
+	fd, err := strconv.Atoi(secretFD(t, "secret\n"))
+	require.NoError(t, err)
+	secret, err := readSecretFD(fd)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", secret)
+
+	_, err = readSecretFD(-1)
+	assert.Error(t, err, "invalid file descriptor")
+}
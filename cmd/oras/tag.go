@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/deislabs/oras/pkg/artifact"
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/reference"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// tagPattern is the format of tags in the OCI distribution specification.
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]{0,127}$`)
+
+type tagOptions struct {
+	sourceRef  string
+	tags       []string
+	idempotent bool
+	format     formatOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func tagCmd() *cobra.Command {
+	var opts tagOptions
+	cmd := &cobra.Command{
+		Use:   "tag <name:tag|name@digest> <new-tag> [new-tag...]",
+		Short: "Tag a manifest in a remote registry",
+		Long: `Tag a manifest in a remote registry
+
+The manifest is pushed under the new tags in the same repository. Only the
+manifest is transferred, the blobs are left untouched.
+
+Example - Tag a manifest by digest:
+  oras tag localhost:5000/hello@sha256:9463e0d192846bc994279417b50114606712d516aab45f4d8b31cbc6e46aad71 v1.2.3
+
+Example - Promote a tag to multiple tags:
+  oras tag localhost:5000/hello:rc v1.2.3 v1.2 latest
+`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.sourceRef = args[0]
+			opts.tags = args[1:]
+			return runTag(opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.idempotent, "idempotent", "", false, "skip the tags already referring to the manifest, and report whether anything changed")
+	opts.format.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runTag(opts tagOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.sourceRef = opts.remote.reference(opts.sourceRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if err := opts.format.validate(); err != nil {
+		return err
+	}
+	if err := checkWritable(opts.sourceRef); err != nil {
+		return err
+	}
+	for _, tag := range opts.tags {
+		if !tagPattern.MatchString(tag) {
+			return fmt.Errorf("invalid tag %q", tag)
+		}
+	}
+	refspec, err := reference.Parse(opts.sourceRef)
+	if err != nil {
+		return err
+	}
+
+	hosts := opts.remote.registryHosts()
+	client := registry.NewClient(hosts)
+	mediaTypes := append(defaultManifestMediaTypes[:len(defaultManifestMediaTypes):len(defaultManifestMediaTypes)], artifact.ArtifactManifestMediaType)
+	resolver := newManifestResolver(hosts, mediaTypes)
+	_, desc, err := resolver.Resolve(ctx, opts.sourceRef)
+	if err != nil {
+		return err
+	}
+	manifest, err := client.FetchManifest(ctx, opts.sourceRef, desc)
+	if err != nil {
+		return err
+	}
+
+	var (
+		changed *bool
+		tagged  []string
+	)
+	if opts.idempotent {
+		changed = new(bool)
+	}
+	for _, tag := range opts.tags {
+		ref := refspec.Locator + ":" + tag
+		if opts.idempotent {
+			_, existing, err := resolver.Resolve(ctx, ref)
+			if err == nil && existing.Digest == desc.Digest {
+				continue
+			}
+			if err != nil && !errdefs.IsNotFound(err) {
+				return err
+			}
+			*changed = true
+		}
+		if err := client.PushManifest(ctx, ref, desc, manifest); err != nil {
+			return err
+		}
+		tagged = append(tagged, tag)
+		if !opts.format.enabled() {
+			fmt.Println("Tagged", ref)
+		}
+	}
+
+	if opts.format.enabled() {
+		if tagged == nil {
+			tagged = []string{}
+		}
+		return opts.format.write("", tagResult{
+			Reference:  opts.sourceRef,
+			Descriptor: desc,
+			Tags:       tagged,
+			Changed:    changed,
+		})
+	}
+	if changed != nil && !*changed {
+		fmt.Println("No changes to", opts.sourceRef)
+	}
+	fmt.Println("Digest:", desc.Digest)
+	return nil
+}
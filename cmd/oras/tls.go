@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/deislabs/oras/internal/config"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/spf13/pflag"
+)
+
+// tlsOptions are the TLS settings of registry connections. The settings
+// specified take precedence over the per-registry settings in the oras config.
+type tlsOptions struct {
+	certFile string
+	keyFile  string
+	caFile   string
+}
+
+func (opts *tlsOptions) applyFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&opts.certFile, "cert-file", "", "", "client certificate file for mutual TLS with the registries")
+	fs.StringVarP(&opts.keyFile, "key-file", "", "", "client key file for mutual TLS with the registries")
+	fs.StringVarP(&opts.caFile, "ca-file", "", "", "CA certificate bundle trusted in addition to the system ones")
+}
+
+// registryTransport dispatches the requests to the transports configured with
+// the TLS settings of the requested hosts. The client certificate of the
+// options is presented to the registries only, not to other hosts such as the
+// token servers.
+type registryTransport struct {
+	insecure  bool
+	plainHTTP bool
+	tls       tlsOptions
+	config    *config.Config
+
+	lock       sync.Mutex
+	transports map[string]http.RoundTripper
+	registries map[string]bool
+}
+
+func newRegistryTransport(insecure, plainHTTP bool, tlsOpts tlsOptions) *registryTransport {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Error loading oras config: %v\n", err)
+		cfg = &config.Config{}
+	}
+	return &registryTransport{
+		insecure:   insecure,
+		plainHTTP:  plainHTTP,
+		tls:        tlsOpts,
+		config:     cfg,
+		transports: make(map[string]http.RoundTripper),
+		registries: make(map[string]bool),
+	}
+}
+
+// addRegistry marks the hosts as registries, which are presented the client
+// certificate of the options.
+func (t *registryTransport) addRegistry(hosts ...string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, host := range hosts {
+		t.registries[host] = true
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *registryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if offline {
+		return nil, fmt.Errorf("%s %s: %v", req.Method, req.URL, errOffline)
+	}
+	transport, err := t.transport(req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	return transport.RoundTrip(req)
+}
+
+// isPlainHTTP reports whether the host is accessed with plain http.
+func (t *registryTransport) isPlainHTTP(host string) bool {
+	return t.plainHTTP || t.config.Registry(host).PlainHTTP
+}
+
+// isCustomized reports whether the TLS settings of the host are specified by
+// the options or the oras config.
+func (t *registryTransport) isCustomized(host string) bool {
+	settings := t.config.Registry(host)
+	return t.tls != tlsOptions{} || settings.CertFile != "" || settings.KeyFile != "" || settings.CAFile != ""
+}
+
+// redirectPolicy returns the redirect policy of the oras config.
+func (t *registryTransport) redirectPolicy() registry.RedirectPolicy {
+	return registry.RedirectPolicy{
+		MaxRedirects:   t.config.Redirects.MaxRedirects,
+		AllowDowngrade: t.config.Redirects.AllowDowngrade,
+		AllowedHosts: func(host string) []string {
+			return t.config.Registry(host).AllowedRedirectHosts
+		},
+	}
+}
+
+// transport returns the transport of the host, which is created on first use.
+func (t *registryTransport) transport(host string) (http.RoundTripper, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if transport, ok := t.transports[host]; ok {
+		return transport, nil
+	}
+
+	settings := t.config.Registry(host)
+	if t.registries[host] && (t.tls.certFile != "" || t.tls.keyFile != "") {
+		settings.CertFile = t.tls.certFile
+		settings.KeyFile = t.tls.keyFile
+	}
+	if t.tls.caFile != "" {
+		settings.CAFile = t.tls.caFile
+	}
+	settings.Insecure = settings.Insecure || t.insecure
+
+	var transport http.RoundTripper = http.DefaultTransport
+	if settings.Insecure || settings.CertFile != "" || settings.KeyFile != "" || settings.CAFile != "" {
+		tlsConfig, err := newTLSConfig(settings)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", host, err)
+		}
+		custom := http.DefaultTransport.(*http.Transport).Clone()
+		custom.TLSClientConfig = tlsConfig
+		transport = custom
+	}
+	t.transports[host] = transport
+	return transport, nil
+}
+
+// newTLSConfig creates the TLS config with the client certificate and the CA
+// certificates of the registry.
+func newTLSConfig(settings config.RegistryConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: settings.Insecure,
+	}
+	if settings.CertFile != "" || settings.KeyFile != "" {
+		if settings.CertFile == "" || settings.KeyFile == "" {
+			return nil, errors.New("both the client certificate and key files are required")
+		}
+		cert, err := tls.LoadX509KeyPair(settings.CertFile, settings.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if settings.CAFile != "" {
+		pem, err := ioutil.ReadFile(settings.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no CA certificates found in %s", settings.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/deislabs/oras/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCertificate writes a self-signed certificate and its key to the
+// directory.
+func writeTestCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "oras"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func newTestRegistryTransport(opts tlsOptions) *registryTransport {
+	return &registryTransport{
+		tls:        opts,
+		config:     &config.Config{},
+		transports: make(map[string]http.RoundTripper),
+		registries: make(map[string]bool),
+	}
+}
+
+func TestRegistryTransportClientCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oras_tls_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCertificate(t, dir)
+
+	transport := newTestRegistryTransport(tlsOptions{
+		certFile: certFile,
+		keyFile:  keyFile,
+	})
+	transport.addRegistry("registry.example.com")
+	assert.True(t, transport.isCustomized("registry.example.com"))
+
+	// the client certificate is presented to the registry
+	rt, err := transport.transport("registry.example.com")
+	require.NoError(t, err)
+	require.IsType(t, &http.Transport{}, rt)
+	assert.Len(t, rt.(*http.Transport).TLSClientConfig.Certificates, 1)
+
+	// but not to the token server
+	rt, err = transport.transport("auth.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, http.DefaultTransport, rt)
+}
+
+func TestRegistryTransportInvalidClientCertificate(t *testing.T) {
+	transport := newTestRegistryTransport(tlsOptions{
+		certFile: "cert.pem",
+	})
+	transport.addRegistry("registry.example.com")
+	_, err := transport.transport("registry.example.com")
+	assert.Error(t, err, "key file required")
+	_, err = transport.transport("auth.example.com")
+	assert.NoError(t, err, "client certificate not used for other hosts")
+}
+
+func TestRegistryTransportCAFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oras_tls_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, _ := writeTestCertificate(t, dir)
+
+	// the CA certificates are trusted for all the hosts
+	transport := newTestRegistryTransport(tlsOptions{
+		caFile: certFile,
+	})
+	for _, host := range []string{"registry.example.com", "auth.example.com"} {
+		rt, err := transport.transport(host)
+		require.NoError(t, err)
+		require.IsType(t, &http.Transport{}, rt)
+		assert.NotNil(t, rt.(*http.Transport).TLSClientConfig.RootCAs, host)
+	}
+
+	_, err = newTestRegistryTransport(tlsOptions{
+		caFile: filepath.Join(dir, "missing.pem"),
+	}).transport("registry.example.com")
+	assert.Error(t, err, "missing CA file")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/deislabs/oras/pkg/artifact"
+
+	"github.com/spf13/pflag"
+)
+
+// deprecationOptions decide whether tags replaced by a tombstone, as pushed by
+// `oras manifest delete --tombstone`, are warned about or rejected.
+type deprecationOptions struct {
+	noDeprecated bool
+}
+
+func (opts *deprecationOptions) applyFlags(fs *pflag.FlagSet) {
+	fs.BoolVarP(&opts.noDeprecated, "no-deprecated", "", false, "fail instead of warning if the tag is deprecated by a tombstone")
+}
+
+// check warns on stderr, or fails with --no-deprecated, if the annotations of
+// the manifest of ref are the ones of a tombstone.
+func (opts *deprecationOptions) check(ref string, annotations map[string]string) error {
+	tombstone, ok, err := artifact.ParseTombstone(annotations)
+	if err != nil || !ok {
+		return err
+	}
+	message := fmt.Sprintf("%s is deprecated since %s", ref, tombstone.Time.Format(time.RFC3339))
+	if tombstone.Replacement != "" {
+		message += ", use " + tombstone.Replacement + " instead"
+	}
+	if opts.noDeprecated {
+		return errors.New(message)
+	}
+	fmt.Fprintln(os.Stderr, "WARNING:", message)
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deislabs/oras/pkg/artifact"
+
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newArtifactRegistry serves a registry with the artifact hello:v1 of a single
+// file hello.txt, and returns the registry and the repository.
+func newArtifactRegistry(t *testing.T) (*testRegistry, string, func()) {
+	registry := newTestRegistry()
+	server := httptest.NewServer(registry)
+	config := registry.putBlob([]byte("{}"))
+	config.MediaType = ocispec.MediaTypeImageConfig
+	layer := registry.putBlob([]byte("hello world"))
+	layer.Annotations = map[string]string{ocispec.AnnotationTitle: "hello.txt"}
+	registry.put("hello", "v1", ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Config:    config,
+		Layers:    []ocispec.Descriptor{layer},
+	})
+	return registry, strings.TrimPrefix(server.URL, "http://") + "/hello", server.Close
+}
+
+// putTombstone replaces the manifest of hello:v1 with a tombstone.
+func putTombstone(registry *testRegistry, tombstone artifact.Tombstone) ocispec.Descriptor {
+	config := registry.putBlob([]byte("{}"))
+	config.MediaType = artifact.TombstoneConfigMediaType
+	return registry.put("hello", "v1", ocispec.Manifest{
+		Versioned:   specs.Versioned{SchemaVersion: 2},
+		Config:      config,
+		Layers:      []ocispec.Descriptor{},
+		Annotations: tombstone.Annotations(),
+	})
+}
+
+func TestManifestDelete(t *testing.T) {
+	defer isolateConfig(t)()
+
+	t.Run("verify", func(t *testing.T) {
+		registry, repository, closer := newArtifactRegistry(t)
+		defer closer()
+		registry.lag = 2
+
+		output, err := runCmd(t, manifestDeleteCmd(), "-f", "--verify", "--retry", "3", "--retry-delay", "1ms", repository+":v1")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Deleted "+repository+":v1")
+		assert.Empty(t, registry.tags("hello"))
+	})
+
+	t.Run("verify after all retries", func(t *testing.T) {
+		registry, repository, closer := newArtifactRegistry(t)
+		defer closer()
+		registry.lag = 5
+
+		_, err := runCmd(t, manifestDeleteCmd(), "-f", "--verify", "--retry", "1", "--retry-delay", "1ms", repository+":v1")
+		assert.Contains(t, errString(err), "failed to verify the deletion")
+	})
+
+	t.Run("deletes disallowed", func(t *testing.T) {
+		registry, repository, closer := newArtifactRegistry(t)
+		defer closer()
+		registry.disallowDelete = true
+		before, _, _ := registry.resolve("hello", "v1")
+
+		_, err := runCmd(t, manifestDeleteCmd(), "-f", repository+":v1")
+		assert.Contains(t, errString(err), "use --tombstone")
+		after, _, ok := registry.resolve("hello", "v1")
+		assert.True(t, ok)
+		assert.Equal(t, before.Digest, after.Digest, "tag unchanged")
+	})
+
+	t.Run("tombstone", func(t *testing.T) {
+		registry, repository, closer := newArtifactRegistry(t)
+		defer closer()
+		registry.disallowDelete = true
+		registry.lag = 1
+		deleted, _, _ := registry.resolve("hello", "v1")
+
+		output, err := runCmd(t, manifestDeleteCmd(), "-f", "--tombstone", "--replacement", repository+":v2",
+			"--verify", "--retry", "3", "--retry-delay", "1ms", "--format", "json", repository+":v1")
+		require.NoError(t, err)
+		var result artifactResult
+		require.NoError(t, json.Unmarshal([]byte(output), &result))
+		assert.Equal(t, repository+":v1", result.Reference)
+
+		desc, content, ok := registry.resolve("hello", "v1")
+		require.True(t, ok)
+		assert.Equal(t, desc.Digest, result.Digest, "tag references the tombstone")
+		var manifest ocispec.Manifest
+		require.NoError(t, json.Unmarshal(content, &manifest))
+		assert.Equal(t, artifact.TombstoneConfigMediaType, manifest.Config.MediaType)
+		tombstone, ok, err := artifact.ParseTombstone(manifest.Annotations)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, deleted.Digest, tombstone.Digest)
+		assert.Equal(t, repository+":v2", tombstone.Replacement)
+		assert.Equal(t, manifest.Annotations, result.Annotations)
+	})
+
+	t.Run("tombstone after all retries", func(t *testing.T) {
+		registry, repository, closer := newArtifactRegistry(t)
+		defer closer()
+		registry.disallowDelete = true
+		registry.lag = 5
+
+		_, err := runCmd(t, manifestDeleteCmd(), "-f", "--tombstone", "--verify", "--retry", "1", "--retry-delay", "1ms", repository+":v1")
+		assert.Contains(t, errString(err), "failed to verify the tombstone")
+	})
+
+	t.Run("tombstone of a digest", func(t *testing.T) {
+		registry, repository, closer := newArtifactRegistry(t)
+		defer closer()
+		desc, _, _ := registry.resolve("hello", "v1")
+
+		_, err := runCmd(t, manifestDeleteCmd(), "-f", "--tombstone", repository+"@"+desc.Digest.String())
+		assert.Contains(t, errString(err), "--tombstone requires a reference by tag")
+	})
+}
+
+func TestPullDeprecated(t *testing.T) {
+	defer isolateConfig(t)()
+	registry, repository, closer := newArtifactRegistry(t)
+	defer closer()
+	dir, err := ioutil.TempDir("", "oras_pull_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "v1")
+	registry.requests = 0
+	_, err = runCmd(t, pullCmd(), "--no-deprecated", "-o", output, repository+":v1")
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(output, "hello.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+	assert.Equal(t, 1, registry.requests, "manifest fetched once")
+
+	putTombstone(registry, artifact.Tombstone{
+		Time:        time.Now(),
+		Replacement: repository + ":v2",
+	})
+	output = filepath.Join(dir, "tombstone")
+	_, err = runCmd(t, pullCmd(), "--no-deprecated", "-o", output, repository+":v1")
+	assert.Contains(t, errString(err), repository+":v1 is deprecated")
+	assert.Contains(t, errString(err), "use "+repository+":v2 instead")
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err), "nothing written")
+
+	_, err = runCmd(t, pullCmd(), "-o", output, repository+":v1")
+	assert.NoError(t, err, "warning only")
+}
+
+func TestResolveDeprecated(t *testing.T) {
+	defer isolateConfig(t)()
+	registry, repository, closer := newArtifactRegistry(t)
+	defer closer()
+
+	desc, _, _ := registry.resolve("hello", "v1")
+	registry.requests = 0
+	output, err := runCmd(t, resolveCmd(), "--no-deprecated", repository+":v1")
+	require.NoError(t, err)
+	assert.Contains(t, output, desc.Digest.String())
+	assert.Equal(t, 1, registry.requests, "manifest fetched once")
+
+	tombstone := putTombstone(registry, artifact.Tombstone{Time: time.Now()})
+	_, err = runCmd(t, resolveCmd(), "--no-deprecated", repository+":v1")
+	assert.Contains(t, errString(err), repository+":v1 is deprecated")
+
+	output, err = runCmd(t, resolveCmd(), repository+":v1")
+	require.NoError(t, err, "warning only")
+	assert.Contains(t, output, tombstone.Digest.String())
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/deislabs/oras/internal/notation"
+	ctxo "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/registry"
+
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes/docker"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+type verifyOptions struct {
+	targetRef string
+	notation  bool
+	trust     trustOptions
+
+	debug  bool
+	remote remoteOptions
+}
+
+func verifyCmd() *cobra.Command {
+	var opts verifyOptions
+	cmd := &cobra.Command{
+		Use:   "verify <name:tag|name@digest>",
+		Short: "Verify the signatures of an artifact",
+		Long: `Verify the signatures of an artifact
+
+The notation (Notary v2) signatures attached to the artifact are verified
+against the trust policy and the trust stores of notation, which default to
+"trustpolicy.json" and "truststore" in the notation config directory. The
+verification succeeds if any of the signatures is valid.
+
+Only JWS signature envelopes are supported. Timestamps and revocation are not
+checked.
+
+Example - Verify the notation signatures of an artifact:
+  oras verify --notation localhost:5000/hello:latest
+
+Example - Verify with a specific trust policy and trust store:
+  oras verify --notation --trust-policy policy.json --trust-store ./truststore localhost:5000/hello:latest
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.targetRef = args[0]
+			return runVerify(opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.notation, "notation", "", false, "verify notation signatures")
+	opts.trust.applyFlags(cmd.Flags())
+
+	cmd.Flags().BoolVarP(&opts.debug, "debug", "d", false, "debug mode")
+	opts.remote.applyFlags(cmd.Flags())
+	return cmd
+}
+
+func runVerify(opts verifyOptions) error {
+	if err := opts.remote.resolveSecret(); err != nil {
+		return err
+	}
+	opts.targetRef = opts.remote.reference(opts.targetRef)
+	ctx := context.Background()
+	if opts.debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		ctx = ctxo.WithLoggerDiscarded(ctx)
+	}
+	if !opts.notation {
+		return errors.New("no signature format specified: use --notation")
+	}
+	verifier, err := opts.trust.verifier()
+	if err != nil {
+		return err
+	}
+
+	hosts := opts.remote.registryHosts()
+	_, desc, err := newManifestResolver(hosts, nil).Resolve(ctx, opts.targetRef)
+	if err != nil {
+		return err
+	}
+	refspec, err := reference.Parse(opts.targetRef)
+	if err != nil {
+		return err
+	}
+	verified, err := verifyNotationSignatures(ctx, hosts, verifier, refspec.Locator, desc)
+	if err != nil {
+		return err
+	}
+	printVerification(os.Stdout, verified, refspec.Locator+"@"+desc.Digest.String())
+	return nil
+}
+
+// trustOptions are the notation trust policy and trust stores verifying the
+// signatures.
+type trustOptions struct {
+	trustPolicy string
+	trustStore  string
+}
+
+func (o *trustOptions) applyFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.trustPolicy, "trust-policy", "", "", "notation trust policy file")
+	fs.StringVarP(&o.trustStore, "trust-store", "", "", "notation trust store directory")
+}
+
+// verifier loads the trust policy and the trust store, which default to the
+// ones in the notation config directory.
+func (o *trustOptions) verifier() (*notation.Verifier, error) {
+	trustPolicy, trustStore := o.trustPolicy, o.trustStore
+	if trustPolicy == "" || trustStore == "" {
+		dir, err := notation.DefaultDir()
+		if err != nil {
+			return nil, err
+		}
+		if trustPolicy == "" {
+			trustPolicy = filepath.Join(dir, "trustpolicy.json")
+		}
+		if trustStore == "" {
+			trustStore = filepath.Join(dir, "truststore")
+		}
+	}
+	policy, err := notation.LoadTrustPolicy(trustPolicy)
+	if err != nil {
+		return nil, err
+	}
+	return notation.NewVerifier(policy, trustStore), nil
+}
+
+// verification is the result of a successful signature verification.
+type verification struct {
+	// Signature is the valid signature, and empty if the verification is
+	// skipped by the trust policy.
+	Signature digest.Digest
+	Outcome   *notation.Outcome
+}
+
+// verifyNotationSignatures verifies the notation signatures of the manifest
+// in the repository. It succeeds if any of the signatures is valid, or if the
+// trust policy skips the verification.
+func verifyNotationSignatures(ctx context.Context, hosts docker.RegistryHosts, verifier *notation.Verifier, locator string, desc ocispec.Descriptor) (*verification, error) {
+	policy, err := verifier.Policy(locator)
+	if err != nil {
+		return nil, err
+	}
+	if policy.SignatureVerification.Level == notation.LevelSkip {
+		return &verification{
+			Outcome: &notation.Outcome{
+				Policy: policy.Name,
+				Level:  notation.LevelSkip,
+			},
+		}, nil
+	}
+
+	ref := locator + "@" + desc.Digest.String()
+	client := registry.NewClient(hosts)
+	signatures, err := client.ListReferrers(ctx, ref, desc, notation.ArtifactTypeSignature)
+	if err != nil {
+		return nil, err
+	}
+	if len(signatures) == 0 {
+		return nil, fmt.Errorf("no signatures found for %s", ref)
+	}
+	fetcher, err := newManifestResolver(hosts, nil).Fetcher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, signature := range signatures {
+		content, err := client.FetchManifest(ctx, ref, signature.Descriptor)
+		if err != nil {
+			return nil, err
+		}
+		var manifest struct {
+			Layers []ocispec.Descriptor `json:"layers"`
+			Blobs  []ocispec.Descriptor `json:"blobs"`
+		}
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, err
+		}
+		envelopes := append(manifest.Layers, manifest.Blobs...)
+		if len(envelopes) != 1 {
+			fmt.Fprintf(os.Stderr, "Signature %s: expected 1 signature envelope, got %d\n", signature.Digest, len(envelopes))
+			continue
+		}
+		envelope, err := fetchAll(ctx, fetcher, envelopes[0])
+		if err != nil {
+			return nil, err
+		}
+
+		outcome, err := verifier.Verify(locator, desc, envelopes[0].MediaType, envelope)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Signature %s: %v\n", signature.Digest, err)
+			continue
+		}
+		for _, warning := range outcome.Warnings {
+			fmt.Fprintf(os.Stderr, "WARNING: Signature %s: %s\n", signature.Digest, warning)
+		}
+		return &verification{
+			Signature: signature.Digest,
+			Outcome:   outcome,
+		}, nil
+	}
+	return nil, fmt.Errorf("no valid signatures for %s", ref)
+}
+
+// printVerification prints the result of the verification of the reference.
+func printVerification(out io.Writer, verified *verification, ref string) {
+	if verified.Signature == "" {
+		fmt.Fprintf(out, "Trust policy %q skips the verification of %s\n", verified.Outcome.Policy, ref)
+		return
+	}
+	fmt.Fprintf(out, "Successfully verified signature %s for %s\n", verified.Signature, ref)
+	fmt.Fprintln(out, "Trust policy:", verified.Outcome.Policy)
+	fmt.Fprintln(out, "Signer:", verified.Outcome.Signer)
+	fmt.Fprintln(out, "Signing time:", verified.Outcome.SigningTime)
+}
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EnvConfigPath is the environment variable overriding the config path.
+const EnvConfigPath = "ORAS_CONFIG"
+
+// EnvProfile is the environment variable selecting the profile.
+const EnvProfile = "ORAS_PROFILE"
+
+// Config is the configuration of the oras CLI.
+type Config struct {
+	// DefaultAnnotations are merged into the annotations of every pushed
+	// manifest. Environment variables in the values are expanded, e.g.
+	// "${BUILD_URL}".
+	DefaultAnnotations map[string]string `json:"defaultAnnotations,omitempty"`
+	// Registries are the connection settings keyed by the registry host, e.g.
+	// "registry.example.com:5000".
+	Registries map[string]RegistryConfig `json:"registries,omitempty"`
+	// HostsDir is the directory of the registry host configurations in the
+	// containerd layout, i.e. `<host>/hosts.toml` with `:port` written as
+	// `_port_` if needed, e.g. "/etc/containerd/certs.d". A registry with a
+	// hosts.toml is configured by it instead of Registries.
+	HostsDir string `json:"hostsDir,omitempty"`
+	// MediaTypeRules rewrite the media types of the blobs in the manifests
+	// copied by `oras cp`. The first rule matching a media type applies.
+	MediaTypeRules []MediaTypeRule `json:"mediaTypeRules,omitempty"`
+	// CredentialStore is where the registry credentials are stored, either
+	// "docker" for the docker config files, which is the default, or
+	// "keyring" for the native keyring of the OS.
+	CredentialStore string `json:"credentialStore,omitempty"`
+	// Redirects limits the redirects followed by the requests to the
+	// registries.
+	Redirects RedirectConfig `json:"redirects,omitempty"`
+	// WritableRegistries are the glob patterns of the registry hosts the
+	// commands modifying registries, such as push and delete, may target,
+	// e.g. "*.corp.example.com". All registries are writable if empty.
+	WritableRegistries []string `json:"writableRegistries,omitempty"`
+	// SharedStorage are the groups of the glob patterns of the registry hosts
+	// sharing their backing storage, e.g. the replicas of a Harbor
+	// deployment. The blobs copied between the registries of a group are
+	// mounted from the source repository if possible instead of uploaded.
+	SharedStorage [][]string `json:"sharedStorage,omitempty"`
+	// ContentPolicy are the rules the files pushed by `oras push` must
+	// follow, checked before any upload.
+	ContentPolicy ContentPolicyConfig `json:"contentPolicy,omitempty"`
+	// Profiles are the named groups of settings selected by --profile or
+	// ORAS_PROFILE, e.g. "staging" and "prod".
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+
+	// Profile is the selected profile, applied by WithProfile.
+	Profile Profile `json:"-"`
+}
+
+// Profile is a named group of settings, taking precedence over the ones of
+// the config.
+type Profile struct {
+	// CredentialsFile is the docker config file of the registry credentials,
+	// used unless the --config flag is given.
+	CredentialsFile string `json:"credentialsFile,omitempty"`
+	// DefaultRegistry is the registry host of the references without one,
+	// e.g. "staging.example.com" for "hello:v1".
+	DefaultRegistry string `json:"defaultRegistry,omitempty"`
+	// Concurrency is the number of blobs transferred in parallel, used unless
+	// the --concurrency flag is given.
+	Concurrency int `json:"concurrency,omitempty"`
+	// DefaultAnnotations are merged over the default annotations of the
+	// config.
+	DefaultAnnotations map[string]string `json:"defaultAnnotations,omitempty"`
+	// Registries replace the connection settings of the config for their
+	// hosts, e.g. the TLS certificates of the staging registry.
+	Registries map[string]RegistryConfig `json:"registries,omitempty"`
+}
+
+// ContentPolicyConfig are the rules on the pushed files. Unset rules do not
+// apply.
+type ContentPolicyConfig struct {
+	// MaxLayerSize is the maximum size of a layer, e.g. "500MB".
+	MaxLayerSize string `json:"maxLayerSize,omitempty"`
+	// MaxFileCount is the maximum number of files, including the files in
+	// directories.
+	MaxFileCount int `json:"maxFileCount,omitempty"`
+	// ForbiddenExtensions are the extensions no file may have, e.g. ".pem".
+	ForbiddenExtensions []string `json:"forbiddenExtensions,omitempty"`
+	// RequiredFiles are the glob patterns of the paths which must each match
+	// a file, e.g. "README*".
+	RequiredFiles []string `json:"requiredFiles,omitempty"`
+}
+
+// RedirectConfig limits the redirects followed by the requests to the
+// registries, e.g. to the storage backends serving the blobs.
+type RedirectConfig struct {
+	// MaxRedirects is the maximum number of redirects per request, which
+	// defaults to 10. Negative values disable redirects.
+	MaxRedirects int `json:"maxRedirects,omitempty"`
+	// AllowDowngrade allows redirects from https to http.
+	AllowDowngrade bool `json:"allowDowngrade,omitempty"`
+}
+
+// MediaTypeRule rewrites the media types matching the glob pattern From to
+// To, e.g. a deprecated custom media type to a standard OCI one.
+type MediaTypeRule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// RegistryConfig is the connection settings of a registry.
+type RegistryConfig struct {
+	// CertFile and KeyFile are the client certificate and key presented to
+	// registries requiring mutual TLS.
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// CAFile is the bundle of the CA certificates trusted in addition to the
+	// system ones.
+	CAFile string `json:"caFile,omitempty"`
+	// Insecure allows connections without verifying the registry certificate.
+	Insecure bool `json:"insecure,omitempty"`
+	// PlainHTTP uses plain http instead of https.
+	PlainHTTP bool `json:"plainHTTP,omitempty"`
+	// Mirrors are the endpoints tried in order before the registry for
+	// pulling and resolving, e.g. "https://mirror.example.com". Pushes always
+	// go to the registry. The connection settings of a mirror are the ones of
+	// its host.
+	Mirrors []string `json:"mirrors,omitempty"`
+	// Endpoint replaces the endpoint of the registry, e.g.
+	// "http://registry.internal:5000".
+	Endpoint string `json:"endpoint,omitempty"`
+	// AllowedRedirectHosts are the glob patterns of the hosts the requests to
+	// the registry may be redirected to, e.g. "*.blob.core.windows.net". All
+	// hosts are allowed if empty.
+	AllowedRedirectHosts []string `json:"allowedRedirectHosts,omitempty"`
+}
+
+// Path returns the path of the config file, which is specified by the
+// ORAS_CONFIG environment variable or defaults to `oras/config.json` in the
+// user config directory.
+func Path() (string, error) {
+	if path := os.Getenv(EnvConfigPath); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "oras", "config.json"), nil
+}
+
+// Load reads the config file from the given path.
+// An empty config is returned if the file does not exist.
+func Load(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Config{}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var cfg Config
+	if err := json.NewDecoder(file).Decode(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// LoadDefault reads the config file from the default path, with the profile
+// selected by the ORAS_PROFILE environment variable applied.
+func LoadDefault() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if name := os.Getenv(EnvProfile); name != "" {
+		return cfg.WithProfile(name)
+	}
+	return cfg, nil
+}
+
+// WithProfile returns a copy of the config with the settings of the named
+// profile applied.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, errors.Errorf("profile %q not found in the config", name)
+	}
+	cfg := *c
+	cfg.Profile = profile
+	if len(profile.DefaultAnnotations) > 0 {
+		cfg.DefaultAnnotations = make(map[string]string, len(c.DefaultAnnotations)+len(profile.DefaultAnnotations))
+		for k, v := range c.DefaultAnnotations {
+			cfg.DefaultAnnotations[k] = v
+		}
+		for k, v := range profile.DefaultAnnotations {
+			cfg.DefaultAnnotations[k] = v
+		}
+	}
+	if len(profile.Registries) > 0 {
+		cfg.Registries = make(map[string]RegistryConfig, len(c.Registries)+len(profile.Registries))
+		for host, registry := range c.Registries {
+			cfg.Registries[host] = registry
+		}
+		for host, registry := range profile.Registries {
+			cfg.Registries[host] = registry
+		}
+	}
+	return &cfg, nil
+}
+
+// ExpandedDefaultAnnotations returns the default annotations with the
+// environment variables expanded.
+func (c *Config) ExpandedDefaultAnnotations() map[string]string {
+	if len(c.DefaultAnnotations) == 0 {
+		return nil
+	}
+	annotations := make(map[string]string, len(c.DefaultAnnotations))
+	for k, v := range c.DefaultAnnotations {
+		annotations[k] = os.ExpandEnv(v)
+	}
+	return annotations
+}
+
+// Registry returns the connection settings of the registry host with the
+// environment variables in the file paths expanded.
+func (c *Config) Registry(host string) RegistryConfig {
+	registry := c.Registries[host]
+	registry.CertFile = os.ExpandEnv(registry.CertFile)
+	registry.KeyFile = os.ExpandEnv(registry.KeyFile)
+	registry.CAFile = os.ExpandEnv(registry.CAFile)
+	return registry
+}
+
+// IsWritable reports whether the registry host, with or without its port,
+// matches the WritableRegistries patterns, or whether there are no patterns.
+func (c *Config) IsWritable(host string) bool {
+	if len(c.WritableRegistries) == 0 {
+		return true
+	}
+	return matchHost(c.WritableRegistries, host)
+}
+
+// SharesStorage reports whether the registry hosts are in the same group of
+// SharedStorage.
+func (c *Config) SharesStorage(host, other string) bool {
+	for _, group := range c.SharedStorage {
+		if matchHost(group, host) && matchHost(group, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost reports whether the registry host, with or without its port,
+// matches any of the glob patterns.
+func matchHost(patterns []string, host string) bool {
+	hostname := host
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasSuffix(host, "]") {
+		hostname = host[:i]
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, hostname); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandedHostsDir returns the hosts directory with the environment variables
+// expanded.
+func (c *Config) ExpandedHostsDir() string {
+	return os.ExpandEnv(c.HostsDir)
+}
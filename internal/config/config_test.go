@@ -0,0 +1,127 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigSuite struct {
+	suite.Suite
+	TempTestDir string
+}
+
+func (suite *ConfigSuite) SetupSuite() {
+	tempDir, err := ioutil.TempDir("", "oras_config_test")
+	suite.Nil(err, "no error creating temp directory for test")
+	suite.TempTestDir = tempDir
+}
+
+func (suite *ConfigSuite) TearDownSuite() {
+	os.RemoveAll(suite.TempTestDir)
+}
+
+func (suite *ConfigSuite) TestLoad() {
+	// missing config
+	cfg, err := Load(filepath.Join(suite.TempTestDir, "missing.json"))
+	suite.Nil(err, "no error loading missing config")
+	suite.Nil(cfg.ExpandedDefaultAnnotations(), "no default annotations")
+
+	// default annotations
+	path := filepath.Join(suite.TempTestDir, "config.json")
+	err = ioutil.WriteFile(path, []byte(`{"defaultAnnotations":{"team":"storage","build":"${ORAS_TEST_BUILD_URL}"}}`), 0644)
+	suite.Nil(err, "no error writing config")
+	os.Setenv("ORAS_TEST_BUILD_URL", "https://ci.example.com/42")
+	defer os.Unsetenv("ORAS_TEST_BUILD_URL")
+	cfg, err = Load(path)
+	suite.Nil(err, "no error loading config")
+	suite.Equal(map[string]string{
+		"team":  "storage",
+		"build": "https://ci.example.com/42",
+	}, cfg.ExpandedDefaultAnnotations(), "default annotations expanded")
+
+	// invalid config
+	err = ioutil.WriteFile(path, []byte(`{`), 0644)
+	suite.Nil(err, "no error writing config")
+	_, err = Load(path)
+	suite.NotNil(err, "error loading invalid config")
+}
+
+func (suite *ConfigSuite) TestRegistry() {
+	path := filepath.Join(suite.TempTestDir, "registries.json")
+	err := ioutil.WriteFile(path, []byte(`{"registries":{"registry.example.com":{"certFile":"${ORAS_TEST_CERTS}/client.crt","caFile":"/etc/ca.pem","plainHTTP":true}}}`), 0644)
+	suite.Nil(err, "no error writing config")
+	os.Setenv("ORAS_TEST_CERTS", "/certs")
+	defer os.Unsetenv("ORAS_TEST_CERTS")
+	cfg, err := Load(path)
+	suite.Nil(err, "no error loading config")
+	suite.Equal(RegistryConfig{
+		CertFile:  "/certs/client.crt",
+		CAFile:    "/etc/ca.pem",
+		PlainHTTP: true,
+	}, cfg.Registry("registry.example.com"), "registry settings loaded")
+	suite.Equal(RegistryConfig{}, cfg.Registry("localhost:5000"), "no settings for unknown registry")
+}
+
+func (suite *ConfigSuite) TestIsWritable() {
+	cfg := &Config{}
+	suite.True(cfg.IsWritable("docker.io"), "all registries writable by default")
+
+	cfg.WritableRegistries = []string{"*.corp.example.com", "localhost"}
+	suite.True(cfg.IsWritable("registry.corp.example.com"), "pattern matched")
+	suite.True(cfg.IsWritable("registry.corp.example.com:5000"), "pattern matched without port")
+	suite.True(cfg.IsWritable("localhost:5000"), "host matched without port")
+	suite.False(cfg.IsWritable("corp.example.com"), "parent domain not matched")
+	suite.False(cfg.IsWritable("docker.io"), "other registry not writable")
+	suite.False(cfg.IsWritable("registry.corp.example.com.evil.io"), "suffix not matched")
+}
+
+func (suite *ConfigSuite) TestSharesStorage() {
+	cfg := &Config{
+		SharedStorage: [][]string{
+			{"harbor-a.example.com", "harbor-b.example.com"},
+			{"*.mirror.example.com"},
+		},
+	}
+	suite.True(cfg.SharesStorage("harbor-a.example.com", "harbor-b.example.com:443"), "hosts of a group")
+	suite.True(cfg.SharesStorage("eu.mirror.example.com", "us.mirror.example.com"), "hosts matching a pattern")
+	suite.False(cfg.SharesStorage("harbor-a.example.com", "eu.mirror.example.com"), "hosts of different groups")
+	suite.False(cfg.SharesStorage("harbor-a.example.com", "docker.io"), "host out of the groups")
+}
+
+func (suite *ConfigSuite) TestWithProfile() {
+	cfg := &Config{
+		DefaultAnnotations: map[string]string{"team": "storage", "env": "dev"},
+		Registries: map[string]RegistryConfig{
+			"registry.example.com": {CAFile: "/etc/ca.pem"},
+		},
+		Profiles: map[string]Profile{
+			"staging": {
+				DefaultRegistry:    "staging.example.com",
+				Concurrency:        8,
+				DefaultAnnotations: map[string]string{"env": "staging"},
+				Registries: map[string]RegistryConfig{
+					"staging.example.com": {Insecure: true},
+				},
+			},
+		},
+	}
+	_, err := cfg.WithProfile("prod")
+	suite.NotNil(err, "error selecting unknown profile")
+
+	staging, err := cfg.WithProfile("staging")
+	suite.Nil(err, "no error selecting profile")
+	suite.Equal("staging.example.com", staging.Profile.DefaultRegistry)
+	suite.Equal(8, staging.Profile.Concurrency)
+	suite.Equal(map[string]string{"team": "storage", "env": "staging"}, staging.ExpandedDefaultAnnotations(), "profile annotations merged")
+	suite.True(staging.Registry("staging.example.com").Insecure, "profile registry settings applied")
+	suite.Equal("/etc/ca.pem", staging.Registry("registry.example.com").CAFile, "config registry settings kept")
+	suite.Equal("dev", cfg.DefaultAnnotations["env"], "config not modified")
+}
+
+func TestConfigSuite(t *testing.T) {
+	suite.Run(t, new(ConfigSuite))
+}
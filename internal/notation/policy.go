@@ -0,0 +1,168 @@
+package notation
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Verification levels of trust policies.
+const (
+	LevelStrict     = "strict"
+	LevelPermissive = "permissive"
+	LevelAudit      = "audit"
+	LevelSkip       = "skip"
+)
+
+// TrustPolicyDocument is the trust policy file of notation, which is usually
+// `trustpolicy.json` in the notation config directory.
+type TrustPolicyDocument struct {
+	Version       string        `json:"version"`
+	TrustPolicies []TrustPolicy `json:"trustPolicies"`
+}
+
+// TrustPolicy specifies how the signatures of the artifacts in the registry
+// scopes are verified.
+type TrustPolicy struct {
+	Name                  string                `json:"name"`
+	RegistryScopes        []string              `json:"registryScopes"`
+	SignatureVerification SignatureVerification `json:"signatureVerification"`
+	TrustStores           []string              `json:"trustStores,omitempty"`
+	TrustedIdentities     []string              `json:"trustedIdentities,omitempty"`
+}
+
+// SignatureVerification is the verification level of a trust policy.
+type SignatureVerification struct {
+	Level string `json:"level"`
+}
+
+// LoadTrustPolicy reads and validates the trust policy file.
+func LoadTrustPolicy(path string) (*TrustPolicyDocument, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var doc TrustPolicyDocument
+	if err := json.NewDecoder(file).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := doc.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &doc, nil
+}
+
+// validate checks the fields of the trust policies.
+func (d *TrustPolicyDocument) validate() error {
+	if d.Version != "1.0" {
+		return fmt.Errorf("unsupported trust policy version %q", d.Version)
+	}
+	names := make(map[string]bool)
+	for _, policy := range d.TrustPolicies {
+		if policy.Name == "" {
+			return fmt.Errorf("trust policy without name")
+		}
+		if names[policy.Name] {
+			return fmt.Errorf("duplicate trust policy %q", policy.Name)
+		}
+		names[policy.Name] = true
+		if len(policy.RegistryScopes) == 0 {
+			return fmt.Errorf("trust policy %q: no registry scopes", policy.Name)
+		}
+		switch policy.SignatureVerification.Level {
+		case LevelStrict, LevelPermissive, LevelAudit:
+			if len(policy.TrustStores) == 0 || len(policy.TrustedIdentities) == 0 {
+				return fmt.Errorf("trust policy %q: trust stores and trusted identities are required", policy.Name)
+			}
+		case LevelSkip:
+		default:
+			return fmt.Errorf("trust policy %q: unknown verification level %q", policy.Name, policy.SignatureVerification.Level)
+		}
+	}
+	return nil
+}
+
+// Match returns the trust policy of the repository in the form of
+// `registry/repository`. A policy listing the repository takes precedence
+// over the one with the `*` wildcard scope.
+func (d *TrustPolicyDocument) Match(repository string) (*TrustPolicy, error) {
+	var wildcard *TrustPolicy
+	for i, policy := range d.TrustPolicies {
+		for _, scope := range policy.RegistryScopes {
+			switch scope {
+			case repository:
+				return &d.TrustPolicies[i], nil
+			case "*":
+				wildcard = &d.TrustPolicies[i]
+			}
+		}
+	}
+	if wildcard == nil {
+		return nil, fmt.Errorf("no trust policy applies to %s", repository)
+	}
+	return wildcard, nil
+}
+
+// isTrustedIdentity reports whether the subject of the signing certificate
+// matches any of the trusted identities of the policy.
+func (p *TrustPolicy) isTrustedIdentity(cert *x509.Certificate) bool {
+	for _, identity := range p.TrustedIdentities {
+		if identity == "*" {
+			return true
+		}
+		dn := strings.TrimPrefix(identity, "x509.subject:")
+		if dn == identity {
+			continue
+		}
+		if matchSubject(cert.Subject, dn) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSubject reports whether the name contains all the attributes of the
+// distinguished name in the form of `C=US, O=example, CN=signer`.
+func matchSubject(name pkix.Name, dn string) bool {
+	for _, attr := range strings.Split(dn, ",") {
+		kv := strings.SplitN(strings.TrimSpace(attr), "=", 2)
+		if len(kv) != 2 {
+			return false
+		}
+		var values []string
+		switch key := strings.TrimSpace(kv[0]); key {
+		case "C":
+			values = name.Country
+		case "ST":
+			values = name.Province
+		case "L":
+			values = name.Locality
+		case "O":
+			values = name.Organization
+		case "OU":
+			values = name.OrganizationalUnit
+		case "CN":
+			values = []string{name.CommonName}
+		default:
+			return false
+		}
+		if !contains(values, strings.TrimSpace(kv[1])) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package notation
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Types of trust stores.
+const (
+	TrustStoreCA               = "ca"
+	TrustStoreSigningAuthority = "signingAuthority"
+)
+
+// DefaultDir returns the notation config directory holding `trustpolicy.json`
+// and the trust stores.
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "notation"), nil
+}
+
+// loadTrustStores reads the certificates of the named trust stores in the
+// form of `<type>:<name>` from `<dir>/x509/<type>/<name>`.
+func loadTrustStores(dir string, names []string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, name := range names {
+		parts := strings.SplitN(name, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid trust store %q", name)
+		}
+		switch parts[0] {
+		case TrustStoreCA, TrustStoreSigningAuthority:
+		default:
+			return nil, fmt.Errorf("unsupported trust store type %q", parts[0])
+		}
+		storeDir := filepath.Join(dir, "x509", parts[0], parts[1])
+		files, err := ioutil.ReadDir(storeDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			certs, err := loadCertificates(filepath.Join(storeDir, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			for _, cert := range certs {
+				pool.AddCert(cert)
+			}
+		}
+	}
+	return pool, nil
+}
+
+// loadCertificates reads the PEM or DER encoded certificates in the file.
+func loadCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		if certs, err = x509.ParseCertificates(data); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if len(certs) == 0 {
+			return nil, fmt.Errorf("%s: no certificates found", path)
+		}
+	}
+	return certs, nil
+}
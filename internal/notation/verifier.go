@@ -0,0 +1,303 @@
+// Package notation verifies notation (Notary v2) signatures of artifacts
+// against the notation trust policies and trust stores.
+//
+// Only JWS signature envelopes signed with the `notary.x509` signing scheme
+// are supported. Timestamps and revocation are not checked.
+package notation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// Media types of notation signatures.
+const (
+	ArtifactTypeSignature = "application/vnd.cncf.notary.signature"
+	MediaTypeJWSEnvelope  = "application/jose+json"
+	MediaTypeCOSEEnvelope = "application/cose"
+	MediaTypePayload      = "application/vnd.cncf.notary.payload.v1+json"
+)
+
+// Protected header names of notation JWS envelopes.
+const (
+	headerSigningScheme = "io.cncf.notary.signingScheme"
+	headerExpiry        = "io.cncf.notary.expiry"
+	signingSchemeX509   = "notary.x509"
+)
+
+// Common errors
+var (
+	ErrUnsupportedEnvelope = errors.New("unsupported signature envelope")
+	ErrVerificationFailed  = errors.New("signature verification failed")
+)
+
+// Outcome is the result of a successful verification.
+type Outcome struct {
+	// Policy is the name of the trust policy applied.
+	Policy string
+	// Level is the verification level of the policy.
+	Level string
+	// Signer is the subject of the signing certificate.
+	Signer string
+	// SigningTime is the time claimed by the signer.
+	SigningTime time.Time
+	// Warnings are the failures logged but not enforced by the level.
+	Warnings []string
+}
+
+// Verifier verifies signatures against the trust policies.
+type Verifier struct {
+	policy        *TrustPolicyDocument
+	trustStoreDir string
+}
+
+// NewVerifier creates a verifier with the trust policies and the directory
+// of the trust stores, which is usually `truststore` in the notation config
+// directory.
+func NewVerifier(policy *TrustPolicyDocument, trustStoreDir string) *Verifier {
+	return &Verifier{
+		policy:        policy,
+		trustStoreDir: trustStoreDir,
+	}
+}
+
+// Policy returns the trust policy of the repository in the form of
+// `registry/repository`.
+func (v *Verifier) Policy(repository string) (*TrustPolicy, error) {
+	return v.policy.Match(repository)
+}
+
+// Verify verifies the signature envelope signing the subject artifact in the
+// repository.
+func (v *Verifier) Verify(repository string, subject ocispec.Descriptor, envelopeMediaType string, envelope []byte) (*Outcome, error) {
+	policy, err := v.Policy(repository)
+	if err != nil {
+		return nil, err
+	}
+	outcome := &Outcome{
+		Policy: policy.Name,
+		Level:  policy.SignatureVerification.Level,
+	}
+	if outcome.Level == LevelSkip {
+		return outcome, nil
+	}
+	if envelopeMediaType != MediaTypeJWSEnvelope {
+		return nil, errors.Wrap(ErrUnsupportedEnvelope, envelopeMediaType)
+	}
+
+	// integrity is enforced by all levels
+	sig, err := parseJWS(envelope)
+	if err != nil {
+		return nil, errors.Wrap(ErrVerificationFailed, err.Error())
+	}
+	if err := sig.verifyIntegrity(subject); err != nil {
+		return nil, errors.Wrap(ErrVerificationFailed, err.Error())
+	}
+	outcome.Signer = sig.certs[0].Subject.String()
+	outcome.SigningTime = sig.signingTime
+
+	// authenticity is logged only by the audit level
+	if err := v.verifyAuthenticity(policy, sig); err != nil {
+		if outcome.Level != LevelAudit {
+			return nil, errors.Wrap(ErrVerificationFailed, err.Error())
+		}
+		outcome.Warnings = append(outcome.Warnings, err.Error())
+	}
+
+	// expiry is enforced by the strict level only
+	if !sig.expiry.IsZero() && time.Now().After(sig.expiry) {
+		err := fmt.Errorf("signature expired at %s", sig.expiry.Format(time.RFC3339))
+		if outcome.Level == LevelStrict {
+			return nil, errors.Wrap(ErrVerificationFailed, err.Error())
+		}
+		outcome.Warnings = append(outcome.Warnings, err.Error())
+	}
+	return outcome, nil
+}
+
+// verifyAuthenticity verifies the certificate chain of the signature is
+// trusted by the policy.
+func (v *Verifier) verifyAuthenticity(policy *TrustPolicy, sig *jwsSignature) error {
+	roots, err := loadTrustStores(v.trustStoreDir, policy.TrustStores)
+	if err != nil {
+		return err
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range sig.certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := sig.certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return err
+	}
+	if !policy.isTrustedIdentity(sig.certs[0]) {
+		return fmt.Errorf("signer %q is not a trusted identity", sig.certs[0].Subject)
+	}
+	return nil
+}
+
+// jwsEnvelope is the JWS JSON serialization of notation signatures.
+type jwsEnvelope struct {
+	Payload   string `json:"payload"`
+	Protected string `json:"protected"`
+	Header    struct {
+		CertChain [][]byte `json:"x5c"`
+	} `json:"header"`
+	Signature string `json:"signature"`
+}
+
+// jwsProtectedHeader is the protected header of notation JWS envelopes.
+type jwsProtectedHeader struct {
+	Algorithm     string   `json:"alg"`
+	ContentType   string   `json:"cty"`
+	Critical      []string `json:"crit"`
+	SigningScheme string   `json:"io.cncf.notary.signingScheme"`
+	SigningTime   string   `json:"io.cncf.notary.signingTime"`
+	Expiry        string   `json:"io.cncf.notary.expiry,omitempty"`
+}
+
+// payload is the signed content of notation signatures.
+type payload struct {
+	TargetArtifact ocispec.Descriptor `json:"targetArtifact"`
+}
+
+// jwsSignature is a parsed JWS envelope.
+type jwsSignature struct {
+	header      jwsProtectedHeader
+	payload     payload
+	certs       []*x509.Certificate
+	signingTime time.Time
+	expiry      time.Time
+
+	signingInput []byte
+	signature    []byte
+}
+
+// parseJWS parses the envelope and its protected header and payload.
+func parseJWS(envelope []byte) (*jwsSignature, error) {
+	var env jwsEnvelope
+	if err := json.Unmarshal(envelope, &env); err != nil {
+		return nil, err
+	}
+	sig := &jwsSignature{
+		signingInput: []byte(env.Protected + "." + env.Payload),
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(env.Protected)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protected header: %v", err)
+	}
+	if err := json.Unmarshal(headerBytes, &sig.header); err != nil {
+		return nil, fmt.Errorf("invalid protected header: %v", err)
+	}
+	if sig.header.ContentType != MediaTypePayload {
+		return nil, fmt.Errorf("unsupported payload content type %q", sig.header.ContentType)
+	}
+	if sig.header.SigningScheme != signingSchemeX509 {
+		return nil, fmt.Errorf("unsupported signing scheme %q", sig.header.SigningScheme)
+	}
+	for _, name := range sig.header.Critical {
+		switch name {
+		case headerSigningScheme, headerExpiry:
+		default:
+			return nil, fmt.Errorf("unsupported critical header %q", name)
+		}
+	}
+	if sig.signingTime, err = time.Parse(time.RFC3339, sig.header.SigningTime); err != nil {
+		return nil, fmt.Errorf("invalid signing time: %v", err)
+	}
+	if sig.header.Expiry != "" {
+		if sig.expiry, err = time.Parse(time.RFC3339, sig.header.Expiry); err != nil {
+			return nil, fmt.Errorf("invalid expiry: %v", err)
+		}
+	}
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+	if err := json.Unmarshal(payloadBytes, &sig.payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+	if sig.signature, err = base64.RawURLEncoding.DecodeString(env.Signature); err != nil {
+		return nil, fmt.Errorf("invalid signature: %v", err)
+	}
+
+	if len(env.Header.CertChain) == 0 {
+		return nil, errors.New("missing certificate chain")
+	}
+	for _, der := range env.Header.CertChain {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate chain: %v", err)
+		}
+		sig.certs = append(sig.certs, cert)
+	}
+	return sig, nil
+}
+
+// verifyIntegrity verifies the signature with the signing certificate and
+// the payload refers to the subject.
+func (s *jwsSignature) verifyIntegrity(subject ocispec.Descriptor) error {
+	if err := verifySignature(s.header.Algorithm, s.certs[0].PublicKey, s.signingInput, s.signature); err != nil {
+		return err
+	}
+	target := s.payload.TargetArtifact
+	if target.Digest != subject.Digest || target.Size != subject.Size || target.MediaType != subject.MediaType {
+		return fmt.Errorf("signature is for %s, not %s", target.Digest, subject.Digest)
+	}
+	return nil
+}
+
+// verifySignature verifies the JWS signature with the algorithm.
+func verifySignature(alg string, key crypto.PublicKey, signingInput, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "PS256", "ES256":
+		hash = crypto.SHA256
+	case "PS384", "ES384":
+		hash = crypto.SHA384
+	case "PS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write(signingInput)
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if alg[0] != 'P' {
+			return fmt.Errorf("algorithm %s does not match the RSA key", alg)
+		}
+		return rsa.VerifyPSS(key, hash, digest, signature, &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+		})
+	case *ecdsa.PublicKey:
+		if alg[0] != 'E' || len(signature)%2 != 0 {
+			return fmt.Errorf("algorithm %s does not match the EC key", alg)
+		}
+		n := len(signature) / 2
+		r := new(big.Int).SetBytes(signature[:n])
+		s := new(big.Int).SetBytes(signature[n:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return errors.New("unsupported public key type")
+}
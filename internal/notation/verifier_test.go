@@ -0,0 +1,148 @@
+package notation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type VerifierSuite struct {
+	suite.Suite
+	TempTestDir string
+	Key         *ecdsa.PrivateKey
+	Cert        *x509.Certificate
+	Subject     ocispec.Descriptor
+}
+
+func (suite *VerifierSuite) SetupSuite() {
+	tempDir, err := ioutil.TempDir("", "oras_notation_test")
+	suite.Nil(err, "no error creating temp directory for test")
+	suite.TempTestDir = tempDir
+
+	suite.Key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Nil(err, "no error generating key")
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "signer", Organization: []string{"example"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &suite.Key.PublicKey, suite.Key)
+	suite.Nil(err, "no error creating certificate")
+	suite.Cert, err = x509.ParseCertificate(der)
+	suite.Nil(err, "no error parsing certificate")
+
+	storeDir := filepath.Join(tempDir, "x509", "ca", "example")
+	suite.Nil(os.MkdirAll(storeDir, 0755), "no error creating trust store")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	suite.Nil(ioutil.WriteFile(filepath.Join(storeDir, "root.crt"), certPEM, 0644), "no error writing trust store")
+
+	content := []byte("{}")
+	suite.Subject = ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(content),
+		Size:      int64(len(content)),
+	}
+}
+
+func (suite *VerifierSuite) TearDownSuite() {
+	os.RemoveAll(suite.TempTestDir)
+}
+
+// sign creates a JWS envelope signing the target.
+func (suite *VerifierSuite) sign(target ocispec.Descriptor) []byte {
+	header, err := json.Marshal(jwsProtectedHeader{
+		Algorithm:     "ES256",
+		ContentType:   MediaTypePayload,
+		Critical:      []string{headerSigningScheme},
+		SigningScheme: signingSchemeX509,
+		SigningTime:   time.Now().Format(time.RFC3339),
+	})
+	suite.Nil(err, "no error marshaling header")
+	payloadBytes, err := json.Marshal(payload{TargetArtifact: target})
+	suite.Nil(err, "no error marshaling payload")
+
+	protected := base64.RawURLEncoding.EncodeToString(header)
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payloadBytes)
+	hash := sha256.Sum256([]byte(protected + "." + encodedPayload))
+	r, s, err := ecdsa.Sign(rand.Reader, suite.Key, hash[:])
+	suite.Nil(err, "no error signing")
+	signature := make([]byte, 64)
+	rBytes, sBytes := r.Bytes(), s.Bytes()
+	copy(signature[32-len(rBytes):32], rBytes)
+	copy(signature[64-len(sBytes):], sBytes)
+
+	var env jwsEnvelope
+	env.Protected = protected
+	env.Payload = encodedPayload
+	env.Signature = base64.RawURLEncoding.EncodeToString(signature)
+	env.Header.CertChain = [][]byte{suite.Cert.Raw}
+	envelope, err := json.Marshal(env)
+	suite.Nil(err, "no error marshaling envelope")
+	return envelope
+}
+
+func (suite *VerifierSuite) newVerifier(level string, identity string) *Verifier {
+	return NewVerifier(&TrustPolicyDocument{
+		Version: "1.0",
+		TrustPolicies: []TrustPolicy{{
+			Name:                  "test",
+			RegistryScopes:        []string{"*"},
+			SignatureVerification: SignatureVerification{Level: level},
+			TrustStores:           []string{"ca:example"},
+			TrustedIdentities:     []string{identity},
+		}},
+	}, suite.TempTestDir)
+}
+
+func (suite *VerifierSuite) TestVerify() {
+	envelope := suite.sign(suite.Subject)
+
+	verifier := suite.newVerifier(LevelStrict, "x509.subject: O=example, CN=signer")
+	outcome, err := verifier.Verify("localhost:5000/hello", suite.Subject, MediaTypeJWSEnvelope, envelope)
+	suite.Nil(err, "no error verifying valid signature")
+	suite.Equal("test", outcome.Policy, "policy matches")
+	suite.Empty(outcome.Warnings, "no warnings")
+
+	// signature of another artifact
+	other := suite.Subject
+	other.Digest = digest.FromString("other")
+	_, err = verifier.Verify("localhost:5000/hello", other, MediaTypeJWSEnvelope, envelope)
+	suite.Equal(ErrVerificationFailed, errors.Cause(err), "error verifying signature of another artifact")
+
+	// untrusted identity
+	verifier = suite.newVerifier(LevelStrict, "x509.subject: CN=someone")
+	_, err = verifier.Verify("localhost:5000/hello", suite.Subject, MediaTypeJWSEnvelope, envelope)
+	suite.Equal(ErrVerificationFailed, errors.Cause(err), "error verifying untrusted identity")
+
+	// logged only by audit
+	verifier = suite.newVerifier(LevelAudit, "x509.subject: CN=someone")
+	outcome, err = verifier.Verify("localhost:5000/hello", suite.Subject, MediaTypeJWSEnvelope, envelope)
+	suite.Nil(err, "no error auditing untrusted identity")
+	suite.Equal(1, len(outcome.Warnings), "authenticity failure logged")
+}
+
+func TestVerifierSuite(t *testing.T) {
+	suite.Run(t, new(VerifierSuite))
+}
@@ -0,0 +1,333 @@
+// Package refcache keeps the registries, repositories, tags and manifests seen
+// by the oras CLI, which power the completion of references and the offline
+// mode.
+package refcache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// EnvCachePath is the environment variable overriding the cache path.
+const EnvCachePath = "ORAS_REF_CACHE"
+
+// Cache is the set of known references keyed by the registry host, e.g.
+// "localhost:5000", and the repository name.
+type Cache struct {
+	Registries map[string]map[string]*Repository `json:"registries,omitempty"`
+}
+
+// Repository is a repository and its known tags.
+type Repository struct {
+	// Tags are keyed by the tag name.
+	Tags map[string]*Tag `json:"tags,omitempty"`
+	// Manifests are the manifests of the repository whose content is
+	// cached, keyed by digest.
+	Manifests map[digest.Digest]*Manifest `json:"manifests,omitempty"`
+	// Updated is when the repository was last seen.
+	Updated time.Time `json:"updated"`
+}
+
+// Tag is a known tag, with the manifest it was last seen pointing to.
+type Tag struct {
+	// Descriptor is the descriptor of the manifest, or nil if the tag was
+	// only listed.
+	Descriptor *ocispec.Descriptor `json:"descriptor,omitempty"`
+	// Updated is when the tag was last seen.
+	Updated time.Time `json:"updated"`
+}
+
+// Manifest is a manifest whose content is cached.
+type Manifest struct {
+	MediaType string `json:"mediaType"`
+	Size      int64  `json:"size"`
+}
+
+// Path returns the path of the cache file, which is specified by the
+// ORAS_REF_CACHE environment variable or defaults to `oras/refs.json` in the
+// user cache directory.
+func Path() (string, error) {
+	if path := os.Getenv(EnvCachePath); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "oras", "refs.json"), nil
+}
+
+// Load reads the cache file from the given path.
+// An empty cache is returned if the file does not exist.
+func Load(path string) (*Cache, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Cache{}, nil
+		}
+		return nil, err
+	}
+	var cache Cache
+	if err := json.Unmarshal(content, &cache); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// Save writes the cache file to the given path, replacing it atomically so
+// that concurrent readers never see a partial file.
+func (c *Cache) Save(path string) error {
+	content, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// Update loads the cache file, applies the change and saves it.
+func Update(path string, change func(*Cache)) error {
+	cache, err := Load(path)
+	if err != nil {
+		return err
+	}
+	change(cache)
+	return cache.Save(path)
+}
+
+// WriteManifest saves the content of the manifest next to the cache file at
+// path, in the `manifests` directory addressed by digest.
+func WriteManifest(path string, dgst digest.Digest, content []byte) error {
+	if err := dgst.Validate(); err != nil {
+		return err
+	}
+	if actual := dgst.Algorithm().FromBytes(content); actual != dgst {
+		return errors.Errorf("manifest digest mismatch: expected %s, got %s", dgst, actual)
+	}
+	manifestPath := manifestPath(path, dgst)
+	if _, err := os.Stat(manifestPath); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(manifestPath), 0700); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(filepath.Dir(manifestPath), dgst.Encoded()+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), manifestPath)
+}
+
+// ReadManifest reads the content of the manifest saved by WriteManifest.
+func ReadManifest(path string, dgst digest.Digest) ([]byte, error) {
+	if err := dgst.Validate(); err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(manifestPath(path, dgst))
+	if err != nil {
+		return nil, err
+	}
+	if actual := dgst.Algorithm().FromBytes(content); actual != dgst {
+		return nil, errors.Errorf("manifest digest mismatch: expected %s, got %s", dgst, actual)
+	}
+	return content, nil
+}
+
+func manifestPath(path string, dgst digest.Digest) string {
+	return filepath.Join(filepath.Dir(path), "manifests", dgst.Algorithm().String(), dgst.Encoded())
+}
+
+// AddRepositories records the repositories of the registry.
+func (c *Cache) AddRepositories(host string, names []string, now time.Time) {
+	for _, name := range names {
+		c.repository(host, name).Updated = now
+	}
+}
+
+// AddTags records the tags of the repository. The manifests of the tags
+// already known are kept.
+func (c *Cache) AddTags(host, name string, tags []string, now time.Time) {
+	repo := c.repository(host, name)
+	repo.Updated = now
+	for _, tag := range tags {
+		if t, ok := repo.Tags[tag]; ok {
+			t.Updated = now
+			continue
+		}
+		repo.Tags[tag] = &Tag{
+			Updated: now,
+		}
+	}
+}
+
+// SetTag records the manifest the tag points to.
+func (c *Cache) SetTag(host, name, tag string, desc ocispec.Descriptor, now time.Time) {
+	repo := c.repository(host, name)
+	repo.Updated = now
+	repo.Tags[tag] = &Tag{
+		Descriptor: &ocispec.Descriptor{
+			MediaType: desc.MediaType,
+			Digest:    desc.Digest,
+			Size:      desc.Size,
+		},
+		Updated: now,
+	}
+}
+
+// AddManifest records that the content of the manifest of the repository is
+// cached.
+func (c *Cache) AddManifest(host, name string, desc ocispec.Descriptor, now time.Time) {
+	repo := c.repository(host, name)
+	repo.Updated = now
+	repo.Manifests[desc.Digest] = &Manifest{
+		MediaType: desc.MediaType,
+		Size:      desc.Size,
+	}
+}
+
+// RemoveTag forgets the tag.
+func (c *Cache) RemoveTag(host, name, tag string) {
+	if repo, ok := c.Registries[host][name]; ok {
+		delete(repo.Tags, tag)
+	}
+}
+
+// RemoveManifest forgets the manifest and the tags pointing to it.
+func (c *Cache) RemoveManifest(host, name string, dgst digest.Digest) {
+	repo, ok := c.Registries[host][name]
+	if !ok {
+		return
+	}
+	for tag, t := range repo.Tags {
+		if t.Descriptor != nil && t.Descriptor.Digest == dgst {
+			delete(repo.Tags, tag)
+		}
+	}
+	delete(repo.Manifests, dgst)
+}
+
+// Resolve returns the manifest the tag was last seen pointing to, or the
+// manifest of the digest if known in the repository, with the time it was
+// last seen.
+func (c *Cache) Resolve(host, name, object string) (ocispec.Descriptor, time.Time, bool) {
+	repo, ok := c.Registries[host][name]
+	if !ok {
+		return ocispec.Descriptor{}, time.Time{}, false
+	}
+	if !strings.Contains(object, ":") {
+		if t, ok := repo.Tags[object]; ok && t.Descriptor != nil {
+			return *t.Descriptor, t.Updated, true
+		}
+		return ocispec.Descriptor{}, time.Time{}, false
+	}
+	var (
+		desc    ocispec.Descriptor
+		updated time.Time
+		found   bool
+	)
+	if m, ok := repo.Manifests[digest.Digest(object)]; ok {
+		desc = ocispec.Descriptor{
+			MediaType: m.MediaType,
+			Digest:    digest.Digest(object),
+			Size:      m.Size,
+		}
+		updated, found = repo.Updated, true
+	}
+	for _, t := range repo.Tags {
+		if t.Descriptor != nil && t.Descriptor.Digest.String() == object && (!found || t.Updated.After(updated)) {
+			desc, updated, found = *t.Descriptor, t.Updated, true
+		}
+	}
+	return desc, updated, found
+}
+
+// Hosts returns the known registry hosts, sorted.
+func (c *Cache) Hosts() []string {
+	hosts := make([]string, 0, len(c.Registries))
+	for host := range c.Registries {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Repositories returns the known repositories of the registry, sorted.
+func (c *Cache) Repositories(host string) []string {
+	names := make([]string, 0, len(c.Registries[host]))
+	for name := range c.Registries[host] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Tags returns the known tags of the repository, sorted.
+func (c *Cache) Tags(host, name string) []string {
+	repo, ok := c.Registries[host][name]
+	if !ok {
+		return nil
+	}
+	tags := make([]string, 0, len(repo.Tags))
+	for tag := range repo.Tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// repository returns the repository, adding it if unknown.
+func (c *Cache) repository(host, name string) *Repository {
+	if c.Registries == nil {
+		c.Registries = make(map[string]map[string]*Repository)
+	}
+	repos, ok := c.Registries[host]
+	if !ok {
+		repos = make(map[string]*Repository)
+		c.Registries[host] = repos
+	}
+	repo, ok := repos[name]
+	if !ok {
+		repo = &Repository{}
+		repos[name] = repo
+	}
+	if repo.Tags == nil {
+		repo.Tags = make(map[string]*Tag)
+	}
+	if repo.Manifests == nil {
+		repo.Manifests = make(map[digest.Digest]*Manifest)
+	}
+	return repo
+}
@@ -0,0 +1,105 @@
+package refcache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/suite"
+)
+
+type RefCacheSuite struct {
+	suite.Suite
+	TempTestDir string
+}
+
+func (suite *RefCacheSuite) SetupSuite() {
+	tempDir, err := ioutil.TempDir("", "oras_refcache_test")
+	suite.Nil(err, "no error creating temp directory for test")
+	suite.TempTestDir = tempDir
+}
+
+func (suite *RefCacheSuite) TearDownSuite() {
+	os.RemoveAll(suite.TempTestDir)
+}
+
+func (suite *RefCacheSuite) TestUpdate() {
+	path := filepath.Join(suite.TempTestDir, "cache", "refs.json")
+	cache, err := Load(path)
+	suite.Nil(err, "no error loading missing cache")
+	suite.Empty(cache.Hosts(), "missing cache is empty")
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromString("manifest"),
+		Size:      8,
+	}
+	err = Update(path, func(c *Cache) {
+		c.AddRepositories("localhost:5000", []string{"hello", "world"}, now)
+		c.AddTags("localhost:5000", "hello", []string{"v1", "v2"}, now)
+		c.SetTag("localhost:5000", "hello", "v1", desc, now)
+		c.AddTags("localhost:5000", "hello", []string{"v1"}, now.Add(time.Hour))
+	})
+	suite.Nil(err, "no error updating cache")
+
+	cache, err = Load(path)
+	suite.Nil(err, "no error loading cache")
+	suite.Equal([]string{"localhost:5000"}, cache.Hosts())
+	suite.Equal([]string{"hello", "world"}, cache.Repositories("localhost:5000"))
+	suite.Equal([]string{"v1", "v2"}, cache.Tags("localhost:5000", "hello"))
+
+	resolved, updated, ok := cache.Resolve("localhost:5000", "hello", "v1")
+	suite.True(ok, "listed tag keeps its manifest")
+	suite.Equal(desc, resolved)
+	suite.Equal(now.Add(time.Hour), updated)
+	_, _, ok = cache.Resolve("localhost:5000", "hello", "v2")
+	suite.False(ok, "listed tag without manifest is not resolved")
+	resolved, _, ok = cache.Resolve("localhost:5000", "hello", desc.Digest.String())
+	suite.True(ok, "digest of a known manifest is resolved")
+	suite.Equal(desc, resolved)
+
+	cache.RemoveManifest("localhost:5000", "hello", desc.Digest)
+	suite.Equal([]string{"v2"}, cache.Tags("localhost:5000", "hello"), "tags of a removed manifest are forgotten")
+	cache.RemoveTag("localhost:5000", "hello", "v2")
+	suite.Empty(cache.Tags("localhost:5000", "hello"), "removed tag is forgotten")
+}
+
+func (suite *RefCacheSuite) TestManifest() {
+	path := filepath.Join(suite.TempTestDir, "manifest", "refs.json")
+	content := []byte(`{"schemaVersion":2}`)
+	dgst := digest.FromBytes(content)
+
+	err := WriteManifest(path, digest.FromString("other"), content)
+	suite.NotNil(err, "error writing manifest with mismatching digest")
+	_, err = ReadManifest(path, dgst)
+	suite.True(os.IsNotExist(err), "missing manifest is not found")
+
+	err = WriteManifest(path, dgst, content)
+	suite.Nil(err, "no error writing manifest")
+	read, err := ReadManifest(path, dgst)
+	suite.Nil(err, "no error reading manifest")
+	suite.Equal(content, read)
+
+	cache := &Cache{}
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    dgst,
+		Size:      int64(len(content)),
+	}
+	cache.AddManifest("localhost:5000", "hello", desc, now)
+	resolved, _, ok := cache.Resolve("localhost:5000", "hello", dgst.String())
+	suite.True(ok, "cached manifest is resolved")
+	suite.Equal(desc, resolved)
+	_, _, ok = cache.Resolve("localhost:5000", "world", dgst.String())
+	suite.False(ok, "cached manifest is not resolved in other repositories")
+}
+
+func TestRefCacheSuite(t *testing.T) {
+	suite.Run(t, new(RefCacheSuite))
+}
@@ -0,0 +1,11 @@
+package artifact
+
+const (
+	// AnnotationsMediaType is the media type of the blob holding the manifest
+	// annotations, which are too large to be kept in the manifest. The blob
+	// is a JSON object of annotations.
+	AnnotationsMediaType = "application/vnd.oras.annotations.v1+json"
+	// AnnotationExternalAnnotations is the manifest annotation pointing to
+	// the digest of the annotations blob.
+	AnnotationExternalAnnotations = "io.deis.oras.annotations.external"
+)
@@ -0,0 +1,64 @@
+package artifact
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AnnotationExpires is the annotation key for the time after which an
+// artifact may be deleted, in RFC 3339 format.
+const AnnotationExpires = "io.deis.oras.expires"
+
+// ParseRetention parses a retention period, which is a Go duration such as
+// "12h", or a number of days or weeks such as "30d" or "2w".
+func ParseRetention(s string) (time.Duration, error) {
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	var retention time.Duration
+	if unit != 0 {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return 0, errors.Errorf("invalid retention period %q", s)
+		}
+		retention = time.Duration(n) * unit
+	} else {
+		var err error
+		if retention, err = time.ParseDuration(s); err != nil {
+			return 0, errors.Errorf("invalid retention period %q", s)
+		}
+	}
+	if retention <= 0 {
+		return 0, errors.Errorf("invalid retention period %q: must be positive", s)
+	}
+	return retention, nil
+}
+
+// ExpiryAnnotations returns the annotations recording the expiry of an
+// artifact pushed now and retained for the period.
+func ExpiryAnnotations(now time.Time, retention time.Duration) map[string]string {
+	return map[string]string{
+		AnnotationExpires: now.Add(retention).UTC().Format(time.RFC3339),
+	}
+}
+
+// Expiry returns the expiry recorded in the annotations of an artifact, and
+// false if there is none.
+func Expiry(annotations map[string]string) (time.Time, bool, error) {
+	value, ok := annotations[AnnotationExpires]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	expires, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, errors.Errorf("invalid %s annotation %q", AnnotationExpires, value)
+	}
+	return expires, true, nil
+}
@@ -0,0 +1,60 @@
+package artifact
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ExpirySuite struct {
+	suite.Suite
+}
+
+func (suite *ExpirySuite) TestParseRetention() {
+	for value, expected := range map[string]time.Duration{
+		"12h":   12 * time.Hour,
+		"90m":   90 * time.Minute,
+		"1h30m": 90 * time.Minute,
+		"30d":   30 * 24 * time.Hour,
+		"2w":    14 * 24 * time.Hour,
+	} {
+		retention, err := ParseRetention(value)
+		suite.Nil(err, "no error parsing %s", value)
+		suite.Equal(expected, retention, "retention of %s", value)
+	}
+
+	for _, value := range []string{"", "d", "w", "1.5d", "xw", "30", "-1d", "0w", "-12h", "0s", "1y"} {
+		_, err := ParseRetention(value)
+		suite.NotNil(err, "error parsing %q", value)
+	}
+}
+
+func (suite *ExpirySuite) TestExpiry() {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 6, time.FixedZone("UTC+1", 3600))
+	annotations := ExpiryAnnotations(now, 30*24*time.Hour)
+	suite.Equal(map[string]string{
+		AnnotationExpires: "2020-02-01T02:04:05Z",
+	}, annotations, "expiry recorded in UTC without fractions of seconds")
+
+	expires, ok, err := Expiry(annotations)
+	suite.Nil(err, "no error parsing expiry")
+	suite.True(ok, "expiry found")
+	suite.True(expires.Equal(now.Add(30*24*time.Hour).Truncate(time.Second)), "expiry matches")
+
+	_, ok, err = Expiry(map[string]string{"other": "value"})
+	suite.Nil(err, "no error without expiry")
+	suite.False(ok, "no expiry found")
+
+	_, ok, err = Expiry(nil)
+	suite.Nil(err, "no error without annotations")
+	suite.False(ok, "no expiry found without annotations")
+
+	_, ok, err = Expiry(map[string]string{AnnotationExpires: "tomorrow"})
+	suite.NotNil(err, "error parsing invalid expiry")
+	suite.False(ok, "invalid expiry not found")
+}
+
+func TestExpirySuite(t *testing.T) {
+	suite.Run(t, new(ExpirySuite))
+}
@@ -0,0 +1,51 @@
+package artifact
+
+import (
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+const (
+	// TagHistoryArtifactType is the artifact type of the history of a tag,
+	// which refers to the manifest the tag points to.
+	TagHistoryArtifactType = "application/vnd.oras.tag.history.v1"
+
+	// TagHistoryMediaType is the media type of the blob holding a
+	// TagHistory.
+	TagHistoryMediaType = "application/vnd.oras.tag.history.v1+json"
+
+	// AnnotationHistoryTag is the annotation key for the tag of a history
+	// manifest.
+	AnnotationHistoryTag = "io.deis.oras.history.tag"
+)
+
+// TagHistory is the change log of a tag, carried over from the manifest the
+// tag pointed to before each change.
+type TagHistory struct {
+	// Tag is the tag in its repository.
+	Tag string `json:"tag"`
+	// Changes are the changes of the tag, the oldest first.
+	Changes []TagChange `json:"changes"`
+}
+
+// TagChange is a change of the manifest a tag points to.
+type TagChange struct {
+	// Previous is the digest of the manifest before the change, or empty if
+	// the tag was created.
+	Previous digest.Digest `json:"previous,omitempty"`
+	// Digest is the digest of the manifest after the change.
+	Digest digest.Digest `json:"digest"`
+	// Actor is who made the change, as reported by the client.
+	Actor string `json:"actor,omitempty"`
+	// Time is when the change was made.
+	Time time.Time `json:"time"`
+}
+
+// Latest returns the latest change, or nil if there is none.
+func (h *TagHistory) Latest() *TagChange {
+	if len(h.Changes) == 0 {
+		return nil
+	}
+	return &h.Changes[len(h.Changes)-1]
+}
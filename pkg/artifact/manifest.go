@@ -0,0 +1,18 @@
+package artifact
+
+import (
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ArtifactManifestMediaType is the media type of the artifact manifest.
+const ArtifactManifestMediaType = "application/vnd.oci.artifact.manifest.v1+json"
+
+// Manifest describes an artifact manifest, which lists the blobs of an
+// artifact without a config and may refer to a subject manifest.
+type Manifest struct {
+	MediaType    string               `json:"mediaType"`
+	ArtifactType string               `json:"artifactType"`
+	Blobs        []ocispec.Descriptor `json:"blobs,omitempty"`
+	Subject      *ocispec.Descriptor  `json:"subject,omitempty"`
+	Annotations  map[string]string    `json:"annotations,omitempty"`
+}
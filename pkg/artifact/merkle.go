@@ -0,0 +1,168 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+const (
+	// MerkleTreeArtifactType is the artifact type of the referrers holding
+	// the Merkle trees of the layers of their subjects.
+	MerkleTreeArtifactType = "application/vnd.oras.merkle.v1"
+	// MerkleTreeMediaType is the media type of the blobs holding the Merkle
+	// tree of a layer, which is described by MerkleTree.
+	MerkleTreeMediaType = "application/vnd.oras.merkle.tree.v1+json"
+	// AnnotationMerkleLayer is the annotation key for the digest of the layer
+	// a Merkle tree blob is computed over.
+	AnnotationMerkleLayer = "io.deis.oras.merkle.layer"
+	// AnnotationMerkleRoot is the annotation key for the root of the Merkle
+	// tree of a layer.
+	AnnotationMerkleRoot = "io.deis.oras.merkle.root"
+
+	// DefaultMerkleChunkSize is the default size of the chunks hashed as the
+	// leaves of Merkle trees.
+	DefaultMerkleChunkSize = 1024 * 1024
+)
+
+// MerkleTree is a binary hash tree over the fixed-size chunks of a layer,
+// allowing to verify ranges of the layer without reading all of it.
+//
+// The leaves are the sha256 of 0x00 followed by the chunks, and the nodes the
+// sha256 of 0x01 followed by the hashes of their children. A node without a
+// right child is promoted to the upper level as is.
+type MerkleTree struct {
+	// Layer is the digest of the layer.
+	Layer digest.Digest `json:"layer"`
+	// Size is the size of the layer.
+	Size int64 `json:"size"`
+	// ChunkSize is the size of the chunks, except the last one which may be
+	// shorter.
+	ChunkSize int64 `json:"chunkSize"`
+	// Root is the root hash of the tree.
+	Root digest.Digest `json:"root"`
+	// Leaves are the hashes of the chunks in order. An empty layer has a
+	// single leaf of an empty chunk.
+	Leaves []digest.Digest `json:"leaves"`
+}
+
+// NewMerkleTree computes the Merkle tree of the content read from r over
+// chunks of chunkSize.
+func NewMerkleTree(r io.Reader, chunkSize int64) (*MerkleTree, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+	tree := &MerkleTree{
+		ChunkSize: chunkSize,
+	}
+	digester := digest.SHA256.Digester()
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 || len(tree.Leaves) == 0 {
+			digester.Hash().Write(buf[:n])
+			tree.Leaves = append(tree.Leaves, merkleLeaf(buf[:n]))
+			tree.Size += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	tree.Layer = digester.Digest()
+	root, err := merkleRoot(tree.Leaves)
+	if err != nil {
+		return nil, err
+	}
+	tree.Root = root
+	return tree, nil
+}
+
+// Validate checks that the leaves match the size of the layer and the root.
+func (t *MerkleTree) Validate() error {
+	if t.ChunkSize <= 0 {
+		return fmt.Errorf("invalid chunk size %d", t.ChunkSize)
+	}
+	count := (t.Size + t.ChunkSize - 1) / t.ChunkSize
+	if count == 0 {
+		count = 1
+	}
+	if int64(len(t.Leaves)) != count {
+		return fmt.Errorf("%d leaves, expected %d", len(t.Leaves), count)
+	}
+	root, err := merkleRoot(t.Leaves)
+	if err != nil {
+		return err
+	}
+	if root != t.Root {
+		return fmt.Errorf("root %s, expected %s", root, t.Root)
+	}
+	return nil
+}
+
+// Chunk returns the offset and the size of the i-th chunk of the layer.
+func (t *MerkleTree) Chunk(i int) (int64, int64) {
+	offset := int64(i) * t.ChunkSize
+	size := t.ChunkSize
+	if offset+size > t.Size {
+		size = t.Size - offset
+	}
+	return offset, size
+}
+
+// VerifyChunk checks the content p of the i-th chunk against its leaf. The
+// tree should be validated against a trusted root first.
+func (t *MerkleTree) VerifyChunk(i int, p []byte) error {
+	if i < 0 || i >= len(t.Leaves) {
+		return fmt.Errorf("chunk %d out of range", i)
+	}
+	if _, size := t.Chunk(i); int64(len(p)) != size {
+		return fmt.Errorf("chunk %d: size %d, expected %d", i, len(p), size)
+	}
+	if merkleLeaf(p) != t.Leaves[i] {
+		return fmt.Errorf("chunk %d: digest mismatch", i)
+	}
+	return nil
+}
+
+func merkleLeaf(p []byte) digest.Digest {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(p)
+	return digest.NewDigest(digest.SHA256, h)
+}
+
+func merkleRoot(leaves []digest.Digest) (digest.Digest, error) {
+	if len(leaves) == 0 {
+		return "", errors.New("no leaves")
+	}
+	level := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		if err := leaf.Validate(); err != nil || leaf.Algorithm() != digest.SHA256 {
+			return "", fmt.Errorf("invalid leaf %q", leaf)
+		}
+		level[i], _ = hex.DecodeString(leaf.Hex())
+	}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			h := sha256.New()
+			h.Write([]byte{0x01})
+			h.Write(level[i])
+			h.Write(level[i+1])
+			next = append(next, h.Sum(nil))
+		}
+		level = next
+	}
+	return digest.NewDigestFromBytes(digest.SHA256, level[0]), nil
+}
@@ -0,0 +1,34 @@
+package artifact
+
+import (
+	digest "github.com/opencontainers/go-digest"
+)
+
+const (
+	// ModelConfigMediaType is the media type of the config of model
+	// artifacts, which is described by ModelConfig.
+	ModelConfigMediaType = "application/vnd.oras.model.config.v1+json"
+	// ModelLayerMediaType is the default media type of the layers of model
+	// artifacts holding the weights or the shards of them.
+	ModelLayerMediaType = "application/vnd.oras.model.layer.v1"
+)
+
+// ModelConfig describes a machine learning model stored as an artifact.
+type ModelConfig struct {
+	// Name is the name of the model.
+	Name string `json:"name,omitempty"`
+	// Format is the serialization format of the weights, such as
+	// `safetensors`, `gguf` or `onnx`.
+	Format string `json:"format,omitempty"`
+	// Files lists the files of the model in order.
+	Files []ModelFile `json:"files"`
+}
+
+// ModelFile describes a file of a model, which may be split into shards
+// stored as separate layers.
+type ModelFile struct {
+	Name   string        `json:"name"`
+	Digest digest.Digest `json:"digest"`
+	Size   int64         `json:"size"`
+	Shards int           `json:"shards"`
+}
@@ -0,0 +1,64 @@
+package artifact
+
+import (
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// TombstoneConfigMediaType is the config media type of tombstones, which are
+// empty manifests pushed to the tags whose manifests cannot be deleted.
+const TombstoneConfigMediaType = "application/vnd.oras.tombstone.v1+json"
+
+const (
+	// AnnotationDeprecated marks a tombstone, recording the time the tag was
+	// deprecated in RFC 3339 format.
+	AnnotationDeprecated = "io.deis.oras.deprecated"
+	// AnnotationDeprecatedDigest is the annotation key for the digest of the
+	// manifest the tag referenced before the tombstone.
+	AnnotationDeprecatedDigest = "io.deis.oras.deprecated.digest"
+	// AnnotationReplacement is the annotation key for the reference of the
+	// artifact replacing the deprecated one.
+	AnnotationReplacement = "io.deis.oras.deprecated.replacement"
+)
+
+// Tombstone is the deprecation of a tag recorded in its tombstone.
+type Tombstone struct {
+	// Time is when the tag was deprecated.
+	Time time.Time
+	// Digest is the digest of the manifest the tag referenced.
+	Digest digest.Digest
+	// Replacement is the reference of the replacement, if any.
+	Replacement string
+}
+
+// Annotations returns the manifest annotations of the tombstone.
+func (t Tombstone) Annotations() map[string]string {
+	annotations := map[string]string{
+		AnnotationDeprecated:       t.Time.UTC().Format(time.RFC3339),
+		AnnotationDeprecatedDigest: t.Digest.String(),
+	}
+	if t.Replacement != "" {
+		annotations[AnnotationReplacement] = t.Replacement
+	}
+	return annotations
+}
+
+// ParseTombstone returns the tombstone recorded in the annotations of a
+// manifest, and false if the manifest is not a tombstone.
+func ParseTombstone(annotations map[string]string) (Tombstone, bool, error) {
+	value, ok := annotations[AnnotationDeprecated]
+	if !ok {
+		return Tombstone{}, false, nil
+	}
+	deprecated, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return Tombstone{}, false, errors.Errorf("invalid %s annotation %q", AnnotationDeprecated, value)
+	}
+	return Tombstone{
+		Time:        deprecated,
+		Digest:      digest.Digest(annotations[AnnotationDeprecatedDigest]),
+		Replacement: annotations[AnnotationReplacement],
+	}, true, nil
+}
@@ -10,15 +10,50 @@ import (
 
 // Common errors
 var (
-	ErrNotLoggedIn = errors.New("not logged in")
+	ErrNotLoggedIn        = errors.New("not logged in")
+	ErrConflictingSecrets = errors.New("identity token cannot be used with username or password")
 )
 
 // Client provides authentication operations for remotes.
 type Client interface {
 	// Login logs in to a remote server identified by the hostname.
 	Login(ctx context.Context, hostname, username, secret string, insecure bool) error
+	// LoginWithOpts logs in to a remote server with the given settings.
+	LoginWithOpts(options ...LoginOption) error
 	// Logout logs out from a remote server identified by the hostname.
 	Logout(ctx context.Context, hostname string) error
 	// Resolver returns a new authenticated resolver.
 	Resolver(ctx context.Context, client *http.Client, plainHTTP bool) (remotes.Resolver, error)
+	// Credentials returns the stored credentials of the hostnames, or all
+	// the stored credentials if no hostname is given.
+	Credentials(ctx context.Context, hostnames ...string) ([]Credential, error)
+	// StoreCredentials stores the credentials as is, without logging in.
+	StoreCredentials(ctx context.Context, credentials ...Credential) error
+	// Credential returns the username and the secret of the hostname for the
+	// Credentials callback of the docker resolver.
+	Credential(hostname string) (string, string, error)
+}
+
+// CredentialStore stores the credentials of remote servers, such as the
+// docker config files, the OS keyring, or an external secret manager. The
+// server addresses are the hostnames of the remotes.
+type CredentialStore interface {
+	// Get returns the credential of the server address, or an empty
+	// credential if none is stored.
+	Get(ctx context.Context, serverAddress string) (Credential, error)
+	// Store stores the credential, replacing the existing one.
+	Store(ctx context.Context, credential Credential) error
+	// Erase removes the credential of the server address. An ErrNotLoggedIn
+	// error is returned if none is stored.
+	Erase(ctx context.Context, serverAddress string) error
+	// List returns all the stored credentials.
+	List(ctx context.Context) ([]Credential, error)
+}
+
+// Credential is the credential of a remote server.
+type Credential struct {
+	ServerAddress string `json:"serverAddress"`
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	IdentityToken string `json:"identityToken,omitempty"`
 }
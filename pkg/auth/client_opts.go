@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+// LoginSettings represent all the various settings on login.
+type LoginSettings struct {
+	Context       context.Context
+	Hostname      string
+	Username      string
+	Secret        string
+	IdentityToken string
+	Insecure      bool
+	PlainHTTP     bool
+	Client        *http.Client
+}
+
+// LoginOption allows specifying various settings on login.
+type LoginOption func(*LoginSettings)
+
+// WithLoginContext returns a function that sets the Context setting on login.
+func WithLoginContext(context context.Context) LoginOption {
+	return func(settings *LoginSettings) {
+		settings.Context = context
+	}
+}
+
+// WithLoginHostname returns a function that sets the Hostname setting on login.
+func WithLoginHostname(hostname string) LoginOption {
+	return func(settings *LoginSettings) {
+		settings.Hostname = hostname
+	}
+}
+
+// WithLoginUsername returns a function that sets the Username setting on login.
+func WithLoginUsername(username string) LoginOption {
+	return func(settings *LoginSettings) {
+		settings.Username = username
+	}
+}
+
+// WithLoginSecret returns a function that sets the Secret setting on login.
+// The secret is taken as an identity token if no username is set.
+func WithLoginSecret(secret string) LoginOption {
+	return func(settings *LoginSettings) {
+		settings.Secret = secret
+	}
+}
+
+// WithLoginIdentityToken returns a function that sets the IdentityToken
+// setting on login. Identity tokens are OAuth2 refresh tokens exchanged for
+// access tokens instead of using basic auth.
+func WithLoginIdentityToken(token string) LoginOption {
+	return func(settings *LoginSettings) {
+		settings.IdentityToken = token
+	}
+}
+
+// WithLoginInsecure returns a function that sets the Insecure setting to true
+// on login.
+func WithLoginInsecure() LoginOption {
+	return func(settings *LoginSettings) {
+		settings.Insecure = true
+	}
+}
+
+// WithLoginPlainHTTP returns a function that sets the PlainHTTP setting to
+// true on login.
+func WithLoginPlainHTTP() LoginOption {
+	return func(settings *LoginSettings) {
+		settings.PlainHTTP = true
+	}
+}
+
+// WithLoginClient returns a function that sets the Client setting on login.
+// The credential is verified with the client, which carries the transport
+// settings of the registry such as the client certificates.
+func WithLoginClient(client *http.Client) LoginOption {
+	return func(settings *LoginSettings) {
+		settings.Client = client
+	}
+}
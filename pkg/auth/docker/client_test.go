@@ -2,17 +2,27 @@ package docker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"testing"
 	"time"
 
+	"github.com/deislabs/oras/pkg/auth"
+
 	"github.com/docker/distribution/configuration"
 	"github.com/docker/distribution/registry"
 	_ "github.com/docker/distribution/registry/auth/htpasswd"
 	_ "github.com/docker/distribution/registry/storage/driver/inmemory"
+	"github.com/docker/docker-credential-helpers/client"
+	helpers "github.com/docker/docker-credential-helpers/credentials"
 	"github.com/phayes/freeport"
 	"github.com/stretchr/testify/suite"
 	"golang.org/x/crypto/bcrypt"
@@ -75,6 +85,15 @@ func (suite *DockerClientTestSuite) SetupSuite() {
 
 	// Start Docker registry
 	go dockerRegistry.ListenAndServe()
+
+	// Wait for the registry to accept connections
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("tcp", suite.DockerRegistryHost); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
 }
 
 func (suite *DockerClientTestSuite) TearDownSuite() {
@@ -89,7 +108,36 @@ func (suite *DockerClientTestSuite) Test_0_Login() {
 
 	err = suite.Client.Login(newContext(), suite.DockerRegistryHost, testUsername, testPassword, false)
 	suite.Nil(err, "no error logging into registry with valid credentials")
+
+	err = suite.Client.LoginWithOpts(
+		auth.WithLoginHostname(suite.DockerRegistryHost),
+		auth.WithLoginUsername(testUsername),
+		auth.WithLoginIdentityToken("token"),
+	)
+	suite.Equal(auth.ErrConflictingSecrets, err, "error logging in with both username and identity token")
+}
+
+func (suite *DockerClientTestSuite) Test_1_Credentials() {
+	creds, err := suite.Client.Credentials(newContext())
+	suite.Nil(err, "no error listing credentials")
+	suite.Equal([]auth.Credential{{
+		ServerAddress: suite.DockerRegistryHost,
+		Username:      testUsername,
+		Password:      testPassword,
+	}}, creds, "credentials match")
+
+	_, err = suite.Client.Credentials(newContext(), "non-existing-host:42")
+	suite.True(errors.Is(err, auth.ErrNotLoggedIn), "error getting credentials not stored")
+
+	client, err := NewClient(filepath.Join(suite.TempTestDir, "imported.config"))
+	suite.Nil(err, "no error creating client")
+	err = client.StoreCredentials(newContext(), creds...)
+	suite.Nil(err, "no error storing credentials")
+	imported, err := client.Credentials(newContext(), suite.DockerRegistryHost)
+	suite.Nil(err, "no error getting imported credentials")
+	suite.Equal(creds, imported, "imported credentials match")
 }
+
 func (suite *DockerClientTestSuite) Test_2_Logout() {
 	var err error
 
@@ -100,6 +148,236 @@ func (suite *DockerClientTestSuite) Test_2_Logout() {
 	suite.Nil(err, "no error logging out of registry")
 }
 
+func (suite *DockerClientTestSuite) Test_3_CredentialHelper() {
+	if runtime.GOOS == "windows" {
+		suite.T().Skip("the fake credential helper is a symbolic link to the test binary")
+	}
+	bin := filepath.Join(suite.TempTestDir, "bin")
+	err := os.Mkdir(bin, 0755)
+	suite.Nil(err, "no error creating directory of the credential helper")
+	executable, err := os.Executable()
+	suite.Nil(err, "no error locating the test binary")
+	err = os.Symlink(executable, filepath.Join(bin, "docker-credential-"+fileHelperName))
+	suite.Nil(err, "no error installing the credential helper")
+	defer setenv(suite.T(), "PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))()
+
+	for name, config := range map[string]string{
+		"credHelpers": fmt.Sprintf(`{"credHelpers": {%q: %q}}`, suite.DockerRegistryHost, fileHelperName),
+		"credsStore":  fmt.Sprintf(`{"credsStore": %q}`, fileHelperName),
+	} {
+		store := filepath.Join(suite.TempTestDir, name+".creds")
+		defer setenv(suite.T(), envFileHelperPath, store)()
+		configPath := filepath.Join(suite.TempTestDir, name+".config")
+		err := ioutil.WriteFile(configPath, []byte(config), 0644)
+		suite.Nil(err, "no error writing the config of %s", name)
+		client, err := NewClient(configPath)
+		suite.Nil(err, "no error creating client with %s", name)
+
+		err = client.Login(newContext(), suite.DockerRegistryHost, testUsername, testPassword, false)
+		suite.Nil(err, "no error logging in with %s", name)
+		content, err := ioutil.ReadFile(configPath)
+		suite.Nil(err, "no error reading the config of %s", name)
+		suite.NotContains(string(content), testUsername, "credentials not in the config with %s", name)
+		content, err = ioutil.ReadFile(store)
+		suite.Nil(err, "no error reading the store of the helper with %s", name)
+		suite.Contains(string(content), testUsername, "credentials in the helper with %s", name)
+
+		username, secret, err := client.(*Client).Credential(suite.DockerRegistryHost)
+		suite.Nil(err, "no error getting the credential with %s", name)
+		suite.Equal(testUsername, username, "username from the helper with %s", name)
+		suite.Equal(testPassword, secret, "password from the helper with %s", name)
+
+		err = client.Logout(newContext(), suite.DockerRegistryHost)
+		suite.Nil(err, "no error logging out with %s", name)
+		_, err = client.Credentials(newContext(), suite.DockerRegistryHost)
+		suite.True(errors.Is(err, auth.ErrNotLoggedIn), "credentials erased from the helper with %s", name)
+	}
+}
+
 func TestDockerClientTestSuite(t *testing.T) {
 	suite.Run(t, new(DockerClientTestSuite))
 }
+
+// fileHelperName is the name of the credential helper run by the test binary,
+// which keeps the credentials in the JSON file of envFileHelperPath.
+const (
+	fileHelperName    = "fake"
+	envFileHelperPath = "ORAS_TEST_CREDENTIAL_HELPER_PATH"
+)
+
+func TestMain(m *testing.M) {
+	if filepath.Base(os.Args[0]) == "docker-credential-"+fileHelperName {
+		helpers.Serve(fileHelper(os.Getenv(envFileHelperPath)))
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// fileHelper is a credential helper keeping the credentials in a JSON file.
+type fileHelper string
+
+func (h fileHelper) load() (map[string]helpers.Credentials, error) {
+	creds := make(map[string]helpers.Credentials)
+	content, err := ioutil.ReadFile(string(h))
+	if os.IsNotExist(err) {
+		return creds, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return creds, json.Unmarshal(content, &creds)
+}
+
+func (h fileHelper) save(creds map[string]helpers.Credentials) error {
+	content, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(string(h), content, 0600)
+}
+
+func (h fileHelper) Add(cred *helpers.Credentials) error {
+	creds, err := h.load()
+	if err != nil {
+		return err
+	}
+	creds[cred.ServerURL] = *cred
+	return h.save(creds)
+}
+
+func (h fileHelper) Delete(serverURL string) error {
+	creds, err := h.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := creds[serverURL]; !ok {
+		return helpers.NewErrCredentialsNotFound()
+	}
+	delete(creds, serverURL)
+	return h.save(creds)
+}
+
+func (h fileHelper) Get(serverURL string) (string, string, error) {
+	creds, err := h.load()
+	if err != nil {
+		return "", "", err
+	}
+	cred, ok := creds[serverURL]
+	if !ok {
+		return "", "", helpers.NewErrCredentialsNotFound()
+	}
+	return cred.Username, cred.Secret, nil
+}
+
+func (h fileHelper) List() (map[string]string, error) {
+	creds, err := h.load()
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]string)
+	for serverURL, cred := range creds {
+		all[serverURL] = cred.Username
+	}
+	return all, nil
+}
+
+// setenv sets the environment variable, and returns a function restoring it.
+func setenv(t *testing.T, key, value string) func() {
+	old, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// fakeHelper is a credential helper program keeping the credentials in memory.
+type fakeHelper struct {
+	creds  map[string]helpers.Credentials
+	action string
+	input  []byte
+}
+
+func (h *fakeHelper) program(args ...string) client.Program {
+	h.action = args[0]
+	return h
+}
+
+func (h *fakeHelper) Input(in io.Reader) {
+	h.input, _ = ioutil.ReadAll(in)
+}
+
+func (h *fakeHelper) Output() ([]byte, error) {
+	switch h.action {
+	case "store":
+		var creds helpers.Credentials
+		if err := json.Unmarshal(h.input, &creds); err != nil {
+			return nil, err
+		}
+		h.creds[creds.ServerURL] = creds
+		return nil, nil
+	case "get":
+		creds, ok := h.creds[string(h.input)]
+		if !ok {
+			return []byte(helpers.NewErrCredentialsNotFound().Error()), errors.New("exit status 1")
+		}
+		return json.Marshal(creds)
+	case "erase":
+		delete(h.creds, string(h.input))
+		return nil, nil
+	case "list":
+		all := make(map[string]string)
+		for serverURL, creds := range h.creds {
+			all[serverURL] = creds.Username
+		}
+		return json.Marshal(all)
+	}
+	return nil, fmt.Errorf("unknown action %q", h.action)
+}
+
+func TestKeyringStore(t *testing.T) {
+	helper := &fakeHelper{
+		creds: make(map[string]helpers.Credentials),
+	}
+	cli := NewClientWithStore(&keyringStore{
+		program: helper.program,
+	})
+	creds := []auth.Credential{{
+		ServerAddress: "localhost:5000",
+		Username:      testUsername,
+		Password:      testPassword,
+	}, {
+		ServerAddress: "registry.example.com",
+		IdentityToken: "token",
+	}}
+	if err := cli.StoreCredentials(newContext(), creds...); err != nil {
+		t.Fatalf("StoreCredentials() error = %v", err)
+	}
+	if got := helper.creds["registry.example.com"].Username; got != tokenUsername {
+		t.Errorf("identity token stored with username %q, want %q", got, tokenUsername)
+	}
+
+	stored, err := cli.Credentials(newContext())
+	if err != nil {
+		t.Fatalf("Credentials() error = %v", err)
+	}
+	if !reflect.DeepEqual(stored, creds) {
+		t.Errorf("Credentials() = %v, want %v", stored, creds)
+	}
+	username, secret, err := cli.(*Client).Credential("registry.example.com")
+	if err != nil || username != "" || secret != "token" {
+		t.Errorf("Credential() = %q, %q, %v, want the identity token", username, secret, err)
+	}
+
+	if err := cli.Logout(newContext(), "localhost:5000"); err != nil {
+		t.Errorf("Logout() error = %v", err)
+	}
+	if err := cli.Logout(newContext(), "localhost:5000"); !errors.Is(err, auth.ErrNotLoggedIn) {
+		t.Errorf("Logout() error = %v, want %v", err, auth.ErrNotLoggedIn)
+	}
+}
@@ -0,0 +1,174 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"sort"
+
+	"github.com/deislabs/oras/pkg/auth"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/docker/cli/cli/config/credentials"
+	ctypes "github.com/docker/cli/cli/config/types"
+	"github.com/pkg/errors"
+)
+
+// configStore stores the credentials in the docker config files.
+type configStore struct {
+	configs []*configfile.ConfigFile
+}
+
+// NewConfigStore creates a credential store based on provided config paths.
+// If not config path is provided, the default path is used.
+// Credentials are read from the first config and fall backs to next.
+// All changes will only be written to the first config file.
+func NewConfigStore(configPaths ...string) (auth.CredentialStore, error) {
+	if len(configPaths) == 0 {
+		cfg, err := config.Load(config.Dir())
+		if err != nil {
+			return nil, err
+		}
+		if !cfg.ContainsAuth() {
+			cfg.CredentialsStore = credentials.DetectDefaultStore(cfg.CredentialsStore)
+		}
+
+		return &configStore{
+			configs: []*configfile.ConfigFile{cfg},
+		}, nil
+	}
+
+	var configs []*configfile.ConfigFile
+	for _, path := range configPaths {
+		cfg, err := loadConfigFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, path)
+		}
+		configs = append(configs, cfg)
+	}
+
+	return &configStore{
+		configs: configs,
+	}, nil
+}
+
+// Get returns the credential of the server address from the first config
+// holding it.
+func (s *configStore) Get(_ context.Context, serverAddress string) (auth.Credential, error) {
+	var err error
+	for _, cfg := range s.configs {
+		var cred ctypes.AuthConfig
+		cred, err = cfg.GetCredentialsStore(serverAddress).Get(serverAddress)
+		if err != nil {
+			// fall back to next config
+			continue
+		}
+		if cred.Username == "" && cred.Password == "" && cred.IdentityToken == "" {
+			// fall back to next config
+			continue
+		}
+		return newCredential(serverAddress, cred), nil
+	}
+	return auth.Credential{}, err
+}
+
+// Store stores the credential in the primary config.
+func (s *configStore) Store(_ context.Context, cred auth.Credential) error {
+	return s.primaryCredentialsStore(cred.ServerAddress).Store(ctypes.AuthConfig{
+		ServerAddress: cred.ServerAddress,
+		Username:      cred.Username,
+		Password:      cred.Password,
+		IdentityToken: cred.IdentityToken,
+	})
+}
+
+// Erase removes the credential from the primary config only as backups are
+// read-only.
+func (s *configStore) Erase(_ context.Context, serverAddress string) error {
+	var loggedIn bool
+	for _, cfg := range s.configs {
+		if isLoggedIn(cfg, serverAddress) {
+			loggedIn = true
+			break
+		}
+	}
+	if !loggedIn {
+		return auth.ErrNotLoggedIn
+	}
+	return s.primaryCredentialsStore(serverAddress).Erase(serverAddress)
+}
+
+// List returns the credentials of all the configs. The credentials of the
+// first configs take precedence.
+func (s *configStore) List(_ context.Context) ([]auth.Credential, error) {
+	stored := make(map[string]auth.Credential)
+	for i := len(s.configs) - 1; i >= 0; i-- {
+		all, err := s.configs[i].GetAllCredentials()
+		if err != nil {
+			return nil, errors.Wrap(err, s.configs[i].Filename)
+		}
+		for hostname, cred := range all {
+			if cred.Username == "" && cred.Password == "" && cred.IdentityToken == "" {
+				continue
+			}
+			stored[hostname] = newCredential(hostname, cred)
+		}
+	}
+
+	creds := make([]auth.Credential, 0, len(stored))
+	for _, cred := range stored {
+		creds = append(creds, cred)
+	}
+	sort.Slice(creds, func(i, j int) bool {
+		return creds[i].ServerAddress < creds[j].ServerAddress
+	})
+	return creds, nil
+}
+
+func (s *configStore) primaryCredentialsStore(hostname string) credentials.Store {
+	return s.configs[0].GetCredentialsStore(hostname)
+}
+
+// newCredential converts the docker auth config of the hostname.
+func newCredential(hostname string, cred ctypes.AuthConfig) auth.Credential {
+	return auth.Credential{
+		ServerAddress: hostname,
+		Username:      cred.Username,
+		Password:      cred.Password,
+		IdentityToken: cred.IdentityToken,
+	}
+}
+
+// isLoggedIn checks if the config holds credentials of the hostname, either in
+// the plaintext auths or in the configured credential helper.
+func isLoggedIn(config *configfile.ConfigFile, hostname string) bool {
+	if _, ok := config.AuthConfigs[hostname]; ok {
+		return true
+	}
+	auth, err := config.GetCredentialsStore(hostname).Get(hostname)
+	if err != nil {
+		return false
+	}
+	return auth.Username != "" || auth.Password != "" || auth.IdentityToken != ""
+}
+
+// loadConfigFile reads the configuration files from the given path.
+func loadConfigFile(path string) (*configfile.ConfigFile, error) {
+	cfg := configfile.New(path)
+	if _, err := os.Stat(path); err == nil {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		if err := cfg.LoadFromReader(file); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if !cfg.ContainsAuth() {
+		cfg.CredentialsStore = credentials.DetectDefaultStore(cfg.CredentialsStore)
+	}
+	return cfg, nil
+}
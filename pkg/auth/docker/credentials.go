@@ -0,0 +1,47 @@
+package docker
+
+import (
+	"context"
+
+	"github.com/deislabs/oras/pkg/auth"
+
+	"github.com/pkg/errors"
+)
+
+// Credentials returns the stored credentials of the hostnames, or all the
+// stored credentials if no hostname is given.
+func (c *Client) Credentials(ctx context.Context, hostnames ...string) ([]auth.Credential, error) {
+	if len(hostnames) == 0 {
+		return c.store.List(ctx)
+	}
+	var creds []auth.Credential
+	for _, hostname := range hostnames {
+		cred, err := c.store.Get(ctx, resolveHostname(hostname))
+		if err != nil {
+			return nil, errors.Wrap(err, hostname)
+		}
+		if cred.Username == "" && cred.Password == "" && cred.IdentityToken == "" {
+			return nil, errors.Wrap(auth.ErrNotLoggedIn, hostname)
+		}
+		creds = append(creds, cred)
+	}
+	return creds, nil
+}
+
+// StoreCredentials stores the credentials in the store as is, without
+// logging in.
+func (c *Client) StoreCredentials(ctx context.Context, creds ...auth.Credential) error {
+	for _, cred := range creds {
+		if cred.ServerAddress == "" {
+			return errors.New("credential without server address")
+		}
+		if cred.IdentityToken != "" && (cred.Username != "" || cred.Password != "") {
+			return errors.Wrap(auth.ErrConflictingSecrets, cred.ServerAddress)
+		}
+		cred.ServerAddress = resolveHostname(cred.ServerAddress)
+		if err := c.store.Store(ctx, cred); err != nil {
+			return errors.Wrap(err, cred.ServerAddress)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"context"
+	"sort"
+
+	"github.com/deislabs/oras/pkg/auth"
+
+	"github.com/docker/cli/cli/config/credentials"
+	"github.com/docker/docker-credential-helpers/client"
+	helpers "github.com/docker/docker-credential-helpers/credentials"
+	"github.com/pkg/errors"
+)
+
+// tokenUsername is the username of the identity tokens stored by the
+// credential helpers, as stored by the docker CLI.
+const tokenUsername = "<token>"
+
+// keyringStore stores the credentials in the native keyring of the OS through
+// a docker credential helper program.
+type keyringStore struct {
+	program client.ProgramFunc
+}
+
+// NewKeyringStore creates a credential store in the native keyring of the OS,
+// i.e. the macOS keychain, the Windows credential manager, or the secret
+// service on Linux, without touching the docker config files. The keyring is
+// accessed through the `docker-credential-<helper>` program, which defaults
+// to the one of the platform, e.g. `docker-credential-osxkeychain`.
+func NewKeyringStore(helper string) (auth.CredentialStore, error) {
+	if helper == "" {
+		if helper = credentials.DetectDefaultStore(""); helper == "" {
+			return nil, errors.New("no credential helper of the native keyring found in PATH")
+		}
+	}
+	return &keyringStore{
+		program: client.NewShellProgramFunc("docker-credential-" + helper),
+	}, nil
+}
+
+// Get returns the credential of the server address from the keyring.
+func (s *keyringStore) Get(_ context.Context, serverAddress string) (auth.Credential, error) {
+	creds, err := client.Get(s.program, serverAddress)
+	if err != nil {
+		if helpers.IsErrCredentialsNotFound(err) {
+			return auth.Credential{}, nil
+		}
+		return auth.Credential{}, err
+	}
+	cred := auth.Credential{
+		ServerAddress: serverAddress,
+	}
+	if creds.Username == tokenUsername {
+		cred.IdentityToken = creds.Secret
+	} else {
+		cred.Username = creds.Username
+		cred.Password = creds.Secret
+	}
+	return cred, nil
+}
+
+// Store stores the credential in the keyring.
+func (s *keyringStore) Store(_ context.Context, cred auth.Credential) error {
+	creds := &helpers.Credentials{
+		ServerURL: cred.ServerAddress,
+		Username:  cred.Username,
+		Secret:    cred.Password,
+	}
+	if cred.IdentityToken != "" {
+		creds.Username = tokenUsername
+		creds.Secret = cred.IdentityToken
+	}
+	return client.Store(s.program, creds)
+}
+
+// Erase removes the credential of the server address from the keyring.
+func (s *keyringStore) Erase(ctx context.Context, serverAddress string) error {
+	cred, err := s.Get(ctx, serverAddress)
+	if err != nil {
+		return err
+	}
+	if cred.Username == "" && cred.Password == "" && cred.IdentityToken == "" {
+		return auth.ErrNotLoggedIn
+	}
+	return client.Erase(s.program, serverAddress)
+}
+
+// List returns the credentials in the keyring, which are shared with the
+// docker CLI if it uses the same credential helper.
+func (s *keyringStore) List(ctx context.Context) ([]auth.Credential, error) {
+	all, err := client.List(s.program)
+	if err != nil {
+		return nil, err
+	}
+	creds := make([]auth.Credential, 0, len(all))
+	for serverAddress := range all {
+		cred, err := s.Get(ctx, serverAddress)
+		if err != nil {
+			return nil, errors.Wrap(err, serverAddress)
+		}
+		if cred.Username == "" && cred.Password == "" && cred.IdentityToken == "" {
+			continue
+		}
+		creds = append(creds, cred)
+	}
+	sort.Slice(creds, func(i, j int) bool {
+		return creds[i].ServerAddress < creds[j].ServerAddress
+	})
+	return creds, nil
+}
@@ -2,44 +2,143 @@ package docker
 
 import (
 	"context"
+	"net/http"
 
-	ctypes "github.com/docker/cli/cli/config/types"
+	"github.com/deislabs/oras/pkg/auth"
+
+	"github.com/containerd/containerd/remotes/docker"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/registry"
+	"github.com/pkg/errors"
 )
 
 // Login logs in to a docker registry identified by the hostname.
 func (c *Client) Login(ctx context.Context, hostname, username, secret string, insecure bool) error {
-	hostname = resolveHostname(hostname)
+	options := []auth.LoginOption{
+		auth.WithLoginContext(ctx),
+		auth.WithLoginHostname(hostname),
+		auth.WithLoginUsername(username),
+		auth.WithLoginSecret(secret),
+	}
+	if insecure {
+		options = append(options, auth.WithLoginInsecure())
+	}
+	return c.LoginWithOpts(options...)
+}
+
+// LoginWithOpts logs in to a docker registry with the given settings.
+// If an identity token is provided, or the secret is given without a username,
+// the token is stored as the identity token and later exchanged for access
+// tokens via the OAuth2 refresh token grant.
+func (c *Client) LoginWithOpts(options ...auth.LoginOption) error {
+	settings := &auth.LoginSettings{
+		Context: context.Background(),
+	}
+	for _, option := range options {
+		option(settings)
+	}
+	if settings.IdentityToken != "" && (settings.Username != "" || settings.Secret != "") {
+		return auth.ErrConflictingSecrets
+	}
+
+	hostname := resolveHostname(settings.Hostname)
 	cred := types.AuthConfig{
-		Username:      username,
+		Username:      settings.Username,
 		ServerAddress: hostname,
 	}
-	if username == "" {
-		cred.IdentityToken = secret
-	} else {
-		cred.Password = secret
+	switch {
+	case settings.IdentityToken != "":
+		cred.IdentityToken = settings.IdentityToken
+	case settings.Username == "":
+		cred.IdentityToken = settings.Secret
+	default:
+		cred.Password = settings.Secret
 	}
 
-	opts := registry.ServiceOptions{}
+	if settings.Client != nil || settings.PlainHTTP {
+		if err := verifyCredential(settings, cred); err != nil {
+			return err
+		}
+	} else {
+		opts := registry.ServiceOptions{}
 
-	if insecure {
-		opts.InsecureRegistries = []string{hostname}
+		if settings.Insecure {
+			opts.InsecureRegistries = []string{hostname}
+		}
+
+		// Login to ensure valid credential
+		remote, err := registry.NewService(opts)
+		if err != nil {
+			return err
+		}
+		if _, token, err := remote.Auth(settings.Context, &cred, "oras"); err != nil {
+			return err
+		} else if token != "" {
+			cred.Username = ""
+			cred.Password = ""
+			cred.IdentityToken = token
+		}
 	}
 
-	// Login to ensure valid credential
-	remote, err := registry.NewService(opts)
-	if err != nil {
-		return err
+	// Store credential
+	return c.store.Store(settings.Context, auth.Credential{
+		ServerAddress: hostname,
+		Username:      cred.Username,
+		Password:      cred.Password,
+		IdentityToken: cred.IdentityToken,
+	})
+}
+
+// verifyCredential ensures the credential is valid by accessing the registry
+// API with the client in the settings.
+func verifyCredential(settings *auth.LoginSettings, cred types.AuthConfig) error {
+	client := settings.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	host := settings.Hostname
+	if resolveHostname(host) == registry.IndexServer {
+		host = registry.DefaultV2Registry.Host
 	}
-	if _, token, err := remote.Auth(ctx, &cred, "oras"); err != nil {
-		return err
-	} else if token != "" {
-		cred.Username = ""
-		cred.Password = ""
-		cred.IdentityToken = token
+	scheme := "https"
+	if settings.PlainHTTP {
+		scheme = "http"
 	}
+	authorizer := docker.NewDockerAuthorizer(
+		docker.WithAuthClient(client),
+		docker.WithAuthCreds(func(string) (string, string, error) {
+			if cred.IdentityToken != "" {
+				return "", cred.IdentityToken, nil
+			}
+			return cred.Username, cred.Password, nil
+		}),
+	)
 
-	// Store credential
-	return c.primaryCredentialsStore(hostname).Store(ctypes.AuthConfig(cred))
+	ctx := settings.Context
+	var responses []*http.Response
+	for {
+		req, err := http.NewRequest(http.MethodGet, scheme+"://"+host+"/v2/", nil)
+		if err != nil {
+			return err
+		}
+		if err := authorizer.Authorize(ctx, req); err != nil {
+			return err
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return nil
+		case resp.StatusCode == http.StatusUnauthorized && len(responses) == 0:
+			responses = append(responses, resp)
+			if err := authorizer.AddResponses(ctx, responses); err != nil {
+				return err
+			}
+		default:
+			return errors.Errorf("login attempt to %s failed with status: %s", req.URL, resp.Status)
+		}
+	}
 }
@@ -6,7 +6,6 @@ import (
 
 	"github.com/containerd/containerd/remotes"
 	"github.com/containerd/containerd/remotes/docker"
-	ctypes "github.com/docker/cli/cli/config/types"
 	"github.com/docker/docker/registry"
 )
 
@@ -20,28 +19,17 @@ func (c *Client) Resolver(_ context.Context, client *http.Client, plainHTTP bool
 }
 
 // Credential returns the login credential of the request host.
+// Identity tokens are returned with an empty username so that the authorizer
+// exchanges them via the OAuth2 refresh token grant instead of basic auth.
 func (c *Client) Credential(hostname string) (string, string, error) {
-	hostname = resolveHostname(hostname)
-	var (
-		auth ctypes.AuthConfig
-		err  error
-	)
-	for _, cfg := range c.configs {
-		auth, err = cfg.GetAuthConfig(hostname)
-		if err != nil {
-			// fall back to next config
-			continue
-		}
-		if auth.IdentityToken != "" {
-			return "", auth.IdentityToken, nil
-		}
-		if auth.Username == "" && auth.Password == "" {
-			// fall back to next config
-			continue
-		}
-		return auth.Username, auth.Password, nil
+	cred, err := c.store.Get(context.Background(), resolveHostname(hostname))
+	if err != nil {
+		return "", "", err
 	}
-	return "", "", err
+	if cred.IdentityToken != "" {
+		return "", cred.IdentityToken, nil
+	}
+	return cred.Username, cred.Password, nil
 }
 
 // resolveHostname resolves Docker specific hostnames
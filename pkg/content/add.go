@@ -0,0 +1,86 @@
+package content
+
+import (
+	"context"
+	"os"
+	"runtime"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+// FileRef is a file added by AddAll.
+type FileRef struct {
+	Name      string
+	MediaType string
+	// Path is the path of the file or directory, Name if empty.
+	Path string
+	// ShardSize splits the regular files larger than it into shards if
+	// positive, as AddShards.
+	ShardSize int64
+	// ChunkSize splits the regular files into content-defined chunks of this
+	// average size if positive, as AddChunked.
+	ChunkSize int64
+}
+
+// AddAll adds the files, hashing up to concurrency files in parallel, or as
+// many as the CPUs if concurrency is not positive, as computing the digests is
+// the bottleneck of adding large files. The descriptors of each file are
+// returned in the order of the files.
+func (s *FileStore) AddAll(ctx context.Context, files []FileRef, concurrency int) ([][]ocispec.Descriptor, error) {
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	var (
+		descs   = make([][]ocispec.Descriptor, len(files))
+		eg      errgroup.Group
+		limiter = semaphore.NewWeighted(int64(concurrency))
+	)
+	for i, file := range files {
+		i, file := i, file
+		if err := limiter.Acquire(ctx, 1); err != nil {
+			eg.Wait()
+			return nil, err
+		}
+		eg.Go(func() error {
+			defer limiter.Release(1)
+			added, err := s.addFile(file)
+			if err != nil {
+				return err
+			}
+			descs[i] = added
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return descs, nil
+}
+
+// addFile adds the file, split into chunks or shards if requested.
+func (s *FileStore) addFile(file FileRef) ([]ocispec.Descriptor, error) {
+	if file.ChunkSize > 0 || file.ShardSize > 0 {
+		path := file.Path
+		if path == "" {
+			path = file.Name
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case info.IsDir():
+		case file.ChunkSize > 0:
+			return s.AddChunked(file.Name, file.MediaType, file.Path, file.ChunkSize)
+		case info.Size() > file.ShardSize:
+			return s.AddShards(file.Name, file.MediaType, file.Path, file.ShardSize)
+		}
+	}
+	desc, err := s.Add(file.Name, file.MediaType, file.Path)
+	if err != nil {
+		return nil, err
+	}
+	return []ocispec.Descriptor{desc}, nil
+}
@@ -0,0 +1,354 @@
+package content
+
+import (
+	"encoding/json"
+	"hash"
+	"io"
+	"io/ioutil"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strings"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// Media types of the files split into content-defined chunks (experimental).
+const (
+	// ChunkMediaType is the media type of a chunk of one or more files.
+	ChunkMediaType = "application/vnd.oras.chunk.v1"
+	// ChunkRecipeMediaType is the media type of the ChunkRecipe of a file.
+	ChunkRecipeMediaType = "application/vnd.oras.chunk.recipe.v1+json"
+)
+
+// DefaultChunkAvgSize is the default average size of content-defined chunks.
+const DefaultChunkAvgSize = 4 * 1024 * 1024
+
+// ChunkRecipe describes how a file is reassembled from its chunks.
+type ChunkRecipe struct {
+	// Name is the name of the file.
+	Name string `json:"name"`
+	// MediaType is the media type the file would have been pushed with.
+	MediaType string `json:"mediaType"`
+	// Digest and Size are the ones of the whole file.
+	Digest digest.Digest `json:"digest"`
+	Size   int64         `json:"size"`
+	// Annotations are the annotations of the file, such as its attributes.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Chunks are the chunks of the file in order. The same chunk may occur
+	// several times.
+	Chunks []ChunkRef `json:"chunks"`
+}
+
+// ChunkRef refers to a chunk of a file.
+type ChunkRef struct {
+	Digest digest.Digest `json:"digest"`
+	Size   int64         `json:"size"`
+}
+
+// AddChunked adds a file reference split into chunks at content-defined
+// boundaries, of avgSize bytes on average, so that the unchanged parts of a
+// new version of the file result in the same chunks, which are not uploaded
+// again. The returned descriptors are the recipe of the file, followed by its
+// distinct chunks. Chunks are named `<name>.chunk-<encoded digest>`, and the
+// recipe `<name>.chunks.json`. The file is reassembled by JoinChunks after
+// pulling.
+func (s *FileStore) AddChunked(name, mediaType, path string, avgSize int64) ([]ocispec.Descriptor, error) {
+	if avgSize < 64 {
+		return nil, errors.Wrapf(ErrUnsupportedSize, "average chunk size %d", avgSize)
+	}
+	if path == "" {
+		path = name
+	}
+	path = s.MapPath(name, path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errors.Errorf("%s: directories cannot be chunked", name)
+	}
+	if mediaType == "" {
+		mediaType = DefaultBlobMediaType
+	}
+
+	recipe := ChunkRecipe{
+		Name:      name,
+		MediaType: mediaType,
+		Size:      info.Size(),
+	}
+	if s.PreserveAttributes {
+		recipe.Annotations = s.fileAttributes(info)
+	}
+	var (
+		chunks []ocispec.Descriptor
+		seen   = make(map[digest.Digest]bool)
+		offset int64
+	)
+	recipe.Digest, err = splitChunks(file, avgSize, s.NewHash, func(size int64, dgst digest.Digest) error {
+		recipe.Chunks = append(recipe.Chunks, ChunkRef{
+			Digest: dgst,
+			Size:   size,
+		})
+		if !seen[dgst] {
+			seen[dgst] = true
+			chunkName := name + ".chunk-" + dgst.Encoded()
+			s.MapPath(chunkName, path)
+			s.shards.Store(chunkName, fileShard{offset: offset})
+			desc := ocispec.Descriptor{
+				MediaType: ChunkMediaType,
+				Digest:    dgst,
+				Size:      size,
+				Annotations: map[string]string{
+					ocispec.AnnotationTitle: chunkName,
+				},
+			}
+			s.set(desc)
+			chunks = append(chunks, desc)
+		}
+		offset += size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(recipe)
+	if err != nil {
+		return nil, err
+	}
+	recipeFile, err := s.tempFile()
+	if err != nil {
+		return nil, err
+	}
+	_, err = recipeFile.Write(content)
+	if closeErr := recipeFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	recipeName := name + ".chunks.json"
+	s.MapPath(recipeName, recipeFile.Name())
+	desc := ocispec.Descriptor{
+		MediaType: ChunkRecipeMediaType,
+		Digest:    digest.FromBytes(content),
+		Size:      int64(len(content)),
+		Annotations: map[string]string{
+			ocispec.AnnotationTitle: recipeName,
+		},
+	}
+	s.set(desc)
+	return append([]ocispec.Descriptor{desc}, chunks...), nil
+}
+
+// ChunkedFileName returns the name of the file of a recipe or a chunk, which
+// is the first file of a chunk shared by several files.
+func ChunkedFileName(desc ocispec.Descriptor) (string, bool) {
+	name, ok := ResolveName(desc)
+	if !ok {
+		return "", false
+	}
+	switch desc.MediaType {
+	case ChunkRecipeMediaType:
+		return strings.TrimSuffix(name, ".chunks.json"), true
+	case ChunkMediaType:
+		if i := strings.LastIndex(name, ".chunk-"); i >= 0 {
+			return name[:i], true
+		}
+	}
+	return "", false
+}
+
+// JoinChunks reassembles the files from the pulled recipes and chunks
+// described by descs, and removes the recipes and the chunks. The names of the
+// reassembled files are returned.
+func (s *FileStore) JoinChunks(descs []ocispec.Descriptor) ([]string, error) {
+	var (
+		recipes []string
+		chunks  = make(map[digest.Digest]string)
+	)
+	for _, desc := range descs {
+		name, ok := ResolveName(desc)
+		if !ok {
+			continue
+		}
+		switch desc.MediaType {
+		case ChunkRecipeMediaType:
+			recipes = append(recipes, s.ResolvePath(name))
+		case ChunkMediaType:
+			chunks[desc.Digest] = s.ResolvePath(name)
+		}
+	}
+	if len(recipes) == 0 {
+		return nil, nil
+	}
+
+	var names []string
+	for _, path := range recipes {
+		var recipe ChunkRecipe
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(content, &recipe); err != nil {
+			return nil, errors.Wrapf(ErrInvalidChunk, "%s: %v", path, err)
+		}
+		if err := s.joinChunks(recipe, chunks); err != nil {
+			return nil, err
+		}
+		names = append(names, recipe.Name)
+	}
+	for _, path := range recipes {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range chunks {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+func (s *FileStore) joinChunks(recipe ChunkRecipe, chunks map[digest.Digest]string) error {
+	path, err := s.resolveWritePath(recipe.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, rename, err := s.createReplacePath(path)
+	if err != nil {
+		return err
+	}
+	digester := newDigester(s.NewHash)
+	var size int64
+	for _, chunk := range recipe.Chunks {
+		chunkPath, ok := chunks[chunk.Digest]
+		if !ok {
+			err = errors.Wrapf(ErrInvalidChunk, "%s: missing chunk %s", recipe.Name, chunk.Digest)
+			break
+		}
+		var n int64
+		if n, err = copyFile(io.MultiWriter(file, digester.Hash()), chunkPath); err != nil {
+			break
+		}
+		size += n
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && (size != recipe.Size || digester.Digest() != recipe.Digest) {
+		err = errors.Wrapf(ErrInvalidChunk, "%s: digest mismatch", recipe.Name)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		s.tmpFiles.Delete(file.Name())
+		return err
+	}
+	if err := rename(); err != nil {
+		return err
+	}
+	if s.PreserveAttributes {
+		return restoreAttributes(path, ocispec.Descriptor{Annotations: recipe.Annotations})
+	}
+	return nil
+}
+
+func copyFile(w io.Writer, path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return io.Copy(w, file)
+}
+
+// splitChunks splits the content of r at content-defined boundaries found by
+// a gear rolling hash, calling fn with the size and the digest of each chunk.
+// Chunks are between a quarter and four times avgSize, except the last one.
+// The digest of the whole content is returned.
+func splitChunks(r io.Reader, avgSize int64, newHash func() hash.Hash, fn func(int64, digest.Digest) error) (digest.Digest, error) {
+	minSize, maxSize := avgSize/4, avgSize*4
+	// the boundaries are where the top log2(avgSize) bits of the hash are 0
+	maskBits := uint(bits.Len64(uint64(avgSize)) - 1)
+	mask := ^uint64(0) << (64 - maskBits)
+
+	contentDigester := newDigester(newHash)
+	chunkDigester := newDigester(newHash)
+	var (
+		rolling uint64
+		size    int64
+		buf     = make([]byte, 64*1024)
+	)
+	emit := func() error {
+		err := fn(size, chunkDigester.Digest())
+		chunkDigester = newDigester(newHash)
+		rolling, size = 0, 0
+		return err
+	}
+	for {
+		n, err := r.Read(buf)
+		data := buf[:n]
+		for len(data) > 0 {
+			cut := -1
+			for i, b := range data {
+				size++
+				rolling = rolling<<1 + gear[b]
+				if size >= maxSize || (size >= minSize && rolling&mask == 0) {
+					cut = i + 1
+					break
+				}
+			}
+			if cut < 0 {
+				chunkDigester.Hash().Write(data)
+				contentDigester.Hash().Write(data)
+				break
+			}
+			chunkDigester.Hash().Write(data[:cut])
+			contentDigester.Hash().Write(data[:cut])
+			if err := emit(); err != nil {
+				return "", err
+			}
+			data = data[cut:]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if size > 0 {
+		if err := emit(); err != nil {
+			return "", err
+		}
+	}
+	return contentDigester.Digest(), nil
+}
+
+// gear is the table of the gear rolling hash, generated with splitmix64 from
+// a fixed seed. It must never change, so that the chunk boundaries are stable
+// across versions.
+var gear = func() (table [256]uint64) {
+	x := uint64(0x6f7261732d636463) // "oras-cdc"
+	for i := range table {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return
+}()
@@ -0,0 +1,126 @@
+package content
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// Compression is the compression of a blob.
+type Compression string
+
+// Compressions
+const (
+	// CompressionNone leaves the content uncompressed.
+	CompressionNone Compression = "none"
+	// CompressionGzip compresses the content with gzip.
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd compresses the content with zstd.
+	CompressionZstd Compression = "zstd"
+)
+
+// ParseCompression parses the name of a compression.
+func ParseCompression(name string) (Compression, error) {
+	switch compression := Compression(name); compression {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+		return compression, nil
+	}
+	return "", errors.Errorf("unknown compression %q", name)
+}
+
+// MediaType returns the media type of content of the media type compressed
+// with the compression, which has the name of the compression as suffix, e.g.
+// application/vnd.oci.image.layer.v1.tar+gzip.
+func (c Compression) MediaType(mediaType string) string {
+	switch c {
+	case CompressionGzip, CompressionZstd:
+		return mediaType + "+" + string(c)
+	}
+	return mediaType
+}
+
+// NewWriter returns a writer compressing to w, which must be closed to flush
+// the content. The output is deterministic for the same content.
+func (c Compression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	switch c {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	case CompressionNone, "":
+		return nopWriteCloser{w}, nil
+	}
+	return nil, errors.Errorf("unknown compression %q", c)
+}
+
+// NewReader returns a reader decompressing r.
+func (c Compression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	switch c {
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case CompressionNone, "":
+		return ioutil.NopCloser(r), nil
+	}
+	return nil, errors.Errorf("unknown compression %q", c)
+}
+
+// compressionOf returns the compression of the blob described by desc.
+// Unpacked directories are compressed with gzip if not annotated.
+func compressionOf(desc ocispec.Descriptor) Compression {
+	if value, ok := desc.Annotations[AnnotationCompression]; ok {
+		return Compression(value)
+	}
+	if desc.Annotations[AnnotationUnpack] == "true" {
+		return CompressionGzip
+	}
+	return ""
+}
+
+// decompressFile decompresses the file to the writer, verifying the content
+// against the checksum if any.
+func decompressFile(w io.Writer, filename string, compression Compression, checksum string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	zr, err := compression.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	var verifier digest.Verifier
+	if checksum != "" {
+		if digest, err := digest.Parse(checksum); err == nil {
+			verifier = digest.Verifier()
+			w = io.MultiWriter(w, verifier)
+		}
+	}
+	if _, err := io.Copy(w, zr); err != nil {
+		return err
+	}
+	if verifier != nil && !verifier.Verified() {
+		return errors.New("content digest mismatch")
+	}
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,254 @@
+package content
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// ConflictAction is the resolution of a conflict.
+type ConflictAction string
+
+// Conflict actions
+const (
+	// ConflictOverwrite replaces the existing file with the incoming blob.
+	ConflictOverwrite ConflictAction = "overwrite"
+	// ConflictSkip keeps the existing file and drops the incoming blob.
+	ConflictSkip ConflictAction = "skip"
+	// ConflictRename writes the incoming blob next to the existing file, with
+	// the short digest of the blob added to its name.
+	ConflictRename ConflictAction = "rename"
+	// ConflictFail fails the write.
+	ConflictFail ConflictAction = "fail"
+)
+
+// ParseConflictAction parses the name of a conflict action.
+func ParseConflictAction(name string) (ConflictAction, error) {
+	switch action := ConflictAction(name); action {
+	case ConflictOverwrite, ConflictSkip, ConflictRename, ConflictFail:
+		return action, nil
+	}
+	return "", errors.Errorf("unknown conflict action %q", name)
+}
+
+// Conflict describes a blob to be written to a path which is already taken,
+// either by another blob written by the store, or by a different file.
+type Conflict struct {
+	// Name is the name of the incoming blob.
+	Name string
+	// Path is the path the incoming blob would be written to.
+	Path string
+	// Incoming describes the incoming blob.
+	Incoming ocispec.Descriptor
+	// Existing describes the content at the path.
+	Existing ocispec.Descriptor
+	// Written is set if the existing content is written by the store, rather
+	// than found on the disk.
+	Written bool
+}
+
+// ConflictResolver decides how a conflict is resolved. It is called
+// sequentially.
+type ConflictResolver func(Conflict) (ConflictAction, error)
+
+// RenamedPath returns the path a conflicting blob is renamed to, which has
+// the short digest of the blob inserted before the extension.
+func RenamedPath(path string, desc ocispec.Descriptor) string {
+	encoded := desc.Digest.Encoded()
+	if len(encoded) > 12 {
+		encoded = encoded[:12]
+	}
+	ext := filepath.Ext(path)
+	if ext == filepath.Base(path) {
+		ext = ""
+	}
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(path, ext), encoded, ext)
+}
+
+// resolveConflict checks whether writing the blob to the path conflicts, and
+// returns the path resolved, or an empty path if the blob is skipped. replace
+// is set if the path is being written by another blob, which is replaced on
+// commit rather than written over concurrently.
+func (s *FileStore) resolveConflict(name, path string, desc ocispec.Descriptor) (resolved string, replace bool, err error) {
+	s.conflictLock.Lock()
+	defer s.conflictLock.Unlock()
+
+	conflict := Conflict{
+		Name:     name,
+		Path:     path,
+		Incoming: desc,
+	}
+	if value, ok := s.written.Load(path); ok {
+		conflict.Existing = value.(ocispec.Descriptor)
+		conflict.Written = true
+		if conflict.Existing.Digest == desc.Digest {
+			return path, false, nil
+		}
+	} else {
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				s.written.Store(path, desc)
+				return path, false, nil
+			}
+			return "", false, err
+		}
+		if !info.Mode().IsRegular() {
+			// left to fail on write
+			return path, false, nil
+		}
+		written := contentDigest(desc)
+		existing, err := describeFile(path, written.Algorithm())
+		if err != nil {
+			return "", false, err
+		}
+		if existing.Digest == written {
+			s.written.Store(path, desc)
+			return path, false, nil
+		}
+		conflict.Existing = existing
+	}
+
+	action, err := s.ResolveConflict(conflict)
+	if err != nil {
+		return "", false, err
+	}
+	switch action {
+	case ConflictOverwrite:
+		s.written.Store(path, desc)
+		return path, conflict.Written, nil
+	case ConflictSkip:
+		return "", false, nil
+	case ConflictRename:
+		renamed := RenamedPath(path, desc)
+		s.written.Store(renamed, desc)
+		s.renamed.Store(renamedKey(name, desc), renamed)
+		return renamed, false, nil
+	}
+	return "", false, errors.Wrapf(ErrConflict, "%s: existing %s, incoming %s", name, conflict.Existing.Digest, desc.Digest)
+}
+
+// contentDigest returns the digest of the content written for the blob, i.e.
+// of the decompressed content of compressed blobs, or an empty digest if it is
+// unknown.
+func contentDigest(desc ocispec.Descriptor) digest.Digest {
+	if compression := compressionOf(desc); compression == "" || compression == CompressionNone {
+		return desc.Digest
+	}
+	dgst, err := digest.Parse(desc.Annotations[AnnotationDigest])
+	if err != nil {
+		return ""
+	}
+	return dgst
+}
+
+// writeEntry writes the regular file of a tar entry of a directory blob,
+// resolving its conflicts as the ones of the blobs. The entry is written next
+// to the path, and put in place once the conflict is resolved.
+func (s *FileStore) writeEntry(name, path string, r io.Reader, perm os.FileMode) (string, error) {
+	file, err := createSiblingFile(path)
+	if err != nil {
+		return "", err
+	}
+	digester := newDigester(s.NewHash)
+	size, err := io.Copy(io.MultiWriter(file, digester.Hash()), r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), perm)
+	}
+	var resolved string
+	if err == nil {
+		resolved, _, err = s.resolveConflict(name, path, ocispec.Descriptor{
+			Digest: digester.Digest(),
+			Size:   size,
+		})
+	}
+	if err == nil && resolved != "" {
+		err = os.Rename(file.Name(), resolved)
+	}
+	if err != nil || resolved == "" {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return resolved, nil
+}
+
+// RenamedPathOf returns the path the named blob is renamed to on conflict.
+func (s *FileStore) RenamedPathOf(name string, desc ocispec.Descriptor) (string, bool) {
+	value, ok := s.renamed.Load(renamedKey(name, desc))
+	if !ok {
+		return "", false
+	}
+	return value.(string), true
+}
+
+func renamedKey(name string, desc ocispec.Descriptor) string {
+	return name + "@" + desc.Digest.String()
+}
+
+// createReplacePath creates a temporary file next to the path, which replaces
+// the path on commit.
+func (s *FileStore) createReplacePath(path string) (*os.File, func() error, error) {
+	file, err := createSiblingFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.tmpFiles.Store(file.Name(), file)
+	afterCommit := func() error {
+		if err := os.Rename(file.Name(), path); err != nil {
+			return err
+		}
+		s.tmpFiles.Delete(file.Name())
+		return nil
+	}
+	return file, afterCommit, nil
+}
+
+// createSiblingFile creates a hidden temporary file next to path. Unlike
+// ioutil.TempFile, the permissions are the ones of os.Create, so that the file
+// can be renamed to path as is.
+func createSiblingFile(path string) (*os.File, error) {
+	dir, base := filepath.Split(path)
+	for i := 0; ; i++ {
+		name := filepath.Join(dir, fmt.Sprintf(".%s.%d", base, rand.Uint32()))
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) && i < 100 {
+			continue
+		}
+		return file, err
+	}
+}
+
+// describeFile describes the file at the path with the digest of the
+// algorithm.
+func describeFile(path string, algorithm digest.Algorithm) (ocispec.Descriptor, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if !algorithm.Available() {
+		algorithm = digest.Canonical
+	}
+	digester := algorithm.Digester()
+	if _, err := io.Copy(digester.Hash(), file); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return ocispec.Descriptor{
+		Digest: digester.Digest(),
+		Size:   info.Size(),
+	}, nil
+}
@@ -22,6 +22,26 @@ const (
 	AnnotationDigest = "io.deis.oras.content.digest"
 	// AnnotationUnpack is the annotation key for indication of unpacking
 	AnnotationUnpack = "io.deis.oras.content.unpack"
+	// AnnotationCompression is the annotation key for the compression of the content, which is gzip if not set for unpacked directories
+	AnnotationCompression = "io.deis.oras.content.compression"
+)
+
+const (
+	// AnnotationFileMode is the annotation key for the permissions of a file in octal
+	AnnotationFileMode = "io.deis.oras.content.file.mode"
+	// AnnotationFileModTime is the annotation key for the modification time of a file in RFC 3339 format
+	AnnotationFileModTime = "io.deis.oras.content.file.mtime"
+)
+
+const (
+	// AnnotationShardFile is the annotation key for the name of the file a shard belongs to
+	AnnotationShardFile = "io.deis.oras.content.shard.file"
+	// AnnotationShardFileDigest is the annotation key for the digest of the file a shard belongs to
+	AnnotationShardFileDigest = "io.deis.oras.content.shard.file.digest"
+	// AnnotationShardIndex is the annotation key for the zero-based position of a shard in the file
+	AnnotationShardIndex = "io.deis.oras.content.shard.index"
+	// AnnotationShardCount is the annotation key for the number of shards of the file
+	AnnotationShardCount = "io.deis.oras.content.shard.count"
 )
 
 const (
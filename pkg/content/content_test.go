@@ -1,14 +1,24 @@
 package content
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
 	digest "github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/suite"
@@ -21,8 +31,8 @@ type ContentTestSuite struct {
 }
 
 var (
-	testDirRoot, _ = filepath.Abs("../../.test")
-	testFileName   = filepath.Join(testDirRoot, "testfile")
+	testDirRoot    string
+	testFileName   string
 	testRef        = "abc123"
 	testContent    = []byte("Hello World!")
 	testDescriptor = ocispec.Descriptor{
@@ -46,8 +56,11 @@ func (suite *ContentTestSuite) SetupSuite() {
 	testMemoryStore.Add(testRef, "", testContent)
 	suite.TestMemoryStore = testMemoryStore
 
-	os.Remove(testFileName)
-	err := ioutil.WriteFile(testFileName, testContent, 0644)
+	var err error
+	testDirRoot, err = ioutil.TempDir("", "oras_content_test")
+	suite.Nil(err, "no error creating test directory")
+	testFileName = filepath.Join(testDirRoot, "testfile")
+	err = ioutil.WriteFile(testFileName, testContent, 0644)
 	suite.Nil(err, "no error creating test file on disk")
 	testFileStore := NewFileStore(testDirRoot)
 	_, err = testFileStore.Add(testRef, "", testFileName)
@@ -55,6 +68,10 @@ func (suite *ContentTestSuite) SetupSuite() {
 	suite.TestFileStore = testFileStore
 }
 
+func (suite *ContentTestSuite) TearDownSuite() {
+	os.RemoveAll(testDirRoot)
+}
+
 // Tests all Writers (Ingesters)
 func (suite *ContentTestSuite) Test_0_Ingesters() {
 	ingesters := map[string]content.Ingester{
@@ -167,6 +184,570 @@ func (suite *ContentTestSuite) Test_2_GetByName() {
 	suite.True(ok, "able to find non-existant ref by name for memory store")
 }
 
+func (suite *ContentTestSuite) Test_3_NewHash() {
+	var called bool
+	store := NewFileStore(testDirRoot)
+	store.NewHash = func() hash.Hash {
+		called = true
+		return sha256.New()
+	}
+
+	err := ioutil.WriteFile(testFileName, testContent, 0644)
+	suite.Nil(err, "no error creating test file on disk")
+	defer os.Remove(testFileName)
+	desc, err := store.Add(testRef, "", testFileName)
+	suite.Nil(err, "no error adding item to file store with custom hash")
+	suite.True(called, "custom hash is used")
+	suite.Equal(testDescriptor.Digest, desc.Digest, "custom hash computes the same digest")
+
+	RegisterSHA256("test", sha256.New)
+	suite.Contains(SHA256Implementations(), "test", "registered implementation listed")
+	newHash, err := SHA256("test")
+	suite.Nil(err, "no error getting registered implementation")
+	suite.NotNil(newHash, "registered implementation returned")
+	_, err = SHA256("missing")
+	suite.NotNil(err, "error getting unknown implementation")
+}
+
+func (suite *ContentTestSuite) Test_4_OCIResolver() {
+	root, err := ioutil.TempDir("", "oras_oci_resolver_test")
+	suite.Nil(err, "no error creating temp directory for test")
+	defer os.RemoveAll(root)
+	store, err := NewOCIStore(root)
+	suite.Nil(err, "no error creating OCI store")
+	resolver := store.Resolver()
+	ctx := context.Background()
+
+	// Push a manifest with a tag
+	manifest := []byte(`{"schemaVersion":2}`)
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(manifest),
+		Size:      int64(len(manifest)),
+	}
+	pusher, err := resolver.Pusher(ctx, "v1")
+	suite.Nil(err, "no error getting pusher")
+	writer, err := pusher.Push(ctx, desc)
+	suite.Nil(err, "no error pushing manifest")
+	_, err = writer.Write(manifest)
+	suite.Nil(err, "no error writing manifest")
+	err = writer.Commit(ctx, desc.Size, desc.Digest)
+	suite.Nil(err, "no error committing manifest")
+
+	// Resolve by tag and digest
+	_, resolved, err := resolver.Resolve(ctx, "v1")
+	suite.Nil(err, "no error resolving tag")
+	suite.Equal(desc.Digest, resolved.Digest, "resolved digest matches")
+	_, resolved, err = resolver.Resolve(ctx, "@"+desc.Digest.String())
+	suite.Nil(err, "no error resolving digest")
+	suite.Equal(desc.Digest, resolved.Digest, "resolved digest matches")
+	_, _, err = resolver.Resolve(ctx, "v2")
+	suite.NotNil(err, "error resolving non-existing tag")
+
+	// Fetch
+	fetcher, err := resolver.Fetcher(ctx, "v1")
+	suite.Nil(err, "no error getting fetcher")
+	rc, err := fetcher.Fetch(ctx, desc)
+	suite.Nil(err, "no error fetching manifest")
+	defer rc.Close()
+	actual, err := ioutil.ReadAll(rc)
+	suite.Nil(err, "no error reading manifest")
+	suite.Equal(manifest, actual, "fetched manifest matches")
+
+	// Index is saved
+	reloaded, err := NewOCIStore(root)
+	suite.Nil(err, "no error reloading OCI store")
+	suite.Contains(reloaded.ListReferences(), "v1", "tag saved in index")
+
+	// Push an index pinned to the root, where only the root is tagged
+	child := []byte(`{"schemaVersion":2,"annotations":{"child":"true"}}`)
+	childDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(child),
+		Size:      int64(len(child)),
+	}
+	index := []byte(fmt.Sprintf(`{"schemaVersion":2,"manifests":[{"mediaType":%q,"digest":%q,"size":%d}]}`,
+		childDesc.MediaType, childDesc.Digest, childDesc.Size))
+	indexDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(index),
+		Size:      int64(len(index)),
+	}
+	pusher, err = resolver.Pusher(ctx, "v1@"+indexDesc.Digest.String())
+	suite.Nil(err, "no error getting pusher pinned to the root")
+	for _, blob := range []struct {
+		desc ocispec.Descriptor
+		data []byte
+	}{{childDesc, child}, {indexDesc, index}} {
+		writer, err := pusher.Push(ctx, blob.desc)
+		suite.Nil(err, "no error pushing manifest")
+		_, err = writer.Write(blob.data)
+		suite.Nil(err, "no error writing manifest")
+		err = writer.Commit(ctx, blob.desc.Size, blob.desc.Digest)
+		suite.Nil(err, "no error committing manifest")
+		_, resolved, err = resolver.Resolve(ctx, "v1")
+		suite.Nil(err, "no error resolving tag")
+		suite.NotEqual(childDesc.Digest, resolved.Digest, "child manifest not tagged")
+	}
+	suite.Equal(indexDesc.Digest, resolved.Digest, "root tagged")
+	suite.Equal(indexDesc.Digest, store.ListReferences()["v1"].Digest, "replaced reference listed")
+	reloaded, err = NewOCIStore(root)
+	suite.Nil(err, "no error reloading OCI store")
+	suite.Equal(1, len(reloaded.ListReferences()), "tag replaced in index")
+	suite.Equal(indexDesc.Digest, reloaded.ListReferences()["v1"].Digest, "replaced tag saved in index")
+}
+
+func (suite *ContentTestSuite) Test_5_Shards() {
+	root, err := ioutil.TempDir("", "oras_shards_test")
+	suite.Nil(err, "no error creating temp directory for test")
+	defer os.RemoveAll(root)
+	data := []byte("0123456789")
+	err = ioutil.WriteFile(filepath.Join(root, "weights.bin"), data, 0644)
+	suite.Nil(err, "no error creating test file on disk")
+
+	// Split the file into shards
+	src := NewFileStore(root)
+	_, err = src.AddShards("weights.bin", "", "", 0)
+	suite.NotNil(err, "error adding shards of zero size")
+	descs, err := src.AddShards("weights.bin", "", "", 4)
+	suite.Nil(err, "no error adding shards")
+	suite.Equal(3, len(descs), "number of shards matches")
+	suite.Equal(int64(2), descs[2].Size, "last shard size matches")
+
+	// Copy the shards in reverse order and reassemble them
+	dst := NewFileStore(filepath.Join(root, "out"))
+	ctx := context.Background()
+	for i := len(descs) - 1; i >= 0; i-- {
+		ra, err := src.ReaderAt(ctx, descs[i])
+		suite.Nil(err, "no error reading shard")
+		writer, err := dst.Writer(ctx, content.WithDescriptor(descs[i]))
+		suite.Nil(err, "no error writing shard")
+		err = content.Copy(ctx, writer, content.NewReader(ra), descs[i].Size, descs[i].Digest)
+		suite.Nil(err, "no error copying shard")
+		ra.Close()
+	}
+	names, err := dst.JoinShards(descs)
+	suite.Nil(err, "no error joining shards")
+	suite.Equal([]string{"weights.bin"}, names, "joined files match")
+	actual, err := ioutil.ReadFile(filepath.Join(root, "out", "weights.bin"))
+	suite.Nil(err, "no error reading joined file")
+	suite.Equal(data, actual, "joined content matches")
+	_, err = os.Stat(filepath.Join(root, "out", "weights.bin.shard-00001-of-00003"))
+	suite.True(os.IsNotExist(err), "shards are removed")
+
+	_, err = dst.JoinShards(descs[:2])
+	suite.True(errors.Is(err, ErrInvalidShard), "error joining incomplete shards")
+}
+
+func (suite *ContentTestSuite) Test_6_Conflicts() {
+	root, err := ioutil.TempDir("", "oras_conflicts_test")
+	suite.Nil(err, "no error creating temp directory for test")
+	defer os.RemoveAll(root)
+	err = ioutil.WriteFile(filepath.Join(root, "a.txt"), []byte("existing"), 0644)
+	suite.Nil(err, "no error creating test file on disk")
+
+	data := []byte("incoming")
+	desc := ocispec.Descriptor{
+		MediaType: DefaultBlobMediaType,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+		Annotations: map[string]string{
+			ocispec.AnnotationTitle: "a.txt",
+		},
+	}
+	ctx := context.Background()
+	write := func(store *FileStore) error {
+		writer, err := store.Writer(ctx, content.WithDescriptor(desc))
+		if err != nil {
+			return err
+		}
+		defer writer.Close()
+		return content.Copy(ctx, writer, bytes.NewReader(data), desc.Size, desc.Digest)
+	}
+	resolveWith := func(action ConflictAction) *FileStore {
+		store := NewFileStore(root)
+		store.ResolveConflict = func(conflict Conflict) (ConflictAction, error) {
+			suite.Equal(digest.FromString("existing"), conflict.Existing.Digest, "existing digest matches")
+			return action, nil
+		}
+		return store
+	}
+
+	err = write(resolveWith(ConflictFail))
+	suite.True(errors.Is(err, ErrConflict), "error on conflict")
+
+	err = write(resolveWith(ConflictSkip))
+	suite.True(errdefs.IsAlreadyExists(err), "conflicting blob skipped")
+
+	store := resolveWith(ConflictRename)
+	err = write(store)
+	suite.Nil(err, "no error renaming conflicting blob")
+	renamed, ok := store.RenamedPathOf("a.txt", desc)
+	suite.True(ok, "renamed path recorded")
+	suite.Equal(filepath.Join(root, "a."+desc.Digest.Encoded()[:12]+".txt"), renamed, "renamed path matches")
+	actual, err := ioutil.ReadFile(renamed)
+	suite.Nil(err, "no error reading renamed file")
+	suite.Equal(data, actual, "renamed content matches")
+
+	err = write(resolveWith(ConflictOverwrite))
+	suite.Nil(err, "no error overwriting conflicting blob")
+	actual, err = ioutil.ReadFile(filepath.Join(root, "a.txt"))
+	suite.Nil(err, "no error reading overwritten file")
+	suite.Equal(data, actual, "overwritten content matches")
+}
+
+func (suite *ContentTestSuite) Test_7_ObjectStore() {
+	uploader := &memoryUploader{objects: make(map[string][]byte)}
+	store := NewObjectStore(uploader, "/prefix/")
+	ctx := context.Background()
+	write := func(desc ocispec.Descriptor, data []byte) error {
+		writer, err := store.Writer(ctx, content.WithDescriptor(desc))
+		if err != nil {
+			return err
+		}
+		defer writer.Close()
+		return content.Copy(ctx, writer, bytes.NewReader(data), desc.Size, desc.Digest)
+	}
+
+	err := write(testDescriptor, testContent)
+	suite.Nil(err, "no error uploading blob")
+	suite.Equal(testContent, uploader.objects["prefix/"+testRef], "uploaded content matches")
+
+	corrupted := testDescriptor
+	corrupted.Annotations = map[string]string{ocispec.AnnotationTitle: "corrupted"}
+	err = write(corrupted, []byte("Hello Earth!"))
+	suite.NotNil(err, "error uploading mismatching content")
+	_, ok := uploader.objects["prefix/corrupted"]
+	suite.False(ok, "mismatching content not uploaded")
+
+	_, err = store.Key("../escaped")
+	suite.True(errors.Is(err, ErrPathTraversalDisallowed), "error on path traversal")
+
+	// Upload the files of a directory
+	root, err := ioutil.TempDir("", "oras_object_test")
+	suite.Nil(err, "no error creating temp directory for test")
+	defer os.RemoveAll(root)
+	err = os.MkdirAll(filepath.Join(root, "dir", "sub"), 0755)
+	suite.Nil(err, "no error creating test directory on disk")
+	err = ioutil.WriteFile(filepath.Join(root, "dir", "sub", "file.txt"), testContent, 0644)
+	suite.Nil(err, "no error creating test file on disk")
+	src := NewFileStore(root)
+	defer src.Close()
+	desc, err := src.Add("dir", "", filepath.Join(root, "dir"))
+	suite.Nil(err, "no error adding directory")
+	ra, err := src.ReaderAt(ctx, desc)
+	suite.Nil(err, "no error reading directory blob")
+	defer ra.Close()
+	data := make([]byte, desc.Size)
+	_, err = ra.ReadAt(data, 0)
+	suite.Nil(err, "no error reading directory blob")
+	err = write(desc, data)
+	suite.Nil(err, "no error uploading directory")
+	suite.Equal(testContent, uploader.objects["prefix/dir/sub/file.txt"], "uploaded file of directory matches")
+
+	// No file of a directory failing the verification is uploaded
+	for _, tampered := range []ocispec.Descriptor{
+		func() ocispec.Descriptor {
+			tampered := desc
+			tampered.Digest = digest.FromString("tampered blob")
+			return tampered
+		}(),
+		func() ocispec.Descriptor {
+			tampered := desc
+			tampered.Annotations = make(map[string]string)
+			for k, v := range desc.Annotations {
+				tampered.Annotations[k] = v
+			}
+			tampered.Annotations[AnnotationDigest] = digest.FromString("tampered content").String()
+			return tampered
+		}(),
+	} {
+		uploader.objects = make(map[string][]byte)
+		err = write(tampered, data)
+		suite.NotNil(err, "error uploading tampered directory")
+		suite.Empty(uploader.objects, "no file of tampered directory uploaded")
+	}
+}
+
+// memoryUploader stores the uploaded objects in memory.
+type memoryUploader struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+}
+
+func (u *memoryUploader) Upload(ctx context.Context, key string, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.objects[key] = data
+	return nil
+}
+
+func (suite *ContentTestSuite) Test_8_VerifiedWrites() {
+	root, err := ioutil.TempDir("", "oras_verified_test")
+	suite.Nil(err, "no error creating temp directory for test")
+	defer os.RemoveAll(root)
+	src := filepath.Join(root, "src.sh")
+	err = ioutil.WriteFile(src, []byte("#!/bin/sh"), 0750)
+	suite.Nil(err, "no error creating test file on disk")
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	suite.Nil(os.Chtimes(src, modTime, modTime), "no error setting modification time")
+
+	pushed := NewFileStore("")
+	pushed.PreserveAttributes = true
+	desc, err := pushed.Add("run.sh", "", src)
+	suite.Nil(err, "no error adding file")
+	suite.Equal("0750", desc.Annotations[AnnotationFileMode], "mode recorded")
+	suite.Equal("2020-01-02T03:04:05Z", desc.Annotations[AnnotationFileModTime], "modification time recorded")
+
+	ctx := context.Background()
+	out := filepath.Join(root, "out")
+	store := NewFileStore(out)
+	store.PreserveAttributes = true
+	write := func(data []byte) error {
+		writer, err := store.Writer(ctx, content.WithDescriptor(desc))
+		if err != nil {
+			return err
+		}
+		defer writer.Close()
+		return content.Copy(ctx, writer, bytes.NewReader(data), desc.Size, desc.Digest)
+	}
+
+	err = write([]byte("#!/bin/ls"))
+	suite.True(errdefs.IsFailedPrecondition(err), "error writing corrupt content")
+	files, err := ioutil.ReadDir(out)
+	suite.Nil(err, "no error reading output directory")
+	suite.Empty(files, "no corrupt output left")
+
+	err = write([]byte("#!/bin/sh"))
+	suite.Nil(err, "no error writing content")
+	info, err := os.Stat(filepath.Join(out, "run.sh"))
+	suite.Nil(err, "no error reading written file")
+	suite.Equal(os.FileMode(0750), info.Mode().Perm(), "mode restored")
+	suite.True(modTime.Equal(info.ModTime()), "modification time restored")
+}
+
+func (suite *ContentTestSuite) Test_9_Compression() {
+	root, err := ioutil.TempDir("", "oras_compression_test")
+	suite.Nil(err, "no error creating temp directory for test")
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "src")
+	suite.Nil(os.MkdirAll(filepath.Join(dir, "sub"), 0755), "no error creating test directory")
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("hello"), 0644), "no error creating test file")
+
+	ctx := context.Background()
+	for _, compression := range []Compression{CompressionGzip, CompressionZstd, CompressionNone} {
+		var descs []ocispec.Descriptor
+		for i := 0; i < 2; i++ {
+			modTime := time.Now().Add(time.Duration(i) * time.Hour)
+			suite.Nil(os.Chtimes(filepath.Join(dir, "sub", "a.txt"), modTime, modTime), "no error touching file")
+			pushed := NewFileStore("")
+			pushed.Compression = compression
+			pushed.Reproducible = true
+			desc, err := pushed.Add("src", "", dir)
+			suite.Nil(err, "no error adding directory")
+			descs = append(descs, desc)
+
+			file, err := pushed.Add("a.txt", "", filepath.Join(dir, "sub", "a.txt"))
+			suite.Nil(err, "no error adding file")
+			suite.Equal(compression.MediaType(DefaultBlobMediaType), file.MediaType, "media type of file")
+
+			ra, err := pushed.ReaderAt(ctx, file)
+			suite.Nil(err, "no error reading file")
+			data := make([]byte, ra.Size())
+			_, err = ra.ReadAt(data, 0)
+			ra.Close()
+			suite.Nil(err, "no error reading file")
+
+			out := filepath.Join(root, "out", string(compression))
+			store := NewFileStore(out)
+			writer, err := store.Writer(ctx, content.WithDescriptor(file))
+			suite.Nil(err, "no error getting writer")
+			err = content.Copy(ctx, writer, bytes.NewReader(data), file.Size, file.Digest)
+			writer.Close()
+			suite.Nil(err, "no error writing file")
+			written, err := ioutil.ReadFile(filepath.Join(out, "a.txt"))
+			suite.Nil(err, "no error reading written file")
+			suite.Equal("hello", string(written), "file decompressed")
+			pushed.Close()
+		}
+		suite.Equal(compression.MediaType(ocispec.MediaTypeImageLayer), descs[0].MediaType, "media type of directory")
+		suite.Equal(descs[0].Digest, descs[1].Digest, "reproducible digest")
+	}
+}
+
+func (suite *ContentTestSuite) Test_10_Staged() {
+	root, err := ioutil.TempDir("", "oras_staged_test")
+	suite.Nil(err, "no error creating temp directory for test")
+	defer os.RemoveAll(root)
+	ctx := context.Background()
+	data := []byte("staged")
+	desc := ocispec.Descriptor{
+		MediaType: DefaultBlobMediaType,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+		Annotations: map[string]string{
+			ocispec.AnnotationTitle: "staged.txt",
+		},
+	}
+
+	store := NewFileStore(root)
+	store.Staged = true
+	writer, err := store.Writer(ctx, content.WithDescriptor(desc))
+	suite.Nil(err, "no error getting writer")
+	err = content.Copy(ctx, writer, bytes.NewReader(data), desc.Size, desc.Digest)
+	writer.Close()
+	suite.Nil(err, "no error writing file")
+	_, err = os.Stat(filepath.Join(root, "staged.txt"))
+	suite.True(os.IsNotExist(err), "file not in place before promotion")
+
+	suite.Nil(store.Promote(), "no error promoting files")
+	written, err := ioutil.ReadFile(filepath.Join(root, "staged.txt"))
+	suite.Nil(err, "no error reading promoted file")
+	suite.Equal(data, written, "file in place after promotion")
+	store.Close()
+	files, err := ioutil.ReadDir(root)
+	suite.Nil(err, "no error reading output directory")
+	suite.Len(files, 1, "no staged files left")
+}
+
+func (suite *ContentTestSuite) Test_11_Chunks() {
+	root, err := ioutil.TempDir("", "oras_chunks_test")
+	suite.Nil(err, "no error creating temp directory for test")
+	defer os.RemoveAll(root)
+	data := make([]byte, 256*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+	copy(data[200*1024:], data[:16*1024])
+	err = ioutil.WriteFile(filepath.Join(root, "db.bin"), data, 0644)
+	suite.Nil(err, "no error creating test file on disk")
+
+	src := NewFileStore(root)
+	defer src.Close()
+	descs, err := src.AddChunked("db.bin", "", "", 8*1024)
+	suite.Nil(err, "no error adding chunks")
+	suite.Equal(ChunkRecipeMediaType, descs[0].MediaType, "recipe first")
+	suite.True(len(descs) > 10, "file split into chunks")
+
+	// a change in the middle of the file leaves most chunks unchanged
+	changed := append([]byte{}, data...)
+	copy(changed[100*1024:], "changed")
+	err = ioutil.WriteFile(filepath.Join(root, "db2.bin"), changed, 0644)
+	suite.Nil(err, "no error creating test file on disk")
+	changedDescs, err := src.AddChunked("db2.bin", "", "", 8*1024)
+	suite.Nil(err, "no error adding chunks")
+	known := make(map[digest.Digest]bool)
+	for _, desc := range descs[1:] {
+		known[desc.Digest] = true
+	}
+	var added int
+	for _, desc := range changedDescs[1:] {
+		if !known[desc.Digest] {
+			added++
+		}
+	}
+	suite.True(added <= 2, "only the changed chunks differ")
+
+	// copy the recipe and the chunks, and reassemble the file
+	dst := NewFileStore(filepath.Join(root, "out"))
+	ctx := context.Background()
+	for _, desc := range descs {
+		ra, err := src.ReaderAt(ctx, desc)
+		suite.Nil(err, "no error reading chunk")
+		writer, err := dst.Writer(ctx, content.WithDescriptor(desc))
+		suite.Nil(err, "no error writing chunk")
+		err = content.Copy(ctx, writer, content.NewReader(ra), desc.Size, desc.Digest)
+		suite.Nil(err, "no error copying chunk")
+		ra.Close()
+	}
+	names, err := dst.JoinChunks(descs)
+	suite.Nil(err, "no error joining chunks")
+	suite.Equal([]string{"db.bin"}, names, "joined files match")
+	actual, err := ioutil.ReadFile(filepath.Join(root, "out", "db.bin"))
+	suite.Nil(err, "no error reading joined file")
+	suite.Equal(data, actual, "joined content matches")
+	files, err := ioutil.ReadDir(filepath.Join(root, "out"))
+	suite.Nil(err, "no error reading output directory")
+	suite.Len(files, 1, "recipe and chunks are removed")
+}
+
+func (suite *ContentTestSuite) Test_12_VerifyLayer() {
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		suite.Nil(tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 5}), "no error writing tar header")
+		_, err := tw.Write([]byte("hello"))
+		suite.Nil(err, "no error writing tar entry")
+	}
+	suite.Nil(tw.Close(), "no error closing tar")
+
+	for _, compression := range []Compression{CompressionGzip, CompressionZstd, CompressionNone} {
+		var layer bytes.Buffer
+		zw, err := compression.NewWriter(&layer)
+		suite.Nil(err, "no error creating compressed writer")
+		_, err = zw.Write(tarball.Bytes())
+		suite.Nil(err, "no error compressing layer")
+		suite.Nil(zw.Close(), "no error closing compressed writer")
+		mediaType := compression.MediaType(ocispec.MediaTypeImageLayer)
+
+		report, err := VerifyLayer(bytes.NewReader(layer.Bytes()), mediaType)
+		suite.Nil(err, "no error verifying valid layer")
+		suite.Equal(LayerReport{
+			Compression:      compression,
+			Tar:              true,
+			Entries:          2,
+			UncompressedSize: int64(tarball.Len()),
+		}, report)
+		report, err = VerifyLayer(bytes.NewReader(layer.Bytes()), "")
+		suite.Nil(err, "no error verifying valid layer without media type")
+		suite.Equal(compression, report.Compression, "compression detected")
+		suite.Equal(2, report.Entries, "tar detected")
+
+		_, err = VerifyLayer(bytes.NewReader(layer.Bytes()[:layer.Len()-20]), mediaType)
+		suite.NotNil(err, "error verifying truncated layer")
+	}
+
+	var layer bytes.Buffer
+	zw, _ := CompressionZstd.NewWriter(&layer)
+	zw.Write(tarball.Bytes())
+	zw.Close()
+	_, err := VerifyLayer(&layer, CompressionGzip.MediaType(ocispec.MediaTypeImageLayer))
+	suite.NotNil(err, "error verifying layer with mismatching compression")
+}
+
+func (suite *ContentTestSuite) Test_13_AddAll() {
+	root, err := ioutil.TempDir("", "oras_add_all_test")
+	suite.Nil(err, "no error creating temp directory for test")
+	defer os.RemoveAll(root)
+	var refs []FileRef
+	for i := 0; i < 4; i++ {
+		path := filepath.Join(root, fmt.Sprintf("file-%d.txt", i))
+		suite.Nil(ioutil.WriteFile(path, bytes.Repeat([]byte{byte('a' + i)}, 10), 0644), "no error creating test file")
+		refs = append(refs, FileRef{
+			Name: filepath.Base(path),
+			Path: path,
+		})
+	}
+	refs[3].ShardSize = 4
+
+	store := NewFileStore("")
+	defer store.Close()
+	files, err := store.AddAll(context.Background(), refs, 2)
+	suite.Nil(err, "no error adding files")
+	suite.Len(files, 4, "descriptors of every file")
+	for i := 0; i < 3; i++ {
+		suite.Len(files[i], 1, "single blob")
+		suite.Equal(refs[i].Name, files[i][0].Annotations[ocispec.AnnotationTitle], "files in order")
+		suite.Equal(digest.FromBytes(bytes.Repeat([]byte{byte('a' + i)}, 10)), files[i][0].Digest, "digest of the file")
+	}
+	suite.Len(files[3], 3, "large file split into shards")
+
+	_, err = store.AddAll(context.Background(), []FileRef{{Name: "missing", Path: filepath.Join(root, "missing")}}, 0)
+	suite.NotNil(err, "error adding missing file")
+}
+
 func TestContentTestSuite(t *testing.T) {
 	suite.Run(t, new(ContentTestSuite))
 }
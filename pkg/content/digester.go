@@ -0,0 +1,79 @@
+package content
+
+import (
+	"crypto/sha256"
+	"hash"
+	"sort"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// hashDigester implements digest.Digester with a custom sha256 hash
+// implementation.
+type hashDigester struct {
+	hash hash.Hash
+}
+
+// newDigester creates a sha256 digester backed by the hash created by newHash.
+// The default crypto/sha256 implementation is used if newHash is nil.
+func newDigester(newHash func() hash.Hash) digest.Digester {
+	if newHash == nil {
+		return digest.Canonical.Digester()
+	}
+	return &hashDigester{
+		hash: newHash(),
+	}
+}
+
+func (d *hashDigester) Hash() hash.Hash {
+	return d.hash
+}
+
+func (d *hashDigester) Digest() digest.Digest {
+	return digest.NewDigest(digest.SHA256, d.hash)
+}
+
+// DefaultSHA256 is the name of the crypto/sha256 implementation, which uses
+// the SHA extensions of the CPU where available.
+const DefaultSHA256 = "go"
+
+var (
+	sha256Lock  sync.RWMutex
+	sha256Impls = map[string]func() hash.Hash{
+		DefaultSHA256: sha256.New,
+	}
+)
+
+// RegisterSHA256 registers a sha256 implementation selectable by name, e.g. a
+// SIMD or hardware accelerated one, for the NewHash of the stores.
+func RegisterSHA256(name string, newHash func() hash.Hash) {
+	sha256Lock.Lock()
+	defer sha256Lock.Unlock()
+	sha256Impls[name] = newHash
+}
+
+// SHA256 returns the sha256 implementation registered by name.
+func SHA256(name string) (func() hash.Hash, error) {
+	sha256Lock.RLock()
+	defer sha256Lock.RUnlock()
+	newHash, ok := sha256Impls[name]
+	if !ok {
+		return nil, errors.Errorf("unknown sha256 implementation %q", name)
+	}
+	return newHash, nil
+}
+
+// SHA256Implementations returns the names of the registered sha256
+// implementations, sorted.
+func SHA256Implementations() []string {
+	sha256Lock.RLock()
+	defer sha256Lock.RUnlock()
+	names := make([]string, 0, len(sha256Impls))
+	for name := range sha256Impls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -14,4 +14,7 @@ var (
 var (
 	ErrPathTraversalDisallowed = errors.New("path_traversal_disallowed")
 	ErrOverwriteDisallowed     = errors.New("overwrite_disallowed")
+	ErrInvalidShard            = errors.New("invalid_shard")
+	ErrInvalidChunk            = errors.New("invalid_chunk")
+	ErrConflict                = errors.New("conflict")
 )
@@ -1,12 +1,14 @@
 package content
 
 import (
-	"compress/gzip"
 	"context"
+	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -31,10 +33,44 @@ type FileStore struct {
 	// Reproducible enables stripping times from added files
 	Reproducible bool
 
+	// Compression is the compression of the added files and directories.
+	// If not set, directories are compressed with gzip and files are left
+	// uncompressed. The compressed files are decompressed on write.
+	Compression Compression
+
+	// PreserveAttributes enables recording the permissions and the
+	// modification times of the added files in their annotations, and
+	// restoring them on the written files.
+	PreserveAttributes bool
+
+	// NewHash creates the sha256 hash used to compute and verify digests.
+	// It allows offloading digest computation to a hardware or SIMD
+	// accelerated implementation. The default is crypto/sha256, which
+	// already uses the SHA extensions of the CPU where available.
+	NewHash func() hash.Hash
+
+	// ResolveConflict, if set, is called when a blob, or a file extracted
+	// from a directory blob, would be written to a path already written by
+	// another blob, or holding a different file. Existing files are
+	// overwritten if not set.
+	ResolveConflict ConflictResolver
+
+	// Staged defers putting the written files in place until Promote is
+	// called, so that no content is in place if the written content is
+	// rejected, e.g. by a signature verification.
+	Staged bool
+
 	root       string
 	descriptor *sync.Map // map[digest.Digest]ocispec.Descriptor
 	pathMap    *sync.Map
+	shards     *sync.Map // map[string]fileShard
 	tmpFiles   *sync.Map
+	written    *sync.Map // map[string]ocispec.Descriptor
+	renamed    *sync.Map // map[string]string
+
+	conflictLock sync.Mutex
+	stageLock    sync.Mutex
+	staged       []func() error
 }
 
 // NewFileStore creats a new file store
@@ -43,7 +79,10 @@ func NewFileStore(rootPath string) *FileStore {
 		root:       rootPath,
 		descriptor: &sync.Map{},
 		pathMap:    &sync.Map{},
+		shards:     &sync.Map{},
 		tmpFiles:   &sync.Map{},
+		written:    &sync.Map{},
+		renamed:    &sync.Map{},
 	}
 }
 
@@ -63,7 +102,7 @@ func (s *FileStore) Add(name, mediaType, path string) (ocispec.Descriptor, error
 	if fileInfo.IsDir() {
 		desc, err = s.descFromDir(name, mediaType, path)
 	} else {
-		desc, err = s.descFromFile(fileInfo, mediaType, path)
+		desc, err = s.descFromFile(name, fileInfo, mediaType, path)
 	}
 	if err != nil {
 		return ocispec.Descriptor{}, err
@@ -77,24 +116,96 @@ func (s *FileStore) Add(name, mediaType, path string) (ocispec.Descriptor, error
 	return desc, nil
 }
 
-func (s *FileStore) descFromFile(info os.FileInfo, mediaType, path string) (ocispec.Descriptor, error) {
+func (s *FileStore) descFromFile(name string, info os.FileInfo, mediaType, path string) (ocispec.Descriptor, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return ocispec.Descriptor{}, err
 	}
 	defer file.Close()
-	digest, err := digest.FromReader(file)
+
+	var desc ocispec.Descriptor
+	if s.Compression == "" || s.Compression == CompressionNone {
+		digester := newDigester(s.NewHash)
+		if _, err := io.Copy(digester.Hash(), file); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		if mediaType == "" {
+			mediaType = DefaultBlobMediaType
+		}
+		desc = ocispec.Descriptor{
+			MediaType: mediaType,
+			Digest:    digester.Digest(),
+			Size:      info.Size(),
+		}
+	} else if desc, err = s.compressFile(name, mediaType, file); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	if s.PreserveAttributes {
+		if desc.Annotations == nil {
+			desc.Annotations = make(map[string]string)
+		}
+		for k, v := range s.fileAttributes(info) {
+			desc.Annotations[k] = v
+		}
+	}
+	return desc, nil
+}
+
+// fileAttributes returns the annotations recording the permissions and the
+// modification time of the file.
+func (s *FileStore) fileAttributes(info os.FileInfo) map[string]string {
+	annotations := map[string]string{
+		AnnotationFileMode: fmt.Sprintf("%#o", info.Mode().Perm()),
+	}
+	if !s.Reproducible {
+		annotations[AnnotationFileModTime] = info.ModTime().UTC().Format(time.RFC3339Nano)
+	}
+	return annotations
+}
+
+// compressFile compresses the file to a temporary file, which the name is
+// mapped to.
+func (s *FileStore) compressFile(name, mediaType string, r io.Reader) (ocispec.Descriptor, error) {
+	file, err := s.tempFile()
 	if err != nil {
 		return ocispec.Descriptor{}, err
 	}
+	defer file.Close()
+	s.MapPath(name, file.Name())
+
+	digester := newDigester(s.NewHash)
+	zw, err := s.Compression.NewWriter(io.MultiWriter(file, digester.Hash()))
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer zw.Close()
+	contentDigester := newDigester(s.NewHash)
+	if _, err := io.Copy(io.MultiWriter(zw, contentDigester.Hash()), r); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := file.Sync(); err != nil {
+		return ocispec.Descriptor{}, err
+	}
 
 	if mediaType == "" {
-		mediaType = DefaultBlobMediaType
+		mediaType = s.Compression.MediaType(DefaultBlobMediaType)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return ocispec.Descriptor{}, err
 	}
 	return ocispec.Descriptor{
 		MediaType: mediaType,
-		Digest:    digest,
+		Digest:    digester.Digest(),
 		Size:      info.Size(),
+		Annotations: map[string]string{
+			AnnotationDigest:      contentDigester.Digest().String(),
+			AnnotationCompression: string(s.Compression),
+		},
 	}, nil
 }
 
@@ -108,10 +219,17 @@ func (s *FileStore) descFromDir(name, mediaType, root string) (ocispec.Descripto
 	s.MapPath(name, file.Name())
 
 	// compress directory
-	digester := digest.Canonical.Digester()
-	zw := gzip.NewWriter(io.MultiWriter(file, digester.Hash()))
+	compression := s.Compression
+	if compression == "" {
+		compression = CompressionGzip
+	}
+	digester := newDigester(s.NewHash)
+	zw, err := compression.NewWriter(io.MultiWriter(file, digester.Hash()))
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
 	defer zw.Close()
-	tarDigester := digest.Canonical.Digester()
+	tarDigester := newDigester(s.NewHash)
 	if err := tarDirectory(root, name, io.MultiWriter(zw, tarDigester.Hash()), s.Reproducible); err != nil {
 		return ocispec.Descriptor{}, err
 	}
@@ -126,13 +244,13 @@ func (s *FileStore) descFromDir(name, mediaType, root string) (ocispec.Descripto
 
 	// generate descriptor
 	if mediaType == "" {
-		mediaType = DefaultBlobDirMediaType
+		mediaType = compression.MediaType(ocispec.MediaTypeImageLayer)
 	}
 	info, err := file.Stat()
 	if err != nil {
 		return ocispec.Descriptor{}, err
 	}
-	return ocispec.Descriptor{
+	desc := ocispec.Descriptor{
 		MediaType: mediaType,
 		Digest:    digester.Digest(),
 		Size:      info.Size(),
@@ -140,7 +258,11 @@ func (s *FileStore) descFromDir(name, mediaType, root string) (ocispec.Descripto
 			AnnotationDigest: tarDigester.Digest().String(),
 			AnnotationUnpack: "true",
 		},
-	}, nil
+	}
+	if compression != CompressionGzip {
+		desc.Annotations[AnnotationCompression] = string(compression)
+	}
+	return desc, nil
 }
 
 func (s *FileStore) tempFile() (*os.File, error) {
@@ -179,6 +301,16 @@ func (s *FileStore) ReaderAt(ctx context.Context, desc ocispec.Descriptor) (cont
 	if err != nil {
 		return nil, err
 	}
+	if value, ok := s.shards.Load(name); ok {
+		shard := value.(fileShard)
+		return sizeReaderAt{
+			readAtCloser: shardReaderAt{
+				SectionReader: io.NewSectionReader(file, shard.offset, desc.Size),
+				file:          file,
+			},
+			size: desc.Size,
+		}, nil
+	}
 
 	return sizeReaderAt{
 		readAtCloser: file,
@@ -204,7 +336,25 @@ func (s *FileStore) Writer(ctx context.Context, opts ...content.WriterOpt) (cont
 	if err != nil {
 		return nil, err
 	}
-	file, afterCommit, err := s.createWritePath(path, desc, name)
+	var replace bool
+	// the conflicts of directories are resolved per file on extraction
+	if s.ResolveConflict != nil && desc.Annotations[AnnotationUnpack] != "true" {
+		if path, replace, err = s.resolveConflict(name, path, desc); err != nil {
+			return nil, err
+		}
+		if path == "" {
+			return nil, errors.Wrapf(errdefs.ErrAlreadyExists, "%s: skipped on conflict", name)
+		}
+	}
+	var (
+		file        *os.File
+		afterCommit func() error
+	)
+	if replace && compressionOf(desc) == "" {
+		file, afterCommit, err = s.createReplacePath(path)
+	} else {
+		file, afterCommit, err = s.createWritePath(path, desc, name)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -214,7 +364,7 @@ func (s *FileStore) Writer(ctx context.Context, opts ...content.WriterOpt) (cont
 		store:    s,
 		file:     file,
 		desc:     desc,
-		digester: digest.Canonical.Digester(),
+		digester: newDigester(s.NewHash),
 		status: content.Status{
 			Ref:       name,
 			Total:     desc.Size,
@@ -256,23 +406,89 @@ func (s *FileStore) resolveWritePath(name string) (string, error) {
 }
 
 func (s *FileStore) createWritePath(path string, desc ocispec.Descriptor, prefix string) (*os.File, func() error, error) {
-	if value, ok := desc.Annotations[AnnotationUnpack]; !ok || value != "true" {
-		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-			return nil, nil, err
+	compression := compressionOf(desc)
+	checksum := desc.Annotations[AnnotationDigest]
+	if value, ok := desc.Annotations[AnnotationUnpack]; ok && value == "true" {
+		file, err := s.tempFile()
+		afterCommit := func() error {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			write := entryWriter(writeFile)
+			if s.ResolveConflict != nil {
+				write = s.writeEntry
+			}
+			return extractTar(path, prefix, file.Name(), compression, checksum, write)
 		}
-		file, err := os.Create(path)
-		return file, nil, err
+		return file, afterCommit, err
 	}
 
-	if err := os.MkdirAll(path, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, nil, err
+	}
+	// the file is in place only once its content is verified on commit
+	if compression != "" && compression != CompressionNone {
+		file, err := s.tempFile()
+		afterCommit := func() error {
+			decompressed, rename, err := s.createReplacePath(path)
+			if err != nil {
+				return err
+			}
+			err = decompressFile(decompressed, file.Name(), compression, checksum)
+			if closeErr := decompressed.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(decompressed.Name())
+				s.tmpFiles.Delete(decompressed.Name())
+				return errors.Wrap(err, path)
+			}
+			if err := rename(); err != nil {
+				return err
+			}
+			if s.PreserveAttributes {
+				return restoreAttributes(path, desc)
+			}
+			return nil
+		}
+		return file, afterCommit, err
+	}
+	file, rename, err := s.createReplacePath(path)
+	if err != nil {
 		return nil, nil, err
 	}
-	file, err := s.tempFile()
-	checksum := desc.Annotations[AnnotationDigest]
 	afterCommit := func() error {
-		return extractTarGzip(path, prefix, file.Name(), checksum)
+		if err := rename(); err != nil {
+			return err
+		}
+		if s.PreserveAttributes {
+			return restoreAttributes(path, desc)
+		}
+		return nil
+	}
+	return file, afterCommit, nil
+}
+
+// stage defers putting a committed file in place until Promote.
+func (s *FileStore) stage(promote func() error) {
+	s.stageLock.Lock()
+	defer s.stageLock.Unlock()
+	s.staged = append(s.staged, promote)
+}
+
+// Promote puts the files committed in the staged mode in place, in the order
+// they were committed.
+func (s *FileStore) Promote() error {
+	s.stageLock.Lock()
+	defer s.stageLock.Unlock()
+	for len(s.staged) > 0 {
+		promote := s.staged[0]
+		s.staged = s.staged[1:]
+		if err := promote(); err != nil {
+			return err
+		}
 	}
-	return file, afterCommit, err
+	return nil
 }
 
 // MapPath maps name to path
@@ -371,20 +587,38 @@ func (w *fileWriter) Commit(ctx context.Context, size int64, expected digest.Dig
 		return errors.Wrap(err, "failed to close file")
 	}
 
+	if expected == "" {
+		expected = w.desc.Digest
+	}
 	if size > 0 && size != fileInfo.Size() {
+		w.discard(file.Name())
 		return errors.Wrapf(errdefs.ErrFailedPrecondition, "unexpected commit size %d, expected %d", fileInfo.Size(), size)
 	}
 	if dgst := w.digester.Digest(); expected != "" && expected != dgst {
+		w.discard(file.Name())
 		return errors.Wrapf(errdefs.ErrFailedPrecondition, "unexpected commit digest %s, expected %s", dgst, expected)
 	}
 
 	w.store.set(w.desc)
 	if w.afterCommit != nil {
+		if w.store.Staged {
+			w.store.stage(w.afterCommit)
+			return nil
+		}
 		return w.afterCommit()
 	}
 	return nil
 }
 
+// discard removes the temporary file of content failing verification, so that
+// no corrupt output is left.
+func (w *fileWriter) discard(name string) {
+	if _, ok := w.store.tmpFiles.Load(name); ok {
+		os.Remove(name)
+		w.store.tmpFiles.Delete(name)
+	}
+}
+
 // Close the writer, flushing any unwritten data and leaving the progress in
 // tact.
 func (w *fileWriter) Close() error {
@@ -409,3 +643,27 @@ func (w *fileWriter) Truncate(size int64) error {
 	}
 	return w.file.Truncate(0)
 }
+
+// restoreAttributes restores the permissions and the modification time of the
+// file at path from the annotations of desc.
+func restoreAttributes(path string, desc ocispec.Descriptor) error {
+	if value, ok := desc.Annotations[AnnotationFileMode]; ok {
+		mode, err := strconv.ParseUint(value, 8, 32)
+		if err != nil {
+			return errors.Wrapf(err, "invalid file mode %q", value)
+		}
+		if err := os.Chmod(path, os.FileMode(mode).Perm()); err != nil {
+			return err
+		}
+	}
+	if value, ok := desc.Annotations[AnnotationFileModTime]; ok {
+		modTime, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid file modification time %q", value)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
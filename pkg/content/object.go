@@ -0,0 +1,346 @@
+package content
+
+import (
+	"archive/tar"
+	"context"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// ensure interface
+var (
+	_ content.Ingester = &ObjectStore{}
+)
+
+// errUploadAborted aborts the upload of a writer closed before committing.
+var errUploadAborted = errors.New("upload aborted")
+
+// ObjectUploader uploads objects to an object storage, such as S3 or GCS.
+type ObjectUploader interface {
+	// Upload stores the content read from r under key. No object is stored
+	// if reading r fails.
+	Upload(ctx context.Context, key string, r io.Reader) error
+}
+
+// ObjectStore writes blobs as objects named by their titles under a key
+// prefix. The blobs are streamed to the uploader as they are written, without
+// being staged on the local disk, except the files of directories, which are
+// uploaded once the directory is verified.
+type ObjectStore struct {
+	// NewHash creates the sha256 hash used to verify digests.
+	NewHash func() hash.Hash
+
+	uploader ObjectUploader
+	prefix   string
+}
+
+// NewObjectStore creates a new object store uploading under prefix
+func NewObjectStore(uploader ObjectUploader, prefix string) *ObjectStore {
+	return &ObjectStore{
+		uploader: uploader,
+		prefix:   strings.Trim(prefix, "/"),
+	}
+}
+
+// Key returns the key of the object of name
+func (s *ObjectStore) Key(name string) (string, error) {
+	name = path.Clean(filepath.ToSlash(name))
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return "", ErrPathTraversalDisallowed
+	}
+	return path.Join(s.prefix, name), nil
+}
+
+// Writer begins the upload of the blob identified by desc. Directories are
+// extracted to an object per regular file, while links are skipped. The
+// digest of a directory is verified after its files are uploaded.
+func (s *ObjectStore) Writer(ctx context.Context, opts ...content.WriterOpt) (content.Writer, error) {
+	var wOpts content.WriterOpts
+	for _, opt := range opts {
+		if err := opt(&wOpts); err != nil {
+			return nil, err
+		}
+	}
+	desc := wOpts.Desc
+
+	name, ok := ResolveName(desc)
+	if !ok {
+		return nil, ErrNoName
+	}
+	if _, ok := desc.Annotations[AnnotationShardFile]; ok {
+		return nil, errors.Errorf("%s: shards cannot be written to object storage", name)
+	}
+	key, err := s.Key(name)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		compression := compressionOf(desc)
+		if desc.Annotations[AnnotationUnpack] == "true" {
+			err = s.uploadTar(ctx, name, pr, compression, desc.Annotations[AnnotationDigest])
+		} else if compression != "" && compression != CompressionNone {
+			err = s.uploadDecompressed(ctx, key, pr, compression, desc.Annotations[AnnotationDigest])
+		} else {
+			err = s.uploader.Upload(ctx, key, pr)
+		}
+		// unblock the writer if the upload stopped reading
+		pr.CloseWithError(err)
+		done <- err
+	}()
+
+	now := time.Now()
+	return &objectWriter{
+		pipe:     pw,
+		done:     done,
+		digester: newDigester(s.NewHash),
+		status: content.Status{
+			Ref:       name,
+			Total:     desc.Size,
+			StartedAt: now,
+			UpdatedAt: now,
+		},
+	}, nil
+}
+
+// uploadTar uploads the regular files of the compressed tarball of the
+// directory name. The files are staged on the local disk and uploaded only
+// after the whole blob is read and verified, so that no object is stored for
+// content failing the verification.
+func (s *ObjectStore) uploadTar(ctx context.Context, name string, r io.Reader, compression Compression, checksum string) error {
+	zr, err := compression.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	tr, verifier := verifyingReader(zr, checksum)
+
+	staging, err := ioutil.TempDir("", "oras_objects_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	type stagedFile struct {
+		key  string
+		path string
+	}
+	var files []stagedFile
+	reader := tar.NewReader(tr)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue // directories are implied by the keys
+		}
+		rel, err := filepath.Rel(name, header.Name)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(filepath.ToSlash(rel), "../") {
+			return errors.Errorf("%q does not have prefix %q", header.Name, name)
+		}
+		key, err := s.Key(path.Join(name, filepath.ToSlash(rel)))
+		if err != nil {
+			return err
+		}
+		file, err := ioutil.TempFile(staging, "file-")
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(file, reader)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		files = append(files, stagedFile{
+			key:  key,
+			path: file.Name(),
+		})
+	}
+
+	// consume the padding so that the digest of the blob is computed, and
+	// wait for the blob to be committed
+	if _, err := io.Copy(ioutil.Discard, tr); err != nil {
+		return err
+	}
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return err
+	}
+	if verifier != nil && !verifier.Verified() {
+		return errors.New("content digest mismatch")
+	}
+
+	for _, staged := range files {
+		file, err := os.Open(staged.path)
+		if err != nil {
+			return err
+		}
+		err = s.uploader.Upload(ctx, staged.key, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadDecompressed uploads the decompressed content of a compressed file.
+// The end of the content is withheld from the uploader until the blob is
+// committed and the content is verified, so that no object is stored for
+// content failing the verification.
+func (s *ObjectStore) uploadDecompressed(ctx context.Context, key string, r io.Reader, compression Compression, checksum string) error {
+	zr, err := compression.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	cr, verifier := verifyingReader(zr, checksum)
+	return s.uploader.Upload(ctx, key, &verifiedReader{
+		Reader:   cr,
+		blob:     r,
+		verifier: verifier,
+	})
+}
+
+// verifyingReader returns the reader verifying the content against the
+// checksum, if valid.
+func verifyingReader(r io.Reader, checksum string) (io.Reader, digest.Verifier) {
+	if checksum == "" {
+		return r, nil
+	}
+	dgst, err := digest.Parse(checksum)
+	if err != nil {
+		return r, nil
+	}
+	verifier := dgst.Verifier()
+	return io.TeeReader(r, verifier), verifier
+}
+
+// verifiedReader reads the content, and reports the end of it only once the
+// rest of the blob, such as the trailer of the compression, is read and the
+// content is verified.
+type verifiedReader struct {
+	io.Reader
+	blob     io.Reader
+	verifier digest.Verifier
+}
+
+func (r *verifiedReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != io.EOF {
+		return n, err
+	}
+	if _, err := io.Copy(ioutil.Discard, r.blob); err != nil {
+		return n, err
+	}
+	if r.verifier != nil && !r.verifier.Verified() {
+		return n, errors.New("content digest mismatch")
+	}
+	return n, io.EOF
+}
+
+type objectWriter struct {
+	pipe     *io.PipeWriter
+	done     <-chan error
+	digester digest.Digester
+	status   content.Status
+}
+
+func (w *objectWriter) Status() (content.Status, error) {
+	return w.status, nil
+}
+
+// Digest returns the current digest of the content, up to the current write.
+//
+// Cannot be called concurrently with `Write`.
+func (w *objectWriter) Digest() digest.Digest {
+	return w.digester.Digest()
+}
+
+// Write p to the upload.
+func (w *objectWriter) Write(p []byte) (n int, err error) {
+	if w.pipe == nil {
+		return 0, errors.Wrap(errdefs.ErrFailedPrecondition, "cannot write on closed writer")
+	}
+	n, err = w.pipe.Write(p)
+	w.digester.Hash().Write(p[:n])
+	w.status.Offset += int64(n)
+	w.status.UpdatedAt = time.Now()
+	return n, err
+}
+
+// Commit completes the upload if the content matches size and expected, and
+// aborts it otherwise.
+func (w *objectWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...content.Opt) error {
+	var base content.Info
+	for _, opt := range opts {
+		if err := opt(&base); err != nil {
+			return err
+		}
+	}
+
+	if w.pipe == nil {
+		return errors.Wrap(errdefs.ErrFailedPrecondition, "cannot commit on closed writer")
+	}
+	pipe := w.pipe
+	w.pipe = nil
+
+	var err error
+	if size > 0 && size != w.status.Offset {
+		err = errors.Wrapf(errdefs.ErrFailedPrecondition, "unexpected commit size %d, expected %d", w.status.Offset, size)
+	} else if dgst := w.digester.Digest(); expected != "" && expected != dgst {
+		err = errors.Wrapf(errdefs.ErrFailedPrecondition, "unexpected commit digest %s, expected %s", dgst, expected)
+	}
+	if err != nil {
+		pipe.CloseWithError(err)
+		<-w.done
+		return err
+	}
+
+	pipe.Close()
+	if err := <-w.done; err != nil {
+		return errors.Wrap(err, "upload failed")
+	}
+	return nil
+}
+
+// Close aborts the upload if not committed.
+func (w *objectWriter) Close() error {
+	if w.pipe == nil {
+		return nil
+	}
+	w.pipe.CloseWithError(errUploadAborted)
+	w.pipe = nil
+	<-w.done
+	return nil
+}
+
+// Truncate only supports truncating a writer not written yet, since the
+// uploaded content cannot be rewound.
+func (w *objectWriter) Truncate(size int64) error {
+	if size != 0 || w.status.Offset != 0 {
+		return ErrUnsupportedSize
+	}
+	return nil
+}
@@ -101,6 +101,7 @@ func (s *OCIStore) AddReference(name string, desc ocispec.Descriptor) {
 		for i, ref := range s.index.Manifests {
 			if name == ref.Annotations[ocispec.AnnotationRefName] {
 				s.index.Manifests[i] = desc
+				s.nameMap[name] = desc
 				return
 			}
 		}
@@ -0,0 +1,77 @@
+package content
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// BlobLinker adds blobs by linking the files of another store instead of
+// copying the content.
+type BlobLinker interface {
+	// LinkBlob adds the blob described by desc from the source resolver. An
+	// errdefs.ErrNotImplemented error is returned if the blob cannot be
+	// linked, e.g. the source is a remote or on another file system, and an
+	// errdefs.ErrAlreadyExists error if the blob exists.
+	LinkBlob(ctx context.Context, src remotes.Resolver, desc ocispec.Descriptor) error
+}
+
+// ensure interface
+var (
+	_ BlobLinker = &ociResolver{}
+)
+
+// LinkBlob links the blob from the source OCI image layout by a hard link,
+// or by a reflink if hard links are not possible. Only the size of the blob is
+// verified, as the blobs of the layout are trusted.
+func (r *ociResolver) LinkBlob(ctx context.Context, src remotes.Resolver, desc ocispec.Descriptor) error {
+	srcResolver, ok := src.(*ociResolver)
+	if !ok {
+		return errors.Wrap(errdefs.ErrNotImplemented, "link from a non OCI image layout")
+	}
+	if _, err := r.store.Info(ctx, desc.Digest); err == nil {
+		return errors.Wrapf(errdefs.ErrAlreadyExists, "content %v", desc.Digest)
+	}
+	srcPath := srcResolver.store.blobPath(desc.Digest)
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.Wrapf(errdefs.ErrNotFound, "content %v", desc.Digest)
+		}
+		return err
+	}
+	if info.Size() != desc.Size {
+		return errors.Wrapf(errdefs.ErrFailedPrecondition, "unexpected size %d of content %v, expected %d", info.Size(), desc.Digest, desc.Size)
+	}
+
+	dstPath := r.store.blobPath(desc.Digest)
+	dir := filepath.Dir(dstPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// link to a temporary name first so that partial blobs are never visible
+	tmp, err := ioutil.TempDir(dir, ".link-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	tmpPath := filepath.Join(tmp, desc.Digest.Encoded())
+	if err := os.Link(srcPath, tmpPath); err != nil {
+		if err := reflink(srcPath, tmpPath); err != nil {
+			return errors.Wrapf(errdefs.ErrNotImplemented, "link content %v: %v", desc.Digest, err)
+		}
+	}
+	return os.Rename(tmpPath, dstPath)
+}
+
+// blobPath returns the path of the blob in the layout.
+func (s *OCIStore) blobPath(dgst digest.Digest) string {
+	return filepath.Join(s.root, "blobs", dgst.Algorithm().String(), dgst.Encoded())
+}
@@ -0,0 +1,29 @@
+package content
+
+import (
+	"os"
+	"syscall"
+)
+
+// ficlone is the FICLONE ioctl request sharing the extents of a file.
+const ficlone = 0x40049409
+
+// reflink clones the file at src to dst, which is supported by file systems
+// with copy-on-write, e.g. btrfs and xfs.
+func reflink(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		return err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dstFile.Fd(), ficlone, srcFile.Fd()); errno != 0 {
+		dstFile.Close()
+		os.Remove(dst)
+		return errno
+	}
+	return dstFile.Close()
+}
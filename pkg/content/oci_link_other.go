@@ -0,0 +1,10 @@
+// +build !linux
+
+package content
+
+import "github.com/pkg/errors"
+
+// reflink is not supported on this platform.
+func reflink(src, dst string) error {
+	return errors.New("reflink not supported")
+}
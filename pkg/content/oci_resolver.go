@@ -0,0 +1,166 @@
+package content
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/remotes"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// ensure interface
+var (
+	_ remotes.Resolver = &ociResolver{}
+)
+
+// ociResolver resolves references against an OCI image layout, where the
+// references are the names in the index, i.e. tags, or `@digest`.
+type ociResolver struct {
+	store *OCIStore
+	lock  *sync.Mutex
+}
+
+// Resolver returns a resolver to push to and pull from the OCI image layout
+// as if it were a remote. Pushed manifests are tagged in the index, which
+// is saved on commit.
+func (s *OCIStore) Resolver() remotes.Resolver {
+	return &ociResolver{
+		store: s,
+		lock:  &sync.Mutex{},
+	}
+}
+
+// Resolve resolves the reference in the index.
+func (r *ociResolver) Resolve(ctx context.Context, ref string) (string, ocispec.Descriptor, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if dgst, ok := parseDigestRef(ref); ok {
+		for _, desc := range r.store.index.Manifests {
+			if desc.Digest == dgst {
+				return ref, desc, nil
+			}
+		}
+		return "", ocispec.Descriptor{}, errors.Wrapf(errdefs.ErrNotFound, "%s", ref)
+	}
+	desc, ok := r.store.ListReferences()[ref]
+	if !ok {
+		return "", ocispec.Descriptor{}, errors.Wrapf(errdefs.ErrNotFound, "%s", ref)
+	}
+	return ref, desc, nil
+}
+
+// Fetcher returns a fetcher reading from the OCI image layout.
+func (r *ociResolver) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	return remotes.FetcherFunc(func(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+		readerAt, err := r.store.ReaderAt(ctx, desc)
+		if err != nil {
+			return nil, err
+		}
+		return &readCloser{
+			Reader: content.NewReader(readerAt),
+			Closer: readerAt,
+		}, nil
+	}), nil
+}
+
+// Pusher returns a pusher writing to the OCI image layout. Manifests are
+// tagged by the reference unless the reference is a digest. As with the
+// docker pusher, a reference in the form of `tag@digest` tags only the
+// manifest of the digest, i.e. the root, and not the children of an index.
+func (r *ociResolver) Pusher(ctx context.Context, ref string) (remotes.Pusher, error) {
+	tag, root := parseTagRef(ref)
+	return remotes.PusherFunc(func(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+		tagged := tag != "" && isManifestMediaType(desc.MediaType) && (root == "" || desc.Digest == root)
+		if _, err := r.store.Info(ctx, desc.Digest); err == nil {
+			if tagged {
+				if err := r.tag(tag, desc); err != nil {
+					return nil, err
+				}
+			}
+			return nil, errors.Wrapf(errdefs.ErrAlreadyExists, "content %v", desc.Digest)
+		}
+
+		writer, err := r.store.Writer(ctx, content.WithRef(desc.Digest.String()), content.WithDescriptor(desc))
+		if err != nil {
+			return nil, err
+		}
+		if !tagged {
+			return writer, nil
+		}
+		return &taggingWriter{
+			Writer: writer,
+			tag: func() error {
+				return r.tag(tag, desc)
+			},
+		}, nil
+	}), nil
+}
+
+// tag adds the reference to the index and saves the index.
+func (r *ociResolver) tag(name string, desc ocispec.Descriptor) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.store.AddReference(name, ocispec.Descriptor{
+		MediaType: desc.MediaType,
+		Digest:    desc.Digest,
+		Size:      desc.Size,
+		Platform:  desc.Platform,
+	})
+	return r.store.SaveIndex()
+}
+
+// taggingWriter tags the content after commit.
+type taggingWriter struct {
+	content.Writer
+	tag func() error
+}
+
+func (w *taggingWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...content.Opt) error {
+	if err := w.Writer.Commit(ctx, size, expected, opts...); err != nil {
+		return err
+	}
+	return w.tag()
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// parseDigestRef parses references in the form of `@digest` or `digest`.
+func parseDigestRef(ref string) (digest.Digest, bool) {
+	dgst, err := digest.Parse(strings.TrimPrefix(ref, "@"))
+	return dgst, err == nil
+}
+
+// parseTagRef parses references in the form of `tag`, `tag@digest` or a
+// digest reference into the tag and the digest of the root to tag.
+func parseTagRef(ref string) (string, digest.Digest) {
+	if _, ok := parseDigestRef(ref); ok {
+		return "", ""
+	}
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		if root, ok := parseDigestRef(ref[i+1:]); ok {
+			return ref[:i], root
+		}
+	}
+	return ref, ""
+}
+
+func isManifestMediaType(mediaType string) bool {
+	switch mediaType {
+	case ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageIndex,
+		images.MediaTypeDockerSchema2Manifest, images.MediaTypeDockerSchema2ManifestList:
+		return true
+	}
+	return false
+}
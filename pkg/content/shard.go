@@ -0,0 +1,186 @@
+package content
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// fileShard locates a shard in the file mapped to its name.
+type fileShard struct {
+	offset int64
+}
+
+type shardReaderAt struct {
+	*io.SectionReader
+	file *os.File
+}
+
+func (ra shardReaderAt) Close() error {
+	return ra.file.Close()
+}
+
+// AddShards adds a file reference split into shards of at most shardSize
+// bytes, so that large files can be transferred as multiple blobs. The shards
+// are named `<name>.shard-<index>-of-<count>` and annotated with their
+// position in the file, which is reassembled by JoinShards after pulling.
+func (s *FileStore) AddShards(name, mediaType, path string, shardSize int64) ([]ocispec.Descriptor, error) {
+	if shardSize <= 0 {
+		return nil, errors.Wrapf(ErrUnsupportedSize, "shard size %d", shardSize)
+	}
+	if path == "" {
+		path = name
+	}
+	path = s.MapPath(name, path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errors.Errorf("%s: directories cannot be sharded", name)
+	}
+	if mediaType == "" {
+		mediaType = DefaultBlobMediaType
+	}
+
+	count := int((info.Size() + shardSize - 1) / shardSize)
+	if count == 0 {
+		count = 1
+	}
+	fileDigester := newDigester(s.NewHash)
+	descs := make([]ocispec.Descriptor, 0, count)
+	for i := 0; i < count; i++ {
+		offset := int64(i) * shardSize
+		size := shardSize
+		if remaining := info.Size() - offset; remaining < size {
+			size = remaining
+		}
+		shardDigester := newDigester(s.NewHash)
+		section := io.NewSectionReader(file, offset, size)
+		if _, err := io.Copy(io.MultiWriter(fileDigester.Hash(), shardDigester.Hash()), section); err != nil {
+			return nil, err
+		}
+
+		shardName := fmt.Sprintf("%s.shard-%05d-of-%05d", name, i+1, count)
+		s.MapPath(shardName, path)
+		s.shards.Store(shardName, fileShard{offset: offset})
+		descs = append(descs, ocispec.Descriptor{
+			MediaType: mediaType,
+			Digest:    shardDigester.Digest(),
+			Size:      size,
+			Annotations: map[string]string{
+				ocispec.AnnotationTitle: shardName,
+				AnnotationShardFile:     name,
+				AnnotationShardIndex:    strconv.Itoa(i),
+				AnnotationShardCount:    strconv.Itoa(count),
+			},
+		})
+	}
+
+	fileDigest := fileDigester.Digest().String()
+	for _, desc := range descs {
+		desc.Annotations[AnnotationShardFileDigest] = fileDigest
+		s.set(desc)
+	}
+	return descs, nil
+}
+
+// JoinShards reassembles the files from the pulled shards described by descs
+// and removes the shards. Descriptors without shard annotations are ignored.
+// The names of the reassembled files are returned.
+func (s *FileStore) JoinShards(descs []ocispec.Descriptor) ([]string, error) {
+	shards := make(map[string][]ocispec.Descriptor)
+	var names []string
+	for _, desc := range descs {
+		name, ok := desc.Annotations[AnnotationShardFile]
+		if !ok {
+			continue
+		}
+		if _, ok := shards[name]; !ok {
+			names = append(names, name)
+		}
+		shards[name] = append(shards[name], desc)
+	}
+
+	for _, name := range names {
+		if err := s.joinShards(name, shards[name]); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+func (s *FileStore) joinShards(name string, descs []ocispec.Descriptor) error {
+	count, err := strconv.Atoi(descs[0].Annotations[AnnotationShardCount])
+	if err != nil || count != len(descs) {
+		return errors.Wrapf(ErrInvalidShard, "%s: expected %s shards, got %d", name, descs[0].Annotations[AnnotationShardCount], len(descs))
+	}
+	ordered := make([]ocispec.Descriptor, count)
+	for _, desc := range descs {
+		index, err := strconv.Atoi(desc.Annotations[AnnotationShardIndex])
+		if err != nil || index < 0 || index >= count || ordered[index].Digest != "" {
+			return errors.Wrapf(ErrInvalidShard, "%s: invalid shard index %q", name, desc.Annotations[AnnotationShardIndex])
+		}
+		ordered[index] = desc
+	}
+
+	path, err := s.resolveWritePath(name)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	digester := newDigester(s.NewHash)
+	if err := s.copyShards(io.MultiWriter(file, digester.Hash()), ordered); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if expected := descs[0].Annotations[AnnotationShardFileDigest]; expected != "" && digest.Digest(expected) != digester.Digest() {
+		os.Remove(path)
+		return errors.Wrapf(ErrInvalidShard, "%s: digest mismatch", name)
+	}
+
+	for _, desc := range ordered {
+		shardName, _ := ResolveName(desc)
+		if err := os.Remove(s.ResolvePath(shardName)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) copyShards(w io.Writer, descs []ocispec.Descriptor) error {
+	for _, desc := range descs {
+		shardName, ok := ResolveName(desc)
+		if !ok {
+			return ErrNoName
+		}
+		shard, err := os.Open(s.ResolvePath(shardName))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, shard)
+		shard.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
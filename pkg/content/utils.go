@@ -2,7 +2,6 @@ package content
 
 import (
 	"archive/tar"
-	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
@@ -85,10 +84,14 @@ func tarDirectory(root, prefix string, w io.Writer, stripTimes bool) error {
 	return nil
 }
 
+// entryWriter writes the regular file of the named tar entry to the path, and
+// returns the path written, or an empty path if the entry is skipped.
+type entryWriter func(name, path string, r io.Reader, perm os.FileMode) (string, error)
+
 // extractTarDirectory extracts tar file to a directory specified by the `root`
 // parameter. The file name prefix is ensured to be the string specified by the
-// `prefix` parameter and is trimmed.
-func extractTarDirectory(root, prefix string, r io.Reader) error {
+// `prefix` parameter and is trimmed. The regular files are written by write.
+func extractTarDirectory(root, prefix string, r io.Reader, write entryWriter) error {
 	tr := tar.NewReader(r)
 	for {
 		header, err := tr.Next()
@@ -113,7 +116,10 @@ func extractTarDirectory(root, prefix string, r io.Reader) error {
 		// Create content
 		switch header.Typeflag {
 		case tar.TypeReg:
-			err = writeFile(path, tr, header.FileInfo().Mode())
+			path, err = write(name, path, tr, header.FileInfo().Mode())
+			if err == nil && path == "" {
+				continue
+			}
 		case tar.TypeDir:
 			err = os.MkdirAll(path, header.FileInfo().Mode())
 		case tar.TypeLink:
@@ -132,23 +138,28 @@ func extractTarDirectory(root, prefix string, r io.Reader) error {
 	}
 }
 
-func writeFile(path string, r io.Reader, perm os.FileMode) error {
+// writeFile writes the regular file of a tar entry over the path.
+func writeFile(_, path string, r io.Reader, perm os.FileMode) (string, error) {
 	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer file.Close()
-	_, err = io.Copy(file, r)
-	return err
+	if _, err := io.Copy(file, r); err != nil {
+		return "", err
+	}
+	return path, nil
 }
 
-func extractTarGzip(root, prefix, filename, checksum string) error {
+// extractTar extracts the compressed tar file to a directory, verifying the
+// tar against the checksum if any.
+func extractTar(root, prefix, filename string, compression Compression, checksum string, write entryWriter) error {
 	file, err := os.Open(filename)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	zr, err := gzip.NewReader(file)
+	zr, err := compression.NewReader(file)
 	if err != nil {
 		return err
 	}
@@ -161,7 +172,7 @@ func extractTarGzip(root, prefix, filename, checksum string) error {
 			r = io.TeeReader(r, verifier)
 		}
 	}
-	if err := extractTarDirectory(root, prefix, r); err != nil {
+	if err := extractTarDirectory(root, prefix, r, write); err != nil {
 		return err
 	}
 	if verifier != nil && !verifier.Verified() {
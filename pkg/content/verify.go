@@ -0,0 +1,124 @@
+package content
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LayerReport is the structure of a layer read by VerifyLayer.
+type LayerReport struct {
+	// Compression is the compression of the layer.
+	Compression Compression `json:"compression"`
+	// Tar tells whether the layer is a tar archive.
+	Tar bool `json:"tar"`
+	// Entries is the number of entries of the tar archive.
+	Entries int `json:"entries"`
+	// UncompressedSize is the size of the decompressed content.
+	UncompressedSize int64 `json:"uncompressedSize"`
+}
+
+// VerifyLayer reads the whole layer, checking the integrity of its compression
+// and the readability of the tar archive it contains, if any. The compression
+// and the tar format are given by the media type, e.g.
+// application/vnd.oci.image.layer.v1.tar+zstd, or detected from the content
+// if the media type is empty. The report covers the content read so far if
+// the layer is corrupt.
+func VerifyLayer(r io.Reader, mediaType string) (LayerReport, error) {
+	var report LayerReport
+	br := bufio.NewReader(r)
+	detected := detectCompression(br)
+	if mediaType == "" {
+		report.Compression = detected
+	} else {
+		report.Compression = mediaTypeCompression(mediaType)
+		if detected != CompressionNone && detected != report.Compression {
+			return report, errors.Errorf("content is compressed with %s, but the media type %s declares %s", detected, mediaType, report.Compression)
+		}
+	}
+
+	zr, err := report.Compression.NewReader(br)
+	if err != nil {
+		return report, errors.Wrapf(err, "invalid %s stream", report.Compression)
+	}
+	defer zr.Close()
+	counter := &countingReader{Reader: zr}
+	content := bufio.NewReader(counter)
+	if mediaType == "" {
+		report.Tar = isTar(content)
+	} else {
+		report.Tar = strings.Contains(mediaType, "tar")
+	}
+
+	if report.Tar {
+		tr := tar.NewReader(content)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return report, errors.Wrapf(err, "invalid tar entry %d", report.Entries+1)
+			}
+			if _, err := io.Copy(ioutil.Discard, tr); err != nil {
+				return report, errors.Wrapf(err, "invalid tar entry %q", header.Name)
+			}
+			report.Entries++
+		}
+	}
+	// read to the end, which checks the checksums of the compression
+	if _, err := io.Copy(ioutil.Discard, content); err != nil {
+		report.UncompressedSize = counter.n
+		return report, errors.Wrapf(err, "invalid %s stream", report.Compression)
+	}
+	report.UncompressedSize = counter.n
+	return report, nil
+}
+
+// mediaTypeCompression returns the compression of the media type, i.e. the
+// `+gzip` and `+zstd` suffixes, or the `.gzip` and `.zstd` ones of the docker
+// layers.
+func mediaTypeCompression(mediaType string) Compression {
+	switch {
+	case strings.HasSuffix(mediaType, "+gzip"), strings.HasSuffix(mediaType, ".gzip"):
+		return CompressionGzip
+	case strings.HasSuffix(mediaType, "+zstd"), strings.HasSuffix(mediaType, ".zstd"):
+		return CompressionZstd
+	}
+	return CompressionNone
+}
+
+// detectCompression detects the compression of the content by its magic
+// number.
+func detectCompression(br *bufio.Reader) Compression {
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return CompressionGzip
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return CompressionZstd
+	}
+	return CompressionNone
+}
+
+// isTar tells whether the content starts with a POSIX or GNU tar header.
+func isTar(br *bufio.Reader) bool {
+	header, _ := br.Peek(263)
+	return len(header) == 263 && bytes.HasPrefix(header[257:], []byte("ustar"))
+}
+
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
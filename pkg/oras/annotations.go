@@ -0,0 +1,90 @@
+package oras
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/remotes"
+	artifact "github.com/deislabs/oras/pkg/artifact"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// DefaultManifestSizeLimit is the manifest size commonly accepted by
+// registries.
+const DefaultManifestSizeLimit = 4 * 1024 * 1024
+
+// externalizeAnnotations moves the manifest annotations into an annotations
+// blob appended to the descriptors. Only a pointer to the blob is left in
+// the manifest annotations.
+func externalizeAnnotations(provider content.Provider, descriptors []ocispec.Descriptor, opts *pushOpts) (content.Provider, []ocispec.Descriptor, error) {
+	annotationsBytes, err := json.Marshal(opts.manifestAnnotations)
+	if err != nil {
+		return nil, nil, err
+	}
+	desc := ocispec.Descriptor{
+		MediaType: artifact.AnnotationsMediaType,
+		Digest:    digest.FromBytes(annotationsBytes),
+		Size:      int64(len(annotationsBytes)),
+	}
+	store := newHybridStoreFromProvider(provider)
+	store.Set(desc, annotationsBytes)
+
+	opts.manifestAnnotations = map[string]string{
+		artifact.AnnotationExternalAnnotations: desc.Digest.String(),
+	}
+	descriptors = append(descriptors[:len(descriptors):len(descriptors)], desc)
+	return store, descriptors, nil
+}
+
+// FetchManifestAnnotations returns the annotations of the manifest, including
+// the ones moved to an annotations blob on push as the manifest would exceed
+// the size limit otherwise.
+func FetchManifestAnnotations(ctx context.Context, fetcher remotes.Fetcher, manifest ocispec.Manifest) (map[string]string, error) {
+	pointer, ok := manifest.Annotations[artifact.AnnotationExternalAnnotations]
+	if !ok {
+		return manifest.Annotations, nil
+	}
+	var desc *ocispec.Descriptor
+	for i, layer := range manifest.Layers {
+		if layer.MediaType == artifact.AnnotationsMediaType && layer.Digest.String() == pointer {
+			desc = &manifest.Layers[i]
+			break
+		}
+	}
+	if desc == nil {
+		return nil, errors.Wrapf(ErrAnnotationsNotFound, "%s", pointer)
+	}
+
+	rc, err := fetcher.Fetch(ctx, *desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	annotationsBytes, err := ioutil.ReadAll(io.LimitReader(rc, desc.Size))
+	if err != nil {
+		return nil, err
+	}
+	if dgst := desc.Digest.Algorithm().FromBytes(annotationsBytes); dgst != desc.Digest {
+		return nil, errors.Errorf("annotations digest mismatch: expected %s, got %s", desc.Digest, dgst)
+	}
+	var annotations map[string]string
+	if err := json.Unmarshal(annotationsBytes, &annotations); err != nil {
+		return nil, err
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	// inline annotations other than the pointer take precedence
+	for k, v := range manifest.Annotations {
+		if k != artifact.AnnotationExternalAnnotations {
+			annotations[k] = v
+		}
+	}
+	return annotations, nil
+}
@@ -0,0 +1,78 @@
+package oras
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/containerd/containerd/remotes"
+	artifact "github.com/deislabs/oras/pkg/artifact"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/suite"
+)
+
+type AnnotationsSuite struct {
+	suite.Suite
+}
+
+// fetchManifestAnnotations fetches the annotations of a manifest pointing to
+// the annotations blob of the content, served with the extra bytes appended.
+func fetchManifestAnnotations(content []byte, extra []byte) (map[string]string, error) {
+	desc := ocispec.Descriptor{
+		MediaType: artifact.AnnotationsMediaType,
+		Digest:    digest.FromBytes(content),
+		Size:      int64(len(content)),
+	}
+	manifest := ocispec.Manifest{
+		Layers: []ocispec.Descriptor{desc},
+		Annotations: map[string]string{
+			artifact.AnnotationExternalAnnotations: desc.Digest.String(),
+			"inline":                               "true",
+		},
+	}
+	fetcher := remotes.FetcherFunc(func(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(append(content, extra...))), nil
+	})
+	return FetchManifestAnnotations(context.Background(), fetcher, manifest)
+}
+
+func (suite *AnnotationsSuite) TestFetchManifestAnnotations() {
+	annotations, err := fetchManifestAnnotations([]byte(`{"large":"value"}`), nil)
+	suite.Nil(err, "no error fetching annotations")
+	suite.Equal(map[string]string{"large": "value", "inline": "true"}, annotations, "annotations merged")
+
+	annotations, err = fetchManifestAnnotations([]byte(`null`), nil)
+	suite.Nil(err, "no error fetching null annotations")
+	suite.Equal(map[string]string{"inline": "true"}, annotations, "inline annotations kept")
+
+	annotations, err = fetchManifestAnnotations([]byte(`{"large":"value"}`), []byte("trailing"))
+	suite.Nil(err, "no error fetching annotations followed by extra content")
+	suite.Equal("value", annotations["large"], "content read up to the size")
+}
+
+func (suite *AnnotationsSuite) TestFetchManifestAnnotationsMismatch() {
+	content := []byte(`{"large":"value"}`)
+	desc := ocispec.Descriptor{
+		MediaType: artifact.AnnotationsMediaType,
+		Digest:    digest.FromBytes(content),
+		Size:      int64(len(content)),
+	}
+	manifest := ocispec.Manifest{
+		Layers: []ocispec.Descriptor{desc},
+		Annotations: map[string]string{
+			artifact.AnnotationExternalAnnotations: desc.Digest.String(),
+		},
+	}
+	fetcher := remotes.FetcherFunc(func(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader([]byte(`{"large":"other"}`))), nil
+	})
+	_, err := FetchManifestAnnotations(context.Background(), fetcher, manifest)
+	suite.NotNil(err, "error fetching tampered annotations")
+}
+
+func TestAnnotationsSuite(t *testing.T) {
+	suite.Run(t, new(AnnotationsSuite))
+}
@@ -0,0 +1,55 @@
+package oras
+
+import (
+	"context"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Attach pushes files as an artifact referring to the subject manifest
+// referenced by subjectRef. The artifact is pushed by digest to the repository
+// of the subject. The descriptors of the pushed manifest and the resolved
+// subject are returned.
+func Attach(ctx context.Context, resolver remotes.Resolver, subjectRef string, provider content.Provider, descriptors []ocispec.Descriptor, opts ...PushOpt) (ocispec.Descriptor, ocispec.Descriptor, error) {
+	if resolver == nil {
+		return ocispec.Descriptor{}, ocispec.Descriptor{}, ErrResolverUndefined
+	}
+	opt := pushOptsDefaults()
+	for _, o := range opts {
+		if err := o(opt); err != nil {
+			return ocispec.Descriptor{}, ocispec.Descriptor{}, err
+		}
+	}
+	refspec, err := reference.Parse(subjectRef)
+	if err != nil {
+		return ocispec.Descriptor{}, ocispec.Descriptor{}, err
+	}
+	_, subject, err := resolver.Resolve(ctx, subjectRef)
+	if err != nil {
+		return ocispec.Descriptor{}, ocispec.Descriptor{}, err
+	}
+	if opt.subjectPlatform != nil {
+		fetcher, err := resolver.Fetcher(ctx, subjectRef)
+		if err != nil {
+			return ocispec.Descriptor{}, ocispec.Descriptor{}, err
+		}
+		if subject, err = SelectPlatform(ctx, fetcher, subject, opt.subjectPlatform); err != nil {
+			return ocispec.Descriptor{}, ocispec.Descriptor{}, err
+		}
+	}
+	subject = ocispec.Descriptor{
+		MediaType: subject.MediaType,
+		Digest:    subject.Digest,
+		Size:      subject.Size,
+	}
+
+	opts = append(opts[:len(opts):len(opts)], WithSubject(subject))
+	desc, err := Push(ctx, resolver, refspec.Locator, provider, descriptors, opts...)
+	if err != nil {
+		return ocispec.Descriptor{}, ocispec.Descriptor{}, err
+	}
+	return desc, subject, nil
+}
@@ -0,0 +1,220 @@
+package oras
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/containerd/remotes"
+	orascontent "github.com/deislabs/oras/pkg/content"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// CatFile writes the content of the file name in the manifest desc to w,
+// fetching only the blobs holding the file. The file is either a blob titled
+// name, decompressed if compressed on push, the chunks or the shards of the
+// file, or an entry of a tar blob, such as a directory pushed with oras or an
+// image layer, which is read only up to the entry. The tar blobs are searched
+// from the last one, as the upper image layers take precedence, and their
+// digests are not verified since they are not read fully.
+// ErrFileNotFound is returned if there is no such file.
+func CatFile(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor, name string, w io.Writer) error {
+	name = path.Clean("/" + name)[1:]
+	if name == "" {
+		return errors.Wrap(ErrFileNotFound, "empty name")
+	}
+	manifestBytes, err := fetchBlob(ctx, fetcher, desc)
+	if err != nil {
+		return err
+	}
+	// both the image manifests and the artifact manifests are accepted
+	var manifest struct {
+		Layers []ocispec.Descriptor `json:"layers"`
+		Blobs  []ocispec.Descriptor `json:"blobs"`
+	}
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return err
+	}
+	blobs := append(manifest.Layers, manifest.Blobs...)
+
+	var shards []ocispec.Descriptor
+	for _, blob := range blobs {
+		title, ok := orascontent.ResolveName(blob)
+		switch {
+		case !ok || blob.Annotations[orascontent.AnnotationUnpack] == "true":
+		case blob.MediaType == orascontent.ChunkRecipeMediaType:
+			if title == name+".chunks.json" {
+				return catChunks(ctx, fetcher, blob, blobs, w)
+			}
+		case path.Clean(title) == name:
+			return catBlob(ctx, fetcher, blob, orascontent.Compression(blob.Annotations[orascontent.AnnotationCompression]), w)
+		}
+		if blob.Annotations[orascontent.AnnotationShardFile] == name {
+			shards = append(shards, blob)
+		}
+	}
+	if len(shards) > 0 {
+		return catShards(ctx, fetcher, shards, w)
+	}
+
+	for i := len(blobs) - 1; i >= 0; i-- {
+		blob := blobs[i]
+		compression, ok := tarCompression(blob, name)
+		if !ok {
+			continue
+		}
+		found, err := catTarEntry(ctx, fetcher, blob, compression, name, w)
+		if found || err != nil {
+			return err
+		}
+	}
+	return errors.Wrapf(ErrFileNotFound, "%s", name)
+}
+
+// catBlob writes the content of the blob decompressed to w, verifying the
+// digest of the blob.
+func catBlob(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor, compression orascontent.Compression, w io.Writer) error {
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	verifier := desc.Digest.Verifier()
+	zr, err := compression.NewReader(io.TeeReader(io.LimitReader(rc, desc.Size), verifier))
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	if _, err := io.Copy(w, zr); err != nil {
+		return err
+	}
+	if !verifier.Verified() {
+		return errors.Wrapf(ErrContentMismatch, "%s: digest mismatch", desc.Digest)
+	}
+	return nil
+}
+
+// catChunks writes the chunks of the recipe in order to w.
+func catChunks(ctx context.Context, fetcher remotes.Fetcher, recipeDesc ocispec.Descriptor, blobs []ocispec.Descriptor, w io.Writer) error {
+	recipeBytes, err := fetchBlob(ctx, fetcher, recipeDesc)
+	if err != nil {
+		return err
+	}
+	var recipe orascontent.ChunkRecipe
+	if err := json.Unmarshal(recipeBytes, &recipe); err != nil {
+		return errors.Wrapf(orascontent.ErrInvalidChunk, "%s: %v", recipeDesc.Digest, err)
+	}
+	chunks := make(map[digest.Digest]ocispec.Descriptor)
+	for _, blob := range blobs {
+		if blob.MediaType == orascontent.ChunkMediaType {
+			chunks[blob.Digest] = blob
+		}
+	}
+	for _, ref := range recipe.Chunks {
+		chunk, ok := chunks[ref.Digest]
+		if !ok {
+			return errors.Wrapf(orascontent.ErrInvalidChunk, "%s: missing chunk %s", recipe.Name, ref.Digest)
+		}
+		if err := catBlob(ctx, fetcher, chunk, orascontent.CompressionNone, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// catShards writes the shards of a file in order to w.
+func catShards(ctx context.Context, fetcher remotes.Fetcher, shards []ocispec.Descriptor, w io.Writer) error {
+	index := func(desc ocispec.Descriptor) int {
+		i, _ := strconv.Atoi(desc.Annotations[orascontent.AnnotationShardIndex])
+		return i
+	}
+	sort.SliceStable(shards, func(i, j int) bool {
+		return index(shards[i]) < index(shards[j])
+	})
+	for _, shard := range shards {
+		if err := catBlob(ctx, fetcher, shard, orascontent.CompressionNone, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tarCompression returns the compression of the blob if it is a tar which
+// may contain the file name, i.e. a directory pushed with oras which is a
+// parent of the file, or an untitled blob with a tar media type.
+func tarCompression(desc ocispec.Descriptor, name string) (orascontent.Compression, bool) {
+	if title, ok := orascontent.ResolveName(desc); ok {
+		if desc.Annotations[orascontent.AnnotationUnpack] != "true" || !strings.HasPrefix(name, path.Clean(title)+"/") {
+			return "", false
+		}
+		if value, ok := desc.Annotations[orascontent.AnnotationCompression]; ok {
+			return orascontent.Compression(value), true
+		}
+		return orascontent.CompressionGzip, true
+	}
+	isTar := false
+	for _, field := range strings.FieldsFunc(desc.MediaType, func(r rune) bool {
+		return r == '/' || r == '.' || r == '+' || r == '-'
+	}) {
+		isTar = isTar || field == "tar"
+	}
+	switch {
+	case !isTar:
+		return "", false
+	case strings.HasSuffix(desc.MediaType, "gzip"):
+		return orascontent.CompressionGzip, true
+	case strings.HasSuffix(desc.MediaType, "zstd"):
+		return orascontent.CompressionZstd, true
+	}
+	return orascontent.CompressionNone, true
+}
+
+// catTarEntry writes the content of the regular file name in the tar blob to
+// w, reading the blob only up to the file. It reports whether the file is
+// found.
+func catTarEntry(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor, compression orascontent.Compression, name string, w io.Writer) (bool, error) {
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+	zr, err := compression.NewReader(io.LimitReader(rc, desc.Size))
+	if err != nil {
+		return false, err
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Wrapf(err, "%s", desc.Digest)
+		}
+		entry := path.Clean("/" + header.Name)[1:]
+		if entry == path.Join(path.Dir(name), ".wh."+path.Base(name)) {
+			// deleted by an image layer
+			return false, errors.Wrapf(ErrFileNotFound, "%s is deleted", name)
+		}
+		if entry != name {
+			continue
+		}
+		switch header.Typeflag {
+		case tar.TypeReg:
+			_, err := io.Copy(w, tr)
+			return true, err
+		case tar.TypeDir:
+			return false, errors.Errorf("%s is a directory", name)
+		default:
+			return false, errors.Errorf("%s is not a regular file", name)
+		}
+	}
+}
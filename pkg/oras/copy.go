@@ -0,0 +1,364 @@
+package oras
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	artifact "github.com/deislabs/oras/pkg/artifact"
+	orascontent "github.com/deislabs/oras/pkg/content"
+	"github.com/deislabs/oras/pkg/registry"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// Copy copies the artifact referenced by srcRef to dstRef without storing the
+// blobs locally. Blobs already existing at the destination are skipped.
+func Copy(ctx context.Context, src remotes.Resolver, srcRef string, dst remotes.Resolver, dstRef string, opts ...CopyOpt) (ocispec.Descriptor, error) {
+	if src == nil || dst == nil {
+		return ocispec.Descriptor{}, ErrResolverUndefined
+	}
+	opt := copyOptsDefaults()
+	for _, o := range opts {
+		if err := o(opt); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+
+	_, desc, err := src.Resolve(ctx, srcRef)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if opt.platform != nil {
+		fetcher, err := src.Fetcher(ctx, srcRef)
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		if desc, err = SelectPlatform(ctx, fetcher, desc, opt.platform); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+	if len(opt.stripAnnotations) > 0 || len(opt.mediaTypeRules) > 0 {
+		opt.rewriter = newManifestRewriter(opt.stripAnnotations, opt.mediaTypeRules)
+		opt.rewriter.onRewrite = opt.onRewrite
+	}
+	return copyContent(ctx, src, srcRef, dst, dstRef, desc, opt)
+}
+
+// copyContent copies the content of desc and returns the descriptor of the
+// copied root, which differs from desc if the manifests are rewritten.
+func copyContent(ctx context.Context, src remotes.Resolver, srcRef string, dst remotes.Resolver, dstRef string, desc ocispec.Descriptor, opts *copyOpts) (ocispec.Descriptor, error) {
+	fetcher, err := src.Fetcher(ctx, srcRef)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	store := newHybridStoreFromProvider(&fetcherProvider{fetcher: fetcher})
+	root := desc
+	if opts.rewriter != nil {
+		if root, err = opts.rewriter.rewrite(ctx, fetcher, desc); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		opts.rewriter.apply(store)
+	}
+
+	// pin the tag to the root so that the child manifests of an index are
+	// pushed by digest instead of being tagged in turn
+	pusher, err := dst.Pusher(ctx, withRoot(dstRef, root.Digest))
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if opts.mount {
+		if pusher, err = newMountingPusher(pusher, srcRef, dstRef); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+	if linker, ok := dst.(orascontent.BlobLinker); ok {
+		pusher = &linkingPusher{
+			Pusher: pusher,
+			linker: linker,
+			src:    src,
+		}
+	}
+
+	wrapper := limitHandler(opts.limiter)
+	if len(opts.baseHandlers) > 0 {
+		wrapper = func(h images.Handler) images.Handler {
+			return limitHandler(opts.limiter)(images.Handlers(append(opts.baseHandlers, h)...))
+		}
+	}
+	if root.MediaType == artifact.ArtifactManifestMediaType {
+		err = pushArtifactContent(ctx, pusher, root, store, wrapper)
+	} else {
+		err = remotes.PushContent(ctx, pusher, root, store, nil, wrapper)
+	}
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	if opts.referrers != nil {
+		if err := copyReferrers(ctx, src, srcRef, dst, dstRef, desc, root, opts); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+	return root, nil
+}
+
+// copyReferrers copies the referrers of desc recursively. The referrers refer
+// to the copied subject at the destination.
+func copyReferrers(ctx context.Context, src remotes.Resolver, srcRef string, dst remotes.Resolver, dstRef string, desc, copied ocispec.Descriptor, opts *copyOpts) error {
+	referrers, err := opts.referrers.Referrers(ctx, srcRef, desc)
+	if err != nil {
+		return err
+	}
+	if len(referrers) == 0 {
+		return nil
+	}
+	for _, referrer := range referrers {
+		srcReferrerRef, err := withObject(srcRef, "@"+referrer.Digest.String())
+		if err != nil {
+			return err
+		}
+		dstReferrerRef, err := withObject(dstRef, "@"+referrer.Digest.String())
+		if err != nil {
+			return err
+		}
+		if _, err := copyContent(ctx, src, srcReferrerRef, dst, dstReferrerRef, referrer, opts); err != nil {
+			return err
+		}
+	}
+
+	// keep the referrers discoverable on registries without the referrers API
+	srcTagRef, err := withObject(srcRef, ":"+registry.ReferrersTag(desc.Digest))
+	if err != nil {
+		return err
+	}
+	_, index, err := src.Resolve(ctx, srcTagRef)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	dstTagRef, err := withObject(dstRef, ":"+registry.ReferrersTag(copied.Digest))
+	if err != nil {
+		return err
+	}
+	fetcher, err := src.Fetcher(ctx, srcTagRef)
+	if err != nil {
+		return err
+	}
+	pusher, err := dst.Pusher(ctx, dstTagRef)
+	if err != nil {
+		return err
+	}
+	store := newHybridStoreFromProvider(&fetcherProvider{fetcher: fetcher})
+	if opts.rewriter != nil {
+		if index, err = opts.rewriter.rewrite(ctx, fetcher, index); err != nil {
+			return err
+		}
+		opts.rewriter.apply(store)
+	}
+	_, err = remotes.PushHandler(pusher, store)(ctx, index)
+	return err
+}
+
+// pushArtifactContent pushes the blobs of the artifact manifest desc followed
+// by the manifest, whose media type is unknown to remotes.PushContent.
+func pushArtifactContent(ctx context.Context, pusher remotes.Pusher, desc ocispec.Descriptor, store content.Store, wrapper func(images.Handler) images.Handler) error {
+	manifestBytes, err := content.ReadBlob(ctx, store, desc)
+	if err != nil {
+		return err
+	}
+	var manifest artifact.Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return err
+	}
+	for _, blob := range manifest.Blobs {
+		if err := remotes.PushContent(ctx, pusher, blob, store, nil, wrapper); err != nil {
+			return err
+		}
+	}
+	_, err = remotes.PushHandler(pusher, store)(ctx, desc)
+	return err
+}
+
+// linkingPusher links the blobs from the source if possible, e.g. between
+// OCI image layouts on the same file system, instead of copying them.
+type linkingPusher struct {
+	remotes.Pusher
+	linker orascontent.BlobLinker
+	src    remotes.Resolver
+}
+
+// Push implements remotes.Pusher.
+func (p *linkingPusher) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	if isManifestMediaType(desc.MediaType) {
+		return p.Pusher.Push(ctx, desc)
+	}
+	err := p.linker.LinkBlob(ctx, p.src, desc)
+	switch {
+	case err == nil:
+		log.G(ctx).WithField("digest", desc.Digest).Debug("linked blob")
+		// nothing left to copy
+		return nil, errors.Wrapf(errdefs.ErrAlreadyExists, "content %v", desc.Digest)
+	case errdefs.IsNotImplemented(err):
+		log.G(ctx).WithError(err).Debug("falling back to copying blob")
+		return p.Pusher.Push(ctx, desc)
+	}
+	return nil, err
+}
+
+// labelDistributionSource is the prefix of the annotations of the blobs to
+// push naming the repositories to mount the blobs from, which is followed by
+// the host of the registry the blobs are pushed to.
+const labelDistributionSource = "containerd.io/distribution.source."
+
+// mountingPusher hints the pusher to mount the blobs from the source
+// repository, which is used as the source of the mounts by the name of the
+// repository only.
+type mountingPusher struct {
+	remotes.Pusher
+	key        string
+	repository string
+}
+
+func newMountingPusher(pusher remotes.Pusher, srcRef, dstRef string) (*mountingPusher, error) {
+	srcSpec, err := reference.Parse(srcRef)
+	if err != nil {
+		return nil, err
+	}
+	dstSpec, err := reference.Parse(dstRef)
+	if err != nil {
+		return nil, err
+	}
+	// the host is matched without port by the pusher
+	dst, err := url.Parse("dummy://" + dstSpec.Locator)
+	if err != nil {
+		return nil, err
+	}
+	return &mountingPusher{
+		Pusher:     pusher,
+		key:        labelDistributionSource + dst.Hostname(),
+		repository: strings.TrimPrefix(srcSpec.Locator, srcSpec.Hostname()+"/"),
+	}, nil
+}
+
+// Push implements remotes.Pusher.
+func (p *mountingPusher) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	if isManifestMediaType(desc.MediaType) || desc.MediaType == artifact.ArtifactManifestMediaType {
+		return p.Pusher.Push(ctx, desc)
+	}
+	annotations := make(map[string]string, len(desc.Annotations)+1)
+	for k, v := range desc.Annotations {
+		annotations[k] = v
+	}
+	annotations[p.key] = p.repository
+	desc.Annotations = annotations
+	return p.Pusher.Push(ctx, desc)
+}
+
+// withObject replaces the tag or digest of the reference with the given
+// object, which is either `:tag` or `@digest`.
+func withObject(ref, object string) (string, error) {
+	refspec, err := reference.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return refspec.Locator + object, nil
+}
+
+// withRoot returns the reference in the form of `tag@digest`, which the
+// pushers tag with the manifest of the digest only. References by digest are
+// returned as is.
+func withRoot(ref string, root digest.Digest) string {
+	if strings.Contains(ref, "@") {
+		return ref
+	}
+	return ref + "@" + root.String()
+}
+
+// fetcherProvider provides content by streaming it from the remote.
+// Manifests are read in full while blobs can only be read sequentially.
+type fetcherProvider struct {
+	fetcher remotes.Fetcher
+}
+
+// ReaderAt provides contents
+func (p *fetcherProvider) ReaderAt(ctx context.Context, desc ocispec.Descriptor) (content.ReaderAt, error) {
+	rc, err := p.fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	if !isManifestMediaType(desc.MediaType) {
+		return &streamReaderAt{
+			rc:   rc,
+			size: desc.Size,
+		}, nil
+	}
+
+	defer rc.Close()
+	content, err := ioutil.ReadAll(io.LimitReader(rc, desc.Size))
+	if err != nil {
+		return nil, err
+	}
+	if digest.FromBytes(content) != desc.Digest {
+		return nil, errors.Wrapf(errdefs.ErrFailedPrecondition, "digest mismatch: %s", desc.Digest)
+	}
+	return sizeReaderAt{
+		ReaderAt: bytes.NewReader(content),
+		size:     desc.Size,
+	}, nil
+}
+
+// streamReaderAt reads a stream as a content.ReaderAt. The reads must be
+// sequential.
+type streamReaderAt struct {
+	rc     io.ReadCloser
+	offset int64
+	size   int64
+}
+
+func (ra *streamReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off != ra.offset {
+		return 0, errors.Errorf("non-sequential read at %d, expected %d", off, ra.offset)
+	}
+	n, err := io.ReadFull(ra.rc, p)
+	ra.offset += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (ra *streamReaderAt) Size() int64 {
+	return ra.size
+}
+
+func (ra *streamReaderAt) Close() error {
+	return ra.rc.Close()
+}
+
+// sizeReaderAt is a content.ReaderAt over in-memory content.
+type sizeReaderAt struct {
+	io.ReaderAt
+	size int64
+}
+
+func (ra sizeReaderAt) Size() int64 {
+	return ra.size
+}
+
+func (sizeReaderAt) Close() error {
+	return nil
+}
@@ -0,0 +1,162 @@
+package oras
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+
+	orascontent "github.com/deislabs/oras/pkg/content"
+
+	"github.com/containerd/containerd/images"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/sync/semaphore"
+)
+
+// ReferrerLister lists the manifests referring to a manifest.
+type ReferrerLister interface {
+	// Referrers lists the manifests referring to the manifest identified by
+	// desc in the repository of ref.
+	Referrers(ctx context.Context, ref string, desc ocispec.Descriptor) ([]ocispec.Descriptor, error)
+}
+
+type copyOpts struct {
+	referrers    ReferrerLister
+	baseHandlers []images.Handler
+	limiter      *semaphore.Weighted
+	platform     PlatformMatcher
+	mount        bool
+
+	stripAnnotations []string
+	mediaTypeRules   []MediaTypeRule
+	onRewrite        func(original, rewritten ocispec.Descriptor)
+	rewriter         *manifestRewriter
+}
+
+// CopyOpt allows callers to set options on the oras copy
+type CopyOpt func(o *copyOpts) error
+
+func copyOptsDefaults() *copyOpts {
+	return &copyOpts{}
+}
+
+// WithReferrers copies the referrers of the artifact recursively, which are
+// discovered at the source using the provided lister.
+func WithReferrers(lister ReferrerLister) CopyOpt {
+	return func(o *copyOpts) error {
+		o.referrers = lister
+		return nil
+	}
+}
+
+// WithCopyConcurrency limits the number of blobs copied in parallel.
+func WithCopyConcurrency(concurrency int) CopyOpt {
+	return func(o *copyOpts) error {
+		if concurrency <= 0 {
+			return ErrInvalidConcurrency
+		}
+		o.limiter = semaphore.NewWeighted(int64(concurrency))
+		return nil
+	}
+}
+
+// WithCopyPlatform copies only the first manifest matched by the matcher if
+// the artifact is an index. The selected manifest is copied to the
+// destination reference in place of the index.
+func WithCopyPlatform(matcher PlatformMatcher) CopyOpt {
+	return func(o *copyOpts) error {
+		o.platform = matcher
+		return nil
+	}
+}
+
+// WithCrossRepositoryMount asks the destination registry to mount the blobs
+// from the source repository before uploading them, which avoids the upload
+// if the blobs are present at the destination, i.e. if the source and the
+// destination are the same registry or registries sharing their storage.
+// The blobs are uploaded if the mount is refused.
+func WithCrossRepositoryMount() CopyOpt {
+	return func(o *copyOpts) error {
+		o.mount = true
+		return nil
+	}
+}
+
+// WithStripAnnotations strips the annotations matching any of the glob
+// patterns from the copied manifests and the descriptors within them. The
+// blobs are copied as is, while the rewritten manifests get new digests.
+func WithStripAnnotations(patterns ...string) CopyOpt {
+	return func(o *copyOpts) error {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid annotation pattern %q: %w", pattern, err)
+			}
+		}
+		o.stripAnnotations = append(o.stripAnnotations, patterns...)
+		return nil
+	}
+}
+
+// MediaTypeRule rewrites the media types matching the glob pattern From to
+// To.
+type MediaTypeRule struct {
+	From string
+	To   string
+}
+
+// WithMediaTypeRules rewrites the media types of the configs and layers, and
+// the artifact types, in the copied manifests by the first matching rule. The
+// blobs are copied as is, while the rewritten manifests get new digests.
+// Manifest media types are never rewritten.
+func WithMediaTypeRules(rules ...MediaTypeRule) CopyOpt {
+	return func(o *copyOpts) error {
+		for _, rule := range rules {
+			if _, err := path.Match(rule.From, ""); err != nil {
+				return fmt.Errorf("invalid media type pattern %q: %w", rule.From, err)
+			}
+			if rule.To == "" || isManifestMediaType(rule.To) {
+				return fmt.Errorf("invalid media type rule %q: cannot rewrite to %q", rule.From, rule.To)
+			}
+		}
+		o.mediaTypeRules = append(o.mediaTypeRules, rules...)
+		return nil
+	}
+}
+
+// WithCopyRewritten reports the manifests rewritten by the annotation or
+// media type rules, along with their original descriptors.
+func WithCopyRewritten(fn func(original, rewritten ocispec.Descriptor)) CopyOpt {
+	return func(o *copyOpts) error {
+		o.onRewrite = fn
+		return nil
+	}
+}
+
+// WithCopyBaseHandler provides base handlers, which will be called before
+// any copy specific handlers.
+func WithCopyBaseHandler(handlers ...images.Handler) CopyOpt {
+	return func(o *copyOpts) error {
+		o.baseHandlers = append(o.baseHandlers, handlers...)
+		return nil
+	}
+}
+
+// WithCopyStatusTrack report results to a provided writer
+func WithCopyStatusTrack(writer io.Writer) CopyOpt {
+	return WithCopyBaseHandler(copyStatusTrack(writer))
+}
+
+func copyStatusTrack(writer io.Writer) images.Handler {
+	var printLock sync.Mutex
+	return images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		name, ok := orascontent.ResolveName(desc)
+		if !ok {
+			name = desc.MediaType
+		}
+		printLock.Lock()
+		defer printLock.Unlock()
+		fmt.Fprintln(writer, "Copying", desc.Digest.Encoded()[:12], name)
+		return nil, nil
+	})
+}
@@ -0,0 +1,279 @@
+package oras
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/remotes"
+	artifact "github.com/deislabs/oras/pkg/artifact"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// manifestRewriter strips annotations from the manifests being copied, and
+// rewrites the media types of their blobs. Manifests are rewritten bottom-up
+// so that indexes and referrers refer to the rewritten manifests, while the
+// blobs are left untouched.
+type manifestRewriter struct {
+	patterns []string
+	rules    []MediaTypeRule
+	// onRewrite, if set, is called for every manifest rewritten.
+	onRewrite func(original, rewritten ocispec.Descriptor)
+	// rewritten maps the original manifest digests to the rewritten
+	// descriptors.
+	rewritten map[digest.Digest]ocispec.Descriptor
+	// content holds the content of the rewritten manifests.
+	content map[digest.Digest][]byte
+}
+
+func newManifestRewriter(patterns []string, rules []MediaTypeRule) *manifestRewriter {
+	return &manifestRewriter{
+		patterns:  patterns,
+		rules:     rules,
+		rewritten: make(map[digest.Digest]ocispec.Descriptor),
+		content:   make(map[digest.Digest][]byte),
+	}
+}
+
+// rewrite rewrites the manifest described by desc and its child manifests,
+// and returns the descriptor of the rewritten manifest. The original
+// descriptor is returned if nothing is stripped. Artifact manifests are
+// rewritten as well, so that the referrers of either kind refer to their
+// rewritten subjects.
+func (r *manifestRewriter) rewrite(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor) (ocispec.Descriptor, error) {
+	if !isManifestMediaType(desc.MediaType) && desc.MediaType != artifact.ArtifactManifestMediaType {
+		return desc, nil
+	}
+	if rewritten, ok := r.rewritten[desc.Digest]; ok {
+		return rewritten, nil
+	}
+
+	manifest, err := fetchManifest(ctx, fetcher, desc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	changed := r.stripAnnotations(manifest)
+	if r.remapMediaType(manifest, "artifactType") {
+		changed = true
+	}
+	for _, field := range []string{"config", "subject"} {
+		if child, ok := manifest[field].(map[string]interface{}); ok {
+			if r.stripAnnotations(child) {
+				changed = true
+			}
+		}
+	}
+	if config, ok := manifest["config"].(map[string]interface{}); ok && r.remapMediaType(config, "mediaType") {
+		changed = true
+	}
+	if subject, ok := manifest["subject"].(map[string]interface{}); ok {
+		// refer to the rewritten subject, which is copied before its referrers
+		if rewritten, ok := r.rewritten[digest.Digest(asString(subject["digest"]))]; ok && rewritten.Digest.String() != subject["digest"] {
+			setDescriptor(subject, rewritten)
+			changed = true
+		}
+	}
+	for _, field := range []string{"layers", "blobs"} {
+		layers, ok := manifest[field].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, layer := range layers {
+			layer, ok := layer.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if r.stripAnnotations(layer) {
+				changed = true
+			}
+			if r.remapMediaType(layer, "mediaType") {
+				changed = true
+			}
+		}
+	}
+	if manifests, ok := manifest["manifests"].([]interface{}); ok {
+		for _, item := range manifests {
+			child, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if r.stripAnnotations(child) {
+				changed = true
+			}
+			if r.remapMediaType(child, "artifactType") {
+				changed = true
+			}
+			var childDesc ocispec.Descriptor
+			if err := remarshal(child, &childDesc); err != nil {
+				return ocispec.Descriptor{}, err
+			}
+			rewritten, err := r.rewrite(ctx, fetcher, childDesc)
+			if err != nil {
+				return ocispec.Descriptor{}, err
+			}
+			if rewritten.Digest != childDesc.Digest {
+				setDescriptor(child, rewritten)
+				changed = true
+			}
+		}
+	}
+
+	rewritten := desc
+	if changed {
+		content, err := marshalManifest(manifest)
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		rewritten.Digest = digest.FromBytes(content)
+		rewritten.Size = int64(len(content))
+		r.content[rewritten.Digest] = content
+	}
+	rewritten.Annotations = r.filter(desc.Annotations)
+	r.rewritten[desc.Digest] = rewritten
+	if changed && r.onRewrite != nil {
+		r.onRewrite(desc, rewritten)
+	}
+	return rewritten, nil
+}
+
+// apply makes the rewritten manifests available in the store.
+func (r *manifestRewriter) apply(store *hybridStore) {
+	for _, desc := range r.rewritten {
+		if content, ok := r.content[desc.Digest]; ok {
+			store.Set(desc, content)
+		}
+	}
+}
+
+// stripAnnotations strips the matched annotations of the object, and reports
+// whether any annotation is stripped.
+func (r *manifestRewriter) stripAnnotations(object map[string]interface{}) bool {
+	annotations, ok := object["annotations"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	var changed bool
+	for key := range annotations {
+		if r.match(key) {
+			delete(annotations, key)
+			changed = true
+		}
+	}
+	if changed && len(annotations) == 0 {
+		delete(object, "annotations")
+	}
+	return changed
+}
+
+// remapMediaType rewrites the media type in the field of the object by the
+// first matching rule, and reports whether it is rewritten.
+func (r *manifestRewriter) remapMediaType(object map[string]interface{}, field string) bool {
+	mediaType, ok := object[field].(string)
+	if !ok {
+		return false
+	}
+	for _, rule := range r.rules {
+		// patterns are validated on setting the option
+		if matched, _ := path.Match(rule.From, mediaType); matched {
+			if rule.To == mediaType {
+				return false
+			}
+			object[field] = rule.To
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the annotations not matched.
+func (r *manifestRewriter) filter(annotations map[string]string) map[string]string {
+	if len(annotations) == 0 {
+		return annotations
+	}
+	filtered := make(map[string]string, len(annotations))
+	for key, value := range annotations {
+		if !r.match(key) {
+			filtered[key] = value
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+	return filtered
+}
+
+func (r *manifestRewriter) match(key string) bool {
+	for _, pattern := range r.patterns {
+		// patterns are validated on setting the option
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchManifest fetches and decodes the manifest, keeping unknown fields and
+// the precision of numbers.
+func fetchManifest(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor) (map[string]interface{}, error) {
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	content, err := ioutil.ReadAll(io.LimitReader(rc, desc.Size))
+	if err != nil {
+		return nil, err
+	}
+	if digest.FromBytes(content) != desc.Digest {
+		return nil, errors.Wrapf(errdefs.ErrFailedPrecondition, "digest mismatch: %s", desc.Digest)
+	}
+
+	var manifest map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func marshalManifest(manifest map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(manifest); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// setDescriptor points the descriptor object to the given descriptor.
+func setDescriptor(object map[string]interface{}, desc ocispec.Descriptor) {
+	object["mediaType"] = desc.MediaType
+	object["digest"] = desc.Digest.String()
+	object["size"] = desc.Size
+}
+
+func remarshal(in, out interface{}) error {
+	content, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, out)
+}
+
+func asString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func isManifestMediaType(mediaType string) bool {
+	return isAllowedMediaType(mediaType, ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageIndex, images.MediaTypeDockerSchema2Manifest, images.MediaTypeDockerSchema2ManifestList)
+}
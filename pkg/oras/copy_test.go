@@ -0,0 +1,116 @@
+package oras
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/suite"
+)
+
+type MountSuite struct {
+	suite.Suite
+}
+
+// recordingPusher records the descriptors pushed.
+type recordingPusher struct {
+	pushed []ocispec.Descriptor
+}
+
+func (p *recordingPusher) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	p.pushed = append(p.pushed, desc)
+	return nil, errdefs.ErrAlreadyExists
+}
+
+func (suite *MountSuite) TestMountingPusher() {
+	recorder := &recordingPusher{}
+	pusher, err := newMountingPusher(recorder, "localhost:5000/library/src:v1", "localhost:5000/dst:v1")
+	suite.Nil(err, "no error creating the pusher")
+
+	blob := ocispec.Descriptor{
+		MediaType: "application/vnd.example.blob",
+		Digest:    digest.FromString("blob"),
+		Annotations: map[string]string{
+			ocispec.AnnotationTitle: "blob.txt",
+		},
+	}
+	manifest := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromString("manifest"),
+	}
+	for _, desc := range []ocispec.Descriptor{blob, manifest} {
+		_, err = pusher.Push(newContext(), desc)
+		suite.True(errdefs.IsAlreadyExists(err), "push forwarded")
+	}
+	suite.Len(recorder.pushed, 2)
+	suite.Equal(map[string]string{
+		ocispec.AnnotationTitle:               "blob.txt",
+		labelDistributionSource + "localhost": "library/src",
+	}, recorder.pushed[0].Annotations, "source repository of the blob keyed by the host without port")
+	suite.Equal(map[string]string{ocispec.AnnotationTitle: "blob.txt"}, blob.Annotations, "annotations of the blob not modified")
+	suite.Nil(recorder.pushed[1].Annotations, "manifest pushed as is")
+
+	_, err = newMountingPusher(recorder, "localhost:5000/src:v1", "")
+	suite.NotNil(err, "error parsing the destination reference")
+	_, err = newMountingPusher(recorder, "", "localhost:5000/dst:v1")
+	suite.NotNil(err, "error parsing the source reference")
+}
+
+func (suite *MountSuite) TestMountRequest() {
+	var (
+		lock    sync.Mutex
+		uploads []url.Values
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/v2/dst/blobs/"):
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/dst/blobs/uploads/":
+			lock.Lock()
+			uploads = append(uploads, r.URL.Query())
+			lock.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	resolver := docker.NewResolver(docker.ResolverOptions{
+		Hosts: docker.ConfigureDefaultRegistries(docker.WithPlainHTTP(docker.MatchAllHosts)),
+	})
+	dstRef := host + "/dst:v1"
+	var pusher remotes.Pusher
+	pusher, err := resolver.Pusher(newContext(), dstRef)
+	suite.Nil(err, "no error creating the pusher")
+	pusher, err = newMountingPusher(pusher, host+"/library/src:v1", dstRef)
+	suite.Nil(err, "no error creating the mounting pusher")
+
+	blob := ocispec.Descriptor{
+		MediaType: "application/vnd.example.blob",
+		Digest:    digest.FromString("blob"),
+		Size:      4,
+	}
+	_, err = pusher.Push(newContext(), blob)
+	suite.True(errdefs.IsAlreadyExists(err), "blob mounted")
+	suite.Len(uploads, 1)
+	suite.Equal(url.Values{
+		"mount": []string{blob.Digest.String()},
+		"from":  []string{"library/src"},
+	}, uploads[0], "blob mounted from the source repository")
+}
+
+func TestMountSuite(t *testing.T) {
+	suite.Run(t, new(MountSuite))
+}
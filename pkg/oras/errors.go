@@ -7,7 +7,14 @@ import (
 
 // Common errors
 var (
-	ErrResolverUndefined = errors.New("resolver undefined")
+	ErrResolverUndefined     = errors.New("resolver undefined")
+	ErrInvalidConcurrency    = errors.New("concurrency must be positive")
+	ErrInvalidPrefetchBudget = errors.New("prefetch budget must not be negative")
+	ErrPlatformNotMatched    = errors.New("no manifest matches the platform")
+	ErrManifestTooLarge      = errors.New("manifest exceeds the size limit")
+	ErrAnnotationsNotFound   = errors.New("external annotations not found")
+	ErrContentMismatch       = errors.New("content does not match the descriptor")
+	ErrFileNotFound          = errors.New("file not found in the artifact")
 )
 
 // Path validation related errors
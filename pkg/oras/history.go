@@ -0,0 +1,151 @@
+package oras
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	artifact "github.com/deislabs/oras/pkg/artifact"
+	orascontent "github.com/deislabs/oras/pkg/content"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// FetchTagHistory fetches the history of the tag attached to the manifest
+// desc in the repository of ref. An empty history is returned if there is
+// none. If several histories of the tag are attached, e.g. by concurrent
+// pushes, the one with the latest change is returned.
+func FetchTagHistory(ctx context.Context, resolver remotes.Resolver, lister ReferrerLister, ref, tag string, desc ocispec.Descriptor) (*artifact.TagHistory, error) {
+	if resolver == nil {
+		return nil, ErrResolverUndefined
+	}
+	referrers, err := lister.Referrers(ctx, ref, desc)
+	if err != nil {
+		return nil, err
+	}
+	fetcher, err := resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &artifact.TagHistory{
+		Tag: tag,
+	}
+	for _, referrer := range referrers {
+		if referrer.Annotations[artifact.AnnotationHistoryTag] != tag {
+			continue
+		}
+		candidate, err := fetchTagHistory(ctx, fetcher, referrer)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s", referrer.Digest)
+		}
+		latest := candidate.Latest()
+		if candidate.Tag != tag || latest == nil {
+			continue
+		}
+		if current := history.Latest(); current == nil || latest.Time.After(current.Time) {
+			history = candidate
+		}
+	}
+	return history, nil
+}
+
+// PushTagHistory pushes the history of the tag as an artifact referring to
+// the subject manifest, which the tag points to after the latest change. The
+// artifact is pushed by digest to the repository of ref, and its descriptor
+// is returned with the manifest annotations.
+func PushTagHistory(ctx context.Context, resolver remotes.Resolver, ref string, subject ocispec.Descriptor, history *artifact.TagHistory, opts ...PushOpt) (ocispec.Descriptor, error) {
+	if resolver == nil {
+		return ocispec.Descriptor{}, ErrResolverUndefined
+	}
+	refspec, err := reference.Parse(ref)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	historyBytes, err := json.Marshal(history)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	blob := ocispec.Descriptor{
+		MediaType: artifact.TagHistoryMediaType,
+		Digest:    digest.FromBytes(historyBytes),
+		Size:      int64(len(historyBytes)),
+	}
+	store := orascontent.NewMemoryStore()
+	store.Set(blob, historyBytes)
+
+	annotations := map[string]string{
+		artifact.AnnotationHistoryTag: history.Tag,
+		ocispec.AnnotationCreated:     time.Now().UTC().Format(time.RFC3339),
+	}
+	subject = ocispec.Descriptor{
+		MediaType: subject.MediaType,
+		Digest:    subject.Digest,
+		Size:      subject.Size,
+	}
+	opts = append(opts[:len(opts):len(opts)],
+		WithSubject(subject),
+		WithArtifactType(artifact.TagHistoryArtifactType),
+		WithManifestAnnotations(annotations),
+		WithNameValidation(nil),
+	)
+	desc, err := Push(ctx, resolver, refspec.Locator, store, []ocispec.Descriptor{blob}, opts...)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc.Annotations = annotations
+	return desc, nil
+}
+
+// fetchTagHistory fetches the history held by the history manifest desc.
+func fetchTagHistory(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor) (*artifact.TagHistory, error) {
+	manifestBytes, err := fetchBlob(ctx, fetcher, desc)
+	if err != nil {
+		return nil, err
+	}
+	// both the image manifests and the artifact manifests are accepted
+	var manifest struct {
+		Layers []ocispec.Descriptor `json:"layers"`
+		Blobs  []ocispec.Descriptor `json:"blobs"`
+	}
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, err
+	}
+	for _, blob := range append(manifest.Layers, manifest.Blobs...) {
+		if blob.MediaType != artifact.TagHistoryMediaType {
+			continue
+		}
+		historyBytes, err := fetchBlob(ctx, fetcher, blob)
+		if err != nil {
+			return nil, err
+		}
+		var history artifact.TagHistory
+		if err := json.Unmarshal(historyBytes, &history); err != nil {
+			return nil, err
+		}
+		return &history, nil
+	}
+	return nil, errors.Errorf("no %s blob", artifact.TagHistoryMediaType)
+}
+
+// fetchBlob fetches the content of desc, verifying its digest.
+func fetchBlob(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor) ([]byte, error) {
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	content, err := ioutil.ReadAll(io.LimitReader(rc, desc.Size))
+	if err != nil {
+		return nil, err
+	}
+	if desc.Digest.Algorithm().FromBytes(content) != desc.Digest {
+		return nil, errors.Wrapf(ErrContentMismatch, "%s: digest mismatch", desc.Digest)
+	}
+	return content, nil
+}
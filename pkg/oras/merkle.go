@@ -0,0 +1,82 @@
+package oras
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	artifact "github.com/deislabs/oras/pkg/artifact"
+	orascontent "github.com/deislabs/oras/pkg/content"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// PushMerkleTrees computes the Merkle trees of the layers over chunks of
+// chunkSize, and pushes them as an artifact referring to the subject manifest.
+// The artifact is pushed by digest to the repository of ref, with a blob per
+// layer annotated with the layer digest and the tree root.
+func PushMerkleTrees(ctx context.Context, resolver remotes.Resolver, ref string, provider content.Provider, subject ocispec.Descriptor, layers []ocispec.Descriptor, chunkSize int64, opts ...PushOpt) (ocispec.Descriptor, error) {
+	if resolver == nil {
+		return ocispec.Descriptor{}, ErrResolverUndefined
+	}
+	refspec, err := reference.Parse(ref)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	store := orascontent.NewMemoryStore()
+	descriptors := make([]ocispec.Descriptor, 0, len(layers))
+	for _, layer := range layers {
+		tree, err := computeMerkleTree(ctx, provider, layer, chunkSize)
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		treeBytes, err := json.Marshal(tree)
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		desc := ocispec.Descriptor{
+			MediaType: artifact.MerkleTreeMediaType,
+			Digest:    digest.FromBytes(treeBytes),
+			Size:      int64(len(treeBytes)),
+			Annotations: map[string]string{
+				artifact.AnnotationMerkleLayer: layer.Digest.String(),
+				artifact.AnnotationMerkleRoot:  tree.Root.String(),
+			},
+		}
+		store.Set(desc, treeBytes)
+		descriptors = append(descriptors, desc)
+	}
+
+	subject = ocispec.Descriptor{
+		MediaType: subject.MediaType,
+		Digest:    subject.Digest,
+		Size:      subject.Size,
+	}
+	opts = append(opts[:len(opts):len(opts)],
+		WithSubject(subject),
+		WithArtifactType(artifact.MerkleTreeArtifactType),
+		WithNameValidation(nil),
+	)
+	return Push(ctx, resolver, refspec.Locator, store, descriptors, opts...)
+}
+
+func computeMerkleTree(ctx context.Context, provider content.Provider, desc ocispec.Descriptor, chunkSize int64) (*artifact.MerkleTree, error) {
+	ra, err := provider.ReaderAt(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer ra.Close()
+	tree, err := artifact.NewMerkleTree(content.NewReader(ra), chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	if tree.Layer != desc.Digest || tree.Size != desc.Size {
+		return nil, errors.Wrapf(errdefs.ErrFailedPrecondition, "%s: content changed", desc.Digest)
+	}
+	return tree, nil
+}
@@ -1,22 +1,32 @@
 package oras
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	artifact "github.com/deislabs/oras/pkg/artifact"
 	orascontent "github.com/deislabs/oras/pkg/content"
+	orasregistry "github.com/deislabs/oras/pkg/registry"
 
+	containerdcontent "github.com/containerd/containerd/content"
 	"github.com/containerd/containerd/images"
 	"github.com/containerd/containerd/remotes"
 	"github.com/containerd/containerd/remotes/docker"
 	"github.com/docker/distribution/configuration"
 	"github.com/docker/distribution/registry"
 	_ "github.com/docker/distribution/registry/storage/driver/inmemory"
+	digest "github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/phayes/freeport"
 	"github.com/stretchr/testify/suite"
@@ -64,6 +74,15 @@ func (suite *ORASTestSuite) SetupSuite() {
 	suite.Nil(err, "no error finding free port for test registry")
 
 	go dockerRegistry.ListenAndServe()
+
+	// Wait for the registry to accept connections
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("tcp", suite.DockerRegistryHost); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
 }
 
 // Push files to docker registry
@@ -313,6 +332,671 @@ func (suite *ORASTestSuite) Test_3_Conditional_Pull() {
 	}
 }
 
+// Copy between repositories
+func (suite *ORASTestSuite) Test_4_Copy() {
+	var (
+		testData = [][]string{
+			{"hi.txt", "hi"},
+			{"bye.txt", "bye"},
+		}
+		err         error
+		descriptors []ocispec.Descriptor
+		store       *orascontent.Memorystore
+	)
+
+	// Push test content
+	store = orascontent.NewMemoryStore()
+	for _, data := range testData {
+		desc := store.Add(data[0], "", []byte(data[1]))
+		descriptors = append(descriptors, desc)
+	}
+	srcRef := fmt.Sprintf("%s/copy-src:test", suite.DockerRegistryHost)
+	pushed, err := Push(newContext(), newResolver(), srcRef, store, descriptors)
+	suite.Nil(err, "no error pushing test data")
+
+	// Copy to another repository
+	_, err = Copy(newContext(), nil, srcRef, newResolver(), srcRef)
+	suite.NotNil(err, "error copying with empty resolver")
+	dstRef := fmt.Sprintf("%s/copy-dst:test", suite.DockerRegistryHost)
+	copied, err := Copy(newContext(), newResolver(), srcRef, newResolver(), dstRef)
+	suite.Nil(err, "no error copying ref")
+	suite.Equal(pushed, copied, "copied manifest matches")
+
+	// Copy again with referrers, existing blobs are skipped
+	hosts := docker.ConfigureDefaultRegistries(docker.WithPlainHTTP(docker.MatchLocalhost))
+	copied, err = Copy(newContext(), newResolver(), srcRef, newResolver(), dstRef, WithReferrers(orasregistry.NewClient(hosts)))
+	suite.Nil(err, "no error copying ref with referrers")
+	suite.Equal(pushed, copied, "copied manifest matches")
+
+	// Pull the copied content
+	store = orascontent.NewMemoryStore()
+	_, descriptors, err = Pull(newContext(), newResolver(), dstRef, store)
+	suite.Nil(err, "no error pulling copied ref")
+	suite.Equal(2, len(descriptors), "number of contents matches on pull")
+	for _, data := range testData {
+		_, actualContent, ok := store.GetByName(data[0])
+		suite.True(ok, "find in memory")
+		suite.Equal([]byte(data[1]), actualContent, "test content matches on pull")
+	}
+}
+
+func (suite *ORASTestSuite) Test_4_CopyStripAnnotations() {
+	store := orascontent.NewMemoryStore()
+	desc := store.Add("hi.txt", "", []byte("hi"))
+	desc.Annotations["org.example.build.id"] = "42"
+	srcRef := fmt.Sprintf("%s/strip-src:test", suite.DockerRegistryHost)
+	pushed, err := Push(newContext(), newResolver(), srcRef, store, []ocispec.Descriptor{desc},
+		WithManifestAnnotations(map[string]string{"org.example.build.timestamp": "now", "keep": "me"}))
+	suite.Nil(err, "no error pushing test data")
+
+	_, err = Copy(newContext(), newResolver(), srcRef, newResolver(), srcRef, WithStripAnnotations("["))
+	suite.NotNil(err, "error copying with invalid pattern")
+
+	dstRef := fmt.Sprintf("%s/strip-dst:test", suite.DockerRegistryHost)
+	copied, err := Copy(newContext(), newResolver(), srcRef, newResolver(), dstRef, WithStripAnnotations("org.example.build.*"))
+	suite.Nil(err, "no error copying ref")
+	suite.NotEqual(pushed.Digest, copied.Digest, "copied manifest is rewritten")
+
+	manifest, descriptors, err := Pull(newContext(), newResolver(), dstRef, orascontent.NewMemoryStore())
+	suite.Nil(err, "no error pulling copied ref")
+	suite.Equal(copied.Digest, manifest.Digest, "copied manifest matches")
+	suite.Equal(1, len(descriptors), "number of contents matches on pull")
+	suite.Equal(desc.Digest, descriptors[0].Digest, "blob is unchanged")
+	suite.Equal(map[string]string{ocispec.AnnotationTitle: "hi.txt"}, descriptors[0].Annotations, "layer annotations are stripped")
+}
+
+func (suite *ORASTestSuite) Test_4_CopyMediaTypeRules() {
+	store := orascontent.NewMemoryStore()
+	desc := store.Add("hi.txt", "application/vnd.acme.legacy.text", []byte("hi"))
+	srcRef := fmt.Sprintf("%s/remap-src:test", suite.DockerRegistryHost)
+	pushed, err := Push(newContext(), newResolver(), srcRef, store, []ocispec.Descriptor{desc})
+	suite.Nil(err, "no error pushing test data")
+
+	_, err = Copy(newContext(), newResolver(), srcRef, newResolver(), srcRef, WithMediaTypeRules(MediaTypeRule{
+		From: "application/vnd.acme.*",
+		To:   ocispec.MediaTypeImageManifest,
+	}))
+	suite.NotNil(err, "error copying with a rule rewriting to a manifest media type")
+
+	var rewrites []ocispec.Descriptor
+	dstRef := fmt.Sprintf("%s/remap-dst:test", suite.DockerRegistryHost)
+	copied, err := Copy(newContext(), newResolver(), srcRef, newResolver(), dstRef, WithMediaTypeRules(
+		MediaTypeRule{From: "application/vnd.acme.legacy.*", To: orascontent.DefaultBlobMediaType},
+		MediaTypeRule{From: "application/vnd.acme.*", To: "application/octet-stream"},
+	), WithCopyRewritten(func(original, rewritten ocispec.Descriptor) {
+		rewrites = append(rewrites, original, rewritten)
+	}))
+	suite.Nil(err, "no error copying ref")
+	suite.Equal([]ocispec.Descriptor{pushed, copied}, rewrites, "rewritten manifest reported")
+	suite.NotEqual(pushed.Digest, copied.Digest, "copied manifest is rewritten")
+
+	_, descriptors, err := Pull(newContext(), newResolver(), dstRef, orascontent.NewMemoryStore())
+	suite.Nil(err, "no error pulling copied ref")
+	suite.Equal(1, len(descriptors), "number of contents matches on pull")
+	suite.Equal(desc.Digest, descriptors[0].Digest, "blob is unchanged")
+	suite.Equal(orascontent.DefaultBlobMediaType, descriptors[0].MediaType, "media type is rewritten by the first matching rule")
+}
+
+// Push and pull with limited concurrency
+func (suite *ORASTestSuite) Test_4_CopyReferrers() {
+	store := orascontent.NewMemoryStore()
+	srcRef := fmt.Sprintf("%s/copy-referrers-src:test", suite.DockerRegistryHost)
+	subject, err := Push(newContext(), newResolver(), srcRef, store, []ocispec.Descriptor{store.Add("hi.txt", "", []byte("hi"))})
+	suite.Nil(err, "no error pushing subject")
+
+	// attach a signature to the subject, and an attestation to the signature
+	hosts := docker.ConfigureDefaultRegistries(docker.WithPlainHTTP(docker.MatchLocalhost))
+	client := orasregistry.NewClient(hosts)
+	attach := func(ref string, name, artifactType string) ocispec.Descriptor {
+		desc, subject, err := Attach(newContext(), newResolver(), ref, store, []ocispec.Descriptor{store.Add(name, "", []byte(name))},
+			WithArtifactType(artifactType), WithArtifactManifest(client))
+		suite.Nil(err, "no error attaching %s", name)
+		err = client.AddReferrer(newContext(), ref, subject, orasregistry.Referrer{
+			Descriptor:   desc,
+			ArtifactType: artifactType,
+		})
+		suite.Nil(err, "no error adding referrer %s", name)
+		return desc
+	}
+	sig := attach(srcRef, "hi.sig", "application/vnd.example.signature")
+	sigRef := fmt.Sprintf("%s/copy-referrers-src@%s", suite.DockerRegistryHost, sig.Digest)
+	attestation := attach(sigRef, "hi.att", "application/vnd.example.attestation")
+
+	// the referrers are copied recursively
+	dstRef := fmt.Sprintf("%s/copy-referrers-dst:test", suite.DockerRegistryHost)
+	copied, err := Copy(newContext(), newResolver(), srcRef, newResolver(), dstRef, WithReferrers(client))
+	suite.Nil(err, "no error copying with referrers")
+	suite.Equal(subject, copied, "copied manifest matches")
+	referrers, err := client.ListReferrers(newContext(), dstRef, copied, "")
+	suite.Nil(err, "no error listing copied referrers")
+	suite.Equal(1, len(referrers), "referrer of the subject copied")
+	suite.Equal(sig.Digest, referrers[0].Digest, "signature copied")
+	dstSigRef := fmt.Sprintf("%s/copy-referrers-dst@%s", suite.DockerRegistryHost, sig.Digest)
+	referrers, err = client.ListReferrers(newContext(), dstSigRef, sig, "")
+	suite.Nil(err, "no error listing copied referrers of the referrer")
+	suite.Equal(1, len(referrers), "referrer of the referrer copied")
+	suite.Equal(attestation.Digest, referrers[0].Digest, "attestation copied")
+}
+
+func (suite *ORASTestSuite) Test_5_Concurrency() {
+	var (
+		err         error
+		descriptors []ocispec.Descriptor
+		store       *orascontent.Memorystore
+	)
+
+	// Push test content
+	store = orascontent.NewMemoryStore()
+	for i := 0; i < 5; i++ {
+		desc := store.Add(fmt.Sprintf("file%d.txt", i), "", []byte(fmt.Sprintf("content %d", i)))
+		descriptors = append(descriptors, desc)
+	}
+	ref := fmt.Sprintf("%s/concurrency:test", suite.DockerRegistryHost)
+	_, err = Push(newContext(), newResolver(), ref, store, descriptors, WithPushConcurrency(0))
+	suite.Equal(ErrInvalidConcurrency, err, "error pushing with invalid concurrency")
+	_, err = Push(newContext(), newResolver(), ref, store, descriptors, WithPushConcurrency(2))
+	suite.Nil(err, "no error pushing with limited concurrency")
+
+	// Pull test content
+	store = orascontent.NewMemoryStore()
+	_, descriptors, err = Pull(newContext(), newResolver(), ref, store, WithPullConcurrency(0))
+	suite.Equal(ErrInvalidConcurrency, err, "error pulling with invalid concurrency")
+	_, descriptors, err = Pull(newContext(), newResolver(), ref, store, WithPullConcurrency(2))
+	suite.Nil(err, "no error pulling with limited concurrency")
+	suite.Equal(5, len(descriptors), "number of contents matches on pull")
+}
+
+func (suite *ORASTestSuite) Test_6_Attach() {
+	store := orascontent.NewMemoryStore()
+	subjectRef := fmt.Sprintf("%s/attach:test", suite.DockerRegistryHost)
+	_, err := Push(newContext(), newResolver(), subjectRef, store, []ocispec.Descriptor{store.Add("hi.txt", "", []byte("hi"))})
+	suite.Nil(err, "no error pushing subject")
+
+	// The test registry rejects artifact manifests
+	hosts := docker.ConfigureDefaultRegistries(docker.WithPlainHTTP(docker.MatchLocalhost))
+	client := orasregistry.NewClient(hosts)
+	sig := store.Add("hi.sig", "", []byte("signature"))
+	desc, subject, err := Attach(newContext(), newResolver(), subjectRef, store, []ocispec.Descriptor{sig},
+		WithArtifactType("application/vnd.example.signature"), WithArtifactManifest(client))
+	suite.Nil(err, "no error attaching artifact")
+	suite.Equal(ocispec.MediaTypeImageManifest, desc.MediaType, "fall back to image manifest")
+
+	err = client.AddReferrer(newContext(), subjectRef, subject, orasregistry.Referrer{
+		Descriptor:   desc,
+		ArtifactType: "application/vnd.example.signature",
+	})
+	suite.Nil(err, "no error adding referrer")
+	referrers, err := client.ListReferrers(newContext(), subjectRef, subject, "application/vnd.example.signature")
+	suite.Nil(err, "no error listing referrers")
+	suite.Equal(1, len(referrers), "number of referrers matches")
+	suite.Equal(desc.Digest, referrers[0].Digest, "referrer matches")
+}
+
+func (suite *ORASTestSuite) Test_7_ExternalAnnotations() {
+	store := orascontent.NewMemoryStore()
+	ref := fmt.Sprintf("%s/annotations:test", suite.DockerRegistryHost)
+	annotations := map[string]string{
+		"large": strings.Repeat("x", 1024),
+	}
+	files := []ocispec.Descriptor{store.Add("hi.txt", "", []byte("hi"))}
+	_, err := Push(newContext(), newResolver(), ref, store, files,
+		WithManifestAnnotations(annotations), WithManifestSizeLimit(1024))
+	suite.Nil(err, "no error pushing with large annotations")
+
+	resolver := newResolver()
+	_, desc, err := resolver.Resolve(newContext(), ref)
+	suite.Nil(err, "no error resolving manifest")
+	fetcher, err := resolver.Fetcher(newContext(), ref)
+	suite.Nil(err, "no error getting fetcher")
+	rc, err := fetcher.Fetch(newContext(), desc)
+	suite.Nil(err, "no error fetching manifest")
+	defer rc.Close()
+	var manifest ocispec.Manifest
+	suite.Nil(json.NewDecoder(rc).Decode(&manifest), "no error decoding manifest")
+	suite.True(desc.Size <= 1024, "manifest within the size limit")
+	suite.Equal(2, len(manifest.Layers), "annotations blob added")
+
+	got, err := FetchManifestAnnotations(newContext(), fetcher, manifest)
+	suite.Nil(err, "no error fetching manifest annotations")
+	suite.Equal(annotations, got, "annotations restored")
+
+	_, layers, err := Pull(newContext(), resolver, ref, orascontent.NewMemoryStore())
+	suite.Nil(err, "no error pulling")
+	suite.Equal(1, len(layers), "annotations blob not pulled as a file")
+}
+
+func (suite *ORASTestSuite) Test_8_Progress() {
+	store := orascontent.NewMemoryStore()
+	ref := fmt.Sprintf("%s/progress:test", suite.DockerRegistryHost)
+	content := []byte(strings.Repeat("progress", 1024))
+	files := []ocispec.Descriptor{store.Add("progress.txt", "", content)}
+
+	var lock sync.Mutex
+	progress := make(map[digest.Digest]Progress)
+	track := func(p Progress) {
+		lock.Lock()
+		defer lock.Unlock()
+		progress[p.Descriptor.Digest] = p
+	}
+	_, err := Push(newContext(), newResolver(), ref, store, files, WithPushProgress(track))
+	suite.Nil(err, "no error pushing with progress")
+	suite.True(progress[files[0].Digest].Done, "push progress done")
+	suite.Equal(int64(len(content)), progress[files[0].Digest].Offset, "push progress offset")
+
+	progress = make(map[digest.Digest]Progress)
+	_, _, err = Pull(newContext(), newResolver(), ref, orascontent.NewMemoryStore(), WithPullProgress(track))
+	suite.Nil(err, "no error pulling with progress")
+	suite.True(progress[files[0].Digest].Done, "pull progress done")
+	suite.Equal(int64(len(content)), progress[files[0].Digest].Offset, "pull progress offset")
+}
+
+func (suite *ORASTestSuite) Test_9_CopyLayoutLinked() {
+	tempDir, err := ioutil.TempDir("", "oras_layout_test")
+	suite.Nil(err, "no error creating temp directory")
+	defer os.RemoveAll(tempDir)
+	src, err := orascontent.NewOCIStore(filepath.Join(tempDir, "src"))
+	suite.Nil(err, "no error creating source layout")
+	dst, err := orascontent.NewOCIStore(filepath.Join(tempDir, "dst"))
+	suite.Nil(err, "no error creating destination layout")
+
+	store := orascontent.NewMemoryStore()
+	files := []ocispec.Descriptor{store.Add("hi.txt", "", []byte("hi"))}
+	pushed, err := Push(newContext(), src.Resolver(), "v1", store, files)
+	suite.Nil(err, "no error pushing to layout")
+	copied, err := Copy(newContext(), src.Resolver(), "v1", dst.Resolver(), "v1")
+	suite.Nil(err, "no error copying between layouts")
+	suite.Equal(pushed.Digest, copied.Digest, "copied manifest matches")
+
+	blobPath := func(root string, desc ocispec.Descriptor) string {
+		return filepath.Join(tempDir, root, "blobs", desc.Digest.Algorithm().String(), desc.Digest.Encoded())
+	}
+	srcInfo, err := os.Stat(blobPath("src", files[0]))
+	suite.Nil(err, "no error reading source blob")
+	dstInfo, err := os.Stat(blobPath("dst", files[0]))
+	suite.Nil(err, "no error reading copied blob")
+	suite.True(os.SameFile(srcInfo, dstInfo), "blob linked")
+
+	_, layers, err := Pull(newContext(), dst.Resolver(), "v1", orascontent.NewMemoryStore())
+	suite.Nil(err, "no error pulling from copied layout")
+	suite.Equal(1, len(layers), "copied layers")
+}
+
+func (suite *ORASTestSuite) Test_10_Idempotent() {
+	tempDir, err := ioutil.TempDir("", "oras_idempotent_test")
+	suite.Nil(err, "no error creating temp directory")
+	defer os.RemoveAll(tempDir)
+	layout, err := orascontent.NewOCIStore(tempDir)
+	suite.Nil(err, "no error creating layout")
+
+	store := orascontent.NewMemoryStore()
+	files := []ocispec.Descriptor{store.Add("hi.txt", "", []byte("hi"))}
+	var unchanged []ocispec.Descriptor
+	idempotent := WithIdempotent(func(desc ocispec.Descriptor) {
+		unchanged = append(unchanged, desc)
+	})
+	pushed, err := Push(newContext(), layout.Resolver(), "v1", store, files, idempotent)
+	suite.Nil(err, "no error pushing")
+	suite.Empty(unchanged, "first push changes")
+
+	desc, err := Push(newContext(), layout.Resolver(), "v1", store, files, idempotent)
+	suite.Nil(err, "no error pushing again")
+	suite.Equal(pushed.Digest, desc.Digest, "same manifest")
+	suite.Equal(1, len(unchanged), "second push unchanged")
+
+	files = append(files, store.Add("bye.txt", "", []byte("bye")))
+	_, err = Push(newContext(), layout.Resolver(), "v1", store, files, idempotent)
+	suite.Nil(err, "no error pushing different files")
+	suite.Equal(1, len(unchanged), "push of different files changes")
+}
+
+func (suite *ORASTestSuite) Test_11_MerkleTrees() {
+	store := orascontent.NewMemoryStore()
+	ref := fmt.Sprintf("%s/merkle:test", suite.DockerRegistryHost)
+	content := []byte(strings.Repeat("merkle", 1000))
+	files := []ocispec.Descriptor{store.Add("merkle.txt", "", content)}
+	subject, err := Push(newContext(), newResolver(), ref, store, files)
+	suite.Nil(err, "no error pushing subject")
+
+	desc, err := PushMerkleTrees(newContext(), newResolver(), ref, store, subject, files, 1024)
+	suite.Nil(err, "no error pushing Merkle trees")
+
+	resolver := newResolver()
+	fetcher, err := resolver.Fetcher(newContext(), ref)
+	suite.Nil(err, "no error getting fetcher")
+	rc, err := fetcher.Fetch(newContext(), desc)
+	suite.Nil(err, "no error fetching manifest")
+	defer rc.Close()
+	var manifest imageManifest
+	suite.Nil(json.NewDecoder(rc).Decode(&manifest), "no error decoding manifest")
+	suite.Equal(subject.Digest, manifest.Subject.Digest, "subject matches")
+	suite.Equal(1, len(manifest.Layers), "a tree per layer")
+	suite.Equal(files[0].Digest.String(), manifest.Layers[0].Annotations[artifact.AnnotationMerkleLayer], "layer annotated")
+
+	rc, err = fetcher.Fetch(newContext(), manifest.Layers[0])
+	suite.Nil(err, "no error fetching tree")
+	defer rc.Close()
+	var tree artifact.MerkleTree
+	suite.Nil(json.NewDecoder(rc).Decode(&tree), "no error decoding tree")
+	suite.Nil(tree.Validate(), "tree valid")
+	suite.Equal(manifest.Layers[0].Annotations[artifact.AnnotationMerkleRoot], tree.Root.String(), "root annotated")
+	suite.Equal(6, len(tree.Leaves), "number of chunks matches")
+
+	offset, size := tree.Chunk(5)
+	suite.Equal(int64(5120), offset, "offset of the last chunk")
+	suite.Equal(int64(880), size, "size of the last chunk")
+	suite.Nil(tree.VerifyChunk(5, content[offset:offset+size]), "chunk verified")
+	suite.NotNil(tree.VerifyChunk(4, content[offset:offset+size]), "other chunk rejected")
+}
+
+func (suite *ORASTestSuite) Test_12_PullVerification() {
+	tempDir, err := ioutil.TempDir("", "oras_verification_test")
+	suite.Nil(err, "no error creating temp directory")
+	defer os.RemoveAll(tempDir)
+	layout, err := orascontent.NewOCIStore(filepath.Join(tempDir, "layout"))
+	suite.Nil(err, "no error creating layout")
+
+	store := orascontent.NewMemoryStore()
+	files := []ocispec.Descriptor{store.Add("hi.txt", "", []byte("hi"))}
+	_, err = Push(newContext(), layout.Resolver(), "v1", store, files)
+	suite.Nil(err, "no error pushing")
+
+	// corrupt the blob in the layout
+	blob := filepath.Join(tempDir, "layout", "blobs", files[0].Digest.Algorithm().String(), files[0].Digest.Encoded())
+	suite.Nil(ioutil.WriteFile(blob, []byte("ho"), 0644), "no error corrupting blob")
+
+	output := filepath.Join(tempDir, "output")
+	_, _, err = Pull(newContext(), layout.Resolver(), "v1", orascontent.NewFileStore(output))
+	suite.True(errors.Is(err, ErrContentMismatch), "error pulling corrupt blob")
+	_, err = os.Stat(filepath.Join(output, "hi.txt"))
+	suite.True(os.IsNotExist(err), "corrupt file not written")
+}
+
+func (suite *ORASTestSuite) Test_13_TagHistory() {
+	store := orascontent.NewMemoryStore()
+	ref := fmt.Sprintf("%s/history:test", suite.DockerRegistryHost)
+	subject, err := Push(newContext(), newResolver(), ref, store, []ocispec.Descriptor{store.Add("hi.txt", "", []byte("hi"))})
+	suite.Nil(err, "no error pushing subject")
+
+	hosts := docker.ConfigureDefaultRegistries(docker.WithPlainHTTP(docker.MatchLocalhost))
+	client := orasregistry.NewClient(hosts)
+	history, err := FetchTagHistory(newContext(), newResolver(), client, ref, "test", subject)
+	suite.Nil(err, "no error fetching missing history")
+	suite.Nil(history.Latest(), "empty history")
+
+	history.Changes = append(history.Changes, artifact.TagChange{
+		Digest: subject.Digest,
+		Actor:  "tester",
+		Time:   time.Now().UTC(),
+	})
+	desc, err := PushTagHistory(newContext(), newResolver(), ref, subject, history, WithArtifactManifest(client))
+	suite.Nil(err, "no error pushing history")
+	suite.Equal("test", desc.Annotations[artifact.AnnotationHistoryTag], "tag annotated")
+	err = client.AddReferrer(newContext(), ref, subject, orasregistry.Referrer{
+		Descriptor:   desc,
+		ArtifactType: artifact.TagHistoryArtifactType,
+	})
+	suite.Nil(err, "no error adding referrer")
+
+	fetched, err := FetchTagHistory(newContext(), newResolver(), client, ref, "test", subject)
+	suite.Nil(err, "no error fetching history")
+	suite.Equal(1, len(fetched.Changes), "number of changes matches")
+	suite.Equal(subject.Digest, fetched.Latest().Digest, "change matches")
+	suite.Equal("tester", fetched.Latest().Actor, "actor matches")
+
+	other, err := FetchTagHistory(newContext(), newResolver(), client, ref, "other", subject)
+	suite.Nil(err, "no error fetching history of other tag")
+	suite.Nil(other.Latest(), "history of other tag empty")
+}
+
+func (suite *ORASTestSuite) Test_14_Compression() {
+	tempDir, err := ioutil.TempDir("", "oras_compression_test")
+	suite.Nil(err, "no error creating temp directory")
+	defer os.RemoveAll(tempDir)
+
+	content := []byte(strings.Repeat("compress", 1000))
+	path := filepath.Join(tempDir, "compress.txt")
+	suite.Nil(ioutil.WriteFile(path, content, 0644), "no error writing file")
+	store := orascontent.NewFileStore("")
+	defer store.Close()
+	store.Compression = orascontent.CompressionZstd
+	file, err := store.Add("compress.txt", "", path)
+	suite.Nil(err, "no error adding file")
+	ref := fmt.Sprintf("%s/compression:test", suite.DockerRegistryHost)
+	_, err = Push(newContext(), newResolver(), ref, store, []ocispec.Descriptor{file})
+	suite.Nil(err, "no error pushing")
+
+	outDir := filepath.Join(tempDir, "out")
+	output := orascontent.NewFileStore(outDir)
+	_, layers, err := Pull(newContext(), newResolver(), ref, output)
+	suite.Nil(err, "no error pulling")
+	suite.Equal(1, len(layers), "number of layers matches")
+	suite.Equal(orascontent.DefaultBlobMediaType+"+zstd", layers[0].MediaType, "media type suffixed")
+	suite.Equal(digest.FromBytes(content).String(), layers[0].Annotations[orascontent.AnnotationDigest], "content digest annotated")
+	suite.True(layers[0].Size < int64(len(content)), "layer compressed")
+	pulled, err := ioutil.ReadFile(filepath.Join(outDir, "compress.txt"))
+	suite.Nil(err, "no error reading pulled file")
+	suite.Equal(content, pulled, "file decompressed")
+}
+
+func (suite *ORASTestSuite) Test_15_CatFile() {
+	store := orascontent.NewFileStore("")
+	defer store.Close()
+	store.Compression = orascontent.CompressionZstd
+	readme, err := store.Add("README.md", "", filepath.Join(testDir, "README.md"))
+	suite.Nil(err, "no error adding file")
+	dir, err := store.Add("chartmuseum", "", testDir)
+	suite.Nil(err, "no error adding directory")
+	ref := fmt.Sprintf("%s/cat:test", suite.DockerRegistryHost)
+	desc, err := Push(newContext(), newResolver(), ref, store, []ocispec.Descriptor{readme, dir})
+	suite.Nil(err, "no error pushing")
+	fetcher, err := newResolver().Fetcher(newContext(), ref)
+	suite.Nil(err, "no error creating fetcher")
+
+	for name, path := range map[string]string{
+		"README.md":                       "README.md",
+		"chartmuseum/templates/NOTES.txt": "templates/NOTES.txt",
+		"./chartmuseum//Chart.yaml":       "Chart.yaml",
+	} {
+		expected, err := ioutil.ReadFile(filepath.Join(testDir, path))
+		suite.Nil(err, "no error reading test file")
+		var buf bytes.Buffer
+		err = CatFile(newContext(), fetcher, desc, name, &buf)
+		suite.Nil(err, "no error printing %s", name)
+		suite.Equal(string(expected), buf.String(), "content of %s matches", name)
+	}
+
+	err = CatFile(newContext(), fetcher, desc, "chartmuseum/missing.yaml", ioutil.Discard)
+	suite.True(errors.Is(err, ErrFileNotFound), "missing file not found")
+	err = CatFile(newContext(), fetcher, desc, "chartmuseum/templates", ioutil.Discard)
+	suite.NotNil(err, "error printing directory")
+}
+
+func (suite *ORASTestSuite) Test_16_CopyArtifactReferrer() {
+	tempDir, err := ioutil.TempDir("", "oras_referrer_test")
+	suite.Nil(err, "no error creating temp directory")
+	defer os.RemoveAll(tempDir)
+	src, err := orascontent.NewOCIStore(filepath.Join(tempDir, "src"))
+	suite.Nil(err, "no error creating source layout")
+	dst, err := orascontent.NewOCIStore(filepath.Join(tempDir, "dst"))
+	suite.Nil(err, "no error creating destination layout")
+
+	store := orascontent.NewMemoryStore()
+	files := []ocispec.Descriptor{store.Add("hi.txt", "", []byte("hi"))}
+	subject, err := Push(newContext(), src.Resolver(), "v1", store, files, WithManifestAnnotations(map[string]string{
+		"org.example.build": "42",
+	}))
+	suite.Nil(err, "no error pushing subject")
+
+	// attach an artifact manifest to the subject
+	sigContent := []byte("signature")
+	sig := ocispec.Descriptor{
+		MediaType: "application/vnd.example.signature",
+		Digest:    digest.FromBytes(sigContent),
+		Size:      int64(len(sigContent)),
+	}
+	manifestBytes, err := json.Marshal(artifact.Manifest{
+		MediaType:    artifact.ArtifactManifestMediaType,
+		ArtifactType: "application/vnd.example.signature",
+		Blobs:        []ocispec.Descriptor{sig},
+		Subject:      &subject,
+	})
+	suite.Nil(err, "no error marshaling artifact manifest")
+	referrer := ocispec.Descriptor{
+		MediaType: artifact.ArtifactManifestMediaType,
+		Digest:    digest.FromBytes(manifestBytes),
+		Size:      int64(len(manifestBytes)),
+	}
+	for desc, content := range map[*ocispec.Descriptor][]byte{&sig: sigContent, &referrer: manifestBytes} {
+		err = containerdcontent.WriteBlob(newContext(), src, desc.Digest.String(), bytes.NewReader(content), *desc)
+		suite.Nil(err, "no error writing %s", desc.Digest)
+	}
+	src.AddReference("sig", referrer)
+	suite.Nil(src.SaveIndex(), "no error saving index")
+	fetcher, err := src.Resolver().Fetcher(newContext(), "sig")
+	suite.Nil(err, "no error creating fetcher")
+
+	// the referrer refers to the rewritten subject
+	rewriter := newManifestRewriter([]string{"org.example.*"}, nil)
+	rewrittenSubject, err := rewriter.rewrite(newContext(), fetcher, subject)
+	suite.Nil(err, "no error rewriting subject")
+	suite.NotEqual(subject.Digest, rewrittenSubject.Digest, "subject rewritten")
+	rewrittenReferrer, err := rewriter.rewrite(newContext(), fetcher, referrer)
+	suite.Nil(err, "no error rewriting referrer")
+	suite.NotEqual(referrer.Digest, rewrittenReferrer.Digest, "referrer rewritten")
+	var rewrittenManifest artifact.Manifest
+	err = json.Unmarshal(rewriter.content[rewrittenReferrer.Digest], &rewrittenManifest)
+	suite.Nil(err, "no error decoding rewritten referrer")
+	suite.Equal(rewrittenSubject.Digest, rewrittenManifest.Subject.Digest, "subject remapped")
+	suite.Equal([]ocispec.Descriptor{sig}, rewrittenManifest.Blobs, "blobs unchanged")
+
+	// the blobs of artifact manifests are copied
+	copied, err := Copy(newContext(), src.Resolver(), "sig", dst.Resolver(), "sig")
+	suite.Nil(err, "no error copying artifact manifest")
+	suite.Equal(referrer.Digest, copied.Digest, "copied manifest matches")
+	for _, desc := range []ocispec.Descriptor{sig, referrer} {
+		_, err = dst.Info(newContext(), desc.Digest)
+		suite.Nil(err, "%s copied", desc.Digest)
+	}
+}
+
+func (suite *ORASTestSuite) Test_17_ContentPolicy() {
+	tempDir, err := ioutil.TempDir("", "oras_policy_test")
+	suite.Nil(err, "no error creating temp directory")
+	defer os.RemoveAll(tempDir)
+	for name, content := range map[string]string{
+		"hi.txt":             "hi",
+		"dir/server.KEY":     "key",
+		"dir/sub/config.txt": "config",
+	} {
+		path := filepath.Join(tempDir, name)
+		suite.Nil(os.MkdirAll(filepath.Dir(path), 0755), "no error creating directory")
+		suite.Nil(ioutil.WriteFile(path, []byte(content), 0644), "no error writing %s", name)
+	}
+	store := orascontent.NewFileStore(tempDir)
+	defer store.Close()
+	var files []ocispec.Descriptor
+	for _, name := range []string{"hi.txt", "dir"} {
+		desc, err := store.Add(name, "", filepath.Join(tempDir, name))
+		suite.Nil(err, "no error adding %s", name)
+		files = append(files, desc)
+	}
+	layout, err := orascontent.NewOCIStore(filepath.Join(tempDir, "layout"))
+	suite.Nil(err, "no error creating layout")
+
+	// all the violations are reported before any upload
+	_, err = Push(newContext(), layout.Resolver(), "v1", store, files, WithContentPolicy(ContentRules{
+		MaxLayerSize:        1,
+		MaxFileCount:        2,
+		ForbiddenExtensions: []string{".pem", ".key"},
+		RequiredFiles:       []string{"README*"},
+	}))
+	policyErr, ok := err.(*PolicyError)
+	suite.True(ok, "policy error returned")
+	var rules []string
+	for _, v := range policyErr.Violations {
+		rules = append(rules, v.Rule)
+	}
+	suite.Equal([]string{"maxLayerSize", "maxLayerSize", "maxFileCount", "forbiddenExtensions", "requiredFiles"}, rules, "violations")
+	suite.Equal("dir/server.KEY", policyErr.Violations[3].Name, "file in directory checked")
+	_, _, err = layout.Resolver().Resolve(newContext(), "v1")
+	suite.NotNil(err, "nothing pushed")
+
+	// compliant artifacts are pushed
+	_, err = Push(newContext(), layout.Resolver(), "v1", store, files, WithContentPolicy(ContentRules{
+		MaxFileCount:        3,
+		ForbiddenExtensions: []string{".pem"},
+		RequiredFiles:       []string{"hi.*", "dir/sub/*"},
+	}))
+	suite.Nil(err, "no error pushing compliant artifact")
+}
+
+// Pull sequentially while prefetching the next blob
+func (suite *ORASTestSuite) Test_18_PullPrefetch() {
+	store := orascontent.NewMemoryStore()
+	contents := make(map[string][]byte)
+	var descriptors []ocispec.Descriptor
+	for i, size := range []int{0, 3, 10, 1, 64} {
+		name := fmt.Sprintf("file%d.bin", i)
+		contents[name] = bytes.Repeat([]byte{byte('a' + i)}, size)
+		descriptors = append(descriptors, store.Add(name, "", contents[name]))
+	}
+	ref := fmt.Sprintf("%s/prefetch:test", suite.DockerRegistryHost)
+	_, err := Push(newContext(), newResolver(), ref, store, descriptors)
+	suite.Nil(err, "no error pushing")
+
+	_, _, err = Pull(newContext(), newResolver(), ref, orascontent.NewMemoryStore(), WithPullPrefetch(-1))
+	suite.Equal(ErrInvalidPrefetchBudget, err, "error pulling with invalid prefetch budget")
+
+	// blobs larger than the budget are prefetched partially
+	store = orascontent.NewMemoryStore()
+	_, pulled, err := Pull(newContext(), newResolver(), ref, store, WithPullByBFS, WithPullPrefetch(4))
+	suite.Nil(err, "no error pulling with prefetch")
+	suite.Equal(len(descriptors), len(pulled), "number of contents matches on pull")
+	for _, desc := range pulled {
+		name, _ := orascontent.ResolveName(desc)
+		_, content, ok := store.GetByName(name)
+		suite.True(ok, "%s pulled", name)
+		suite.Equal(string(contents[name]), string(content), "%s content matches", name)
+	}
+}
+
+func (suite *ORASTestSuite) Test_19_Tombstone() {
+	store := orascontent.NewMemoryStore()
+	descriptors := []ocispec.Descriptor{store.Add("deprecated.txt", "", []byte("deprecated"))}
+	ref := fmt.Sprintf("%s/tombstone:test", suite.DockerRegistryHost)
+	deprecated, err := Push(newContext(), newResolver(), ref, store, descriptors)
+	suite.Nil(err, "no error pushing")
+
+	tombstone := artifact.Tombstone{
+		Time:        time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Digest:      deprecated.Digest,
+		Replacement: fmt.Sprintf("%s/tombstone:replacement", suite.DockerRegistryHost),
+	}
+	_, err = PushTombstone(newContext(), newResolver(), fmt.Sprintf("%s/tombstone@%s", suite.DockerRegistryHost, deprecated.Digest), tombstone)
+	suite.NotNil(err, "error pushing a tombstone by digest")
+
+	desc, err := PushTombstone(newContext(), newResolver(), ref, tombstone)
+	suite.Nil(err, "no error pushing a tombstone")
+	suite.NotEqual(deprecated.Digest, desc.Digest, "tombstone replaces the manifest")
+
+	resolver := newResolver()
+	_, resolved, err := resolver.Resolve(newContext(), ref)
+	suite.Nil(err, "no error resolving the tag")
+	suite.Equal(desc.Digest, resolved.Digest, "tag points to the tombstone")
+	fetcher, err := resolver.Fetcher(newContext(), ref)
+	suite.Nil(err, "no error getting the fetcher")
+	manifestBytes, err := fetchBlob(newContext(), fetcher, resolved)
+	suite.Nil(err, "no error fetching the tombstone")
+	var manifest ocispec.Manifest
+	suite.Nil(json.Unmarshal(manifestBytes, &manifest), "no error decoding the tombstone")
+	suite.Equal(artifact.TombstoneConfigMediaType, manifest.Config.MediaType, "tombstone config media type")
+	suite.Empty(manifest.Layers, "tombstone has no layers")
+	parsed, ok, err := artifact.ParseTombstone(manifest.Annotations)
+	suite.Nil(err, "no error parsing the tombstone")
+	suite.True(ok, "manifest is a tombstone")
+	suite.Equal(tombstone, parsed, "tombstone round trips")
+}
+
 func TestORASTestSuite(t *testing.T) {
 	suite.Run(t, new(ORASTestSuite))
 }
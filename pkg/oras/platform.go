@@ -0,0 +1,97 @@
+package oras
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/platforms"
+	"github.com/containerd/containerd/remotes"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// PlatformMatcher selects the manifests of an index to be processed.
+// Custom matchers may select manifests by annotations in addition to the
+// platform of the descriptor, e.g. GPU capabilities or OS features.
+type PlatformMatcher interface {
+	// Match returns true if the manifest described by desc is selected.
+	Match(desc ocispec.Descriptor) bool
+}
+
+// PlatformMatcherFunc allows callers to implement a PlatformMatcher with just
+// a function.
+type PlatformMatcherFunc func(desc ocispec.Descriptor) bool
+
+// Match returns true if the manifest described by desc is selected.
+func (fn PlatformMatcherFunc) Match(desc ocispec.Descriptor) bool {
+	return fn(desc)
+}
+
+// MatchPlatform returns a PlatformMatcher with the standard os/arch/variant
+// matching. Manifests without platforms are not selected.
+func MatchPlatform(matcher platforms.Matcher) PlatformMatcher {
+	return PlatformMatcherFunc(func(desc ocispec.Descriptor) bool {
+		return desc.Platform != nil && matcher.Match(*desc.Platform)
+	})
+}
+
+// filterPlatforms filters the manifests of the indexes, which are returned as
+// children by the handler. No filter is applied if the matcher is nil.
+func filterPlatforms(h images.Handler, matcher PlatformMatcher) images.Handler {
+	if matcher == nil {
+		return h
+	}
+	return images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		children, err := h.Handle(ctx, desc)
+		if err != nil || !isIndexMediaType(desc.MediaType) {
+			return children, err
+		}
+		var selected []ocispec.Descriptor
+		for _, child := range children {
+			if matcher.Match(child) {
+				selected = append(selected, child)
+			}
+		}
+		if len(children) > 0 && len(selected) == 0 {
+			return nil, errors.Wrapf(ErrPlatformNotMatched, "index %s", desc.Digest)
+		}
+		return selected, nil
+	})
+}
+
+// SelectPlatform selects the first manifest matched in the index recursively.
+// The descriptor is returned as is if it is not an index. ErrPlatformNotMatched
+// is returned if no manifest is matched.
+func SelectPlatform(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor, matcher PlatformMatcher) (ocispec.Descriptor, error) {
+	for isIndexMediaType(desc.MediaType) {
+		rc, err := fetcher.Fetch(ctx, desc)
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		var index ocispec.Index
+		err = json.NewDecoder(io.LimitReader(rc, desc.Size)).Decode(&index)
+		rc.Close()
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+
+		found := false
+		for _, manifest := range index.Manifests {
+			if matcher.Match(manifest) {
+				desc = manifest
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ocispec.Descriptor{}, errors.Wrapf(ErrPlatformNotMatched, "index %s", desc.Digest)
+		}
+	}
+	return desc, nil
+}
+
+func isIndexMediaType(mediaType string) bool {
+	return mediaType == ocispec.MediaTypeImageIndex || mediaType == images.MediaTypeDockerSchema2ManifestList
+}
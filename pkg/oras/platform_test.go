@@ -0,0 +1,78 @@
+package oras
+
+import (
+	"context"
+	"testing"
+
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/platforms"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type PlatformSuite struct {
+	suite.Suite
+}
+
+func (suite *PlatformSuite) TestFilterPlatforms() {
+	var (
+		amd64 = ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    "sha256:amd64",
+			Platform:  &ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		}
+		arm64 = ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    "sha256:arm64",
+			Platform:  &ocispec.Platform{OS: "linux", Architecture: "arm64"},
+		}
+		gpu = ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    "sha256:gpu",
+			Platform:  &ocispec.Platform{OS: "linux", Architecture: "amd64"},
+			Annotations: map[string]string{
+				"com.example.gpu": "true",
+			},
+		}
+		index = ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageIndex,
+		}
+		children = images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+			return []ocispec.Descriptor{amd64, arm64, gpu}, nil
+		})
+	)
+
+	// no filter
+	selected, err := filterPlatforms(children, nil).Handle(context.Background(), index)
+	suite.NoError(err, "no error handling index")
+	suite.Equal([]ocispec.Descriptor{amd64, arm64, gpu}, selected, "all manifests selected")
+
+	// standard platform matching
+	matcher := MatchPlatform(platforms.NewMatcher(platforms.MustParse("linux/arm64")))
+	selected, err = filterPlatforms(children, matcher).Handle(context.Background(), index)
+	suite.NoError(err, "no error handling index")
+	suite.Equal([]ocispec.Descriptor{arm64}, selected, "arm64 manifest selected")
+
+	// custom matching
+	matcher = PlatformMatcherFunc(func(desc ocispec.Descriptor) bool {
+		return desc.Annotations["com.example.gpu"] == "true"
+	})
+	selected, err = filterPlatforms(children, matcher).Handle(context.Background(), index)
+	suite.NoError(err, "no error handling index")
+	suite.Equal([]ocispec.Descriptor{gpu}, selected, "gpu manifest selected")
+
+	// no manifest matched
+	matcher = MatchPlatform(platforms.NewMatcher(platforms.MustParse("windows/amd64")))
+	_, err = filterPlatforms(children, matcher).Handle(context.Background(), index)
+	suite.Equal(ErrPlatformNotMatched, errors.Cause(err), "error on no manifest matched")
+
+	// manifests are not filtered
+	selected, err = filterPlatforms(children, matcher).Handle(context.Background(), amd64)
+	suite.NoError(err, "no error handling manifest")
+	suite.Equal(3, len(selected), "children of manifest not filtered")
+}
+
+func TestPlatformSuite(t *testing.T) {
+	suite.Run(t, new(PlatformSuite))
+}
@@ -0,0 +1,224 @@
+package oras
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/containerd/containerd/content"
+	orascontent "github.com/deislabs/oras/pkg/content"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// ContentPolicy checks the content of an artifact before any of it is
+// uploaded.
+type ContentPolicy interface {
+	// Evaluate returns the violations of the policy by the layers described
+	// by descriptors, whose content is read from provider if needed.
+	Evaluate(ctx context.Context, provider content.Provider, descriptors []ocispec.Descriptor) ([]PolicyViolation, error)
+}
+
+// PolicyViolation is a rule of a content policy violated by an artifact.
+type PolicyViolation struct {
+	// Rule is the name of the violated rule, e.g. "maxLayerSize".
+	Rule string
+	// Name is the file or the layer violating the rule, or empty if the rule
+	// applies to the whole artifact.
+	Name string
+	// Message describes the violation.
+	Message string
+}
+
+// String returns the violation in a human readable form.
+func (v PolicyViolation) String() string {
+	if v.Name == "" {
+		return fmt.Sprintf("%s: %s", v.Rule, v.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", v.Rule, v.Name, v.Message)
+}
+
+// PolicyError is returned when an artifact violates the content policies. All
+// the violations are listed.
+type PolicyError struct {
+	Violations []PolicyViolation
+}
+
+// Error implements error.
+func (e *PolicyError) Error() string {
+	var b strings.Builder
+	b.WriteString("content policy violated:")
+	for _, v := range e.Violations {
+		b.WriteString("\n  - ")
+		b.WriteString(v.String())
+	}
+	return b.String()
+}
+
+// ContentRules is a ContentPolicy of common rules. The zero value of a rule
+// disables it.
+type ContentRules struct {
+	// MaxLayerSize is the maximum size of a layer in bytes.
+	MaxLayerSize int64
+	// MaxFileCount is the maximum number of files, counting the regular files
+	// in directories, and the chunked or sharded files once.
+	MaxFileCount int
+	// ForbiddenExtensions are the file extensions no file may have, e.g.
+	// ".pem". They are matched case-insensitively.
+	ForbiddenExtensions []string
+	// RequiredFiles are the glob patterns of the file paths each matched by
+	// at least one file, e.g. "README*".
+	RequiredFiles []string
+}
+
+// Evaluate implements ContentPolicy. The directories are read only if there
+// are file rules.
+func (r ContentRules) Evaluate(ctx context.Context, provider content.Provider, descriptors []ocispec.Descriptor) ([]PolicyViolation, error) {
+	var violations []PolicyViolation
+	if r.MaxLayerSize > 0 {
+		for _, desc := range descriptors {
+			if desc.Size > r.MaxLayerSize {
+				name, ok := orascontent.ResolveName(desc)
+				if !ok {
+					name = desc.Digest.String()
+				}
+				violations = append(violations, PolicyViolation{
+					Rule:    "maxLayerSize",
+					Name:    name,
+					Message: fmt.Sprintf("size %d exceeds %d bytes", desc.Size, r.MaxLayerSize),
+				})
+			}
+		}
+	}
+	if r.MaxFileCount <= 0 && len(r.ForbiddenExtensions) == 0 && len(r.RequiredFiles) == 0 {
+		return violations, nil
+	}
+
+	files, err := listFiles(ctx, provider, descriptors)
+	if err != nil {
+		return nil, err
+	}
+	if r.MaxFileCount > 0 && len(files) > r.MaxFileCount {
+		violations = append(violations, PolicyViolation{
+			Rule:    "maxFileCount",
+			Message: fmt.Sprintf("%d files exceed %d", len(files), r.MaxFileCount),
+		})
+	}
+	for _, name := range files {
+		for _, ext := range r.ForbiddenExtensions {
+			if strings.HasSuffix(strings.ToLower(name), strings.ToLower(ext)) {
+				violations = append(violations, PolicyViolation{
+					Rule:    "forbiddenExtensions",
+					Name:    name,
+					Message: fmt.Sprintf("extension %s is forbidden", ext),
+				})
+				break
+			}
+		}
+	}
+	for _, pattern := range r.RequiredFiles {
+		found := false
+		for _, name := range files {
+			if ok, _ := path.Match(pattern, name); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			violations = append(violations, PolicyViolation{
+				Rule:    "requiredFiles",
+				Message: fmt.Sprintf("no file matches %q", pattern),
+			})
+		}
+	}
+	return violations, nil
+}
+
+// listFiles returns the paths of the files in the layers, in order, including
+// the regular files in the directories. The chunks and the shards of a file
+// are listed once by the name of the file, and untitled layers are skipped.
+func listFiles(ctx context.Context, provider content.Provider, descriptors []ocispec.Descriptor) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			files = append(files, name)
+		}
+	}
+	for _, desc := range descriptors {
+		name, ok := orascontent.ResolveName(desc)
+		if !ok {
+			continue
+		}
+		if desc.Annotations[orascontent.AnnotationUnpack] == "true" {
+			entries, err := listTarFiles(ctx, provider, desc)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				add(entry)
+			}
+			continue
+		}
+		if file, ok := orascontent.ChunkedFileName(desc); ok {
+			name = file
+		} else if file, ok := desc.Annotations[orascontent.AnnotationShardFile]; ok {
+			name = file
+		}
+		add(path.Clean(name))
+	}
+	return files, nil
+}
+
+// listTarFiles returns the paths of the regular files in a directory layer.
+func listTarFiles(ctx context.Context, provider content.Provider, desc ocispec.Descriptor) ([]string, error) {
+	ra, err := provider.ReaderAt(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer ra.Close()
+	compression := orascontent.CompressionGzip
+	if value, ok := desc.Annotations[orascontent.AnnotationCompression]; ok {
+		compression = orascontent.Compression(value)
+	}
+	zr, err := compression.NewReader(content.NewReader(ra))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	var files []string
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s", desc.Digest)
+		}
+		if header.Typeflag == tar.TypeReg {
+			files = append(files, path.Clean("/" + header.Name)[1:])
+		}
+	}
+}
+
+// evaluatePolicies evaluates the policies on the layers, returning a
+// PolicyError listing the violations of all the policies.
+func evaluatePolicies(ctx context.Context, policies []ContentPolicy, provider content.Provider, descriptors []ocispec.Descriptor) error {
+	var violations []PolicyViolation
+	for _, policy := range policies {
+		v, err := policy.Evaluate(ctx, provider, descriptors)
+		if err != nil {
+			return err
+		}
+		violations = append(violations, v...)
+	}
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package oras
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/remotes"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// prefetchFetcher starts downloading the next blob to pull whenever a blob is
+// fetched, so that the network is busy while the current blob is written.
+// Up to budget bytes of the next blob are buffered in memory, and the rest is
+// read from the same stream once the blob is fetched.
+type prefetchFetcher struct {
+	remotes.Fetcher
+	ctx    context.Context
+	budget int64
+	accept func(ocispec.Descriptor) bool
+
+	lock    sync.Mutex
+	queue   []ocispec.Descriptor
+	started map[digest.Digest]bool
+	pending map[digest.Digest]*prefetch
+}
+
+// prefetch is a blob being prefetched.
+type prefetch struct {
+	done chan struct{}
+	buf  []byte
+	rc   io.ReadCloser
+	err  error
+}
+
+func newPrefetchFetcher(ctx context.Context, fetcher remotes.Fetcher, budget int64, accept func(ocispec.Descriptor) bool) *prefetchFetcher {
+	return &prefetchFetcher{
+		Fetcher: fetcher,
+		ctx:     ctx,
+		budget:  budget,
+		accept:  accept,
+		started: make(map[digest.Digest]bool),
+		pending: make(map[digest.Digest]*prefetch),
+	}
+}
+
+// Fetch returns the prefetched blob if any, and prefetches the blob queued
+// after desc.
+func (f *prefetchFetcher) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	f.lock.Lock()
+	p := f.pending[desc.Digest]
+	delete(f.pending, desc.Digest)
+	f.started[desc.Digest] = true
+	for i, queued := range f.queue {
+		if queued.Digest == desc.Digest && i+1 < len(f.queue) {
+			f.start(f.queue[i+1])
+			break
+		}
+	}
+	f.lock.Unlock()
+
+	if p == nil {
+		return f.Fetcher.Fetch(ctx, desc)
+	}
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		go p.close()
+		return nil, ctx.Err()
+	}
+	if p.err != nil {
+		log.G(ctx).WithError(p.err).WithField("digest", desc.Digest).Debug("prefetch failed, fetching again")
+		return f.Fetcher.Fetch(ctx, desc)
+	}
+	if p.rc == nil {
+		return nopCloser{bytes.NewReader(p.buf)}, nil
+	}
+	return &prefetchReader{
+		Reader: io.MultiReader(bytes.NewReader(p.buf), p.rc),
+		Closer: p.rc,
+	}, nil
+}
+
+// start prefetches the blob in the background unless it is fetched already.
+// The caller must hold the lock.
+func (f *prefetchFetcher) start(desc ocispec.Descriptor) {
+	if f.started[desc.Digest] {
+		return
+	}
+	f.started[desc.Digest] = true
+	p := &prefetch{
+		done: make(chan struct{}),
+	}
+	f.pending[desc.Digest] = p
+	go func() {
+		defer close(p.done)
+		rc, err := f.Fetcher.Fetch(f.ctx, desc)
+		if err != nil {
+			p.err = err
+			return
+		}
+		size := desc.Size
+		if size > f.budget {
+			size = f.budget
+		}
+		p.buf = make([]byte, size)
+		if _, err := io.ReadFull(rc, p.buf); err != nil {
+			rc.Close()
+			p.err = err
+			return
+		}
+		if size == desc.Size {
+			// read to EOF so that the content is verified
+			n, err := io.Copy(ioutil.Discard, rc)
+			rc.Close()
+			if err == nil && n > 0 {
+				err = errors.Wrapf(ErrContentMismatch, "%s: size exceeds %d", desc.Digest, desc.Size)
+			}
+			p.err = err
+			return
+		}
+		p.rc = rc
+	}()
+}
+
+// queueChildren queues the children to pull returned by the handler, in
+// order, as candidates for prefetching.
+func (f *prefetchFetcher) queueChildren(handler images.Handler) images.HandlerFunc {
+	return func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		children, err := handler.Handle(ctx, desc)
+		if err != nil {
+			return nil, err
+		}
+		f.lock.Lock()
+		for _, child := range children {
+			if f.accept(child) {
+				f.queue = append(f.queue, child)
+			}
+		}
+		f.lock.Unlock()
+		return children, nil
+	}
+}
+
+// close releases the blobs prefetched but never fetched.
+func (f *prefetchFetcher) close() {
+	f.lock.Lock()
+	pending := f.pending
+	f.pending = make(map[digest.Digest]*prefetch)
+	f.lock.Unlock()
+	for _, p := range pending {
+		p.close()
+	}
+}
+
+func (p *prefetch) close() {
+	<-p.done
+	if p.rc != nil {
+		p.rc.Close()
+	}
+}
+
+type prefetchReader struct {
+	io.Reader
+	io.Closer
+}
+
+type nopCloser struct {
+	io.Reader
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
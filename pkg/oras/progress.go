@@ -0,0 +1,137 @@
+package oras
+
+import (
+	"context"
+	"sync"
+
+	orascontent "github.com/deislabs/oras/pkg/content"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Progress is the progress of a blob transfer.
+type Progress struct {
+	// Descriptor describes the blob transferred.
+	Descriptor ocispec.Descriptor
+	// Offset is the number of bytes transferred, including the ones of
+	// resumed transfers.
+	Offset int64
+	// Done is set once the blob is transferred, or found existing at the
+	// destination.
+	Done bool
+}
+
+// ProgressFunc is called on the progress of blob transfers. It may be called
+// concurrently for different blobs.
+type ProgressFunc func(Progress)
+
+// progressPusher reports the progress of the blobs pushed.
+type progressPusher struct {
+	remotes.Pusher
+	progress ProgressFunc
+}
+
+// Push implements remotes.Pusher.
+func (p *progressPusher) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	w, err := p.Pusher.Push(ctx, desc)
+	return newProgressWriter(w, err, desc, p.progress)
+}
+
+// progressIngester reports the progress of the blobs ingested.
+type progressIngester struct {
+	orascontent.ProvideIngester
+	progress ProgressFunc
+}
+
+// Writer implements content.Ingester.
+func (i *progressIngester) Writer(ctx context.Context, opts ...content.WriterOpt) (content.Writer, error) {
+	var wOpts content.WriterOpts
+	for _, opt := range opts {
+		if err := opt(&wOpts); err != nil {
+			return nil, err
+		}
+	}
+	w, err := i.ProvideIngester.Writer(ctx, opts...)
+	return newProgressWriter(w, err, wOpts.Desc, i.progress)
+}
+
+// newProgressWriter wraps the writer created with err. Existing blobs are
+// reported as done.
+func newProgressWriter(w content.Writer, err error, desc ocispec.Descriptor, progress ProgressFunc) (content.Writer, error) {
+	if err != nil {
+		if errdefs.IsAlreadyExists(err) {
+			progress(Progress{
+				Descriptor: desc,
+				Offset:     desc.Size,
+				Done:       true,
+			})
+		}
+		return nil, err
+	}
+	return &progressWriter{
+		Writer:   w,
+		desc:     desc,
+		progress: progress,
+	}, nil
+}
+
+// progressWriter reports the bytes written and committed.
+type progressWriter struct {
+	content.Writer
+	desc     ocispec.Descriptor
+	progress ProgressFunc
+
+	lock   sync.Mutex
+	offset int64
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.lock.Lock()
+	w.offset += int64(n)
+	offset := w.offset
+	w.lock.Unlock()
+	w.progress(Progress{
+		Descriptor: w.desc,
+		Offset:     offset,
+	})
+	return n, err
+}
+
+// Status keeps the offset in sync with the writer, which is ahead of the
+// bytes written when resuming.
+func (w *progressWriter) Status() (content.Status, error) {
+	status, err := w.Writer.Status()
+	if err == nil {
+		w.lock.Lock()
+		w.offset = status.Offset
+		w.lock.Unlock()
+	}
+	return status, err
+}
+
+func (w *progressWriter) Truncate(size int64) error {
+	if err := w.Writer.Truncate(size); err != nil {
+		return err
+	}
+	w.lock.Lock()
+	w.offset = size
+	w.lock.Unlock()
+	return nil
+}
+
+func (w *progressWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...content.Opt) error {
+	err := w.Writer.Commit(ctx, size, expected, opts...)
+	if err == nil || errdefs.IsAlreadyExists(err) {
+		w.progress(Progress{
+			Descriptor: w.desc,
+			Offset:     w.desc.Size,
+			Done:       true,
+		})
+	}
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"sync"
 
+	artifact "github.com/deislabs/oras/pkg/artifact"
 	orascontent "github.com/deislabs/oras/pkg/content"
 
 	"github.com/containerd/containerd/content"
@@ -37,6 +38,7 @@ func Pull(ctx context.Context, resolver remotes.Resolver, ref string, ingester c
 		return ocispec.Descriptor{}, nil, err
 	}
 
+	fetcher = &verifyingFetcher{Fetcher: fetcher}
 	layers, err := fetchContent(ctx, fetcher, desc, ingester, opt)
 	if err != nil {
 		return ocispec.Descriptor{}, nil, err
@@ -63,6 +65,26 @@ func fetchContent(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Des
 	if store == nil {
 		store = newHybridStoreFromIngester(ingester)
 	}
+	if opts.progress != nil {
+		store = &progressIngester{
+			ProvideIngester: store,
+			progress:        opts.progress,
+		}
+	}
+	children := filterPlatforms(images.ChildrenHandler(store), opts.platform)
+	if opts.prefetchBudget > 0 {
+		prefetchCtx, cancel := context.WithCancel(ctx)
+		prefetcher := newPrefetchFetcher(prefetchCtx, fetcher, opts.prefetchBudget, func(desc ocispec.Descriptor) bool {
+			return !isManifestMediaType(desc.MediaType) && desc.MediaType != artifact.AnnotationsMediaType &&
+				isAllowedMediaType(desc.MediaType, opts.allowedMediaTypes...) && opts.filterName(desc)
+		})
+		defer func() {
+			cancel()
+			prefetcher.close()
+		}()
+		fetcher = prefetcher
+		children = prefetcher.queueChildren(children)
+	}
 	handlers := []images.Handler{
 		filterHandler(opts, opts.allowedMediaTypes...),
 	}
@@ -70,11 +92,11 @@ func fetchContent(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Des
 	handlers = append(handlers,
 		remotes.FetchHandler(store, fetcher),
 		picker,
-		images.ChildrenHandler(store),
+		children,
 	)
 	handlers = append(handlers, opts.callbackHandlers...)
 
-	if err := opts.dispatch(ctx, images.Handlers(handlers...), nil, desc); err != nil {
+	if err := opts.dispatch(ctx, images.Handlers(handlers...), opts.limiter, desc); err != nil {
 		return nil, err
 	}
 
@@ -86,6 +108,9 @@ func filterHandler(opts *pullOpts, allowedMediaTypes ...string) images.HandlerFu
 		switch {
 		case isAllowedMediaType(desc.MediaType, ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageIndex):
 			return nil, nil
+		case desc.MediaType == artifact.AnnotationsMediaType:
+			// manifest annotations, see FetchManifestAnnotations
+			return nil, images.ErrStopHandler
 		case isAllowedMediaType(desc.MediaType, allowedMediaTypes...):
 			if opts.filterName(desc) {
 				return nil, nil
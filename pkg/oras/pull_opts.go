@@ -21,6 +21,10 @@ type pullOpts struct {
 	callbackHandlers       []images.Handler
 	contentProvideIngester orascontent.ProvideIngester
 	filterName             func(ocispec.Descriptor) bool
+	limiter                *semaphore.Weighted
+	platform               PlatformMatcher
+	progress               ProgressFunc
+	prefetchBudget         int64
 }
 
 // PullOpt allows callers to set options on the oras pull
@@ -55,6 +59,50 @@ func WithPullByBFS(o *pullOpts) error {
 	return nil
 }
 
+// WithPullConcurrency limits the number of blobs downloaded in parallel.
+// It has no effect on the sequential pulling with WithPullByBFS.
+func WithPullConcurrency(concurrency int) PullOpt {
+	return func(o *pullOpts) error {
+		if concurrency <= 0 {
+			return ErrInvalidConcurrency
+		}
+		o.limiter = semaphore.NewWeighted(int64(concurrency))
+		return nil
+	}
+}
+
+// WithPullPrefetch downloads the next blob while the current one is written,
+// buffering up to budget bytes of it in memory, so that the network and the
+// disk are busy at the same time in sequential pulls, e.g. WithPullByBFS.
+func WithPullPrefetch(budget int64) PullOpt {
+	return func(o *pullOpts) error {
+		if budget < 0 {
+			return ErrInvalidPrefetchBudget
+		}
+		o.prefetchBudget = budget
+		return nil
+	}
+}
+
+// WithPullPlatform selects the manifests of indexes to be pulled by the
+// matcher. All manifests are pulled if not specified. ErrPlatformNotMatched
+// is returned if no manifest of an index is matched.
+func WithPullPlatform(matcher PlatformMatcher) PullOpt {
+	return func(o *pullOpts) error {
+		o.platform = matcher
+		return nil
+	}
+}
+
+// WithPullProgress reports the progress of the blobs downloaded to the
+// function.
+func WithPullProgress(progress ProgressFunc) PullOpt {
+	return func(o *pullOpts) error {
+		o.progress = progress
+		return nil
+	}
+}
+
 // WithPullBaseHandler provides base handlers, which will be called before
 // any pull specific handlers.
 func WithPullBaseHandler(handlers ...images.Handler) PullOpt {
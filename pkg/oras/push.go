@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 
 	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
 	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/reference"
 	"github.com/containerd/containerd/remotes"
 	artifact "github.com/deislabs/oras/pkg/artifact"
 	digest "github.com/opencontainers/go-digest"
 	specs "github.com/opencontainers/image-spec/specs-go"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/sync/semaphore"
 )
 
 // Push pushes files to the remote
@@ -36,25 +40,175 @@ func Push(ctx context.Context, resolver remotes.Resolver, ref string, provider c
 	if err != nil {
 		return ocispec.Descriptor{}, err
 	}
+	if opt.chunkedUploader != nil {
+		pusher = &chunkedPusher{
+			Pusher:   pusher,
+			ref:      ref,
+			uploader: opt.chunkedUploader,
+		}
+	}
+	if opt.progress != nil {
+		pusher = &progressPusher{
+			Pusher:   pusher,
+			progress: opt.progress,
+		}
+	}
+
+	if len(opt.policies) > 0 {
+		if err := evaluatePolicies(ctx, opt.policies, provider, descriptors); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
 
 	desc, store, err := pack(provider, descriptors, opt)
 	if err != nil {
 		return ocispec.Descriptor{}, err
 	}
+	if limit := opt.manifestSizeLimit; limit > 0 && desc.Size > limit && opt.manifest == nil {
+		if len(opt.manifestAnnotations) == 0 {
+			return ocispec.Descriptor{}, ErrManifestTooLarge
+		}
+		log.G(ctx).WithField("size", desc.Size).Debug("moving manifest annotations to an annotations blob")
+		if provider, descriptors, err = externalizeAnnotations(provider, descriptors, opt); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		if desc, store, err = pack(provider, descriptors, opt); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		if desc.Size > limit {
+			return ocispec.Descriptor{}, ErrManifestTooLarge
+		}
+	}
+
+	if opt.unchanged != nil {
+		candidates := []ocispec.Descriptor{desc}
+		if opt.artifactManifest != nil && opt.manifest == nil {
+			artifactDesc, _, err := packArtifactManifest(descriptors, opt)
+			if err != nil {
+				return ocispec.Descriptor{}, err
+			}
+			candidates = append([]ocispec.Descriptor{artifactDesc}, candidates...)
+		}
+		for _, candidate := range candidates {
+			ok, err := isPushed(ctx, resolver, ref, candidate)
+			if err != nil {
+				return ocispec.Descriptor{}, err
+			}
+			if ok {
+				opt.unchanged(candidate)
+				return candidate, nil
+			}
+		}
+	}
 
-	var wrapper func(images.Handler) images.Handler
+	wrapper := limitHandler(opt.limiter)
 	if len(opt.baseHandlers) > 0 {
 		wrapper = func(h images.Handler) images.Handler {
-			return images.Handlers(append(opt.baseHandlers, h)...)
+			return limitHandler(opt.limiter)(images.Handlers(append(opt.baseHandlers, h)...))
 		}
 	}
 
+	if opt.artifactManifest != nil && opt.manifest == nil {
+		artifactDesc, err := pushArtifactManifest(ctx, pusher, ref, store, descriptors, wrapper, opt)
+		if err == nil {
+			return artifactDesc, nil
+		}
+		if !errdefs.IsNotImplemented(err) {
+			return ocispec.Descriptor{}, err
+		}
+		log.G(ctx).WithError(err).Debug("artifact manifest rejected, falling back to image manifest")
+	}
+
 	if err := remotes.PushContent(ctx, pusher, desc, store, nil, wrapper); err != nil {
 		return ocispec.Descriptor{}, err
 	}
 	return desc, nil
 }
 
+// pushArtifactManifest pushes the blobs, and then the artifact manifest
+// listing them.
+func pushArtifactManifest(ctx context.Context, pusher remotes.Pusher, ref string, store content.Store, descriptors []ocispec.Descriptor, wrapper func(images.Handler) images.Handler, opts *pushOpts) (ocispec.Descriptor, error) {
+	if err := images.Dispatch(ctx, wrapper(remotes.PushHandler(pusher, store)), nil, descriptors...); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	desc, manifestBytes, err := packArtifactManifest(descriptors, opts)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := opts.artifactManifest.PushManifest(ctx, ref, desc, manifestBytes); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return desc, nil
+}
+
+// packArtifactManifest packs the artifact manifest listing the blobs.
+func packArtifactManifest(descriptors []ocispec.Descriptor, opts *pushOpts) (ocispec.Descriptor, []byte, error) {
+	artifactType := opts.artifactType
+	if artifactType == "" {
+		artifactType = artifact.UnknownConfigMediaType
+	}
+	manifest := artifact.Manifest{
+		MediaType:    artifact.ArtifactManifestMediaType,
+		ArtifactType: artifactType,
+		Blobs:        descriptors,
+		Subject:      opts.subject,
+		Annotations:  opts.manifestAnnotations,
+	}
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	desc := ocispec.Descriptor{
+		MediaType: artifact.ArtifactManifestMediaType,
+		Digest:    digest.FromBytes(manifestBytes),
+		Size:      int64(len(manifestBytes)),
+	}
+	return desc, manifestBytes, nil
+}
+
+// isPushed checks whether the reference resolves to the manifest. The
+// manifest is looked up by digest if the reference has neither tag nor
+// digest.
+func isPushed(ctx context.Context, resolver remotes.Resolver, ref string, desc ocispec.Descriptor) (bool, error) {
+	_, existing, err := resolver.Resolve(ctx, ref)
+	if err == reference.ErrObjectRequired {
+		_, existing, err = resolver.Resolve(ctx, ref+"@"+desc.Digest.String())
+	}
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return existing.Digest == desc.Digest, nil
+}
+
+// limitHandler returns a wrapper limiting the number of concurrent calls of
+// the handler by the limiter. No limit is applied if the limiter is nil.
+func limitHandler(limiter *semaphore.Weighted) func(images.Handler) images.Handler {
+	return func(h images.Handler) images.Handler {
+		if limiter == nil {
+			return h
+		}
+		return images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+			if err := limiter.Acquire(ctx, 1); err != nil {
+				return nil, err
+			}
+			defer limiter.Release(1)
+			return h.Handle(ctx, desc)
+		})
+	}
+}
+
+// imageManifest is an image manifest with the subject field of the referrers
+// extension of the OCI image specification.
+type imageManifest struct {
+	ocispec.Manifest
+	MediaType string              `json:"mediaType"`
+	Subject   *ocispec.Descriptor `json:"subject,omitempty"`
+}
+
 //func pack(store *hybridStore, descriptors []ocispec.Descriptor, opts *pushOpts) (ocispec.Descriptor, error) {
 func pack(provider content.Provider, descriptors []ocispec.Descriptor, opts *pushOpts) (ocispec.Descriptor, content.Store, error) {
 	store := newHybridStoreFromProvider(provider)
@@ -77,6 +231,8 @@ func pack(provider content.Provider, descriptors []ocispec.Descriptor, opts *pus
 	}
 	if opts.configMediaType != "" {
 		config.MediaType = opts.configMediaType
+	} else if opts.config == nil && opts.artifactType != "" {
+		config.MediaType = opts.artifactType
 	}
 
 	// Manifest
@@ -95,7 +251,17 @@ func pack(provider content.Provider, descriptors []ocispec.Descriptor, opts *pus
 		Layers:      descriptors,
 		Annotations: opts.manifestAnnotations,
 	}
-	manifestBytes, err := json.Marshal(manifest)
+	var manifestBytes []byte
+	var err error
+	if opts.subject != nil {
+		manifestBytes, err = json.Marshal(imageManifest{
+			Manifest:  manifest,
+			MediaType: ocispec.MediaTypeImageManifest,
+			Subject:   opts.subject,
+		})
+	} else {
+		manifestBytes, err = json.Marshal(manifest)
+	}
 	if err != nil {
 		return ocispec.Descriptor{}, nil, err
 	}
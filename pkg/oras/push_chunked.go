@@ -0,0 +1,36 @@
+package oras
+
+import (
+	"context"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/remotes"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ChunkedUploader uploads blobs in chunks, which is preferred over monolithic
+// uploads for large blobs.
+type ChunkedUploader interface {
+	// ChunkSize returns the size of the chunks. Blobs not larger than a chunk
+	// are uploaded by the pusher of the resolver.
+	ChunkSize() int64
+	// Writer returns a writer uploading the blob described by desc to the
+	// repository of ref. An errdefs.ErrAlreadyExists error is returned if
+	// the blob exists.
+	Writer(ctx context.Context, ref string, desc ocispec.Descriptor) (content.Writer, error)
+}
+
+// chunkedPusher pushes the blobs larger than a chunk using the uploader.
+type chunkedPusher struct {
+	remotes.Pusher
+	ref      string
+	uploader ChunkedUploader
+}
+
+// Push implements remotes.Pusher.
+func (p *chunkedPusher) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	if isManifestMediaType(desc.MediaType) || desc.Size <= p.uploader.ChunkSize() {
+		return p.Pusher.Push(ctx, desc)
+	}
+	return p.uploader.Writer(ctx, p.ref, desc)
+}
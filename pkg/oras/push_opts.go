@@ -13,6 +13,7 @@ import (
 
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/semaphore"
 )
 
 type pushOpts struct {
@@ -21,13 +22,32 @@ type pushOpts struct {
 	configAnnotations   map[string]string
 	manifest            *ocispec.Descriptor
 	manifestAnnotations map[string]string
+	subject             *ocispec.Descriptor
+	artifactType        string
+	artifactManifest    ManifestPusher
 	validateName        func(desc ocispec.Descriptor) error
 	baseHandlers        []images.Handler
+	limiter             *semaphore.Weighted
+	manifestSizeLimit   int64
+	chunkedUploader     ChunkedUploader
+	progress            ProgressFunc
+	subjectPlatform     PlatformMatcher
+	unchanged           func(ocispec.Descriptor)
+	policies            []ContentPolicy
+}
+
+// ManifestPusher pushes manifests of media types unknown to remotes.Pusher.
+type ManifestPusher interface {
+	// PushManifest pushes the manifest content described by desc to the
+	// repository of ref. An errdefs.ErrNotImplemented error is returned if
+	// the manifest is rejected.
+	PushManifest(ctx context.Context, ref string, desc ocispec.Descriptor, content []byte) error
 }
 
 func pushOptsDefaults() *pushOpts {
 	return &pushOpts{
-		validateName: ValidateNameAsPath,
+		validateName:      ValidateNameAsPath,
+		manifestSizeLimit: DefaultManifestSizeLimit,
 	}
 }
 
@@ -74,6 +94,80 @@ func WithManifestAnnotations(annotations map[string]string) PushOpt {
 	}
 }
 
+// WithSubject sets the subject of the manifest, which the pushed artifact
+// refers to.
+func WithSubject(subject ocispec.Descriptor) PushOpt {
+	return func(o *pushOpts) error {
+		o.subject = &subject
+		return nil
+	}
+}
+
+// WithArtifactType sets the type of the artifact, which is used as the config
+// media type of image manifests unless specified otherwise.
+func WithArtifactType(artifactType string) PushOpt {
+	return func(o *pushOpts) error {
+		o.artifactType = artifactType
+		return nil
+	}
+}
+
+// WithArtifactManifest pushes an artifact manifest using the provided pusher
+// in place of an image manifest. An image manifest is pushed instead if the
+// registry rejects the artifact manifest.
+func WithArtifactManifest(pusher ManifestPusher) PushOpt {
+	return func(o *pushOpts) error {
+		o.artifactManifest = pusher
+		return nil
+	}
+}
+
+// WithManifestSizeLimit sets the size limit of the manifest, over which the
+// manifest annotations are moved to an annotations blob referenced by the
+// manifest. Pass 0 to disable the limit.
+func WithManifestSizeLimit(limit int64) PushOpt {
+	return func(o *pushOpts) error {
+		o.manifestSizeLimit = limit
+		return nil
+	}
+}
+
+// WithChunkedUpload uploads the blobs larger than a chunk using the uploader.
+func WithChunkedUpload(uploader ChunkedUploader) PushOpt {
+	return func(o *pushOpts) error {
+		o.chunkedUploader = uploader
+		return nil
+	}
+}
+
+// WithPushProgress reports the progress of the blobs uploaded to the function.
+func WithPushProgress(progress ProgressFunc) PushOpt {
+	return func(o *pushOpts) error {
+		o.progress = progress
+		return nil
+	}
+}
+
+// WithIdempotent skips the push if the reference already resolves to the
+// manifest to be pushed, or the manifest already exists in the repository if
+// the reference has neither tag nor digest, as for Attach. unchanged is called
+// with the descriptor of the existing manifest if the push is skipped.
+func WithIdempotent(unchanged func(desc ocispec.Descriptor)) PushOpt {
+	return func(o *pushOpts) error {
+		o.unchanged = unchanged
+		return nil
+	}
+}
+
+// WithSubjectPlatform selects the first manifest matched by the matcher as the
+// subject of Attach if the subject reference refers to an index.
+func WithSubjectPlatform(matcher PlatformMatcher) PushOpt {
+	return func(o *pushOpts) error {
+		o.subjectPlatform = matcher
+		return nil
+	}
+}
+
 // WithNameValidation validates the image title in the descriptor.
 // Pass nil to disable name validation.
 func WithNameValidation(validate func(desc ocispec.Descriptor) error) PushOpt {
@@ -123,6 +217,27 @@ func ValidateNameAsPath(desc ocispec.Descriptor) error {
 	return nil
 }
 
+// WithContentPolicy checks the layers against the policies before any upload.
+// A PolicyError listing the violations of all the policies is returned if
+// any. The layers are checked as uploaded, i.e. after compression.
+func WithContentPolicy(policies ...ContentPolicy) PushOpt {
+	return func(o *pushOpts) error {
+		o.policies = append(o.policies, policies...)
+		return nil
+	}
+}
+
+// WithPushConcurrency limits the number of blobs uploaded in parallel.
+func WithPushConcurrency(concurrency int) PushOpt {
+	return func(o *pushOpts) error {
+		if concurrency <= 0 {
+			return ErrInvalidConcurrency
+		}
+		o.limiter = semaphore.NewWeighted(int64(concurrency))
+		return nil
+	}
+}
+
 // WithPushBaseHandler provides base handlers, which will be called before
 // any push specific handlers.
 func WithPushBaseHandler(handlers ...images.Handler) PushOpt {
@@ -139,10 +254,17 @@ func WithPushStatusTrack(writer io.Writer) PushOpt {
 
 func pushStatusTrack(writer io.Writer) images.Handler {
 	var printLock sync.Mutex
+	// blobs are visited again on falling back from an artifact manifest
+	printed := make(map[string]bool)
 	return images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
 		if name, ok := orascontent.ResolveName(desc); ok {
 			printLock.Lock()
 			defer printLock.Unlock()
+			key := desc.Digest.String() + name
+			if printed[key] {
+				return nil, nil
+			}
+			printed[key] = true
 			fmt.Fprintln(writer, "Uploading", desc.Digest.Encoded()[:12], name)
 		}
 		return nil, nil
@@ -0,0 +1,41 @@
+package oras
+
+import (
+	"context"
+
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	artifact "github.com/deislabs/oras/pkg/artifact"
+	orascontent "github.com/deislabs/oras/pkg/content"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// PushTombstone pushes an empty manifest to the tag of ref recording its
+// deprecation, for registries disallowing deletes. The tombstone is an image
+// manifest without layers, and its descriptor is returned with the manifest
+// annotations.
+func PushTombstone(ctx context.Context, resolver remotes.Resolver, ref string, tombstone artifact.Tombstone, opts ...PushOpt) (ocispec.Descriptor, error) {
+	if resolver == nil {
+		return ocispec.Descriptor{}, ErrResolverUndefined
+	}
+	refspec, err := reference.Parse(ref)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if refspec.Object == "" || refspec.Digest() != "" {
+		return ocispec.Descriptor{}, errors.Errorf("%s: a tombstone requires a tag", ref)
+	}
+
+	annotations := tombstone.Annotations()
+	opts = append(opts[:len(opts):len(opts)],
+		WithConfigMediaType(artifact.TombstoneConfigMediaType),
+		WithManifestAnnotations(annotations),
+	)
+	desc, err := Push(ctx, resolver, ref, orascontent.NewMemoryStore(), nil, opts...)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc.Annotations = annotations
+	return desc, nil
+}
@@ -0,0 +1,61 @@
+package oras
+
+import (
+	"context"
+	"io"
+
+	"github.com/containerd/containerd/remotes"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// verifyingFetcher verifies the size and the digest of the fetched blobs, so
+// that the content failing verification is never committed.
+type verifyingFetcher struct {
+	remotes.Fetcher
+}
+
+// Fetch implements remotes.Fetcher.
+func (f *verifyingFetcher) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	if err := desc.Digest.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "%s", desc.Digest)
+	}
+	rc, err := f.Fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	return &verifyingReader{
+		ReadCloser: rc,
+		desc:       desc,
+		verifier:   desc.Digest.Verifier(),
+	}, nil
+}
+
+// verifyingReader fails as soon as the content exceeds the size of the
+// descriptor, and at the end of the content if the digest does not match.
+type verifyingReader struct {
+	io.ReadCloser
+	desc     ocispec.Descriptor
+	verifier digest.Verifier
+	offset   int64
+}
+
+// Read implements io.Reader.
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.offset += int64(n)
+	if r.offset > r.desc.Size {
+		return 0, errors.Wrapf(ErrContentMismatch, "%s: size exceeds %d", r.desc.Digest, r.desc.Size)
+	}
+	r.verifier.Write(p[:n])
+	if err == io.EOF {
+		if r.offset != r.desc.Size {
+			return n, errors.Wrapf(ErrContentMismatch, "%s: size %d, expected %d", r.desc.Digest, r.offset, r.desc.Size)
+		}
+		if !r.verifier.Verified() {
+			return n, errors.Wrapf(ErrContentMismatch, "%s: digest mismatch", r.desc.Digest)
+		}
+	}
+	return n, err
+}
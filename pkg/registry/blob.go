@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// BlobStatus describes a blob existing in a repository.
+type BlobStatus struct {
+	Digest digest.Digest
+	Size   int64
+	// AcceptRanges is the range unit accepted on fetching the blob, or empty
+	// if range requests are not supported.
+	AcceptRanges string
+}
+
+// StatBlob checks the existence of the blob identified by the digest in the
+// repository of ref without fetching its content. An errdefs.ErrNotFound error
+// is returned if the blob does not exist.
+func (c *Client) StatBlob(ctx context.Context, ref string, dgst digest.Digest) (BlobStatus, error) {
+	repo, err := parseRepository(ref)
+	if err != nil {
+		return BlobStatus{}, err
+	}
+
+	resp, err := c.do(ctx, &request{
+		method: http.MethodHead,
+		host:   repo.host,
+		path:   "/" + repo.name + "/blobs/" + dgst.String(),
+	}, repo.scope("pull"))
+	if err != nil {
+		return BlobStatus{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return BlobStatus{}, responseError(resp)
+	}
+	if resp.ContentLength < 0 {
+		return BlobStatus{}, errors.Errorf("%s %s: missing content length", resp.Request.Method, resp.Request.URL)
+	}
+	if actual := resp.Header.Get("Docker-Content-Digest"); actual != "" && actual != dgst.String() {
+		return BlobStatus{}, errors.Errorf("%s %s: unexpected digest %s", resp.Request.Method, resp.Request.URL, actual)
+	}
+
+	status := BlobStatus{
+		Digest: dgst,
+		Size:   resp.ContentLength,
+	}
+	if ranges := resp.Header.Get("Accept-Ranges"); ranges != "none" {
+		status.AcceptRanges = ranges
+	}
+	return status, nil
+}
+
+// DeleteBlob deletes the blob identified by the digest from the repository of
+// ref.
+func (c *Client) DeleteBlob(ctx context.Context, ref string, dgst digest.Digest) error {
+	repo, err := parseRepository(ref)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, &request{
+		method: http.MethodDelete,
+		host:   repo.host,
+		path:   "/" + repo.name + "/blobs/" + dgst.String(),
+	}, repo.scope("delete"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	return nil
+}
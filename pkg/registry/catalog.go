@@ -0,0 +1,257 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Public API endpoints of the registry providers.
+const (
+	dockerHubAPI = "https://hub.docker.com"
+	gitHubAPI    = "https://api.github.com"
+)
+
+// Catalog lists the repositories of the registries without the catalog API,
+// i.e. Docker Hub and GHCR, with the REST APIs of their providers. The
+// registry credentials are used to authenticate to the APIs.
+type Catalog struct {
+	client     *http.Client
+	credential func(string) (string, string, error)
+
+	dockerHubAPI string
+	gitHubAPI    string
+}
+
+// NewCatalog creates a catalog sending the requests with the client, which
+// defaults to http.DefaultClient, and authenticating with the credentials of
+// the registries if any.
+func NewCatalog(client *http.Client, credential func(host string) (string, string, error)) *Catalog {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Catalog{
+		client:       client,
+		credential:   credential,
+		dockerHubAPI: dockerHubAPI,
+		gitHubAPI:    gitHubAPI,
+	}
+}
+
+// Supports reports whether the repositories of the registry host are listed
+// by the catalog.
+func (c *Catalog) Supports(host string) bool {
+	return isDockerHub(host) || host == "ghcr.io"
+}
+
+// Repositories lists the repositories under the namespace of the registry
+// host, which is the Docker Hub user or organization, or the GitHub user or
+// organization owning the packages, optionally followed by a path. The
+// repositories are sorted and include the namespace.
+func (c *Catalog) Repositories(ctx context.Context, host, namespace string) ([]string, error) {
+	namespace = strings.Trim(namespace, "/")
+	if namespace == "" {
+		return nil, errors.Errorf("listing the repositories of %s requires a namespace, e.g. %s/<organization>", host, host)
+	}
+	owner := namespace
+	if i := strings.Index(namespace, "/"); i >= 0 {
+		owner = namespace[:i]
+	}
+	var (
+		names []string
+		err   error
+	)
+	switch {
+	case isDockerHub(host):
+		names, err = c.dockerHubRepositories(ctx, host, owner)
+	case host == "ghcr.io":
+		names, err = c.gitHubRepositories(ctx, host, owner)
+	default:
+		return nil, errors.Errorf("%s has no catalog provider", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []string
+	for _, name := range names {
+		repo := owner + "/" + name
+		if repo == namespace || strings.HasPrefix(repo, namespace+"/") {
+			repos = append(repos, repo)
+		}
+	}
+	sort.Strings(repos)
+	return repos, nil
+}
+
+// dockerHubRepositories lists the names of the repositories of the Docker
+// Hub namespace.
+func (c *Catalog) dockerHubRepositories(ctx context.Context, host, owner string) ([]string, error) {
+	header := make(http.Header)
+	username, secret, err := c.credentials(host)
+	if err != nil {
+		return nil, err
+	}
+	if username != "" && secret != "" {
+		token, err := c.dockerHubLogin(ctx, username, secret)
+		if err != nil {
+			return nil, err
+		}
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	var names []string
+	next := c.dockerHubAPI + "/v2/repositories/" + url.PathEscape(owner) + "/?page_size=100"
+	for next != "" {
+		var page struct {
+			Next    string `json:"next"`
+			Results []struct {
+				Name string `json:"name"`
+			} `json:"results"`
+		}
+		if _, err := c.getJSON(ctx, next, header, &page); err != nil {
+			return nil, err
+		}
+		for _, result := range page.Results {
+			names = append(names, result.Name)
+		}
+		next = page.Next
+	}
+	return names, nil
+}
+
+// dockerHubLogin exchanges the credentials for a Docker Hub API token.
+func (c *Catalog) dockerHubLogin(ctx context.Context, username, secret string) (string, error) {
+	body, err := json.Marshal(map[string]string{
+		"username": username,
+		"password": secret,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.dockerHubAPI+"/v2/users/login", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to log in to Docker Hub")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError(resp)
+	}
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", errors.Wrap(err, "failed to log in to Docker Hub")
+	}
+	return result.Token, nil
+}
+
+// gitHubRepositories lists the names of the container packages of the GitHub
+// organization or user, which requires a token with the read:packages scope
+// as the password.
+func (c *Catalog) gitHubRepositories(ctx context.Context, host, owner string) ([]string, error) {
+	header := make(http.Header)
+	header.Set("Accept", "application/vnd.github+json")
+	_, secret, err := c.credentials(host)
+	if err != nil {
+		return nil, err
+	}
+	if secret != "" {
+		header.Set("Authorization", "Bearer "+secret)
+	}
+
+	var names []string
+	next := c.gitHubAPI + "/orgs/" + url.PathEscape(owner) + "/packages?package_type=container&per_page=100"
+	for first := true; next != ""; first = false {
+		var page []struct {
+			Name string `json:"name"`
+		}
+		resp, err := c.getJSON(ctx, next, header, &page)
+		if err != nil {
+			if first && resp != nil && resp.StatusCode == http.StatusNotFound {
+				// not an organization
+				next = c.gitHubAPI + "/users/" + url.PathEscape(owner) + "/packages?package_type=container&per_page=100"
+				continue
+			}
+			return nil, err
+		}
+		for _, pkg := range page {
+			names = append(names, pkg.Name)
+		}
+		if next, err = nextLink(resp); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// credentials returns the credentials of the registry host, if any.
+func (c *Catalog) credentials(host string) (string, string, error) {
+	if c.credential == nil {
+		return "", "", nil
+	}
+	username, secret, err := c.credential(host)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to get the credentials of %s", host)
+	}
+	return username, secret, nil
+}
+
+// getJSON decodes the JSON response of the URL into v. The response is
+// returned with its body closed, along with the errors of unexpected status.
+func (c *Catalog) getJSON(ctx context.Context, u string, header http.Header, v interface{}) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to do request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp, responseError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return resp, errors.Wrapf(err, "%s %s", req.Method, u)
+	}
+	return resp, nil
+}
+
+// nextLink returns the absolute URL of the next page indicated by the Link
+// header, or empty for the last page.
+func nextLink(resp *http.Response) (string, error) {
+	for _, link := range strings.Split(resp.Header.Get("Link"), ",") {
+		start, end := strings.Index(link, "<"), strings.Index(link, ">")
+		if start < 0 || end < start || !strings.Contains(link[end:], `rel="next"`) {
+			continue
+		}
+		u, err := resp.Request.URL.Parse(link[start+1 : end])
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+	return "", nil
+}
+
+// isDockerHub reports whether the host is the registry of Docker Hub.
+func isDockerHub(host string) bool {
+	switch host {
+	case "docker.io", "index.docker.io", "registry-1.docker.io":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCatalogRepositories(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/users/login":
+			w.Write([]byte(`{"token":"hub-token"}`))
+		case "/v2/repositories/acme/":
+			if r.Header.Get("Authorization") != "Bearer hub-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("page") == "" {
+				fmt.Fprintf(w, `{"next":"%s/v2/repositories/acme/?page=2","results":[{"name":"web"}]}`, server.URL)
+				return
+			}
+			w.Write([]byte(`{"next":null,"results":[{"name":"api"}]}`))
+		case "/orgs/octocat/packages":
+			w.WriteHeader(http.StatusNotFound)
+		case "/users/octocat/packages":
+			if r.Header.Get("Authorization") != "Bearer gh-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", fmt.Sprintf(`<%s/users/octocat/packages?package_type=container&page=2>; rel="next", <%s/users/octocat/packages?page=2>; rel="last"`, server.URL, server.URL))
+				json.NewEncoder(w).Encode([]map[string]string{{"name": "hello"}, {"name": "tools/lint"}})
+				return
+			}
+			json.NewEncoder(w).Encode([]map[string]string{{"name": "tools/fmt"}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	catalog := NewCatalog(server.Client(), func(host string) (string, string, error) {
+		switch host {
+		case "docker.io":
+			return "user", "secret", nil
+		case "ghcr.io":
+			return "octocat", "gh-token", nil
+		}
+		return "", "", nil
+	})
+	catalog.dockerHubAPI = server.URL
+	catalog.gitHubAPI = server.URL
+
+	for _, tt := range []struct {
+		host      string
+		namespace string
+		want      []string
+	}{
+		{"docker.io", "acme", []string{"acme/api", "acme/web"}},
+		{"ghcr.io", "octocat", []string{"octocat/hello", "octocat/tools/fmt", "octocat/tools/lint"}},
+		{"ghcr.io", "octocat/tools", []string{"octocat/tools/fmt", "octocat/tools/lint"}},
+	} {
+		if !catalog.Supports(tt.host) {
+			t.Fatalf("Supports(%q) = false", tt.host)
+		}
+		repos, err := catalog.Repositories(context.Background(), tt.host, tt.namespace)
+		if err != nil {
+			t.Fatalf("Repositories(%q, %q) error = %v", tt.host, tt.namespace, err)
+		}
+		if !reflect.DeepEqual(repos, tt.want) {
+			t.Errorf("Repositories(%q, %q) = %v, want %v", tt.host, tt.namespace, repos, tt.want)
+		}
+	}
+
+	if _, err := catalog.Repositories(context.Background(), "ghcr.io", ""); err == nil {
+		t.Error("Repositories() without namespace error = nil")
+	}
+	if catalog.Supports("localhost:5000") {
+		t.Error("Supports(localhost:5000) = true")
+	}
+}
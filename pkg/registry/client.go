@@ -0,0 +1,184 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/log"
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/pkg/errors"
+)
+
+// Client provides access to the registry APIs of the OCI distribution
+// specification, which are not covered by remotes.Resolver.
+type Client struct {
+	hosts docker.RegistryHosts
+}
+
+// NewClient creates a new registry client with the given host configurations.
+// The same host configurations should be used to create the resolver so that
+// authorizations are shared.
+func NewClient(hosts docker.RegistryHosts) *Client {
+	return &Client{
+		hosts: hosts,
+	}
+}
+
+// repository identifies a repository in a registry.
+type repository struct {
+	host string
+	name string
+}
+
+// parseRepository parses the repository part of the reference.
+func parseRepository(ref string) (repository, error) {
+	refspec, err := reference.Parse(ref)
+	if err != nil {
+		return repository{}, err
+	}
+	host := refspec.Hostname()
+	return repository{
+		host: host,
+		name: strings.TrimPrefix(refspec.Locator, host+"/"),
+	}, nil
+}
+
+// scope returns the auth scope of the repository with the given actions.
+func (r repository) scope(actions ...string) string {
+	return fmt.Sprintf("repository:%s:%s", r.name, strings.Join(actions, ","))
+}
+
+// request describes a request to the registry.
+type request struct {
+	method string
+	host   string
+	path   string
+	header http.Header
+	body   func() (io.ReadCloser, error)
+	size   int64
+}
+
+// do sends the request to the registry hosts configured, which are capable
+// of the operation, authorizing and retrying as needed. As with the resolver,
+// the next host is tried if a host, e.g. a mirror, is unreachable, misses the
+// content or fails.
+func (c *Client) do(ctx context.Context, r *request, scope string) (*http.Response, error) {
+	hosts, err := c.hosts(r.host)
+	if err != nil {
+		return nil, err
+	}
+	hosts = capableHosts(hosts, r.method)
+	if len(hosts) == 0 {
+		return nil, errors.Wrapf(errdefs.ErrNotFound, "no hosts for %s", r.host)
+	}
+	if scope != "" {
+		ctx = docker.WithScope(ctx, scope)
+	}
+
+	last := len(hosts) - 1
+	for _, host := range hosts[:last] {
+		resp, err := c.doHost(ctx, host, r)
+		if err == nil {
+			if resp.StatusCode != http.StatusNotFound && resp.StatusCode < http.StatusInternalServerError {
+				return resp, nil
+			}
+			resp.Body.Close()
+			err = errors.New(resp.Status)
+		}
+		log.G(ctx).WithError(err).WithField("host", host.Host).Debug("trying next host")
+	}
+	return c.doHost(ctx, hosts[last], r)
+}
+
+// doHost sends the request to the host, authorizing as needed.
+func (c *Client) doHost(ctx context.Context, host docker.RegistryHost, r *request) (*http.Response, error) {
+	var responses []*http.Response
+	for {
+		resp, err := c.send(ctx, host, r)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || host.Authorizer == nil || len(responses) >= 5 {
+			return resp, nil
+		}
+
+		responses = append(responses, resp)
+		log.G(ctx).WithField("header", resp.Header.Get("WWW-Authenticate")).Debug("Unauthorized")
+		if err := host.Authorizer.AddResponses(ctx, responses); err != nil {
+			if errdefs.IsNotImplemented(err) {
+				return resp, nil
+			}
+			resp.Body.Close()
+			return nil, err
+		}
+		resp.Body.Close()
+	}
+}
+
+// capableHosts returns the hosts capable of pulling for reads, or of pushing
+// otherwise, so that mirrors are not written to.
+func capableHosts(hosts []docker.RegistryHost, method string) []docker.RegistryHost {
+	capability := docker.HostCapabilityPush
+	if method == http.MethodGet || method == http.MethodHead {
+		capability = docker.HostCapabilityPull
+	}
+	var capable []docker.RegistryHost
+	for _, host := range hosts {
+		if host.Capabilities.Has(capability) {
+			capable = append(capable, host)
+		}
+	}
+	return capable
+}
+
+func (c *Client) send(ctx context.Context, host docker.RegistryHost, r *request) (*http.Response, error) {
+	u := host.Scheme + "://" + host.Host + host.Path + r.path
+	req, err := http.NewRequest(r.method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = make(http.Header)
+	for k, v := range host.Header {
+		req.Header[k] = v
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	if r.body != nil {
+		body, err := r.body()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+		req.GetBody = r.body
+		req.ContentLength = r.size
+	}
+
+	log.G(ctx).WithField("url", u).Debugf("%s request", r.method)
+	if host.Authorizer != nil {
+		if err := host.Authorizer.Authorize(ctx, req); err != nil {
+			return nil, errors.Wrap(err, "failed to authorize")
+		}
+	}
+	resp, err := host.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to do request")
+	}
+	return resp, nil
+}
+
+// responseError converts an unexpected response to an error.
+func responseError(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return errors.Wrapf(errdefs.ErrNotFound, "%s %s", resp.Request.Method, resp.Request.URL)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errors.Wrapf(ErrUnauthorized, "%s %s: %s", resp.Request.Method, resp.Request.URL, resp.Status)
+	}
+	return errors.Errorf("%s %s: unexpected status: %s", resp.Request.Method, resp.Request.URL, resp.Status)
+}
@@ -0,0 +1,351 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/docker/distribution/configuration"
+	"github.com/docker/distribution/registry"
+	_ "github.com/docker/distribution/registry/storage/driver/inmemory"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/phayes/freeport"
+	"github.com/stretchr/testify/suite"
+)
+
+type RegistryClientTestSuite struct {
+	suite.Suite
+	DockerRegistryHost string
+	Client             *Client
+}
+
+func newContext() context.Context {
+	return context.Background()
+}
+
+func newHosts() docker.RegistryHosts {
+	return docker.ConfigureDefaultRegistries(docker.WithPlainHTTP(docker.MatchLocalhost))
+}
+
+// Start Docker registry
+func (suite *RegistryClientTestSuite) SetupSuite() {
+	config := &configuration.Configuration{}
+	port, err := freeport.GetFreePort()
+	suite.Nil(err, "no error finding free port for test registry")
+	suite.DockerRegistryHost = fmt.Sprintf("localhost:%d", port)
+	config.HTTP.Addr = fmt.Sprintf(":%d", port)
+	config.HTTP.DrainTimeout = time.Duration(10) * time.Second
+	config.Storage = map[string]configuration.Parameters{
+		"inmemory": map[string]interface{}{},
+		"delete":   map[string]interface{}{"enabled": true},
+	}
+	dockerRegistry, err := registry.NewRegistry(context.Background(), config)
+	suite.Nil(err, "no error creating test registry")
+
+	go dockerRegistry.ListenAndServe()
+
+	// Wait for the registry to accept connections
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("tcp", suite.DockerRegistryHost); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	suite.Client = NewClient(newHosts())
+}
+
+// pushManifest pushes an empty manifest with the given annotations.
+func (suite *RegistryClientTestSuite) pushManifest(ref string, annotations string) ocispec.Descriptor {
+	ctx := newContext()
+	resolver := docker.NewResolver(docker.ResolverOptions{
+		Hosts: newHosts(),
+	})
+	pusher, err := resolver.Pusher(ctx, ref)
+	suite.Nil(err, "no error getting pusher")
+
+	config := []byte("{}")
+	configDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(config),
+		Size:      int64(len(config)),
+	}
+	manifest := []byte(fmt.Sprintf(`{"schemaVersion":2,"config":{"mediaType":"%s","digest":"%s","size":%d},"layers":[],"annotations":%s}`,
+		configDesc.MediaType, configDesc.Digest, configDesc.Size, annotations))
+	manifestDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(manifest),
+		Size:      int64(len(manifest)),
+	}
+	for _, blob := range []struct {
+		desc    ocispec.Descriptor
+		content []byte
+	}{
+		{configDesc, config},
+		{manifestDesc, manifest},
+	} {
+		writer, err := pusher.Push(ctx, blob.desc)
+		if err != nil {
+			continue // already exists
+		}
+		err = content.Copy(ctx, writer, bytes.NewReader(blob.content), blob.desc.Size, blob.desc.Digest)
+		suite.Nil(err, "no error pushing content")
+	}
+	return manifestDesc
+}
+
+func (suite *RegistryClientTestSuite) Test_0_Tags() {
+	repo := fmt.Sprintf("%s/tags", suite.DockerRegistryHost)
+	_, err := suite.Client.Tags(newContext(), repo)
+	suite.NotNil(err, "error listing tags of non-existing repository")
+
+	suite.pushManifest(repo+":v1", `{}`)
+	suite.pushManifest(repo+":v2", `{}`)
+	tags, err := suite.Client.Tags(newContext(), repo)
+	suite.Nil(err, "no error listing tags")
+	suite.ElementsMatch([]string{"v1", "v2"}, tags, "tags match")
+}
+
+func (suite *RegistryClientTestSuite) Test_1_Referrers() {
+	repo := fmt.Sprintf("%s/referrers", suite.DockerRegistryHost)
+	desc := suite.pushManifest(repo+":v1", `{}`)
+	referrers, err := suite.Client.Referrers(newContext(), repo, desc)
+	suite.Nil(err, "no error listing referrers on registry without referrers API")
+	suite.Empty(referrers, "no referrers")
+}
+
+func (suite *RegistryClientTestSuite) Test_2_CheckPush() {
+	repo := fmt.Sprintf("%s/check-push", suite.DockerRegistryHost)
+	err := suite.Client.CheckPush(newContext(), repo+":v1", repo+":v2")
+	suite.Nil(err, "no error checking push permission")
+
+	err = suite.Client.CheckPush(newContext(), "localhost:1/unreachable:v1")
+	suite.IsType(&PreflightError{}, err, "preflight error on unreachable registry")
+}
+
+func (suite *RegistryClientTestSuite) Test_3_DeleteManifest() {
+	repo := fmt.Sprintf("%s/delete", suite.DockerRegistryHost)
+	desc := suite.pushManifest(repo+":v1", `{}`)
+	err := suite.Client.DeleteManifest(newContext(), repo+":v1", desc.Digest)
+	suite.Nil(err, "no error deleting manifest")
+
+	err = suite.Client.DeleteManifest(newContext(), repo+":v1", desc.Digest)
+	suite.NotNil(err, "error deleting non-existing manifest")
+}
+
+func (suite *RegistryClientTestSuite) Test_4_StatBlob() {
+	repo := fmt.Sprintf("%s/stat", suite.DockerRegistryHost)
+	suite.pushManifest(repo+":v1", `{}`)
+	config := []byte("{}")
+	status, err := suite.Client.StatBlob(newContext(), repo, digest.FromBytes(config))
+	suite.Nil(err, "no error checking existing blob")
+	suite.Equal(int64(len(config)), status.Size, "blob size matches")
+
+	_, err = suite.Client.StatBlob(newContext(), repo, digest.FromString("missing"))
+	suite.True(errdefs.IsNotFound(err), "not found error on missing blob")
+}
+
+func (suite *RegistryClientTestSuite) Test_5_DeleteBlob() {
+	repo := fmt.Sprintf("%s/delete-blob", suite.DockerRegistryHost)
+	suite.pushManifest(repo+":v1", `{}`)
+	dgst := digest.FromBytes([]byte("{}"))
+	err := suite.Client.DeleteBlob(newContext(), repo, dgst)
+	suite.Nil(err, "no error deleting blob")
+
+	_, err = suite.Client.StatBlob(newContext(), repo, dgst)
+	suite.True(errdefs.IsNotFound(err), "blob deleted")
+}
+
+func (suite *RegistryClientTestSuite) Test_6_ChunkedUpload() {
+	repo := fmt.Sprintf("%s/chunked", suite.DockerRegistryHost)
+	stateDir, err := ioutil.TempDir("", "oras_upload_test")
+	suite.Nil(err, "no error creating state directory")
+	defer os.RemoveAll(stateDir)
+
+	blob := bytes.Repeat([]byte("chunked"), 100)
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    digest.FromBytes(blob),
+		Size:      int64(len(blob)),
+	}
+	uploader := NewChunkedUploader(suite.Client, 64, stateDir)
+
+	// interrupted upload
+	writer, err := uploader.Writer(newContext(), repo, desc)
+	suite.Nil(err, "no error creating writer")
+	_, err = writer.Write(blob[:300])
+	suite.Nil(err, "no error writing chunks")
+	suite.Nil(writer.Close(), "no error closing writer")
+
+	// resumed upload
+	writer, err = uploader.Writer(newContext(), repo, desc)
+	suite.Nil(err, "no error resuming upload")
+	status, err := writer.Status()
+	suite.Nil(err, "no error getting status")
+	suite.Equal(int64(256), status.Offset, "upload resumed at the last chunk")
+	_, err = writer.Write(blob[status.Offset:])
+	suite.Nil(err, "no error writing the rest")
+	suite.Nil(writer.Commit(newContext(), desc.Size, desc.Digest), "no error committing upload")
+
+	info, err := suite.Client.StatBlob(newContext(), repo, desc.Digest)
+	suite.Nil(err, "no error checking uploaded blob")
+	suite.Equal(desc.Size, info.Size, "blob size matches")
+	files, err := ioutil.ReadDir(stateDir)
+	suite.Nil(err, "no error reading state directory")
+	suite.Empty(files, "upload session removed")
+
+	_, err = uploader.Writer(newContext(), repo, desc)
+	suite.True(errdefs.IsAlreadyExists(err), "already exists error on existing blob")
+}
+
+func (suite *RegistryClientTestSuite) Test_7_Repositories() {
+	host := suite.DockerRegistryHost
+	suite.pushManifest(host+"/catalog/a:v1", `{}`)
+	suite.pushManifest(host+"/catalog/b:v1", `{}`)
+	repos, err := suite.Client.Repositories(newContext(), host, "")
+	suite.Nil(err, "no error listing repositories")
+	suite.Subset(repos, []string{"catalog/a", "catalog/b"}, "repositories listed")
+
+	repos, err = suite.Client.Repositories(newContext(), host, "catalog/a")
+	suite.Nil(err, "no error listing repositories after the last one")
+	suite.Contains(repos, "catalog/b", "repositories after the last one listed")
+	suite.NotContains(repos, "catalog/a", "last repository not listed")
+
+	tags, err := suite.Client.TagsAfter(newContext(), host+"/tags", "v1")
+	suite.Nil(err, "no error listing tags after the last one")
+	suite.Equal([]string{"v2"}, tags, "tags after the last one match")
+
+	suite.True(IsDigestTag(ReferrersTag(digest.FromString("foo"))), "referrers tag is a digest tag")
+	suite.True(IsDigestTag("sha256-"+digest.FromString("foo").Encoded()+".sig"), "digest tag with suffix")
+	suite.False(IsDigestTag("v1-abc"), "version tag is not a digest tag")
+}
+
+func (suite *RegistryClientTestSuite) Test_8_Mirrors() {
+	// the registry mirrors an unreachable upstream for pulling only
+	hosts := func(host string) ([]docker.RegistryHost, error) {
+		defaults, err := newHosts()(host)
+		if err != nil {
+			return nil, err
+		}
+		mirror, upstream := defaults[0], defaults[0]
+		mirror.Capabilities = docker.HostCapabilityPull | docker.HostCapabilityResolve
+		upstream.Host = "localhost:1"
+		return []docker.RegistryHost{mirror, upstream}, nil
+	}
+	client := NewClient(hosts)
+	ref := suite.DockerRegistryHost + "/mirrored:v1"
+	suite.pushManifest(ref, `{}`)
+
+	tags, err := client.Tags(newContext(), ref)
+	suite.Nil(err, "no error listing tags from the mirror")
+	suite.Equal([]string{"v1"}, tags, "tags match")
+	err = client.CheckPush(newContext(), ref)
+	suite.NotNil(err, "pushes go to the upstream")
+
+	// the upstream is used if the mirror is unreachable or misses the content
+	for _, mirrorHost := range []string{"localhost:1", ""} {
+		mirrorHost := mirrorHost
+		client := NewClient(func(host string) ([]docker.RegistryHost, error) {
+			defaults, err := newHosts()(host)
+			if err != nil {
+				return nil, err
+			}
+			mirror := defaults[0]
+			mirror.Capabilities = docker.HostCapabilityPull | docker.HostCapabilityResolve
+			if mirrorHost != "" {
+				mirror.Host = mirrorHost
+			} else {
+				mirror.Path = "/missing/v2"
+			}
+			return []docker.RegistryHost{mirror, defaults[0]}, nil
+		})
+		tags, err := client.Tags(newContext(), ref)
+		suite.Nil(err, "no error listing tags from the upstream")
+		suite.Equal([]string{"v1"}, tags, "tags match")
+	}
+}
+
+func (suite *RegistryClientTestSuite) Test_9_Sessions() {
+	repo := fmt.Sprintf("%s/sessions", suite.DockerRegistryHost)
+	blob := bytes.Repeat([]byte("session"), 100)
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    digest.FromBytes(blob),
+		Size:      int64(len(blob)),
+	}
+
+	// upload interrupted and restored by another uploader
+	var state bytes.Buffer
+	session, err := NewChunkedUploader(suite.Client, 64, "").NewUploadSession(repo, desc)
+	suite.Nil(err, "no error creating upload session")
+	writer, err := session.Writer(newContext())
+	suite.Nil(err, "no error starting upload")
+	_, err = writer.Write(blob[:200])
+	suite.Nil(err, "no error writing chunks")
+	suite.Nil(session.Save(&state), "no error saving upload session")
+
+	session, err = NewChunkedUploader(suite.Client, 64, "").NewUploadSession(repo, desc)
+	suite.Nil(err, "no error creating upload session")
+	suite.Nil(session.Restore(newContext(), &state), "no error restoring upload session")
+	suite.Equal(int64(192), session.Offset(), "upload restored at the last chunk")
+	writer, err = session.Writer(newContext())
+	suite.Nil(err, "no error resuming upload")
+	_, err = writer.Write(blob[session.Offset():])
+	suite.Nil(err, "no error writing the rest")
+	suite.Nil(writer.Commit(newContext(), desc.Size, desc.Digest), "no error committing upload")
+
+	// download interrupted and restored by another session
+	state.Reset()
+	download, err := suite.Client.NewDownloadSession(repo, desc)
+	suite.Nil(err, "no error creating download session")
+	reader, err := download.Reader(newContext())
+	suite.Nil(err, "no error starting download")
+	head := make([]byte, 300)
+	_, err = io.ReadFull(reader, head)
+	suite.Nil(err, "no error reading content")
+	reader.Close()
+	suite.Nil(download.Save(&state), "no error saving download session")
+
+	download, err = suite.Client.NewDownloadSession(repo, desc)
+	suite.Nil(err, "no error creating download session")
+	suite.Nil(download.Restore(&state), "no error restoring download session")
+	suite.Equal(int64(300), download.Offset(), "download restored at the offset")
+	reader, err = download.Reader(newContext())
+	suite.Nil(err, "no error resuming download")
+	tail, err := ioutil.ReadAll(reader)
+	suite.Nil(err, "no error reading verified content")
+	reader.Close()
+	suite.Equal(blob, append(head, tail...), "content matches")
+}
+
+func (suite *RegistryClientTestSuite) Test_10_ResolveManifest() {
+	repo := fmt.Sprintf("%s/resolve", suite.DockerRegistryHost)
+	desc := suite.pushManifest(repo+":v1", `{"version":"1"}`)
+	mediaTypes := []string{ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageIndex}
+	for _, ref := range []string{repo + ":v1", repo + "@" + desc.Digest.String(), repo + ":v1@" + desc.Digest.String()} {
+		resolved, content, err := suite.Client.ResolveManifest(newContext(), ref, mediaTypes)
+		suite.Nil(err, "no error resolving %s", ref)
+		suite.Equal(desc, resolved, "descriptor of %s", ref)
+		suite.Equal(desc.Digest, digest.FromBytes(content), "content of %s", ref)
+	}
+
+	_, _, err := suite.Client.ResolveManifest(newContext(), repo+":missing", mediaTypes)
+	suite.True(errdefs.IsNotFound(err), "error resolving missing tag")
+	_, _, err = suite.Client.ResolveManifest(newContext(), repo, mediaTypes)
+	suite.NotNil(err, "error resolving reference without object")
+}
+
+func TestRegistryClientTestSuite(t *testing.T) {
+	suite.Run(t, new(RegistryClientTestSuite))
+}
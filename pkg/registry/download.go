@@ -0,0 +1,198 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// DownloadSession is a download of a blob, which continues from the content
+// already downloaded with range requests. Its state, including the partial
+// digest of the content, can be saved with Save, and restored with Restore by
+// another process, so that long running downloads survive restarts. Storing
+// the downloaded content is up to the caller.
+type DownloadSession struct {
+	client *Client
+	repo   repository
+	desc   ocispec.Descriptor
+	lock   sync.Mutex
+	offset int64
+	hash   hash.Hash
+}
+
+// downloadState is the saved state of a download session.
+type downloadState struct {
+	Repository string        `json:"repository"`
+	Digest     digest.Digest `json:"digest"`
+	Size       int64         `json:"size"`
+	Offset     int64         `json:"offset"`
+	Hash       []byte        `json:"hash"`
+}
+
+// NewDownloadSession creates a session downloading the blob described by desc
+// from the repository of ref.
+func (c *Client) NewDownloadSession(ref string, desc ocispec.Descriptor) (*DownloadSession, error) {
+	repo, err := parseRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := desc.Digest.Validate(); err != nil {
+		return nil, err
+	}
+	return &DownloadSession{
+		client: c,
+		repo:   repo,
+		desc:   desc,
+		hash:   desc.Digest.Algorithm().Hash(),
+	}, nil
+}
+
+// Offset returns the size of the content downloaded. The reader of the session
+// returns the content from the offset.
+func (s *DownloadSession) Offset() int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.offset
+}
+
+// Save writes the state of the session. The state should be saved only once
+// the content read so far is stored, since the download continues from its
+// offset after Restore.
+func (s *DownloadSession) Save(w io.Writer) error {
+	s.lock.Lock()
+	state := downloadState{
+		Repository: s.repo.host + "/" + s.repo.name,
+		Digest:     s.desc.Digest,
+		Size:       s.desc.Size,
+		Offset:     s.offset,
+	}
+	marshaler, ok := s.hash.(encoding.BinaryMarshaler)
+	if !ok {
+		s.lock.Unlock()
+		return errors.Errorf("%s: digest state cannot be saved", s.desc.Digest.Algorithm())
+	}
+	hashState, err := marshaler.MarshalBinary()
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	state.Hash = hashState
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Restore reads the state written by Save. An error is returned if the state
+// is of another blob.
+func (s *DownloadSession) Restore(r io.Reader) error {
+	var state downloadState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return err
+	}
+	if state.Repository != s.repo.host+"/"+s.repo.name || state.Digest != s.desc.Digest || state.Size != s.desc.Size {
+		return errors.New("download session mismatch")
+	}
+	if state.Offset < 0 || state.Offset > s.desc.Size {
+		return errors.Errorf("invalid download offset %d", state.Offset)
+	}
+	h := s.desc.Digest.Algorithm().Hash()
+	unmarshaler, ok := h.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return errors.Errorf("%s: digest state cannot be restored", s.desc.Digest.Algorithm())
+	}
+	if err := unmarshaler.UnmarshalBinary(state.Hash); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.offset = state.Offset
+	s.hash = h
+	return nil
+}
+
+// Reader returns a reader of the content from the offset of the session. The
+// reader fails as soon as the content exceeds the size of the blob, and at the
+// end of the content if the digest does not match.
+func (s *DownloadSession) Reader(ctx context.Context) (io.ReadCloser, error) {
+	offset := s.Offset()
+	r := &request{
+		method: http.MethodGet,
+		host:   s.repo.host,
+		path:   "/" + s.repo.name + "/blobs/" + s.desc.Digest.String(),
+	}
+	if offset > 0 {
+		if offset == s.desc.Size {
+			return &downloadReader{
+				ReadCloser: ioutil.NopCloser(bytes.NewReader(nil)),
+				session:    s,
+			}, nil
+		}
+		r.header = http.Header{
+			"Range": []string{fmt.Sprintf("bytes=%d-", offset)},
+		}
+	}
+	resp, err := s.client.do(ctx, r, s.repo.scope("pull"))
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// the range is not supported, skip the content already downloaded
+		if _, err := io.CopyN(ioutil.Discard, resp.Body, offset); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	default:
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return &downloadReader{
+		ReadCloser: resp.Body,
+		session:    s,
+	}, nil
+}
+
+// downloadReader updates the offset and the digest of the session with the
+// content read.
+type downloadReader struct {
+	io.ReadCloser
+	session *DownloadSession
+}
+
+// Read implements io.Reader.
+func (r *downloadReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	s := r.session
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.offset+int64(n) > s.desc.Size {
+		return 0, errors.Wrapf(ErrContentMismatch, "%s: size exceeds %d", s.desc.Digest, s.desc.Size)
+	}
+	s.offset += int64(n)
+	s.hash.Write(p[:n])
+	if err == io.EOF {
+		if s.offset != s.desc.Size {
+			return n, errors.Wrapf(ErrContentMismatch, "%s: size %d, expected %d", s.desc.Digest, s.offset, s.desc.Size)
+		}
+		if digest.NewDigest(s.desc.Digest.Algorithm(), s.hash) != s.desc.Digest {
+			return n, errors.Wrapf(ErrContentMismatch, "%s: digest mismatch", s.desc.Digest)
+		}
+	}
+	return n, err
+}
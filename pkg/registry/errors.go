@@ -0,0 +1,13 @@
+package registry
+
+import "errors"
+
+// Common errors
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrCircuitOpen  = errors.New("circuit breaker open: too many consecutive failures")
+
+	// ErrContentMismatch is returned when the downloaded content does not
+	// match the size or the digest of the blob.
+	ErrContentMismatch = errors.New("content mismatch")
+)
@@ -0,0 +1,194 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/reference"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// DeleteManifest deletes the manifest identified by the digest from the
+// repository of ref. All tags referencing the manifest are removed as well.
+// Registries disallowing deletes result in an errdefs.ErrNotImplemented error.
+func (c *Client) DeleteManifest(ctx context.Context, ref string, dgst digest.Digest) error {
+	repo, err := parseRepository(ref)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, &request{
+		method: http.MethodDelete,
+		host:   repo.host,
+		path:   "/" + repo.name + "/manifests/" + dgst.String(),
+	}, repo.scope("delete"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusAccepted, http.StatusOK:
+		return nil
+	case http.StatusMethodNotAllowed:
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return errors.Wrapf(errdefs.ErrNotImplemented, "%s %s: %s: %s", resp.Request.Method, resp.Request.URL, resp.Status, bytes.TrimSpace(body))
+	}
+	return responseError(resp)
+}
+
+// PushManifest pushes the manifest content described by desc to the
+// repository of ref. The manifest is tagged if ref has a tag. Manifests of
+// media types rejected by the registry result in an errdefs.ErrNotImplemented
+// error.
+func (c *Client) PushManifest(ctx context.Context, ref string, desc ocispec.Descriptor, content []byte) error {
+	repo, err := parseRepository(ref)
+	if err != nil {
+		return err
+	}
+	object := desc.Digest.String()
+	if refspec, err := reference.Parse(ref); err == nil && refspec.Object != "" && refspec.Digest() == "" {
+		object = refspec.Object
+	}
+
+	resp, err := c.do(ctx, &request{
+		method: http.MethodPut,
+		host:   repo.host,
+		path:   "/" + repo.name + "/manifests/" + object,
+		header: http.Header{
+			"Content-Type": []string{desc.MediaType},
+		},
+		body: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(content)), nil
+		},
+		size: desc.Size,
+	}, repo.scope("pull", "push"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusOK:
+		return nil
+	case http.StatusBadRequest, http.StatusUnsupportedMediaType:
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return errors.Wrapf(errdefs.ErrNotImplemented, "%s %s: %s: %s", resp.Request.Method, resp.Request.URL, resp.Status, bytes.TrimSpace(body))
+	}
+	return responseError(resp)
+}
+
+// FetchManifest fetches the manifest described by desc from the repository
+// of ref. Unlike remotes.Fetcher, manifests of media types unknown to
+// containerd, such as artifact manifests, are fetched from the manifests
+// endpoint as well.
+func (c *Client) FetchManifest(ctx context.Context, ref string, desc ocispec.Descriptor) ([]byte, error) {
+	repo, err := parseRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		host:   repo.host,
+		path:   "/" + repo.name + "/manifests/" + desc.Digest.String(),
+		header: http.Header{
+			"Accept": []string{desc.MediaType},
+		},
+	}, repo.scope("pull"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, desc.Size+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) != desc.Size || desc.Digest.Algorithm().FromBytes(content) != desc.Digest {
+		return nil, errors.Errorf("%s %s: content does not match the descriptor", resp.Request.Method, resp.Request.URL)
+	}
+	return content, nil
+}
+
+// maxManifestSize is the maximum size of the manifests resolved.
+const maxManifestSize = 4 * 1024 * 1024
+
+// ResolveManifest resolves ref, by tag or by digest, and fetches its manifest
+// in a single request, accepting the given media types. The descriptor of the
+// manifest is returned with its content.
+func (c *Client) ResolveManifest(ctx context.Context, ref string, mediaTypes []string) (ocispec.Descriptor, []byte, error) {
+	repo, err := parseRepository(ref)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	refspec, err := reference.Parse(ref)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	if refspec.Object == "" {
+		return ocispec.Descriptor{}, nil, reference.ErrObjectRequired
+	}
+	object := refspec.Object
+	if dgst := refspec.Digest(); dgst != "" {
+		object = dgst.String()
+	}
+
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		host:   repo.host,
+		path:   "/" + repo.name + "/manifests/" + object,
+		header: http.Header{
+			"Accept": []string{strings.Join(mediaTypes, ", ")},
+		},
+	}, repo.scope("pull"))
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ocispec.Descriptor{}, nil, responseError(resp)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	if len(content) > maxManifestSize {
+		return ocispec.Descriptor{}, nil, errors.Errorf("%s %s: manifest exceeds %d bytes", resp.Request.Method, resp.Request.URL, maxManifestSize)
+	}
+
+	dgst := refspec.Digest()
+	if dgst == "" {
+		dgst = digest.FromBytes(content)
+		if header, err := digest.Parse(resp.Header.Get("Docker-Content-Digest")); err == nil && header.Algorithm().Available() {
+			dgst = header
+		}
+	}
+	if dgst.Algorithm().FromBytes(content) != dgst {
+		return ocispec.Descriptor{}, nil, errors.Errorf("%s %s: content does not match the digest %s", resp.Request.Method, resp.Request.URL, dgst)
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType == "application/json" || mediaType == "text/plain" {
+		var manifest struct {
+			MediaType string `json:"mediaType"`
+		}
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return ocispec.Descriptor{}, nil, errors.Wrapf(err, "%s %s: invalid manifest", resp.Request.Method, resp.Request.URL)
+		}
+		mediaType = manifest.MediaType
+	}
+	return ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    dgst,
+		Size:      int64(len(content)),
+	}, content, nil
+}
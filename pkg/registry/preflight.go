@@ -0,0 +1,100 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PreflightError reports the repositories failing the preflight checks.
+type PreflightError struct {
+	// Errors maps the failed repositories to the errors.
+	Errors map[string]error
+}
+
+func (e *PreflightError) Error() string {
+	repos := make([]string, 0, len(e.Errors))
+	for repo := range e.Errors {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	messages := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		messages = append(messages, repo+": "+e.Errors[repo].Error())
+	}
+	return "preflight check failed: " + strings.Join(messages, "; ")
+}
+
+// CheckPush verifies the push permissions on the repositories of the given
+// references by starting and cancelling a blob upload, so that no content is
+// pushed. All repositories are checked in parallel and the failures are
+// reported together as a *PreflightError.
+func (c *Client) CheckPush(ctx context.Context, refs ...string) error {
+	repos := make(map[string]repository)
+	for _, ref := range refs {
+		repo, err := parseRepository(ref)
+		if err != nil {
+			return err
+		}
+		repos[repo.host+"/"+repo.name] = repo
+	}
+
+	var (
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+		errors = make(map[string]error)
+	)
+	for key, repo := range repos {
+		key, repo := key, repo
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.checkPush(ctx, repo); err != nil {
+				lock.Lock()
+				defer lock.Unlock()
+				errors[key] = err
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errors) > 0 {
+		return &PreflightError{
+			Errors: errors,
+		}
+	}
+	return nil
+}
+
+func (c *Client) checkPush(ctx context.Context, repo repository) error {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		host:   repo.host,
+		path:   "/" + repo.name + "/blobs/uploads/",
+	}, repo.scope("pull", "push"))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return responseError(resp)
+	}
+
+	// cancel the upload session
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return err
+	}
+	resp, err = c.do(ctx, &request{
+		method: http.MethodDelete,
+		host:   repo.host,
+		path:   strings.TrimPrefix(location.Path, "/v2") + queryString(location),
+	}, repo.scope("pull", "push"))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,111 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DefaultMaxRedirects is the maximum number of redirects followed per request
+// by default, as by net/http.
+const DefaultMaxRedirects = 10
+
+// RedirectPolicy limits the redirects followed by the HTTP clients of the
+// registries, such as the redirects of blob downloads to storage backends.
+type RedirectPolicy struct {
+	// MaxRedirects is the maximum number of redirects followed per request.
+	// DefaultMaxRedirects applies if zero, and no redirects are followed if
+	// negative.
+	MaxRedirects int
+	// AllowDowngrade allows redirects from https to http, which are rejected
+	// otherwise. Redirects to other schemes are always rejected.
+	AllowDowngrade bool
+	// AllowedHosts returns the glob patterns of the hosts the requests to the
+	// host may be redirected to, e.g. "*.s3.amazonaws.com". Redirects within
+	// the host are always allowed, and all hosts are allowed if nil is
+	// returned.
+	AllowedHosts func(host string) []string
+}
+
+// RedirectError is returned when a redirect is rejected by the policy.
+type RedirectError struct {
+	// Hop is the 1-based number of the rejected redirect.
+	Hop int
+	// From is the URL redirecting, and To the redirect target.
+	From *url.URL
+	To   *url.URL
+	// Reason is why the redirect is rejected.
+	Reason string
+}
+
+// Error implements error.
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("redirect %d from %s to %s rejected: %s", e.Hop, redactURL(e.From), redactURL(e.To), e.Reason)
+}
+
+// CheckRedirect checks the redirect of req after the requests via, and can be
+// used as the CheckRedirect function of an http.Client.
+func (p RedirectPolicy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	hop := len(via)
+	from := via[hop-1].URL
+	reject := func(format string, args ...interface{}) error {
+		return &RedirectError{
+			Hop:    hop,
+			From:   from,
+			To:     req.URL,
+			Reason: fmt.Sprintf(format, args...),
+		}
+	}
+
+	max := p.MaxRedirects
+	if max == 0 {
+		max = DefaultMaxRedirects
+	}
+	if max < 0 {
+		return reject("redirects are disabled")
+	}
+	if hop > max {
+		return reject("more than %d redirects", max)
+	}
+	switch req.URL.Scheme {
+	case "https":
+	case "http":
+		if from.Scheme == "https" && !p.AllowDowngrade {
+			return reject("downgrade from https to http")
+		}
+	default:
+		return reject("scheme %q not allowed", req.URL.Scheme)
+	}
+
+	origin := via[0].URL.Host
+	if p.AllowedHosts == nil || req.URL.Host == origin {
+		return nil
+	}
+	patterns := p.AllowedHosts(origin)
+	if patterns == nil {
+		return nil
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, req.URL.Host); ok {
+			return nil
+		}
+		if ok, _ := path.Match(pattern, req.URL.Hostname()); ok {
+			return nil
+		}
+	}
+	return reject("host %s not allowed for %s", req.URL.Host, origin)
+}
+
+// redactURL returns the URL without the query and the credentials, which may
+// contain signatures of storage backends.
+func redactURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+	if redacted.RawQuery != "" {
+		redacted.RawQuery = ""
+		return strings.TrimSuffix(redacted.String(), "?") + "?..."
+	}
+	return redacted.String()
+}
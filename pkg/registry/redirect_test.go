@@ -0,0 +1,112 @@
+package registry
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedirectPolicy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+	targetURL, _ := url.Parse(target.URL)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/blob":
+			http.Redirect(w, r, target.URL+"/blob?sig=secret", http.StatusTemporaryRedirect)
+		case "/ftp":
+			http.Redirect(w, r, "ftp://example.com/blob", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		name   string
+		policy RedirectPolicy
+		path   string
+		hop    int
+		reason string
+	}{
+		{
+			name: "default",
+			path: "/blob",
+		},
+		{
+			name:   "max redirects",
+			policy: RedirectPolicy{MaxRedirects: 3},
+			path:   "/loop",
+			hop:    4,
+			reason: "more than 3 redirects",
+		},
+		{
+			name:   "disabled",
+			policy: RedirectPolicy{MaxRedirects: -1},
+			path:   "/blob",
+			hop:    1,
+			reason: "redirects are disabled",
+		},
+		{
+			name:   "scheme",
+			path:   "/ftp",
+			hop:    1,
+			reason: `scheme "ftp" not allowed`,
+		},
+		{
+			name: "allowed host",
+			policy: RedirectPolicy{AllowedHosts: func(string) []string {
+				return []string{targetURL.Hostname()}
+			}},
+			path: "/blob",
+		},
+		{
+			name: "disallowed host",
+			policy: RedirectPolicy{AllowedHosts: func(string) []string {
+				return []string{"*.example.com"}
+			}},
+			path:   "/blob",
+			hop:    1,
+			reason: "not allowed for",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{CheckRedirect: tt.policy.CheckRedirect}
+			resp, err := client.Get(server.URL + tt.path)
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+				resp.Body.Close()
+				return
+			}
+			var redirectErr *RedirectError
+			if !errors.As(err, &redirectErr) {
+				t.Fatalf("Get() error = %v, want RedirectError", err)
+			}
+			if redirectErr.Hop != tt.hop {
+				t.Errorf("Hop = %d, want %d", redirectErr.Hop, tt.hop)
+			}
+			if !strings.Contains(redirectErr.Reason, tt.reason) {
+				t.Errorf("Reason = %q, want %q", redirectErr.Reason, tt.reason)
+			}
+			if strings.Contains(redirectErr.Error(), "secret") {
+				t.Errorf("Error() = %q, want query redacted", redirectErr.Error())
+			}
+		})
+	}
+
+	downgrade := &http.Request{URL: &url.URL{Scheme: "http", Host: "storage.example.com"}}
+	via := []*http.Request{{URL: &url.URL{Scheme: "https", Host: "registry.example.com"}}}
+	if err := (RedirectPolicy{}).CheckRedirect(downgrade, via); err == nil {
+		t.Error("CheckRedirect() downgrade error = nil")
+	}
+	if err := (RedirectPolicy{AllowDowngrade: true}).CheckRedirect(downgrade, via); err != nil {
+		t.Errorf("CheckRedirect() allowed downgrade error = %v", err)
+	}
+}
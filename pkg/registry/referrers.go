@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// maxReferrersSize limits the size of a referrers index to be read.
+const maxReferrersSize = 4 * 1024 * 1024
+
+// Referrer describes a manifest referring to another manifest.
+type Referrer struct {
+	ocispec.Descriptor
+
+	// ArtifactType is the type of the referring artifact.
+	ArtifactType string `json:"artifactType,omitempty"`
+}
+
+// Referrers lists the manifests referring to the manifest identified by desc
+// in the repository of ref, using the referrers API of the OCI distribution
+// specification. Registries without the referrers API are queried by the
+// referrers tag schema instead.
+func (c *Client) Referrers(ctx context.Context, ref string, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+	referrers, err := c.ListReferrers(ctx, ref, desc, "")
+	if err != nil {
+		return nil, err
+	}
+	descs := make([]ocispec.Descriptor, 0, len(referrers))
+	for _, referrer := range referrers {
+		descs = append(descs, referrer.Descriptor)
+	}
+	return descs, nil
+}
+
+// ListReferrers lists the referrers of the manifest identified by desc in the
+// repository of ref with their artifact types like Referrers. Only referrers
+// of the artifact type are listed if specified.
+func (c *Client) ListReferrers(ctx context.Context, ref string, desc ocispec.Descriptor, artifactType string) ([]Referrer, error) {
+	repo, err := parseRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	path := "/" + repo.name + "/referrers/" + desc.Digest.String()
+	if artifactType != "" {
+		path += "?" + url.Values{"artifactType": []string{artifactType}}.Encode()
+	}
+	referrers, err := c.fetchIndex(ctx, repo, path)
+	if err != nil && errdefs.IsNotFound(err) {
+		// fall back to the referrers tag schema
+		referrers, err = c.fetchIndex(ctx, repo, "/"+repo.name+"/manifests/"+ReferrersTag(desc.Digest))
+		if errdefs.IsNotFound(err) {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return filterReferrers(referrers, artifactType), nil
+}
+
+// filterReferrers filters the referrers by the artifact type since the
+// registry may not apply the filter.
+func filterReferrers(referrers []Referrer, artifactType string) []Referrer {
+	if artifactType == "" {
+		return referrers
+	}
+	var filtered []Referrer
+	for _, referrer := range referrers {
+		if referrer.ArtifactType == artifactType {
+			filtered = append(filtered, referrer)
+		}
+	}
+	return filtered
+}
+
+// ReferrersTag returns the tag of the index holding the referrers of the
+// given digest for registries without the referrers API.
+// Reference: https://github.com/opencontainers/distribution-spec/blob/main/spec.md#referrers-tag-schema
+func ReferrersTag(dgst digest.Digest) string {
+	tag := dgst.Algorithm().String() + "-" + dgst.Encoded()
+	if len(tag) > 128 {
+		tag = tag[:128]
+	}
+	return tag
+}
+
+func (c *Client) fetchIndex(ctx context.Context, repo repository, path string) ([]Referrer, error) {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		host:   repo.host,
+		path:   path,
+		header: http.Header{
+			"Accept": []string{ocispec.MediaTypeImageIndex},
+		},
+	}, repo.scope("pull"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	if mediaType := resp.Header.Get("Content-Type"); mediaType != "" && !strings.HasPrefix(mediaType, ocispec.MediaTypeImageIndex) {
+		return nil, errors.Wrapf(errdefs.ErrNotFound, "unexpected media type %s", mediaType)
+	}
+
+	var index struct {
+		Manifests []Referrer `json:"manifests"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxReferrersSize)).Decode(&index); err != nil {
+		return nil, err
+	}
+	return index.Manifests, nil
+}
+
+// AddReferrer adds the referrer to the index tagged by the referrers tag
+// schema for the subject, so that the referrer can be discovered on
+// registries without the referrers API. Registries with the referrers API are
+// left untouched since they index the referrers on their own.
+func (c *Client) AddReferrer(ctx context.Context, ref string, subject ocispec.Descriptor, referrer Referrer) error {
+	repo, err := parseRepository(ref)
+	if err != nil {
+		return err
+	}
+	if _, err := c.fetchIndex(ctx, repo, "/"+repo.name+"/referrers/"+subject.Digest.String()); err == nil || !errdefs.IsNotFound(err) {
+		return err
+	}
+
+	tag := ReferrersTag(subject.Digest)
+	referrers, err := c.fetchIndex(ctx, repo, "/"+repo.name+"/manifests/"+tag)
+	if err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+	for _, r := range referrers {
+		if r.Digest == referrer.Digest {
+			return nil
+		}
+	}
+	index := struct {
+		SchemaVersion int        `json:"schemaVersion"`
+		MediaType     string     `json:"mediaType"`
+		Manifests     []Referrer `json:"manifests"`
+	}{
+		SchemaVersion: 2,
+		MediaType:     ocispec.MediaTypeImageIndex,
+		Manifests:     append(referrers, referrer),
+	}
+	content, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(content),
+		Size:      int64(len(content)),
+	}
+	return c.PushManifest(ctx, repo.host+"/"+repo.name+":"+tag, desc, content)
+}
@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+// Tags lists the tags of the repository of ref. The pages returned by the
+// registry are followed until all tags are listed.
+func (c *Client) Tags(ctx context.Context, ref string) ([]string, error) {
+	return c.TagsAfter(ctx, ref, "")
+}
+
+// TagsAfter lists the tags of the repository of ref, which are
+// lexically after the last tag if specified.
+func (c *Client) TagsAfter(ctx context.Context, ref string, last string) ([]string, error) {
+	repo, err := parseRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.list(ctx, repo.host, "/"+repo.name+"/tags/list", last, repo.scope("pull"), "tags")
+}
+
+// Repositories lists the repositories of the registry host with the catalog
+// API, which are lexically after the last repository if specified. The pages
+// returned by the registry are followed until all repositories are listed.
+func (c *Client) Repositories(ctx context.Context, host string, last string) ([]string, error) {
+	return c.list(ctx, host, "/_catalog", last, "registry:catalog:*", "repositories")
+}
+
+// list lists the names under the key of the paginated responses.
+func (c *Client) list(ctx context.Context, host, path, last, scope, key string) ([]string, error) {
+	if last != "" {
+		path += "?last=" + url.QueryEscape(last)
+	}
+	var names []string
+	for path != "" {
+		resp, err := c.do(ctx, &request{
+			method: http.MethodGet,
+			host:   host,
+			path:   path,
+		}, scope)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return nil, responseError(resp)
+		}
+
+		var page map[string]json.RawMessage
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		var pageNames []string
+		if raw, ok := page[key]; ok {
+			if err := json.Unmarshal(raw, &pageNames); err != nil {
+				return nil, err
+			}
+		}
+		for _, name := range pageNames {
+			// registries may ignore `last`
+			if last == "" || name > last {
+				names = append(names, name)
+			}
+		}
+
+		if path, err = nextPage(resp); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// IsDigestTag checks if the tag is derived from a digest, such as the tags of
+// the referrers tag schema, e.g. `sha256-<encoded>`, optionally with a
+// suffix, e.g. `sha256-<encoded>.sig`.
+func IsDigestTag(tag string) bool {
+	if i := strings.Index(tag, "."); i >= 0 {
+		tag = tag[:i]
+	}
+	parts := strings.SplitN(tag, "-", 2)
+	if len(parts) != 2 || !digest.Algorithm(parts[0]).Available() {
+		return false
+	}
+	return digestTagEncoded.MatchString(parts[1])
+}
+
+var digestTagEncoded = regexp.MustCompile(`^[a-f0-9]{32,}$`)
+
+// nextPage returns the path of the next page indicated by the Link header,
+// relative to the API root. Empty path is returned for the last page.
+// Reference: https://docs.docker.com/registry/spec/api/#pagination
+func nextPage(resp *http.Response) (string, error) {
+	link := resp.Header.Get("Link")
+	if link == "" {
+		return "", nil
+	}
+	start, end := strings.Index(link, "<"), strings.Index(link, ">")
+	if start < 0 || end < start || !strings.Contains(link[end:], `rel="next"`) {
+		return "", nil
+	}
+	u, err := resp.Request.URL.Parse(link[start+1 : end])
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(u.Path, "/v2") + queryString(u), nil
+}
+
+func queryString(u *url.URL) string {
+	if u.RawQuery == "" {
+		return ""
+	}
+	return "?" + u.RawQuery
+}
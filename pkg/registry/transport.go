@@ -0,0 +1,372 @@
+package registry
+
+import (
+	"context"
+	"crypto/x509"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/containerd/containerd/log"
+	"github.com/pkg/errors"
+)
+
+// Transport defaults
+const (
+	DefaultMaxRetries       = 3
+	DefaultRetryBackoff     = 200 * time.Millisecond
+	DefaultMaxRetryBackoff  = 5 * time.Second
+	DefaultRetryJitter      = 0.2
+	DefaultRetryBudget      = 20
+	DefaultFailureThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// RetryBudget limits the total number of retries shared by the requests of an
+// operation, so that batch operations against an unhealthy registry fail fast
+// instead of retrying every item. The budget is scoped to the operation by its
+// context, see WithRetryBudget.
+type RetryBudget struct {
+	remaining int64
+}
+
+// NewRetryBudget creates a retry budget allowing n retries in total.
+func NewRetryBudget(n int) *RetryBudget {
+	return &RetryBudget{
+		remaining: int64(n),
+	}
+}
+
+// NewRetryBudgetWithRetry creates the default retry budget, which allows at
+// least the retries of a single request.
+func NewRetryBudgetWithRetry(opts RetryOptions) *RetryBudget {
+	budget := DefaultRetryBudget
+	if opts.MaxRetries > budget {
+		budget = opts.MaxRetries
+	}
+	return NewRetryBudget(budget)
+}
+
+type retryBudgetKey struct{}
+
+// WithRetryBudget returns a context whose requests draw their retries from the
+// budget.
+func WithRetryBudget(ctx context.Context, budget *RetryBudget) context.Context {
+	return context.WithValue(ctx, retryBudgetKey{}, budget)
+}
+
+// retryBudgetFrom returns the retry budget of the context, nil if unlimited.
+func retryBudgetFrom(ctx context.Context) *RetryBudget {
+	budget, _ := ctx.Value(retryBudgetKey{}).(*RetryBudget)
+	return budget
+}
+
+// take consumes one retry from the budget, reporting whether it is allowed.
+func (b *RetryBudget) take() bool {
+	return atomic.AddInt64(&b.remaining, -1) >= 0
+}
+
+// RetryOptions configures the retries of transient failures, such as
+// connection errors and 429, 502, 503 or 504 responses.
+type RetryOptions struct {
+	// MaxRetries is the maximum number of retries per request. Retries are
+	// disabled if not positive.
+	MaxRetries int
+	// Backoff is the initial wait before retrying, doubled on every retry up
+	// to MaxBackoff. The wait requested by the Retry-After header of the
+	// response takes precedence, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Jitter randomizes the backoff by up to the fraction of it, e.g. 0.2 for
+	// ±20%, so that clients do not retry in lockstep.
+	Jitter float64
+}
+
+// DefaultRetryOptions returns the default retry options.
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		MaxRetries: DefaultMaxRetries,
+		Backoff:    DefaultRetryBackoff,
+		MaxBackoff: DefaultMaxRetryBackoff,
+		Jitter:     DefaultRetryJitter,
+	}
+}
+
+// Transport is an http.RoundTripper retrying transient failures of idempotent
+// requests, i.e. GET, HEAD and PUT, with exponential backoff. Retries are drawn
+// from the retry budget of the request context, if any, and requests to a host
+// are rejected with ErrCircuitOpen after consecutive failures, until a probe
+// request succeeds after the cooldown.
+type Transport struct {
+	// Base is the underlying round tripper. http.DefaultTransport is used if
+	// nil.
+	Base http.RoundTripper
+	// MaxRetries is the maximum number of retries per request.
+	MaxRetries int
+	// Backoff is the initial wait before retrying, doubled on every retry up
+	// to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Jitter randomizes the backoff by up to the fraction of it.
+	Jitter float64
+	// FailureThreshold is the number of consecutive failures opening the
+	// circuit of a host. The circuit breaker is disabled if not positive.
+	FailureThreshold int
+	// Cooldown is the time the circuit stays open before allowing a probe.
+	Cooldown time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// NewTransport creates a retrying transport with the default settings.
+func NewTransport(base http.RoundTripper) *Transport {
+	return NewTransportWithRetry(base, DefaultRetryOptions())
+}
+
+// NewTransportWithRetry creates a retrying transport with the retry options.
+func NewTransportWithRetry(base http.RoundTripper, opts RetryOptions) *Transport {
+	return &Transport{
+		Base:             base,
+		MaxRetries:       opts.MaxRetries,
+		Backoff:          opts.Backoff,
+		MaxBackoff:       opts.MaxBackoff,
+		Jitter:           opts.Jitter,
+		FailureThreshold: DefaultFailureThreshold,
+		Cooldown:         DefaultBreakerCooldown,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	breaker := t.breaker(req.URL.Host)
+	backoff := t.Backoff
+	for attempt := 0; ; attempt++ {
+		if !breaker.allow() {
+			return nil, errors.Wrapf(ErrCircuitOpen, "%s", req.URL.Host)
+		}
+		resp, err := t.base().RoundTrip(req)
+		if ctx.Err() != nil {
+			// cancellation says nothing about the health of the host
+			breaker.release()
+			return resp, err
+		}
+		if !isTransientFailure(resp, err) {
+			breaker.success()
+			return resp, err
+		}
+		breaker.failure()
+
+		if attempt >= t.MaxRetries || !isIdempotent(req) || !isReplayable(req) {
+			return resp, err
+		}
+		if budget := retryBudgetFrom(ctx); budget != nil && !budget.take() {
+			log.G(ctx).WithField("host", req.URL.Host).Warn("retry budget exhausted")
+			return resp, err
+		}
+
+		wait := retryAfter(resp, jitter(backoff, t.Jitter), t.MaxBackoff)
+		log.G(ctx).WithError(err).WithField("url", req.URL).Debugf("retrying in %v", wait)
+		if resp != nil {
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if backoff *= 2; t.MaxBackoff > 0 && backoff > t.MaxBackoff {
+			backoff = t.MaxBackoff
+		}
+	}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// breaker returns the circuit breaker of the host.
+func (t *Transport) breaker(host string) *circuitBreaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.breakers == nil {
+		t.breakers = make(map[string]*circuitBreaker)
+	}
+	b, ok := t.breakers[host]
+	if !ok {
+		b = &circuitBreaker{
+			threshold: t.FailureThreshold,
+			cooldown:  t.Cooldown,
+		}
+		t.breakers[host] = b
+	}
+	return b
+}
+
+// isTransientFailure reports whether the request failed in a way worth
+// retrying. 500 is not retried as it usually reports a bug of the registry
+// rather than a temporary condition.
+func isTransientFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !isCertificateError(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isCertificateError reports whether the error is caused by the registry
+// certificate, which is not going to be resolved by retrying.
+func isCertificateError(err error) bool {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		invalid          x509.CertificateInvalidError
+		hostname         x509.HostnameError
+	)
+	return errors.As(err, &unknownAuthority) || errors.As(err, &invalid) || errors.As(err, &hostname)
+}
+
+// isIdempotent reports whether the request can be sent again without side
+// effects. Upload sessions started by POST and appended by PATCH are not.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodPut:
+		return true
+	}
+	return false
+}
+
+// isReplayable reports whether the request body can be sent again.
+func isReplayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind returns a copy of the request with a fresh body.
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Body = body
+	return req, nil
+}
+
+// retryAfter returns the wait time requested by the server in seconds or as
+// an HTTP date, if any and not exceeding max. Otherwise, the backoff is
+// returned.
+func retryAfter(resp *http.Response, backoff, max time.Duration) time.Duration {
+	if resp == nil {
+		return backoff
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return backoff
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return backoff
+		}
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		if wait = time.Until(date); wait < 0 {
+			wait = 0
+		}
+	} else {
+		return backoff
+	}
+	if max <= 0 || wait <= max {
+		return wait
+	}
+	return max
+}
+
+// jitter randomizes the backoff by up to the fraction of it.
+func jitter(backoff time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || backoff <= 0 {
+		return backoff
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	delta := (rand.Float64()*2 - 1) * fraction * float64(backoff)
+	return backoff + time.Duration(delta)
+}
+
+// circuitBreaker tracks consecutive failures of a host.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow reports whether a request may be sent. Once the cooldown of an open
+// circuit elapsed, a single probe request is let through.
+func (b *circuitBreaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// success closes the circuit.
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+// failure records a failure, opening the circuit if the threshold is reached
+// or a probe failed.
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+// release gives up a probe without a verdict.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
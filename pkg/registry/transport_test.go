@@ -0,0 +1,223 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestTransport() *Transport {
+	return &Transport{
+		MaxRetries:       2,
+		Backoff:          time.Millisecond,
+		FailureThreshold: 5,
+		Cooldown:         50 * time.Millisecond,
+	}
+}
+
+func TestTransportRetry(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTestTransport()}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Get() status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if count != 3 {
+		t.Errorf("requests = %d, want 3", count)
+	}
+}
+
+func TestTransportBudgetAndBreaker(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	transport := newTestTransport()
+	client := &http.Client{Transport: transport}
+	ctx := WithRetryBudget(context.Background(), NewRetryBudget(3))
+
+	// first request retries twice, the second one exhausts the budget
+	for i, want := range []int32{3, 5} {
+		resp, err := get(ctx, client, server.URL)
+		if err != nil {
+			t.Fatalf("Get() #%d error = %v", i, err)
+		}
+		resp.Body.Close()
+		if count != want {
+			t.Errorf("requests after #%d = %d, want %d", i, count, want)
+		}
+	}
+
+	// the circuit is open now
+	if _, err := get(ctx, client, server.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Get() error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if count != 5 {
+		t.Errorf("requests with open circuit = %d, want 5", count)
+	}
+
+	// a single probe is allowed after the cooldown
+	time.Sleep(transport.Cooldown)
+	resp, err := get(ctx, client, server.URL)
+	if err != nil {
+		t.Fatalf("Get() probe error = %v", err)
+	}
+	resp.Body.Close()
+	if count != 6 {
+		t.Errorf("requests after probe = %d, want 6", count)
+	}
+}
+
+func TestTransportRetryBudgetScope(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	transport := newTestTransport()
+	transport.FailureThreshold = 0
+	client := &http.Client{Transport: transport}
+
+	// the budget of an operation does not affect the other operations
+	for i, want := range []int32{2, 4} {
+		ctx := WithRetryBudget(context.Background(), NewRetryBudget(1))
+		resp, err := get(ctx, client, server.URL)
+		if err != nil {
+			t.Fatalf("Get() #%d error = %v", i, err)
+		}
+		resp.Body.Close()
+		if count != want {
+			t.Errorf("requests after operation #%d = %d, want %d", i, count, want)
+		}
+	}
+
+	// requests without a budget retry up to the max retries
+	resp, err := get(context.Background(), client, server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if count != 7 {
+		t.Errorf("requests without budget = %d, want 7", count)
+	}
+}
+
+func TestTransportRetryIdempotent(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	transport := newTestTransport()
+	transport.FailureThreshold = 0
+	client := &http.Client{Transport: transport}
+	for _, tc := range []struct {
+		method string
+		want   int32
+	}{
+		{http.MethodGet, 3},
+		{http.MethodHead, 3},
+		{http.MethodPut, 3},
+		{http.MethodPost, 1},
+		{http.MethodPatch, 1},
+	} {
+		atomic.StoreInt32(&count, 0)
+		req, err := http.NewRequest(tc.method, server.URL, http.NoBody)
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s error = %v", tc.method, err)
+		}
+		resp.Body.Close()
+		if count != tc.want {
+			t.Errorf("%s requests = %d, want %d", tc.method, count, tc.want)
+		}
+	}
+}
+
+func TestTransportInternalServerError(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTestTransport()}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if count != 1 {
+		t.Errorf("requests = %d, want 1", count)
+	}
+}
+
+func get(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+func TestRetryAfterAndJitter(t *testing.T) {
+	backoff, max := 100*time.Millisecond, 10*time.Second
+	for _, tc := range []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", backoff},
+		{"2", 2 * time.Second},
+		{"60", max},
+		{"invalid", backoff},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0},
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		if tc.header != "" {
+			resp.Header.Set("Retry-After", tc.header)
+		}
+		if got := retryAfter(resp, backoff, max); got != tc.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", time.Now().Add(5*time.Second).UTC().Format(http.TimeFormat))
+	if got := retryAfter(resp, backoff, max); got <= 3*time.Second || got > 5*time.Second {
+		t.Errorf("retryAfter(date) = %v, want about 5s", got)
+	}
+
+	for i := 0; i < 100; i++ {
+		if got := jitter(backoff, 0.2); got < 80*time.Millisecond || got > 120*time.Millisecond {
+			t.Fatalf("jitter() = %v, want within 20%% of %v", got, backoff)
+		}
+	}
+}
@@ -0,0 +1,492 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/log"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// Chunked upload defaults
+const (
+	DefaultChunkSize         = 64 * 1024 * 1024
+	DefaultChunkRetries      = 5
+	DefaultChunkRetryBackoff = time.Second
+)
+
+// ChunkedUploader uploads blobs in chunks using PATCH requests. Failed chunks
+// are retried from the offset confirmed by the registry. The upload sessions
+// are persisted in the state directory, so that an interrupted upload is
+// resumed by the next push of the same blob.
+type ChunkedUploader struct {
+	client    *Client
+	chunkSize int64
+	stateDir  string
+}
+
+// NewChunkedUploader creates an uploader with the given chunk size. Upload
+// sessions are not persisted if the state directory is empty.
+func NewChunkedUploader(client *Client, chunkSize int64, stateDir string) *ChunkedUploader {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	return &ChunkedUploader{
+		client:    client,
+		chunkSize: chunkSize,
+		stateDir:  stateDir,
+	}
+}
+
+// ChunkSize returns the size of the chunks.
+func (u *ChunkedUploader) ChunkSize() int64 {
+	return u.chunkSize
+}
+
+// Writer returns a writer uploading the blob described by desc to the
+// repository of ref. The upload continues from the persisted session of the
+// blob if it is still alive. An errdefs.ErrAlreadyExists error is returned if
+// the blob exists in the repository.
+func (u *ChunkedUploader) Writer(ctx context.Context, ref string, desc ocispec.Descriptor) (content.Writer, error) {
+	if _, err := u.client.StatBlob(ctx, ref, desc.Digest); err == nil {
+		return nil, errors.Wrapf(errdefs.ErrAlreadyExists, "blob %s", desc.Digest)
+	} else if !errdefs.IsNotFound(err) {
+		return nil, err
+	}
+	session, err := u.NewUploadSession(ref, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	var statePath string
+	if u.stateDir != "" {
+		statePath = filepath.Join(u.stateDir, digest.FromString(session.state.Repository+"@"+desc.Digest.String()).Encoded()+".json")
+		if err := restoreUploadSession(ctx, session, statePath); err != nil {
+			log.G(ctx).WithError(err).Debug("discarding upload session")
+			session.state.Location = ""
+			session.state.Offset = 0
+		}
+	}
+	w := session.writer(ctx)
+	w.statePath = statePath
+	if session.location() == "" {
+		if err := w.start(); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// restoreUploadSession restores the session persisted in the file, if any.
+func restoreUploadSession(ctx context.Context, session *UploadSession, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	if err := session.Restore(ctx, file); err != nil {
+		return err
+	}
+	log.G(ctx).WithField("offset", session.Offset()).Debug("resuming upload")
+	return nil
+}
+
+// UploadSession is a chunked upload of a blob. Its state can be saved with
+// Save, and restored with Restore by another process, so that long running
+// uploads survive restarts.
+type UploadSession struct {
+	uploader *ChunkedUploader
+	repo     repository
+	desc     ocispec.Descriptor
+	lock     sync.Mutex
+	state    uploadState
+}
+
+// uploadState is the saved state of an upload session.
+type uploadState struct {
+	Repository string        `json:"repository"`
+	Digest     digest.Digest `json:"digest"`
+	Location   string        `json:"location"`
+	Offset     int64         `json:"offset"`
+}
+
+// NewUploadSession creates a session uploading the blob described by desc to
+// the repository of ref. The upload is started by Writer unless the session
+// is restored.
+func (u *ChunkedUploader) NewUploadSession(ref string, desc ocispec.Descriptor) (*UploadSession, error) {
+	repo, err := parseRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadSession{
+		uploader: u,
+		repo:     repo,
+		desc:     desc,
+		state: uploadState{
+			Repository: repo.host + "/" + repo.name,
+			Digest:     desc.Digest,
+		},
+	}, nil
+}
+
+// Offset returns the size of the content accepted by the registry. The writer
+// of the session expects the content from the offset.
+func (s *UploadSession) Offset() int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.state.Offset
+}
+
+// Save writes the state of the session. It is safe to call while writing.
+func (s *UploadSession) Save(w io.Writer) error {
+	s.lock.Lock()
+	data, err := json.Marshal(s.state)
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Restore reads the state written by Save, and queries the offset of the
+// upload from the registry. An error is returned if the state is of another
+// blob, or if the upload is no longer alive.
+func (s *UploadSession) Restore(ctx context.Context, r io.Reader) error {
+	var state uploadState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return err
+	}
+	if state.Repository != s.state.Repository || state.Digest != s.state.Digest {
+		return errors.New("upload session mismatch")
+	}
+	if state.Location == "" {
+		return errors.New("upload session not started")
+	}
+	s.setLocation(state.Location)
+	return s.writer(ctx).queryOffset()
+}
+
+// Writer returns a writer uploading the content from the offset of the
+// session. The upload is started if the session is not restored.
+func (s *UploadSession) Writer(ctx context.Context) (content.Writer, error) {
+	w := s.writer(ctx)
+	if s.location() == "" {
+		if err := w.start(); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+func (s *UploadSession) writer(ctx context.Context) *chunkedWriter {
+	now := time.Now()
+	return &chunkedWriter{
+		ctx:      ctx,
+		uploader: s.uploader,
+		repo:     s.repo,
+		desc:     s.desc,
+		session:  s,
+		started:  now,
+		updated:  now,
+	}
+}
+
+func (s *UploadSession) location() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.state.Location
+}
+
+func (s *UploadSession) setLocation(location string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.state.Location = location
+}
+
+func (s *UploadSession) setOffset(offset int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.state.Offset = offset
+}
+
+// chunkedWriter is a content.Writer uploading in chunks.
+type chunkedWriter struct {
+	ctx       context.Context
+	uploader  *ChunkedUploader
+	repo      repository
+	desc      ocispec.Descriptor
+	session   *UploadSession
+	statePath string
+	buf       []byte
+	started   time.Time
+	updated   time.Time
+}
+
+// start starts a new upload session.
+func (w *chunkedWriter) start() error {
+	resp, err := w.uploader.client.do(w.ctx, &request{
+		method: http.MethodPost,
+		host:   w.repo.host,
+		path:   "/" + w.repo.name + "/blobs/uploads/",
+	}, w.repo.scope("pull", "push"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return responseError(resp)
+	}
+	w.session.setLocation("")
+	if err := w.updateLocation(resp); err != nil {
+		return err
+	}
+	w.session.setOffset(0)
+	return w.save()
+}
+
+// queryOffset updates the offset with the upload status of the registry.
+func (w *chunkedWriter) queryOffset() error {
+	resp, err := w.uploader.client.do(w.ctx, &request{
+		method: http.MethodGet,
+		host:   w.repo.host,
+		path:   w.session.location(),
+	}, w.repo.scope("pull", "push"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return responseError(resp)
+	}
+	if err := w.updateLocation(resp); err != nil {
+		return err
+	}
+	return w.updateOffset(resp)
+}
+
+// updateLocation updates the location of the session from the response.
+func (w *chunkedWriter) updateLocation(resp *http.Response) error {
+	location := resp.Header.Get("Location")
+	if location == "" {
+		if w.session.location() == "" {
+			return errors.Errorf("%s %s: missing upload location", resp.Request.Method, resp.Request.URL)
+		}
+		return nil
+	}
+	u, err := resp.Request.URL.Parse(location)
+	if err != nil {
+		return err
+	}
+	w.session.setLocation(strings.TrimPrefix(u.Path, "/v2") + queryString(u))
+	return nil
+}
+
+// updateOffset updates the offset from the Range header of the response,
+// which is in the form of `0-<last byte>`.
+func (w *chunkedWriter) updateOffset(resp *http.Response) error {
+	ranges := resp.Header.Get("Range")
+	if ranges == "" {
+		w.session.setOffset(0)
+		return nil
+	}
+	i := strings.LastIndex(ranges, "-")
+	end, err := strconv.ParseInt(ranges[i+1:], 10, 64)
+	if i < 0 || err != nil {
+		return errors.Errorf("%s %s: invalid range %q", resp.Request.Method, resp.Request.URL, ranges)
+	}
+	w.session.setOffset(end + 1)
+	return nil
+}
+
+// save persists the session in the state directory.
+func (w *chunkedWriter) save() error {
+	if w.statePath == "" {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := w.session.Save(&buf); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(w.statePath), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(w.statePath, buf.Bytes(), 0600)
+}
+
+// Write buffers the data and uploads the full chunks.
+func (w *chunkedWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for int64(len(w.buf)) >= w.uploader.chunkSize {
+		if err := w.uploadChunk(w.buf[:w.uploader.chunkSize]); err != nil {
+			return 0, err
+		}
+		w.buf = append(w.buf[:0], w.buf[w.uploader.chunkSize:]...)
+	}
+	w.updated = time.Now()
+	return len(p), nil
+}
+
+// uploadChunk uploads the chunk starting at the current offset. On failures,
+// the offset is queried and the rest of the chunk is retried.
+func (w *chunkedWriter) uploadChunk(chunk []byte) error {
+	start := w.session.Offset()
+	end := start + int64(len(chunk))
+	backoff := DefaultChunkRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := w.patch(chunk[w.session.Offset()-start:])
+		if err == nil {
+			if w.session.Offset() == end {
+				return w.save()
+			}
+			err = errors.Errorf("chunk accepted up to offset %d, expected %d", w.session.Offset(), end)
+		}
+		if attempt >= DefaultChunkRetries {
+			return err
+		}
+		log.G(w.ctx).WithError(err).WithField("offset", w.session.Offset()).Warn("retrying chunk")
+		select {
+		case <-time.After(backoff):
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		}
+		backoff *= 2
+		if err := w.queryOffset(); err != nil {
+			log.G(w.ctx).WithError(err).Debug("failed to query upload offset")
+			continue
+		}
+		if w.session.Offset() < start || w.session.Offset() > end {
+			return errors.Errorf("unexpected upload offset %d, expected %d-%d", w.session.Offset(), start, end)
+		}
+		if w.session.Offset() == end {
+			return w.save()
+		}
+	}
+}
+
+// patch sends the data at the current offset.
+func (w *chunkedWriter) patch(data []byte) error {
+	offset := w.session.Offset()
+	resp, err := w.uploader.client.do(w.ctx, &request{
+		method: http.MethodPatch,
+		host:   w.repo.host,
+		path:   w.session.location(),
+		header: http.Header{
+			"Content-Type":  []string{"application/octet-stream"},
+			"Content-Range": []string{fmt.Sprintf("%d-%d", offset, offset+int64(len(data))-1)},
+		},
+		body: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		},
+		size: int64(len(data)),
+	}, w.repo.scope("pull", "push"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return responseError(resp)
+	}
+	if err := w.updateLocation(resp); err != nil {
+		return err
+	}
+	if resp.Header.Get("Range") == "" {
+		w.session.setOffset(offset + int64(len(data)))
+		return nil
+	}
+	return w.updateOffset(resp)
+}
+
+// Commit uploads the remaining data and completes the upload.
+func (w *chunkedWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...content.Opt) error {
+	if len(w.buf) > 0 {
+		if err := w.uploadChunk(w.buf); err != nil {
+			return err
+		}
+		w.buf = w.buf[:0]
+	}
+	if size > 0 && size != w.session.Offset() {
+		return errors.Errorf("unexpected commit size %d, expected %d", w.session.Offset(), size)
+	}
+	if expected == "" {
+		expected = w.desc.Digest
+	}
+
+	location := w.session.location()
+	if strings.Contains(location, "?") {
+		location += "&"
+	} else {
+		location += "?"
+	}
+	location += "digest=" + url.QueryEscape(expected.String())
+	resp, err := w.uploader.client.do(ctx, &request{
+		method: http.MethodPut,
+		host:   w.repo.host,
+		path:   location,
+		header: http.Header{
+			"Content-Type": []string{"application/octet-stream"},
+		},
+	}, w.repo.scope("pull", "push"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return responseError(resp)
+	}
+	if w.statePath != "" {
+		os.Remove(w.statePath)
+	}
+	return nil
+}
+
+// Close keeps the upload session to be resumed.
+func (w *chunkedWriter) Close() error {
+	return nil
+}
+
+// Digest returns the digest of the blob being uploaded.
+func (w *chunkedWriter) Digest() digest.Digest {
+	return w.desc.Digest
+}
+
+// Status returns the progress of the upload.
+func (w *chunkedWriter) Status() (content.Status, error) {
+	return content.Status{
+		Ref:       w.session.location(),
+		Offset:    w.session.Offset() + int64(len(w.buf)),
+		Total:     w.desc.Size,
+		Expected:  w.desc.Digest,
+		StartedAt: w.started,
+		UpdatedAt: w.updated,
+	}, nil
+}
+
+// Truncate restarts the upload if size is zero. Other sizes are not
+// supported.
+func (w *chunkedWriter) Truncate(size int64) error {
+	if size != 0 {
+		return errors.Wrap(errdefs.ErrNotImplemented, "chunked upload truncate")
+	}
+	w.buf = w.buf[:0]
+	return w.start()
+}